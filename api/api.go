@@ -1,12 +1,17 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
+	"log"
+	"sort"
+	"strconv"
 	"strings"
+
+	"git.huawei.com/goclient/envelope"
+	"git.huawei.com/goclient/response"
+	"git.huawei.com/goclient/usercontract"
 	"git.huawei.com/goclient/utils"
-	"git.huawei.com/huaweichain/sdk/client"
-	"github.com/deatil/go-cryptobin/cryptobin/crypto"
 )
 
 type Message struct {
@@ -14,68 +19,276 @@ type Message struct {
 	TxHash      string //交易hash
 	Value       string //上链的内容
 	Timestamp   string //时间戳
+	// 存证已撤销时不再解密，Value为空
+	Revoked      bool   `json:",omitempty"`
+	RevokeReason string `json:",omitempty"`
 }
 
-const Key = "dfertf12dfertf12" //上链
+// Version 存证的一个历史版本，Value为解密后的内容
+type Version struct {
+	Value       string `json:"value"`       // 删除操作时为空
+	TxHash      string `json:"txHash"`      // 写入该版本的交易哈希
+	BlockHeight uint64 `json:"blockHeight"` // 交易所在区块高度
+	TxNum       int32  `json:"txNum"`       // 交易在区块中的序号
+	Timestamp   uint64 `json:"timestamp"`   // 交易时间戳
+	IsDeleted   bool   `json:"isDeleted"`
+}
 
-func SaveRecode(datakey string, data string) (string, error) {
-	gatewayClient, err := client.NewGatewayClient(utils.AppConfig().ConfigFilePath)
-	fmt.Println(err)
+// Revocation 存证的撤销记录
+type Revocation struct {
+	Reason    string `json:"reason"`
+	RevokedAt int64  `json:"revokedAt"` // 撤销时间，Unix秒
+}
+
+// 链会话，启动时通过SetSession设置
+var session *utils.Session
+
+// SetSession 设置所有链操作共用的链会话
+func SetSession(s *utils.Session) {
+	session = s
+}
+
+// 存证数据加解密，启动时通过SetCipher设置
+var cipher *envelope.Cipher
+
+// SetCipher 设置存证数据使用的信封加解密
+func SetCipher(c *envelope.Cipher) {
+	cipher = c
+}
+
+// Encrypt 使用随机数据密钥加密存证数据，返回信封密文
+func Encrypt(data string) (string, error) {
+	if cipher == nil {
+		log.Print("cipher is not initialized")
+		return "", utils.ErrorNew(response.CodeInternal, "数据加密失败")
+	}
+	sealed, err := cipher.Seal([]byte(data))
 	if err != nil {
-		fmt.Println("init new gateway client error")
-		return "", utils.ErrorNew(604, "上链失败，建议重试")
+		log.Printf("Encrypt error: %v", err)
+		return "", utils.ErrorNew(response.CodeInternal, "数据加密失败")
+	}
+	return sealed.String(), nil
+}
+
+// KeyLookup 根据交易哈希查询本地索引中的KEK标识和包装后的数据密钥，
+// 轮换KEK后链上密文携带的数据密钥仍由旧KEK包装，需优先使用本地索引中重新包装的结果
+type KeyLookup func(txHash string) (keyID string, wrappedKey []byte, ok bool)
+
+var keyLookup KeyLookup
+
+// SetKeyLookup 设置解密时查询本地索引数据密钥的方法
+func SetKeyLookup(f KeyLookup) {
+	keyLookup = f
+}
+
+// Decrypt 解密链上的存证数据，兼容旧版AES-ECB密文
+func Decrypt(value string) (string, error) {
+	return DecryptWithKey(value, "", nil)
+}
+
+// DecryptWithKey 使用指定的KEK标识和包装后的数据密钥解密，keyID为空时使用密文携带的数据密钥
+func DecryptWithKey(value string, keyID string, wrappedKey []byte) (string, error) {
+	if cipher == nil {
+		log.Print("cipher is not initialized")
+		return "", utils.ErrorNew(response.CodeInternal, "数据解密失败")
 	}
-	//背书节点
-	endorseNodes := strings.Split(utils.AppConfig().EndorserNodes, ",")
-	//创建网络
-	net, err := utils.NewNodes(gatewayClient, endorseNodes, utils.AppConfig().ConsensusNode)
+	plaintext, err := cipher.DecryptWithKey(value, keyID, wrappedKey)
 	if err != nil {
-		fmt.Println("new nodes network error")
-		return "", utils.ErrorNew(604, "上链失败，建议重试")
+		log.Printf("DecryptWithKey error: %v", err)
+		return "", utils.ErrorNew(response.CodeInternal, "数据解密失败")
+	}
+	return string(plaintext), nil
+}
+
+// Rewrap 使用当前KEK重新包装数据密钥
+func Rewrap(keyID string, wrappedKey []byte) (string, []byte, error) {
+	if cipher == nil {
+		log.Print("cipher is not initialized")
+		return "", nil, utils.ErrorNew(response.CodeInternal, "数据密钥包装失败")
 	}
+	newKeyID, wrapped, err := cipher.Rewrap(keyID, wrappedKey)
 	if err != nil {
-		fmt.Println(err)
-		return "", utils.ErrorNew(604, "上链失败，建议重试")
+		log.Printf("Rewrap error: %v", err)
+		return "", nil, utils.ErrorNew(response.CodeInternal, "数据密钥包装失败")
+	}
+	return newKeyID, wrapped, nil
+}
+
+// CurrentKeyID 新数据使用的KEK标识
+func CurrentKeyID() string {
+	if cipher == nil {
+		return ""
+	}
+	return cipher.KeyManager().CurrentKeyID()
+}
+
+// 解密交易中的存证数据，本地索引中有重新包装的数据密钥时优先使用
+func decryptTx(txHash string, value string) (string, error) {
+	if keyLookup != nil {
+		if keyID, wrappedKey, ok := keyLookup(txHash); ok {
+			return DecryptWithKey(value, keyID, wrappedKey)
+		}
+	}
+	return Decrypt(value)
+}
+
+// QueryCiphertext 查询交易写入的存证密文
+func QueryCiphertext(ctx context.Context, txHash string) (string, error) {
+	if session == nil {
+		log.Print("chain session is not initialized")
+		return "", utils.ErrorNew(response.CodeChainFailed, "查询失败")
 	}
-	//交易
-	txEvent, err := net.EventListener.EventAction.GetTxEventService(utils.AppConfig().ChainID)
+	txTool := utils.TxTool{}
+	tx, err := txTool.QueryTxByTxIDCtx(ctx, session.Client, utils.AppConfig(), txHash)
 	if err != nil {
-		fmt.Println("event action register tx event error")
-		return "", utils.ErrorNew(604, "上链失败，建议重试")
+		log.Printf("QueryCiphertext error: %v", err)
+		return "", utils.ErrorNew(response.CodeChainFailed, "查询失败")
+	}
+	keyValues, err := txTool.GetTxKeyValues(*tx)
+	if err != nil || len(keyValues) == 0 {
+		log.Printf("QueryCiphertext: no key values in tx %s, error: %v", txHash, err)
+		return "", utils.ErrorNew(response.CodeChainFailed, "查询失败")
 	}
-	defer txEvent.Close()
+	_, ciphertext := txRecord(keyValues)
+	return ciphertext, nil
+}
 
-	utils.SetTxEvent(txEvent)
+// 从交易写集中取出存证ID和密文：putRecord的写集还包含索引，取其中的结构化存证；
+// saveRecode写入的旧版交易只有一个键值对，键为存证ID，值即为密文
+func txRecord(keyValues []string) (string, string) {
+	for _, kv := range keyValues {
+		parts := strings.SplitN(kv, ": ", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if record, ok := usercontract.ParseRecord([]byte(parts[1])); ok {
+			return record.ID, record.Ciphertext
+		}
+	}
+	parts := strings.SplitN(keyValues[0], ": ", 2)
+	if len(parts) != 2 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
 
+func SaveRecode(datakey string, data string) (string, error) {
+	if session == nil {
+		log.Print("chain session is not initialized")
+		return "", utils.ErrorNew(response.CodeChainFailed, "上链失败，建议重试")
+	}
 	//创建hash值
 	var txHashID string
 	//_, txHashID, err = utils.Send(gatewayClient, net, utils.AppConfig(), "saveRecode",phone+" "+timestamp+";"+data)
-	_, txHashID, err = utils.Send(gatewayClient, net, utils.AppConfig(), "saveRecode", datakey+";"+data)
+	_, txHashID, err := session.Send("saveRecode", datakey+";"+data)
 	if err != nil {
-		fmt.Println(err)
-		return "", utils.ErrorNew(604, "上链失败，建议重试")
+		log.Printf("SaveRecode error: %v", err)
+		return "", utils.ErrorNew(response.CodeChainFailed, "上链失败，建议重试")
 	}
 	return txHashID, nil
 }
 
-// 根据手机号查询
-func QueryByPhone(txHashs []string) (string, error) {
-	gatewayClient, err := client.NewGatewayClient(utils.AppConfig().ConfigFilePath)
+// Recode 通过合约putRecord保存的结构化存证
+type Recode struct {
+	ID          string // 存证ID，即datakey
+	Owner       string // 所有者哈希
+	Ciphertext  string // 加密后的存证内容
+	ContentHash string // 明文的SHA-256
+	Timestamp   int64  // 本次写入时间，Unix秒
+}
+
+// SubmitRecode 保存结构化存证，通过progress回报背书和提交进度；
+// 交易落块但校验失败时返回*utils.TxStatusError，以便记录具体状态
+func SubmitRecode(ctx context.Context, rec Recode, progress utils.TxProgress) (string, error) {
+	if session == nil {
+		log.Print("chain session is not initialized")
+		return "", utils.ErrorNew(response.CodeChainFailed, "上链失败，建议重试")
+	}
+	args := strings.Join([]string{rec.ID, rec.Owner, rec.Ciphertext, rec.ContentHash, strconv.FormatInt(rec.Timestamp, 10)}, ";")
+	_, txHashID, err := session.SendWithProgressCtx(ctx, "putRecord", args, progress)
+	if err != nil {
+		log.Printf("SubmitRecode error: %v", err)
+		if statusErr, ok := err.(*utils.TxStatusError); ok {
+			return "", statusErr
+		}
+		return "", utils.ErrorNew(response.CodeChainFailed, "上链失败，建议重试")
+	}
+	return txHashID, nil
+}
+
+// QueryTxStatus 查询交易落块状态，返回common.TxStatus的名称
+func QueryTxStatus(ctx context.Context, txHash string) (string, error) {
+	if session == nil {
+		log.Print("chain session is not initialized")
+		return "", utils.ErrorNew(response.CodeChainFailed, "查询失败")
+	}
+	txResult, err := session.QueryTxResultCtx(ctx, txHash)
+	if err != nil {
+		log.Printf("QueryTxStatus error: %v", err)
+		return "", utils.ErrorNew(response.CodeChainFailed, "查询失败")
+	}
+	//交易哈希为空表示链上没有该交易
+	if len(txResult.TxHash) == 0 {
+		return "", utils.ErrorNew(response.CodeNotFound, "交易哈希不存在")
+	}
+	return txResult.Status.String(), nil
+}
+
+// NodeStatus 链会话使用的节点及全部节点的健康状态
+type NodeStatus struct {
+	Endorsers     []string           `json:"endorsers"`     // 配置或按背书策略选择的背书节点
+	Proposer      string             `json:"proposer"`      // 共识节点
+	EventListener string             `json:"eventListener"` // 当前订阅交易事件的节点
+	Nodes         []utils.NodeHealth `json:"nodes"`
+}
+
+// QueryNodeStatus 查询节点健康状态，不访问链
+func QueryNodeStatus() (*NodeStatus, error) {
+	if session == nil {
+		log.Print("chain session is not initialized")
+		return nil, utils.ErrorNew(response.CodeChainFailed, "查询失败")
+	}
+	return &NodeStatus{
+		Endorsers:     strings.Split(session.Config.EndorserNodes, ","),
+		Proposer:      session.Config.ConsensusNode,
+		EventListener: session.EventListener(),
+		Nodes:         session.NodeHealth(),
+	}, nil
+}
+
+// QueryBlockHeight 查询交易所在区块高度
+func QueryBlockHeight(ctx context.Context, txHash string) (uint64, error) {
+	if session == nil {
+		log.Print("chain session is not initialized")
+		return 0, utils.ErrorNew(response.CodeChainFailed, "查询失败")
+	}
+	blockTool := utils.BlockTool{}
+	block, err := blockTool.QueryBlockByTxIDCtx(ctx, session.Client, utils.AppConfig(), txHash)
 	if err != nil {
-		fmt.Println("init new gateway client error")
-		return "", utils.ErrorNew(604, "查询失败")
+		log.Printf("QueryBlockHeight error: %v", err)
+		return 0, utils.ErrorNew(response.CodeChainFailed, "查询失败")
+	}
+	return blockTool.GetNumber(*block), nil
+}
+
+// 根据手机号查询
+func QueryByPhone(ctx context.Context, txHashs []string) (string, error) {
+	if session == nil {
+		log.Print("chain session is not initialized")
+		return "", utils.ErrorNew(response.CodeChainFailed, "查询失败")
 	}
+	gatewayClient := session.Client
 	if len(txHashs) == 0 {
-		return "", utils.ErrorNew(602, "手机号不存在")
+		return "", utils.ErrorNew(response.CodePhoneNotFound, "手机号不存在")
 	}
 	result := []Message{}
 	for _, txHash := range txHashs {
 		//BlockHeight
 		blockTool := utils.BlockTool{}
-		block, err := blockTool.QueryBlockByTxID(gatewayClient, utils.AppConfig(), txHash)
+		block, err := blockTool.QueryBlockByTxIDCtx(ctx, gatewayClient, utils.AppConfig(), txHash)
 		if err != nil {
-			fmt.Println(err)
-			return "", utils.ErrorNew(604, "查询失败")
+			log.Printf("QueryByPhone error: %v", err)
+			return "", utils.ErrorNew(response.CodeChainFailed, "查询失败")
 		}
 		blockHeight := blockTool.GetNumber(*block)
 		// base64Hash := blockTool.GetBodyHash(*block)
@@ -84,51 +297,51 @@ func QueryByPhone(txHashs []string) (string, error) {
 		//TxHash
 		//value
 		txTool := utils.TxTool{}
-		tx, err := txTool.QueryTxByTxID(gatewayClient, utils.AppConfig(), txHash)
+		tx, err := txTool.QueryTxByTxIDCtx(ctx, gatewayClient, utils.AppConfig(), txHash)
 		if err != nil {
-			fmt.Println(err)
-			return "", utils.ErrorNew(604, "查询失败")
+			log.Printf("QueryByPhone error: %v", err)
+			return "", utils.ErrorNew(response.CodeChainFailed, "查询失败")
 		}
 		keyValues, err := txTool.GetTxKeyValues(*tx)
-		if err != nil {
-			fmt.Println(err)
-			return "", utils.ErrorNew(604, "查询失败")
+		if err != nil || len(keyValues) == 0 {
+			log.Printf("QueryByPhone: no key values in tx %s, error: %v", txHash, err)
+			return "", utils.ErrorNew(response.CodeChainFailed, "查询失败")
 		}
 
-		fmt.Println(keyValues[0])
 		//对数据解密
-		value := strings.Split(keyValues[0], ": ")[1]
-		fmt.Println(value)
-		crypderesult := crypto.FromBase64String(value).SetKey(Key).Aes().ECB().PKCS7Padding().Decrypt().ToString()
+		_, value := txRecord(keyValues)
+		crypderesult, err := decryptTx(txHash, value)
+		if err != nil {
+			return "", err
+		}
 		//TimeStamp
 		timeStamp, err := txTool.GetTimestamp(*tx)
 		if err != nil {
-			fmt.Println(err)
-			return "", utils.ErrorNew(604, "查询失败")
+			log.Printf("QueryByPhone error: %v", err)
+			return "", utils.ErrorNew(response.CodeChainFailed, "查询失败")
 		}
-		result = append(result, Message{int(blockHeight), txHash, crypderesult, timeStamp})
+		result = append(result, Message{BlockHeight: int(blockHeight), TxHash: txHash, Value: crypderesult, Timestamp: timeStamp})
 	}
 	resultString, err := json.Marshal(result)
 	if err != nil {
-		return "", utils.ErrorNew(604, "查询失败")
+		return "", utils.ErrorNew(response.CodeChainFailed, "查询失败")
 	}
-	fmt.Println(string(resultString))
 	return string(resultString), nil
 }
 
-func QueryByHash(txHash string) (string, error) {
-	gatewayClient, err := client.NewGatewayClient(utils.AppConfig().ConfigFilePath)
-	if err != nil {
-		fmt.Println("init new gateway client error")
-		return "", utils.ErrorNew(604, "查询失败")
+func QueryByHash(ctx context.Context, txHash string) (string, error) {
+	if session == nil {
+		log.Print("chain session is not initialized")
+		return "", utils.ErrorNew(response.CodeChainFailed, "查询失败")
 	}
+	gatewayClient := session.Client
 
 	//BlockNum
 	blockTool := utils.BlockTool{}
-	block, err := blockTool.QueryBlockByTxID(gatewayClient, utils.AppConfig(), txHash)
+	block, err := blockTool.QueryBlockByTxIDCtx(ctx, gatewayClient, utils.AppConfig(), txHash)
 	if err != nil {
-		fmt.Println(err)
-		return "", utils.ErrorNew(604, "查询失败")
+		log.Printf("QueryByHash error: %v", err)
+		return "", utils.ErrorNew(response.CodeChainFailed, "查询失败")
 	}
 	blockHeight := blockTool.GetNumber(*block)
 	// base64Hash := blockTool.GetBodyHash(*block)
@@ -138,40 +351,183 @@ func QueryByHash(txHash string) (string, error) {
 	//TxHash
 	//value
 	txTool := utils.TxTool{}
-	tx, err := txTool.QueryTxByTxID(gatewayClient, utils.AppConfig(), txHash)
+	tx, err := txTool.QueryTxByTxIDCtx(ctx, gatewayClient, utils.AppConfig(), txHash)
 	if err != nil {
-		fmt.Println(err)
-		return "", utils.ErrorNew(604, "查询失败")
+		log.Printf("QueryByHash error: %v", err)
+		return "", utils.ErrorNew(response.CodeChainFailed, "查询失败")
 	}
 	keyValues, err := txTool.GetTxKeyValues(*tx)
-	if err != nil {
-		fmt.Println(err)
-		return "", utils.ErrorNew(604, "查询失败")
+	if err != nil || len(keyValues) == 0 {
+		log.Printf("QueryByHash: no key values in tx %s, error: %v", txHash, err)
+		return "", utils.ErrorNew(response.CodeChainFailed, "查询失败")
 	}
 
-	//对数据解密
-	value := strings.Split(keyValues[0], ": ")[1]
-	fmt.Println(value)
-	crypderesult := crypto.FromBase64String(value).SetKey(Key).Aes().ECB().PKCS7Padding().Decrypt().ToString()
 	//TimeStamp
 	timeStamp, err := txTool.GetTimestamp(*tx)
 	if err != nil {
-		fmt.Println(err)
-		return "", utils.ErrorNew(604, "查询失败")
+		log.Printf("QueryByHash error: %v", err)
+		return "", utils.ErrorNew(response.CodeChainFailed, "查询失败")
 	}
-	result := &Message{int(blockHeight), txHash, crypderesult, timeStamp}
-	resultString, err := json.Marshal(result)
+	result := &Message{BlockHeight: int(blockHeight), TxHash: txHash, Timestamp: timeStamp}
+
+	//已撤销的存证只返回撤销状态，不再解密
+	recordID, value := txRecord(keyValues)
+	revocation, err := QueryRevocation(ctx, recordID)
 	if err != nil {
-		fmt.Println("转json失败")
 		return "", err
 	}
+	if revocation != nil {
+		result.Revoked, result.RevokeReason = true, revocation.Reason
+	} else {
+		//对数据解密
+		if result.Value, err = decryptTx(txHash, value); err != nil {
+			return "", err
+		}
+	}
+	resultString, err := json.Marshal(result)
+	if err != nil {
+		log.Printf("QueryByHash marshal result error: %v", err)
+		return "", utils.ErrorNew(response.CodeChainFailed, "查询失败")
+	}
+	return string(resultString), nil
+}
+
+// QueryHistory 通过合约history函数查询存证ID的全部历史版本并逐个解密
+func QueryHistory(ctx context.Context, recordID string) ([]Version, error) {
+	if session == nil {
+		log.Print("chain session is not initialized")
+		return nil, utils.ErrorNew(response.CodeChainFailed, "查询失败")
+	}
+	versions, err := queryVersions(ctx, recordID)
+	if err != nil {
+		return nil, err
+	}
+	history := make([]Version, 0, len(versions))
+	for _, v := range versions {
+		version := Version{TxHash: v.TxHash, BlockHeight: v.BlockNum, TxNum: v.TxNum, Timestamp: v.Timestamp, IsDeleted: v.IsDeleted}
+		if !v.IsDeleted {
+			ciphertext := v.Value
+			if record, ok := usercontract.ParseRecord([]byte(v.Value)); ok {
+				ciphertext = record.Ciphertext
+			}
+			value, err := decryptTx(v.TxHash, ciphertext)
+			if err != nil {
+				return nil, err
+			}
+			version.Value = value
+		}
+		history = append(history, version)
+	}
+	return history, nil
+}
+
+// 调用合约history函数
+func queryVersions(ctx context.Context, recordID string) ([]usercontract.Version, error) {
+	result, err := session.QueryCtx(ctx, "history", recordID)
+	if err != nil {
+		log.Printf("queryVersions error: %v", err)
+		return nil, utils.ErrorNew(response.CodeChainFailed, "查询失败")
+	}
+	var versions []usercontract.Version
+	if err := json.Unmarshal([]byte(result), &versions); err != nil {
+		log.Printf("queryVersions error: %v", err)
+		return nil, utils.ErrorNew(response.CodeChainFailed, "查询失败")
+	}
+	return versions, nil
+}
+
+// QueryByOwner 直接从链上按所有者查询最近修改的limit条存证，用于本地索引缺失时；
+// 交易哈希和区块高度取自每条存证最新的历史版本
+func QueryByOwner(ctx context.Context, owner string, limit int) (string, error) {
+	if session == nil {
+		log.Print("chain session is not initialized")
+		return "", utils.ErrorNew(response.CodeChainFailed, "查询失败")
+	}
+	result, err := session.QueryCtx(ctx, "listByOwner", owner)
+	if err != nil {
+		log.Printf("QueryByOwner error: %v", err)
+		return "", utils.ErrorNew(response.CodeChainFailed, "查询失败")
+	}
+	var records []usercontract.Record
+	if err := json.Unmarshal([]byte(result), &records); err != nil {
+		log.Printf("QueryByOwner error: %v", err)
+		return "", utils.ErrorNew(response.CodeChainFailed, "查询失败")
+	}
+	if len(records) == 0 {
+		return "", utils.ErrorNew(response.CodePhoneNotFound, "手机号不存在")
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].UpdatedAt > records[j].UpdatedAt
+	})
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+
+	messages := []Message{}
+	for _, record := range records {
+		versions, err := queryVersions(ctx, record.ID)
+		if err != nil {
+			return "", err
+		}
+		if len(versions) == 0 {
+			continue
+		}
+		latest := versions[len(versions)-1]
+		value, err := decryptTx(latest.TxHash, record.Ciphertext)
+		if err != nil {
+			return "", err
+		}
+		timeStamp := utils.FormatTime(utils.ParseNanosecond(int64(latest.Timestamp)))
+		messages = append(messages, Message{BlockHeight: int(latest.BlockNum), TxHash: latest.TxHash, Value: value, Timestamp: timeStamp})
+	}
+	resultString, err := json.Marshal(messages)
+	if err != nil {
+		return "", utils.ErrorNew(response.CodeChainFailed, "查询失败")
+	}
 	return string(resultString), nil
 }
 
+// RevokeRecode 在链上撤销存证，owner需与存证的所有者一致，reason不能包含分号
+func RevokeRecode(ctx context.Context, recordID string, owner string, reason string, timestamp int64) (string, error) {
+	if session == nil {
+		log.Print("chain session is not initialized")
+		return "", utils.ErrorNew(response.CodeChainFailed, "撤销失败")
+	}
+	args := strings.Join([]string{recordID, owner, reason, strconv.FormatInt(timestamp, 10)}, ";")
+	_, txHashID, err := session.SendCtx(ctx, "revokeRecode", args)
+	if err != nil {
+		log.Printf("RevokeRecode error: %v", err)
+		return "", utils.ErrorNew(response.CodeChainFailed, "撤销失败")
+	}
+	return txHashID, nil
+}
+
+// QueryRevocation 查询存证的撤销记录，未撤销时返回nil
+func QueryRevocation(ctx context.Context, recordID string) (*Revocation, error) {
+	if session == nil {
+		log.Print("chain session is not initialized")
+		return nil, utils.ErrorNew(response.CodeChainFailed, "查询失败")
+	}
+	result, err := session.QueryCtx(ctx, "queryRevocation", recordID)
+	if err != nil {
+		log.Printf("QueryRevocation error: %v", err)
+		return nil, utils.ErrorNew(response.CodeChainFailed, "查询失败")
+	}
+	if result == "" {
+		return nil, nil
+	}
+	var revocation usercontract.Revocation
+	if err := json.Unmarshal([]byte(result), &revocation); err != nil {
+		log.Printf("QueryRevocation error: %v", err)
+		return nil, utils.ErrorNew(response.CodeChainFailed, "查询失败")
+	}
+	return &Revocation{Reason: revocation.Reason, RevokedAt: revocation.RevokedAt}, nil
+}
+
 func ChangeRecode(datakey string, data string) (string, error) {
 	txHashID, err := SaveRecode(datakey, data)
 	if err != nil {
-		return "", utils.ErrorNew(604, "修改失败")
+		return "", utils.ErrorNew(response.CodeChainFailed, "修改失败")
 	}
 	return txHashID, nil
 
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"flag"
+	"strconv"
+	"strings"
+
+	"git.huawei.com/goclient/utils"
+	"git.huawei.com/huaweichain/proto/common"
+	"git.huawei.com/huaweichain/proto/nodeservice"
+)
+
+type blockView struct {
+	Number     uint64   `json:"number"`
+	Timestamp  string   `json:"timestamp"`
+	ParentHash string   `json:"parentHash"`
+	BodyHash   string   `json:"bodyHash"`
+	TxIDs      []string `json:"txIds"`
+}
+
+type txView struct {
+	TxID      string   `json:"txId"`
+	Timestamp string   `json:"timestamp"`
+	Contract  string   `json:"contract"`
+	Creator   string   `json:"creator"`
+	Endorsers []string `json:"endorsers"`
+	Writes    []string `json:"writes"`
+}
+
+type txResultView struct {
+	TxID   string `json:"txId"`
+	Status string `json:"status"`
+}
+
+func blockLatest(c *cli, args []string) (*result, error) {
+	if _, err := parseArgs(flag.NewFlagSet("block latest", flag.ContinueOnError), args, 0); err != nil {
+		return nil, err
+	}
+	if err := c.require(true, false); err != nil {
+		return nil, err
+	}
+	bt := utils.BlockTool{}
+	number, err := bt.QueryLastBlockNumberCtx(c.ctx, c.client, c.config)
+	if err != nil {
+		return nil, err
+	}
+	value := map[string]uint64{"number": number, "height": number + 1}
+	return fields(value, &nodeservice.LatestChainState{Height: number + 1},
+		"number", strconv.FormatUint(number, 10),
+		"height", strconv.FormatUint(number+1, 10)), nil
+}
+
+func blockGet(c *cli, args []string) (*result, error) {
+	args, err := parseArgs(flag.NewFlagSet("block get", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.require(true, false); err != nil {
+		return nil, err
+	}
+	bt := utils.BlockTool{}
+	block, err := bt.QueryBlockByNumberCtx(c.ctx, c.client, c.config, args[0])
+	if err != nil {
+		return nil, err
+	}
+	return blockResult(block)
+}
+
+func blockByTx(c *cli, args []string) (*result, error) {
+	args, err := parseArgs(flag.NewFlagSet("block by-tx", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.require(true, false); err != nil {
+		return nil, err
+	}
+	bt := utils.BlockTool{}
+	block, err := bt.QueryBlockByTxIDCtx(c.ctx, c.client, c.config, args[0])
+	if err != nil {
+		return nil, err
+	}
+	return blockResult(block)
+}
+
+func blockResult(block *common.Block) (*result, error) {
+	bt := utils.BlockTool{}
+	txIDs, err := bt.GetTxIdList(block)
+	if err != nil {
+		return nil, err
+	}
+	view := blockView{
+		Number:     bt.GetNumber(*block),
+		Timestamp:  bt.GetTimestamp(*block),
+		ParentHash: bt.GetParentHash(*block),
+		BodyHash:   bt.GetBodyHash(*block),
+		TxIDs:      txIDs,
+	}
+	res := fields(view, block,
+		"number", strconv.FormatUint(view.Number, 10),
+		"timestamp", view.Timestamp,
+		"parentHash", view.ParentHash,
+		"bodyHash", view.BodyHash)
+	for _, txID := range txIDs {
+		res.rows = append(res.rows, []string{"tx", txID})
+	}
+	return res, nil
+}
+
+func txGet(c *cli, args []string) (*result, error) {
+	args, err := parseArgs(flag.NewFlagSet("tx get", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.require(true, false); err != nil {
+		return nil, err
+	}
+	tt := utils.TxTool{}
+	tx, err := tt.QueryTxByTxIDCtx(c.ctx, c.client, c.config, args[0])
+	if err != nil {
+		return nil, err
+	}
+	// 投票交易没有合约调用和读写集，对应字段为空
+	view := txView{TxID: tt.GetTxID(*tx)}
+	view.Timestamp, _ = tt.GetTimestamp(*tx)
+	view.Contract, _ = tt.GetContractName(*tx)
+	view.Creator, _ = tt.GetCreateOrg(*tx)
+	view.Endorsers, _ = tt.GetEndorsersOrg(*tx)
+	view.Writes, _ = tt.GetTxKeyValues(*tx)
+	res := fields(view, tx,
+		"txId", view.TxID,
+		"timestamp", view.Timestamp,
+		"contract", view.Contract,
+		"creator", view.Creator,
+		"endorsers", strings.Join(view.Endorsers, ","))
+	for _, write := range view.Writes {
+		res.rows = append(res.rows, []string{"write", write})
+	}
+	return res, nil
+}
+
+func txResult(c *cli, args []string) (*result, error) {
+	args, err := parseArgs(flag.NewFlagSet("tx result", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.require(true, false); err != nil {
+		return nil, err
+	}
+	tt := utils.TxTool{}
+	txResult, err := tt.QueryTxResultByTxIDCtx(c.ctx, c.client, c.config, args[0])
+	if err != nil {
+		return nil, err
+	}
+	return txResultOutput(txResult), nil
+}
+
+func txResultOutput(txResult *common.TxResult) *result {
+	view := txResultView{TxID: utils.Hash2str(txResult.TxHash), Status: txResult.Status.String()}
+	return fields(view, txResult, "txId", view.TxID, "status", view.Status)
+}
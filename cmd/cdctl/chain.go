@@ -0,0 +1,163 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"git.huawei.com/goclient/utils"
+	"git.huawei.com/huaweichain/proto/common"
+	"git.huawei.com/huaweichain/proto/nodeservice"
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+type chainView struct {
+	ChainID         string   `json:"chainId"`
+	Organizations   []string `json:"organizations"`
+	CommitBlock     uint64   `json:"commitBlock"`
+	DBType          string   `json:"dbType"`
+	ConfigPolicy    string   `json:"configPolicy"`
+	LifecyclePolicy string   `json:"lifecyclePolicy"`
+}
+
+type responseView struct {
+	Node   string `json:"node"`
+	Status string `json:"status"`
+	Info   string `json:"info,omitempty"`
+}
+
+var chainHeader = []string{"CHAIN", "ORGANIZATIONS", "COMMIT BLOCK", "DB TYPE", "CONFIG POLICY", "LIFECYCLE POLICY"}
+
+func chainList(c *cli, args []string) (*result, error) {
+	if _, err := parseArgs(flag.NewFlagSet("chain list", flag.ContinueOnError), args, 0); err != nil {
+		return nil, err
+	}
+	rawMsg, err := c.client.ChainRawMessage.BuildQueryAllChainRawMessage()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query all chain raw message error")
+	}
+	responseMsg, err := c.client.Nodes[c.config.QueryNode].ChainAction.QueryAllChainsCtx(c.ctx, rawMsg)
+	if err != nil {
+		return nil, errors.WithMessage(err, "chain action query all chains error")
+	}
+	payload, err := utils.GetPayloadWithResp(responseMsg)
+	if err != nil {
+		return nil, err
+	}
+	infos := &nodeservice.QueryAllInfoResponse{}
+	if err := proto.Unmarshal(payload, infos); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal query all info response error")
+	}
+	views := make([]chainView, 0, len(infos.ChainInfos))
+	res := &result{header: chainHeader, msg: infos}
+	for _, info := range infos.ChainInfos {
+		view := newChainView(info)
+		views = append(views, view)
+		res.rows = append(res.rows, view.row())
+	}
+	res.value = views
+	return res, nil
+}
+
+func chainQuery(c *cli, args []string) (*result, error) {
+	if _, err := parseArgs(flag.NewFlagSet("chain query", flag.ContinueOnError), args, 0); err != nil {
+		return nil, err
+	}
+	if err := c.require(true, false); err != nil {
+		return nil, err
+	}
+	rawMsg, err := c.client.ChainRawMessage.BuildQueryChainRawMessage(c.config.ChainID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query chain raw message error")
+	}
+	responseMsg, err := c.client.Nodes[c.config.QueryNode].ChainAction.QueryChainCtx(c.ctx, rawMsg)
+	if err != nil {
+		return nil, errors.WithMessage(err, "chain action query chain error")
+	}
+	payload, err := utils.GetPayloadWithResp(responseMsg)
+	if err != nil {
+		return nil, err
+	}
+	info := &nodeservice.QueryInfoResponse{}
+	if err := proto.Unmarshal(payload, info); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal query info response error")
+	}
+	view := newChainView(info.ChainInfo)
+	return &result{value: view, header: chainHeader, rows: [][]string{view.row()}, msg: info}, nil
+}
+
+func chainJoin(c *cli, args []string) (*result, error) {
+	args, err := parseArgs(flag.NewFlagSet("chain join", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return nil, err
+	}
+	genesisBlock, err := ioutil.ReadFile(filepath.Clean(args[0]))
+	if err != nil {
+		return nil, errors.WithMessage(err, "read genesis block error")
+	}
+	rawMsg, err := c.client.ChainRawMessage.BuildJoinChainRawMessage(genesisBlock)
+	if err != nil {
+		return nil, errors.WithMessage(err, "build join chain raw message error")
+	}
+	responseMsg, err := c.client.Nodes[c.config.QueryNode].ChainAction.JoinChainCtx(c.ctx, rawMsg)
+	if err != nil {
+		return nil, errors.WithMessage(err, "chain action join chain error")
+	}
+	return responseOutput(c.config.QueryNode, responseMsg)
+}
+
+func chainQuit(c *cli, args []string) (*result, error) {
+	if _, err := parseArgs(flag.NewFlagSet("chain quit", flag.ContinueOnError), args, 0); err != nil {
+		return nil, err
+	}
+	if err := c.require(true, false); err != nil {
+		return nil, err
+	}
+	rawMsg, err := c.client.ChainRawMessage.BuildQuitChainRawMessage(c.config.ChainID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "build quit chain raw message error")
+	}
+	responseMsg, err := c.client.Nodes[c.config.QueryNode].ChainAction.QuitChainCtx(c.ctx, rawMsg)
+	if err != nil {
+		return nil, errors.WithMessage(err, "chain action quit chain error")
+	}
+	return responseOutput(c.config.QueryNode, responseMsg)
+}
+
+func newChainView(info *nodeservice.ChainInfo) chainView {
+	view := chainView{}
+	if info == nil || info.Config == nil || info.Config.Config == nil {
+		return view
+	}
+	chainConfig := info.Config.Config
+	view.ChainID = chainConfig.ChainId
+	view.CommitBlock = info.Config.CommitBlock
+	view.DBType = chainConfig.DbType.String()
+	view.ConfigPolicy = chainConfig.ConfigPolicy
+	view.LifecyclePolicy = chainConfig.LifecyclePolicy
+	for _, org := range chainConfig.Organizations {
+		view.Organizations = append(view.Organizations, org.Name)
+	}
+	return view
+}
+
+func (v chainView) row() []string {
+	return []string{v.ChainID, strings.Join(v.Organizations, ","), strconv.FormatUint(v.CommitBlock, 10),
+		v.DBType, v.ConfigPolicy, v.LifecyclePolicy}
+}
+
+// responseOutput 解析节点对导入合约、加入和退出链请求的响应，失败时返回错误
+func responseOutput(node string, responseMsg *common.RawMessage) (*result, error) {
+	response := &common.Response{}
+	if err := proto.Unmarshal(responseMsg.Payload, response); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal response error")
+	}
+	if response.Status != common.SUCCESS {
+		return nil, errors.Errorf("node %s response status: %s, info: %v", node, response.Status.String(), response.StatusInfo)
+	}
+	view := responseView{Node: node, Status: response.Status.String(), Info: response.StatusInfo}
+	return fields(view, response, "node", view.Node, "status", view.Status, "info", view.Info), nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"strings"
+
+	"git.huawei.com/goclient/utils"
+	"git.huawei.com/huaweichain/proto/common"
+	"git.huawei.com/huaweichain/sdk/rawmessage"
+	"github.com/pkg/errors"
+)
+
+// contractInvoke 合约参数以分号连接后调用utils.Send，与服务端的调用方式一致
+func contractInvoke(c *cli, args []string) (*result, error) {
+	args, err := parseArgs(flag.NewFlagSet("contract invoke", flag.ContinueOnError), args, -1)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, errors.New("contract invoke requires the function name")
+	}
+	if err := c.require(true, true); err != nil {
+		return nil, err
+	}
+	net, err := c.nodes()
+	if err != nil {
+		return nil, err
+	}
+	_, txID, err := utils.SendCtx(c.ctx, c.client, net, c.config, args[0], strings.Join(args[1:], ";"))
+	if err != nil {
+		return nil, err
+	}
+	txHash, err := hex.DecodeString(txID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "decode tx id error")
+	}
+	return txResultOutput(&common.TxResult{TxHash: txHash, Status: common.VALID}), nil
+}
+
+func contractQuery(c *cli, args []string) (*result, error) {
+	args, err := parseArgs(flag.NewFlagSet("contract query", flag.ContinueOnError), args, -1)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, errors.New("contract query requires the function name")
+	}
+	if err := c.require(true, true); err != nil {
+		return nil, err
+	}
+	net, err := c.nodes()
+	if err != nil {
+		return nil, err
+	}
+	payload, err := utils.QueryCtx(c.ctx, c.client, net, c.config, args[0], strings.Join(args[1:], ";"))
+	if err != nil {
+		return nil, err
+	}
+	res := fields(map[string]string{"result": payload}, nil, "result", payload)
+	res.data = []byte(payload)
+	return res, nil
+}
+
+func contractImport(c *cli, args []string) (*result, error) {
+	flags := flag.NewFlagSet("contract import", flag.ContinueOnError)
+	version := flags.String("version", "", "contract version")
+	sandbox := flags.String("sandbox", "docker", "sandbox type: docker, native, nativewasm or teewasm")
+	language := flags.String("language", "go", "contract language")
+	args, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.require(true, true); err != nil {
+		return nil, err
+	}
+	contract := rawmessage.NewContract(c.config.ChainID, c.config.ContractName, *version)
+	rawMsg, err := c.client.LifecycleRawMessage.BuildImportRawMessage(contract, args[0], *sandbox, *language)
+	if err != nil {
+		return nil, errors.WithMessage(err, "build import raw message error")
+	}
+	responseMsg, err := c.client.Nodes[c.config.QueryNode].ContractAction.ContractImportCtx(c.ctx, rawMsg)
+	if err != nil {
+		return nil, errors.WithMessage(err, "contract action import error")
+	}
+	return responseOutput(c.config.QueryNode, responseMsg)
+}
+
+func contractVote(c *cli, args []string) (*result, error) {
+	flags := flag.NewFlagSet("contract vote", flag.ContinueOnError)
+	version := flags.String("version", "", "contract version")
+	policy := flags.String("policy", "", "endorsement policy, such as \"org1 & (org2 | org3)\"")
+	desc := flags.String("desc", "", "contract description")
+	history := flags.Bool("history", false, "keep the history of the contract state")
+	initRequired := flags.Bool("init", false, "require the contract to be initialized")
+	if _, err := parseArgs(flags, args, 0); err != nil {
+		return nil, err
+	}
+	if err := c.require(true, true); err != nil {
+		return nil, err
+	}
+	if *policy == "" {
+		return nil, errors.New("contract vote requires -policy")
+	}
+	contract := rawmessage.NewContract(c.config.ChainID, c.config.ContractName, *version)
+	txRawMsg, err := c.lifecycle().BuildVoteRawMessage(contract, *desc, *policy, *history, *initRequired)
+	if err != nil {
+		return nil, errors.WithMessage(err, "build vote raw message error")
+	}
+	return c.submit(txRawMsg, fmt.Sprintf("vote for contract %s version %s on chain %s, endorsement policy: %s",
+		c.config.ContractName, *version, c.config.ChainID, *policy))
+}
+
+// contractManage 投票冻结、解冻或销毁合约
+func contractManage(option string) command {
+	return func(c *cli, args []string) (*result, error) {
+		if _, err := parseArgs(flag.NewFlagSet("contract "+option, flag.ContinueOnError), args, 0); err != nil {
+			return nil, err
+		}
+		if err := c.require(true, true); err != nil {
+			return nil, err
+		}
+		txRawMsg, err := c.lifecycle().BuildManageRawMessage(c.config.ChainID, c.config.ContractName, option)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "build %s raw message error", option)
+		}
+		return c.submit(txRawMsg, fmt.Sprintf("%s contract %s on chain %s", option, c.config.ContractName, c.config.ChainID))
+	}
+}
+
+// submit 在-consensus节点提交投票交易，通过-node的交易事件等待落块；指定-export时写入文件，description为交易说明
+func (c *cli) submit(txRawMsg *rawmessage.TxRawMsg, description string) (*result, error) {
+	if c.export != "" {
+		return c.exportTx(txRawMsg, description)
+	}
+	net, err := utils.NewNodes(c.client, []string{c.config.QueryNode}, c.config.ConsensusNode)
+	if err != nil {
+		return nil, err
+	}
+	txResult, err := utils.SendTxCtx(c.ctx, net, c.config, txRawMsg)
+	if err != nil {
+		return nil, err
+	}
+	return txResultOutput(txResult), nil
+}
@@ -0,0 +1,227 @@
+// cdctl 基于sdk.yaml的链管理命令行工具，用于查询区块和交易、调用和管理合约以及管理节点加入的链
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"git.huawei.com/goclient/utils"
+	"git.huawei.com/huaweichain/sdk/client"
+	"git.huawei.com/huaweichain/sdk/config"
+	"git.huawei.com/huaweichain/sdk/crypto"
+	"github.com/pkg/errors"
+)
+
+const usage = `usage: cdctl [flags] <command> <subcommand> [args]
+
+commands:
+  block latest                 latest block number
+  block get <number>           block by number
+  block by-tx <txid>           block containing the transaction
+  tx get <txid>                transaction by id
+  tx result <txid>             validation status of the transaction
+  tx submit <file>             submit the transaction signed offline by -signer
+  contract invoke <func> [arg...]
+                               invoke the contract and wait for the transaction to commit
+  contract query <func> [arg...]
+                               query the contract without submitting a transaction
+  contract import [flags] <path>
+                               import the contract package on -node
+  contract vote [flags]        vote for the contract definition
+  contract freeze|unfreeze|destroy
+                               vote to manage the contract
+  chain list                   chains joined by -node
+  chain query                  config of -chain on -node
+  chain join <genesis-block>   -node joins the chain of the genesis block file
+  chain quit                   -node quits -chain
+
+flags:
+`
+
+// command 子命令，返回待输出的结果
+type command func(c *cli, args []string) (*result, error)
+
+var commands = map[string]map[string]command{
+	"block": {
+		"latest": blockLatest,
+		"get":    blockGet,
+		"by-tx":  blockByTx,
+	},
+	"tx": {
+		"get":    txGet,
+		"result": txResult,
+		"submit": txSubmit,
+	},
+	"contract": {
+		"invoke":   contractInvoke,
+		"query":    contractQuery,
+		"import":   contractImport,
+		"vote":     contractVote,
+		"freeze":   contractManage("freeze"),
+		"unfreeze": contractManage("unfreeze"),
+		"destroy":  contractManage("destroy"),
+	},
+	"chain": {
+		"list":  chainList,
+		"query": chainQuery,
+		"join":  chainJoin,
+		"quit":  chainQuit,
+	},
+}
+
+// cli 命令执行时共享的网关客户端和配置，config.QueryNode为-node指定的节点
+type cli struct {
+	ctx    context.Context
+	client *client.GatewayClient
+	config utils.Config
+	signer *crypto.Identity // -signer指定的离线签名者
+	export string           // -export指定的待签名交易文件
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run 执行命令，返回进程退出码：0成功，1执行失败，2参数错误
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("cdctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	sdkPath := flags.String("sdk", "configuration/sdk.yaml", "sdk config file path")
+	chainID := flags.String("chain", "", "chain id")
+	contract := flags.String("contract", "", "contract name")
+	nodeName := flags.String("node", "", "node for queries, contract import and chain management, defaults to the first node in the sdk config")
+	endorsers := flags.String("endorsers", "", "comma separated endorser nodes for contract invoke and query, defaults to the nodes selected by the endorsement policy")
+	consensus := flags.String("consensus", "", "node that transactions are submitted to, defaults to -node")
+	format := flags.String("o", "json", "output format: json, table or raw (serialized protobuf)")
+	timeout := flags.Duration("timeout", time.Minute, "timeout of the command, including waiting for transactions to commit")
+	signer := flags.String("signer", "", "cert file of the offline signer, required by -export and tx submit")
+	export := flags.String("export", "", "write the unsigned vote transaction to the file for offline signing instead of submitting it")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() < 2 {
+		flags.Usage()
+		return 2
+	}
+	cmd, ok := commands[flags.Arg(0)][flags.Arg(1)]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command: %s %s\n", flags.Arg(0), flags.Arg(1))
+		flags.Usage()
+		return 2
+	}
+	if *format != formatJSON && *format != formatTable && *format != formatRaw {
+		fmt.Fprintf(stderr, "unknown output format: %s\n", *format)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	c, err := newCli(ctx, *sdkPath, utils.Config{
+		ConfigFilePath: *sdkPath,
+		ContractName:   *contract,
+		EndorserNodes:  *endorsers,
+		ConsensusNode:  *consensus,
+		ChainID:        *chainID,
+		QueryNode:      *nodeName,
+		CommitTimeout:  *timeout,
+	})
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer c.client.Close()
+	if err := c.setOffline(*signer, *export); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	res, err := cmd(c, flags.Args()[2:])
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if err := res.write(stdout, *format); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// newCli 根据sdk.yaml创建网关客户端，并补全未指定的节点
+func newCli(ctx context.Context, sdkPath string, cfg utils.Config) (*cli, error) {
+	clientConfig, err := config.NewClientConfig(sdkPath)
+	if err != nil {
+		return nil, errors.WithMessage(err, "load sdk config error")
+	}
+	gatewayClient, err := client.NewGatewayClientWithCfg(clientConfig)
+	if err != nil {
+		return nil, errors.WithMessage(err, "init new gateway client error")
+	}
+	cfg.SignAlgorithm = clientConfig.Client.Type
+	if cfg.QueryNode == "" {
+		names := make([]string, 0, len(gatewayClient.Nodes))
+		for name := range gatewayClient.Nodes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			gatewayClient.Close()
+			return nil, errors.New("no node in sdk config")
+		}
+		cfg.QueryNode = names[0]
+	}
+	if _, ok := gatewayClient.Nodes[cfg.QueryNode]; !ok {
+		gatewayClient.Close()
+		return nil, errors.Errorf("node not exist： %v", cfg.QueryNode)
+	}
+	if cfg.ConsensusNode == "" {
+		cfg.ConsensusNode = cfg.QueryNode
+	}
+	return &cli{ctx: ctx, client: gatewayClient, config: cfg}, nil
+}
+
+// require 检查命令依赖的全局参数
+func (c *cli) require(chain bool, contract bool) error {
+	if chain && c.config.ChainID == "" {
+		return errors.New("-chain is required")
+	}
+	if contract && c.config.ContractName == "" {
+		return errors.New("-contract is required")
+	}
+	return nil
+}
+
+// nodes 返回合约调用使用的节点网络，未指定-endorsers时按合约背书策略选择背书节点
+func (c *cli) nodes() (*utils.Nodes, error) {
+	if c.config.EndorserNodes == "" {
+		endorsers, err := utils.SelectEndorsers(c.client, c.config)
+		if err != nil {
+			return nil, errors.WithMessage(err, "select endorsers error")
+		}
+		c.config.EndorserNodes = strings.Join(endorsers, ",")
+	}
+	return utils.NewNodes(c.client, strings.Split(c.config.EndorserNodes, ","), c.config.ConsensusNode)
+}
+
+// parseArgs 解析子命令的参数，want为位置参数个数，小于0时不限
+func parseArgs(flags *flag.FlagSet, args []string, want int) ([]string, error) {
+	flags.SetOutput(ioutil.Discard)
+	if err := flags.Parse(args); err != nil {
+		return nil, errors.WithMessagef(err, "%s", flags.Name())
+	}
+	if want >= 0 && flags.NArg() != want {
+		return nil, errors.Errorf("%s requires %d argument(s), got %d", flags.Name(), want, flags.NArg())
+	}
+	return flags.Args(), nil
+}
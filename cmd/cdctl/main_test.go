@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"git.huawei.com/goclient/simulator"
+	"git.huawei.com/goclient/usercontract"
+	"git.huawei.com/huaweichain/common/cryptomgr"
+	"git.huawei.com/huaweichain/proto/nodeservice"
+	"git.huawei.com/huaweichain/sdk/crypto"
+	"git.huawei.com/huaweichain/sdk/rawmessage"
+	"git.huawei.com/poissonsearch/wienerchain/contract/docker-container/contract-go/contractapi"
+	"github.com/gogo/protobuf/proto"
+)
+
+// 存证ID为64位十六进制
+var recordID = strings.Repeat("1", 64)
+
+const sdkConfig = `client:
+  type: ecdsa_with_sha256
+  identity:
+    keyPath: %s
+    certPath: %s
+  tls:
+    enable: false
+nodes:
+  node-0.org1:
+    hostOverride: 127.0.0.1
+    host: 127.0.0.1
+    port: %s
+`
+
+// 启动运行存证合约的模拟器，生成org1的客户端身份并写入sdk.yaml，返回sdk.yaml路径
+func startSimulator(t *testing.T) string {
+	sim, err := simulator.New(usercontract.NewSmartContract(), simulator.Options{ChainID: "chain", ContractName: "finance", Policy: "org1"})
+	if err != nil {
+		t.Fatalf("new simulator error: %v", err)
+	}
+	if err := sim.Init(&contractapi.Identity{Org: "org1"}); err != nil {
+		t.Fatalf("init contract error: %v", err)
+	}
+	if err := sim.Start("127.0.0.1:0"); err != nil {
+		t.Fatalf("start simulator error: %v", err)
+	}
+	t.Cleanup(sim.Close)
+
+	dir := t.TempDir()
+	certPath, keyPath := writeIdentity(t, dir, "user1", "org1")
+	configPath := filepath.Join(dir, "sdk.yaml")
+	_, port, _ := net.SplitHostPort(sim.Addr())
+	ioutil.WriteFile(configPath, []byte(fmt.Sprintf(sdkConfig, keyPath, certPath, port)), 0600)
+	return configPath
+}
+
+// writeIdentity 生成自签名的ecdsa证书和私钥，写入dir，返回证书和私钥路径
+func writeIdentity(t *testing.T, dir string, name string, org string) (string, string) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{Organization: []string{org}, CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, _ := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	certPath, keyPath := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), 0600)
+	ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	return certPath, keyPath
+}
+
+// 执行命令，返回标准输出；退出码与want不同时测试失败
+func runCmd(t *testing.T, want int, args ...string) []byte {
+	var stdout, stderr bytes.Buffer
+	if code := run(args, &stdout, &stderr); code != want {
+		t.Fatalf("cdctl %s: exit code %d, want %d, stderr: %s", strings.Join(args, " "), code, want, stderr.String())
+	}
+	return stdout.Bytes()
+}
+
+func Test_Cdctl(t *testing.T) {
+	global := []string{"-sdk", startSimulator(t), "-chain", "chain", "-contract", "finance", "-timeout", "10s"}
+	cmd := func(args ...string) []string {
+		return append(append([]string{}, global...), args...)
+	}
+
+	var invoked txResultView
+	if err := json.Unmarshal(runCmd(t, 0, cmd("contract", "invoke", "putRecord", recordID, "o1", "c1", "h1", "100")...), &invoked); err != nil {
+		t.Fatalf("unmarshal invoke output error: %v", err)
+	}
+	if invoked.Status != "VALID" || invoked.TxID == "" {
+		t.Fatalf("unexpected invoke output: %+v", invoked)
+	}
+	if out := runCmd(t, 0, cmd("contract", "query", "getRecord", recordID)...); !strings.Contains(string(out), `\"ciphertext\":\"c1\"`) {
+		t.Errorf("unexpected query output: %s", out)
+	}
+	if out := runCmd(t, 0, cmd("-o", "raw", "contract", "query", "getRecord", recordID)...); !strings.HasPrefix(string(out), `{"id":"`+recordID) {
+		t.Errorf("unexpected raw query output: %s", out)
+	}
+	runCmd(t, 1, cmd("contract", "invoke", "putRecord", recordID, "o2", "c2", "h2", "200")...)
+
+	var txResult txResultView
+	json.Unmarshal(runCmd(t, 0, cmd("tx", "result", invoked.TxID)...), &txResult)
+	if txResult != invoked {
+		t.Errorf("tx result = %+v, want %+v", txResult, invoked)
+	}
+	var tx txView
+	json.Unmarshal(runCmd(t, 0, cmd("tx", "get", invoked.TxID)...), &tx)
+	if tx.TxID != invoked.TxID || tx.Contract != "finance" || len(tx.Writes) == 0 {
+		t.Errorf("unexpected tx: %+v", tx)
+	}
+	var block blockView
+	json.Unmarshal(runCmd(t, 0, cmd("block", "by-tx", invoked.TxID)...), &block)
+	if len(block.TxIDs) != 1 || block.TxIDs[0] != invoked.TxID {
+		t.Errorf("unexpected block: %+v", block)
+	}
+	if out := runCmd(t, 0, cmd("-o", "table", "block", "get", fmt.Sprint(block.Number))...); !strings.Contains(string(out), "\ntx ") || !strings.Contains(string(out), invoked.TxID) {
+		t.Errorf("unexpected table output:\n%s", out)
+	}
+
+	state := &nodeservice.LatestChainState{}
+	if err := proto.Unmarshal(runCmd(t, 0, cmd("-o", "raw", "block", "latest")...), state); err != nil || state.Height != block.Number+1 {
+		t.Errorf("latest chain state: %v %v, block number %d", state, err, block.Number)
+	}
+
+	runCmd(t, 2, cmd("block", "unknown")...)
+	runCmd(t, 2, cmd("-o", "yaml", "block", "latest")...)
+	runCmd(t, 1, cmd("block", "get")...)
+}
+
+func Test_Offline(t *testing.T) {
+	configPath := startSimulator(t)
+	dir := filepath.Dir(configPath)
+	adminCert, adminKey := writeIdentity(t, dir, "admin", "org1")
+	otherCert, _ := writeIdentity(t, dir, "other", "org1")
+	file := filepath.Join(dir, "vote.json")
+	global := []string{"-sdk", configPath, "-chain", "chain", "-contract", "finance", "-timeout", "10s"}
+	cmd := func(args ...string) []string {
+		return append(append([]string{}, global...), args...)
+	}
+
+	var exported offlineView
+	json.Unmarshal(runCmd(t, 0, cmd("-signer", adminCert, "-export", file, "contract", "vote", "-version", "2.0", "-policy", "org1")...), &exported)
+	if exported.File != file || exported.Kind != rawmessage.OfflineKindTx || exported.Signer != "admin@org1" {
+		t.Fatalf("unexpected export output: %+v", exported)
+	}
+	runCmd(t, 1, cmd("-signer", adminCert, "tx", "submit", file)...)
+
+	m, err := rawmessage.LoadOfflineMessage(file)
+	if err != nil || m.Signed || !strings.Contains(m.Description, "version 2.0") {
+		t.Fatalf("unexpected offline message: %+v %v", m, err)
+	}
+	signer, err := crypto.NewCrypto(cryptomgr.EcdsaWithSha256, adminCert, adminKey, func(bytes []byte) ([]byte, error) {
+		return bytes, nil
+	})
+	if err != nil {
+		t.Fatalf("new crypto error: %v", err)
+	}
+	if err := m.Sign(signer); err != nil {
+		t.Fatalf("sign error: %v", err)
+	}
+	m.Save(file)
+
+	// 签名者与预期的证书不一致时拒绝提交
+	runCmd(t, 1, cmd("-signer", otherCert, "tx", "submit", file)...)
+	var submitted txResultView
+	json.Unmarshal(runCmd(t, 0, cmd("-signer", adminCert, "tx", "submit", file)...), &submitted)
+	if submitted.Status != "VALID" || submitted.TxID != exported.Hash {
+		t.Errorf("submitted %+v, exported hash %s", submitted, exported.Hash)
+	}
+	runCmd(t, 1, cmd("-export", file, "contract", "freeze")...)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+
+	"git.huawei.com/huaweichain/sdk/crypto"
+	"git.huawei.com/huaweichain/sdk/rawmessage"
+	"github.com/pkg/errors"
+)
+
+// offlineView 导出的待签名交易
+type offlineView struct {
+	File   string `json:"file"`
+	Kind   string `json:"kind"`
+	Hash   string `json:"hash"`
+	Signer string `json:"signer"`
+}
+
+// setOffline 加载-signer指定的离线签名者证书。指定-export时投票交易以该证书的身份构造，不签名，
+// 由submit写入文件，在离线机器上用signer签名后通过tx submit提交
+func (c *cli) setOffline(signerPath string, export string) error {
+	if signerPath == "" {
+		if export != "" {
+			return errors.New("-export requires -signer")
+		}
+		return nil
+	}
+	certPem, err := ioutil.ReadFile(filepath.Clean(signerPath))
+	if err != nil {
+		return errors.WithMessage(err, "read signer cert error")
+	}
+	identity, err := crypto.NewIdentity(c.config.SignAlgorithm, certPem)
+	if err != nil {
+		return errors.WithMessage(err, "load signer cert error")
+	}
+	c.signer, c.export = identity, export
+	return nil
+}
+
+// lifecycle 构造合约投票交易，-export时使用不签名的构造器
+func (c *cli) lifecycle() *rawmessage.LifecycleRawMessage {
+	if c.export == "" {
+		return c.client.LifecycleRawMessage
+	}
+	return rawmessage.NewLifecycleRawMessage(rawmessage.NewUnsignedBuilder(c.signer), c.client.Crypto)
+}
+
+// exportTx 将未签名的交易写入-export文件
+func (c *cli) exportTx(txRawMsg *rawmessage.TxRawMsg, description string) (*result, error) {
+	m, err := rawmessage.NewOfflineTx(txRawMsg, description)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Save(c.export); err != nil {
+		return nil, errors.WithMessage(err, "save offline tx error")
+	}
+	view := offlineView{File: c.export, Kind: m.Kind, Hash: m.Hash, Signer: c.signer.GetCommonName() + "@" + c.signer.GetOrg()}
+	res := fields(view, nil, "file", view.File, "kind", view.Kind, "hash", view.Hash, "signer", view.Signer)
+	res.data = m.Message
+	return res, nil
+}
+
+// txSubmit 校验离线签名的交易由-signer的证书签名后提交，等待落块
+func txSubmit(c *cli, args []string) (*result, error) {
+	args, err := parseArgs(flag.NewFlagSet("tx submit", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.require(true, false); err != nil {
+		return nil, err
+	}
+	if c.signer == nil || c.export != "" {
+		return nil, errors.New("tx submit requires -signer and does not support -export")
+	}
+	m, err := rawmessage.LoadOfflineMessage(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if !m.Signed {
+		return nil, errors.Errorf("%s has not been signed", args[0])
+	}
+	txRawMsg, err := m.TxRawMsg(c.signer)
+	if err != nil {
+		return nil, err
+	}
+	return c.submit(txRawMsg, "")
+}
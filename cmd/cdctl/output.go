@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// 输出格式
+const (
+	formatJSON  = "json"
+	formatTable = "table"
+	formatRaw   = "raw"
+)
+
+// result 命令的输出：value为json输出的内容，header和rows为表格输出，
+// msg为raw输出的protobuf消息，没有对应消息的结果（如合约查询）raw输出data
+type result struct {
+	value  interface{}
+	header []string
+	rows   [][]string
+	msg    proto.Message
+	data   []byte
+}
+
+// fields 单个对象以“字段 值”两列的表格输出
+func fields(value interface{}, msg proto.Message, kv ...string) *result {
+	res := &result{value: value, msg: msg, header: []string{"FIELD", "VALUE"}}
+	for i := 0; i+1 < len(kv); i += 2 {
+		res.rows = append(res.rows, []string{kv[i], kv[i+1]})
+	}
+	return res
+}
+
+func (r *result) write(w io.Writer, format string) error {
+	switch format {
+	case formatRaw:
+		data := r.data
+		if r.msg != nil {
+			var err error
+			if data, err = proto.Marshal(r.msg); err != nil {
+				return errors.WithMessage(err, "marshal raw output error")
+			}
+		}
+		_, err := w.Write(data)
+		return err
+	case formatTable:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(r.header, "\t"))
+		for _, row := range r.rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	default:
+		data, err := json.MarshalIndent(r.value, "", "  ")
+		if err != nil {
+			return errors.WithMessage(err, "marshal json output error")
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+}
@@ -0,0 +1,98 @@
+// signer 离线签名工具，在隔离网络的机器上用组织管理员的私钥签名cdctl -export导出的交易，不连接节点
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"git.huawei.com/huaweichain/common/cryptomgr"
+	"git.huawei.com/huaweichain/sdk/crypto"
+	"git.huawei.com/huaweichain/sdk/rawmessage"
+	"github.com/pkg/errors"
+)
+
+const usage = `usage: signer [flags] <file>
+
+Signs the transaction or request exported for offline signing. The certificate must be the
+expected signer recorded in the file, and the hash preview must match the message. Review the
+chain, creator and vote content decoded from the transaction before signing, the description
+is written by the exporter and not bound to the transaction.
+
+flags:
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run 执行签名，返回进程退出码：0成功，1签名失败，2参数错误
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("signer", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	alg := flags.String("alg", cryptomgr.EcdsaWithSha256, "signature algorithm: "+cryptomgr.EcdsaWithSha256+" or "+cryptomgr.Sm2WithSm3)
+	certPath := flags.String("cert", "", "cert file of the signer")
+	keyPath := flags.String("key", "", "private key file of the signer")
+	out := flags.String("out", "", "file to write the signed message, defaults to overwriting the input file")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 || *certPath == "" || *keyPath == "" {
+		flags.Usage()
+		return 2
+	}
+	if *out == "" {
+		*out = flags.Arg(0)
+	}
+	if err := sign(flags.Arg(0), *out, *alg, *certPath, *keyPath, stdout); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// sign 输出待签名内容的说明和哈希供核对，签名后写入out
+func sign(in string, out string, alg string, certPath string, keyPath string, stdout io.Writer) error {
+	m, err := rawmessage.LoadOfflineMessage(in)
+	if err != nil {
+		return err
+	}
+	signer, err := crypto.NewCrypto(alg, certPath, keyPath, func(bytes []byte) ([]byte, error) {
+		return bytes, nil
+	})
+	if err != nil {
+		return errors.WithMessage(err, "load signer identity error")
+	}
+	// 输出从交易中解码的内容，说明由导出方填写，与交易内容无关，仅供参考
+	content, err := m.Content(signer.Hash)
+	if err != nil {
+		return errors.WithMessage(err, "decode offline message error")
+	}
+	fmt.Fprintf(stdout, "kind:        %s\n", m.Kind)
+	if m.Kind == rawmessage.OfflineKindTx {
+		fmt.Fprintf(stdout, "chain:       %s\n", content.ChainID)
+		fmt.Fprintf(stdout, "type:        %s\n", content.TxType)
+		fmt.Fprintf(stdout, "creator:     %s\n", content.Creator)
+	}
+	if content.Handler != "" {
+		fmt.Fprintf(stdout, "vote:        %s\n", strings.TrimSpace(content.Handler+" "+content.Subject))
+		fmt.Fprintf(stdout, "content:     %s\n", content.Detail)
+	}
+	fmt.Fprintf(stdout, "description: %s (written by the exporter, not verified)\n", m.Description)
+	fmt.Fprintf(stdout, "hash:        %s\n", m.Hash)
+	fmt.Fprintf(stdout, "signer:      %s@%s\n", signer.GetCommonName(), signer.GetOrg())
+	if err := m.Sign(signer); err != nil {
+		return errors.WithMessage(err, "sign offline message error")
+	}
+	if err := m.Save(out); err != nil {
+		return errors.WithMessage(err, "save signed message error")
+	}
+	fmt.Fprintf(stdout, "signed:      %s\n", out)
+	return nil
+}
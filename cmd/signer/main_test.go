@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"git.huawei.com/huaweichain/common/cryptomgr"
+	"git.huawei.com/huaweichain/sdk/crypto"
+	"git.huawei.com/huaweichain/sdk/rawmessage"
+)
+
+// writeIdentity 生成自签名的ecdsa证书和私钥，写入dir，返回证书和私钥路径
+func writeIdentity(t *testing.T, dir string, name string) (string, string) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{Organization: []string{"org1"}, CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, _ := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	certPath, keyPath := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), 0600)
+	ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	return certPath, keyPath
+}
+
+func Test_Signer(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := writeIdentity(t, dir, "admin")
+	otherCert, otherKey := writeIdentity(t, dir, "other")
+	certPem, _ := ioutil.ReadFile(certPath)
+	identity, err := crypto.NewIdentity(cryptomgr.EcdsaWithSha256, certPem)
+	if err != nil {
+		t.Fatalf("new identity error: %v", err)
+	}
+
+	// 以管理员证书的身份构造未签名的配置更新投票
+	updateConfig := rawmessage.NewConfigRawMessage(rawmessage.NewUnsignedBuilder(identity)).UpdateConfig
+	txRawMsg, err := updateConfig.BuildUpdateConfPolicyRawMessage("chain", "MAJORITY")
+	if err != nil {
+		t.Fatalf("build unsigned tx error: %v", err)
+	}
+	m, err := rawmessage.NewOfflineTx(txRawMsg, "config policy: MAJORITY")
+	if err != nil {
+		t.Fatalf("export tx error: %v", err)
+	}
+	in, out := filepath.Join(dir, "tx.json"), filepath.Join(dir, "signed.json")
+	if err := m.Save(in); err != nil {
+		t.Fatalf("save tx error: %v", err)
+	}
+	if _, err := m.TxRawMsg(identity); err == nil {
+		t.Fatal("expected error verifying unsigned tx")
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-cert", otherCert, "-key", otherKey, in}, &stdout, &stderr); code != 1 {
+		t.Errorf("sign with other cert: exit code %d", code)
+	}
+	stdout.Reset()
+	if code := run([]string{"-cert", certPath, "-key", keyPath, "-out", out, in}, &stdout, &stderr); code != 0 {
+		t.Fatalf("sign: exit code %d, stderr: %s", code, stderr.String())
+	}
+	// 输出从交易解码的链、发起者和配置更新内容
+	for _, want := range []string{m.Hash, "signer:      admin@org1", "chain:       chain", "type:        VOTE_TRANSACTION",
+		"creator:     admin@org1", "vote:        update", "MAJORITY"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("output does not contain %q: %s", want, stdout.String())
+		}
+	}
+	signed, err := rawmessage.LoadOfflineMessage(out)
+	if err != nil || !signed.Signed {
+		t.Fatalf("load signed message: %+v %v", signed, err)
+	}
+	assembled, err := signed.TxRawMsg(identity)
+	if err != nil || !bytes.Equal(assembled.Hash, txRawMsg.Hash) {
+		t.Fatalf("assemble signed tx: %v", err)
+	}
+
+	// 已签名的文件不能再次签名
+	if code := run([]string{"-cert", certPath, "-key", keyPath, out}, &stdout, &stderr); code != 1 {
+		t.Errorf("sign signed message: exit code %d", code)
+	}
+
+	// 哈希预览与交易不一致时拒绝签名
+	m.Hash = strings.Repeat("0", len(m.Hash))
+	if err := m.Save(in); err != nil {
+		t.Fatalf("save tx error: %v", err)
+	}
+	if code := run([]string{"-cert", certPath, "-key", keyPath, in}, &stdout, &stderr); code != 1 {
+		t.Errorf("sign tampered message: exit code %d", code)
+	}
+	if code := run([]string{"-cert", certPath, in}, &stdout, &stderr); code != 2 {
+		t.Errorf("missing key: exit code %d", code)
+	}
+}
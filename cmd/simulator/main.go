@@ -0,0 +1,56 @@
+// simulator 在本机启动单节点的模拟链并运行存证合约，sdk.yaml中的节点指向监听地址并关闭tls即可连接
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"git.huawei.com/goclient/simulator"
+	"git.huawei.com/goclient/usercontract"
+	"git.huawei.com/poissonsearch/wienerchain/contract/docker-container/contract-go/contractapi"
+)
+
+func main() {
+	listen := flag.String("listen", "127.0.0.1:30605", "grpc listen address")
+	chainID := flag.String("chain", "simulator", "chain id, must match chain.chainID in server.yaml")
+	contractName := flag.String("contract", "finance", "contract name, must match chain.contractName in server.yaml")
+	org := flag.String("org", "", "org that initializes the contract and gets all acl permissions, must match the O of the client cert")
+	algorithm := flag.String("alg", "ecdsa_with_sha256", "client sign algorithm, ecdsa_with_sha256 or sm2_with_sm3")
+	blockInterval := flag.Duration("block-interval", 0, "block interval, 0 cuts a block for every transaction")
+	policy := flag.String("policy", "", "endorsement policy returned by contract info, defaults to -org")
+	endorsedTTL := flag.Duration("endorsed-ttl", simulator.DefaultEndorsedTTL, "how long an endorsed but unsubmitted transaction is kept")
+	flag.Parse()
+	if *org == "" {
+		log.Fatal("-org is required")
+	}
+	if *policy == "" {
+		*policy = *org
+	}
+
+	sim, err := simulator.New(usercontract.NewSmartContract(), simulator.Options{
+		ChainID:       *chainID,
+		ContractName:  *contractName,
+		Algorithm:     *algorithm,
+		BlockInterval: *blockInterval,
+		Policy:        *policy,
+		EndorsedTTL:   *endorsedTTL,
+	})
+	if err != nil {
+		log.Fatalf("init simulator error: %v", err)
+	}
+	if err := sim.Init(&contractapi.Identity{Org: *org}); err != nil {
+		log.Fatalf("init contract error: %v", err)
+	}
+	if err := sim.Start(*listen); err != nil {
+		log.Fatalf("start simulator error: %v", err)
+	}
+	log.Printf("simulator of chain %s listening on %s", *chainID, sim.Addr())
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	sim.Close()
+}
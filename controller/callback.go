@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"git.huawei.com/goclient/response"
+	"git.huawei.com/goclient/utils"
+	"git.huawei.com/goclient/webhook"
+)
+
+// 交易落块回调投递器，未配置 webhook.secret 时为nil，此时不接受callback_url
+var Webhook *webhook.Dispatcher
+
+// 校验回调地址，未传callback_url时返回nil
+func checkCallback(callbackURL string) error {
+	if callbackURL == "" {
+		return nil
+	}
+	if Webhook == nil {
+		return utils.ErrorNew(response.CodeInvalidParam, "未启用回调")
+	}
+	if err := Webhook.CheckURL(callbackURL); err != nil {
+		return utils.ErrorNew(response.CodeInvalidParam, "参数无效")
+	}
+	return nil
+}
+
+// 交易落块后写入回调发件箱，由投递器在后台投递
+func notify(callbackURL string, txHash string, status string) {
+	if callbackURL == "" || Webhook == nil {
+		return
+	}
+	height, err := Chain.QueryBlockHeight(context.Background(), txHash)
+	if err != nil {
+		fmt.Println(err)
+	}
+	payload := webhook.Payload{TxHash: txHash, BlockHeight: height, Status: status, Timestamp: time.Now().Unix()}
+	if err := Webhook.Enqueue(callbackURL, payload); err != nil {
+		fmt.Println(err)
+	}
+}
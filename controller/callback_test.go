@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"git.huawei.com/goclient/webhook"
+)
+
+func Test_UpChain_Callback(t *testing.T) {
+	r := startTestJobs(t)
+	defer stopTestJobs(t)
+
+	var mu sync.Mutex
+	received := make(map[string]webhook.Payload)
+	receiver := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		if !webhook.Verify([]byte("secret"), req.Header.Get(webhook.HeaderTimestamp), body, req.Header.Get(webhook.HeaderSignature)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var payload webhook.Payload
+		json.Unmarshal(body, &payload)
+		mu.Lock()
+		received[payload.TxHash] = payload
+		mu.Unlock()
+	}))
+	defer receiver.Close()
+
+	//未启用回调时拒绝callback_url
+	form := url.Values{"phone": {"13700000000"}, "data": {`{"v":1}`}, "callback_url": {receiver.URL}}
+	if body := post(r, "/upchain", form); body["status"] != float64(601) {
+		t.Errorf("callback should be rejected when webhook is disabled: %v", body)
+	}
+
+	Webhook = webhook.New(Store, "secret", webhook.Options{Backoff: 10 * time.Millisecond, PollInterval: 5 * time.Millisecond,
+		AllowedHosts: []string{"127.0.0.1"}, TLSConfig: receiver.Client().Transport.(*http.Transport).TLSClientConfig})
+	Webhook.Start()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		Webhook.Stop(ctx)
+		Webhook = nil
+	}()
+
+	//只接受https，不在allowedHosts中的内网地址拒绝
+	for _, callbackURL := range []string{"ftp://example.com", "http://loan.example.com/notify", "https://169.254.169.254/latest",
+		"https://localhost/notify", "https://10.0.0.1/notify"} {
+		form.Set("callback_url", callbackURL)
+		if body := post(r, "/upchain", form); body["status"] != float64(601) {
+			t.Errorf("callback url %s should be rejected: %v", callbackURL, body)
+		}
+	}
+
+	form.Set("callback_url", receiver.URL)
+	syncHash, _ := post(r, "/upchain", form)["data"].(string)
+	form.Set("phone", "13700000001")
+	form.Set("async", "true")
+	jobID, _ := post(r, "/upchain", form)["data"].(string)
+	asyncHash := waitJob(t, r, jobID).TxHash
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := len(received)
+		mu.Unlock()
+		if n == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("callbacks not received, got %v", received)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	for _, hash := range []string{syncHash, asyncHash} {
+		payload, ok := received[hash]
+		if !ok || payload.Status != "VALID" || payload.BlockHeight != 1 || payload.Timestamp == 0 {
+			t.Errorf("unexpected callback for %s: %+v", hash, payload)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"context"
+
+	"git.huawei.com/goclient/api"
+	"git.huawei.com/goclient/utils"
+)
+
+// ChainAPI 控制器依赖的链上操作，默认由api包实现，测试时可替换为桩实现；
+// ctx为请求的上下文，客户端断开时取消链上查询和尚未提交的交易
+type ChainAPI interface {
+	QueryByPhone(ctx context.Context, txHashs []string) (string, error)
+	QueryByHash(ctx context.Context, txHash string) (string, error)
+	SubmitRecode(ctx context.Context, rec api.Recode, progress utils.TxProgress) (string, error)
+	QueryTxStatus(ctx context.Context, txHash string) (string, error)
+	QueryBlockHeight(ctx context.Context, txHash string) (uint64, error)
+	QueryCiphertext(ctx context.Context, txHash string) (string, error)
+	QueryHistory(ctx context.Context, recordID string) ([]api.Version, error)
+	QueryByOwner(ctx context.Context, owner string, limit int) (string, error)
+	RevokeRecode(ctx context.Context, recordID string, owner string, reason string, timestamp int64) (string, error)
+	QueryRevocation(ctx context.Context, recordID string) (*api.Revocation, error)
+	QueryNodeStatus() (*api.NodeStatus, error)
+}
+
+// Chain 当前使用的链上操作实现
+var Chain ChainAPI = apiChain{}
+
+type apiChain struct{}
+
+func (apiChain) QueryByPhone(ctx context.Context, txHashs []string) (string, error) {
+	return api.QueryByPhone(ctx, txHashs)
+}
+
+func (apiChain) QueryByHash(ctx context.Context, txHash string) (string, error) {
+	return api.QueryByHash(ctx, txHash)
+}
+
+func (apiChain) SubmitRecode(ctx context.Context, rec api.Recode, progress utils.TxProgress) (string, error) {
+	return api.SubmitRecode(ctx, rec, progress)
+}
+
+func (apiChain) QueryTxStatus(ctx context.Context, txHash string) (string, error) {
+	return api.QueryTxStatus(ctx, txHash)
+}
+
+func (apiChain) QueryBlockHeight(ctx context.Context, txHash string) (uint64, error) {
+	return api.QueryBlockHeight(ctx, txHash)
+}
+
+func (apiChain) QueryCiphertext(ctx context.Context, txHash string) (string, error) {
+	return api.QueryCiphertext(ctx, txHash)
+}
+
+func (apiChain) QueryHistory(ctx context.Context, recordID string) ([]api.Version, error) {
+	return api.QueryHistory(ctx, recordID)
+}
+
+func (apiChain) QueryByOwner(ctx context.Context, owner string, limit int) (string, error) {
+	return api.QueryByOwner(ctx, owner, limit)
+}
+
+func (apiChain) RevokeRecode(ctx context.Context, recordID string, owner string, reason string, timestamp int64) (string, error) {
+	return api.RevokeRecode(ctx, recordID, owner, reason, timestamp)
+}
+
+func (apiChain) QueryRevocation(ctx context.Context, recordID string) (*api.Revocation, error) {
+	return api.QueryRevocation(ctx, recordID)
+}
+
+func (apiChain) QueryNodeStatus() (*api.NodeStatus, error) {
+	return api.QueryNodeStatus()
+}
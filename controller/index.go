@@ -1,53 +1,69 @@
 package controller
 
-// import "C"
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
-	"encoding/json"
 	"fmt"
-	
 	"net/http"
 	"regexp"
 	"strings"
-	"sync"
 	"time"
+	"unicode/utf8"
 
 	"git.huawei.com/goclient/api"
 	"git.huawei.com/goclient/response"
+	"git.huawei.com/goclient/store"
 	"git.huawei.com/goclient/utils"
-	"github.com/deatil/go-cryptobin/cryptobin/crypto"
 	"github.com/gin-gonic/gin"
-	_ "github.com/go-sql-driver/mysql"
 	"github.com/gomodule/redigo/redis"
-	"github.com/jmoiron/sqlx"
 )
 
-// 存证结构
-type Deposit struct {
-	TxHash    string `db:"txhash"`
-	Phone     string `db:"phone"`
-	TimeStamp int64  `db:"timestamp"`
-	IsModify  bool   `db:"ismodify"`
-	DataKey   string `db:"datakey"`
-}
+// 缓存连接池，未配置 cache.address 时为nil，此时不使用缓存
+var Cache *redis.Pool
 
-// 加密密钥
-const Key = "dfertf12dfertf12"
+// 本地存证索引，启动时根据配置创建
+var Store store.DepositStore
 
-// 缓存连接
-var Conn, _ = redis.Dial("tcp", "localhost:6379")
+// 按手机号或交易哈希加锁，保证同一条存证的本地索引更新和缓存失效有序
+var keyLocks = newKeyLock()
 
-//本地数据库连接
-//var Db, err = sqlx.Open("mysql", "root:123456@tcp(127.0.0.1:3306)/credite")
+// 从缓存中读取数据，未命中或未启用缓存时返回空串
+func cacheGet(key string) string {
+	if Cache == nil {
+		return ""
+	}
+	conn := Cache.Get()
+	defer conn.Close()
+	r, _ := redis.String(conn.Do("Get", key))
+	return r
+}
 
-// 协程通道
-var ResponseChannel = make(chan *response.Response, 15)
+// 数据暂存至缓存，过期时间由 cache.expire 配置
+func cacheSet(key string, value string) {
+	if Cache == nil {
+		return
+	}
+	conn := Cache.Get()
+	defer conn.Close()
+	if _, err := conn.Do("Set", key, value, "EX", int64(utils.ServerCfg().Cache.Expire/time.Second)); err != nil {
+		fmt.Println("set cache failed:", err)
+	}
+}
 
-// 协程安全锁
-var lock sync.Mutex
+// 删除对应缓存
+func cacheDel(keys ...string) {
+	if Cache == nil {
+		return
+	}
+	conn := Cache.Get()
+	defer conn.Close()
+	for _, key := range keys {
+		conn.Do("DEL", key)
+	}
+}
 
 // 判断手机号有效性
 func verifyMobileFormat(mobileNum string) bool {
@@ -69,243 +85,102 @@ func VerifyHashFormat(HashNum string) bool {
 
 // 数据上链存储
 func UpChain(c *gin.Context) *response.Response {
-	Db, err := sqlx.Open("mysql", "root:123456@tcp(127.0.0.1:3306)/credite")
-	if err != nil {
-		fmt.Println(err)
-	}
-	//并发操作只能对副本进行
-	cCp := c.Copy()
-	fmt.Println(cCp)
-	//校验数据有效性
-	// phone := cCp.Query("phone")
-	// data := cCp.Query("data")
 	phone := c.PostForm("phone")
 	data := c.PostForm("data")
-	
-	if verifyMobileFormat(phone) && json.Valid([]byte(data)) {
-		go func() {
-			//数据加密
-			cyptdata := crypto.FromString(data).SetKey(Key).Aes().ECB().PKCS7Padding().Encrypt().ToBase64String()
-
-			//生成datakey，即手机号+时间戳的hash
-			timestamp := time.Now().Unix()
-			timestampbyte := make([]byte, 8)
-			binary.BigEndian.PutUint64(timestampbyte, uint64(timestamp))
-			headers := bytes.Join([][]byte{[]byte(phone), timestampbyte}, []byte{})
-			datakeySha := sha256.Sum256(headers)
-			datakey := hex.EncodeToString(datakeySha[:])
-
-			lock.Lock()
-			//上链存储
-			//TxHashID, err := api.SaveRecode(phone, strconv.FormatInt(timestamp, 10), cyptdata)
-			TxHashID, err := api.SaveRecode(datakey, cyptdata)
-			//本地存储
-			//Db.Exec("insert into deposit(txhash,phone,timestamp,datakey,ismodify)values(?,?,?,?,?)", TxHashID, phone, timestamp, datakey, false)
-			Db.Exec("insert into deposit(txhash,phone,timestamp,datakey,ismodify)values(?,?,?,?,?)", TxHashID, phone, timestamp, datakey, false)
-			//删除对应缓存
-			Conn.Do("DEL", phone)
-			Conn.Do("DEL", TxHashID)
-			lock.Unlock()
-
-			if err != nil {
-				ResponseChannel <- response.Resp().Json(gin.H{"status": utils.GetCode(err), "data": "", "msg": utils.GetMsg(err)})
-			} else {
-				ResponseChannel <- response.Resp().Json(gin.H{"status": 200, "data": TxHashID, "msg": "上链成功"})
-			}
-		}()
-		return <-ResponseChannel
-	} else {
-		return response.Resp().Json(gin.H{"status": 601, "data": "", "msg": "参数无效"})
+	callbackURL := c.PostForm("callback_url")
+	//异步上链，立即返回任务ID，通过 /jobs/:id 查询进度
+	if c.PostForm("async") == "true" {
+		jobID, err := saveDepositAsync(phone, data, callbackURL)
+		return legacyResp(jobID, err, "任务已受理")
 	}
+	txHash, _, err := saveDeposit(c.Request.Context(), phone, data, callbackURL)
+	return legacyResp(txHash, err, "上链成功")
 }
 
 // 根据手机号查询
 func QueryByPhone(c *gin.Context) *response.Response {
-	Db, _ := sqlx.Open("mysql", "root:123456@tcp(127.0.0.1:3306)/credite")
-	defer Db.Close()
-	phone := c.Query("phone")
-	//phone := c.PostForm("phone")
-	fmt.Println(phone)
-	//先在缓存中查找数据
-	r, _ := redis.String(Conn.Do("Get", phone))
-	if r == "" {
-		if verifyMobileFormat(phone) {
-			go func() {
-				//先在本地数据库中找到对应交易哈希
-				var deposit []Deposit
-				hashlist := make([]string, 0, 3)
-				Db.Select(&deposit, "select txhash,phone,timestamp,ismodify from deposit where phone=? AND ismodify=? order by timestamp Desc LIMIT 0,3", phone, false)
-				for i := 0; i < len(deposit); i++ {
-					hashlist = append(hashlist, deposit[i].TxHash)
-				}
-				result, err := api.QueryByPhone(hashlist)
-				if err != nil {
-					ResponseChannel <- response.Resp().Json(gin.H{"status": utils.GetCode(err), "data": "", "msg": utils.GetMsg(err)})
-				} else {
-					//数据解密
-					//crypderesult := crypto.FromBase64String(result).SetKey(Key).Aes().ECB().PKCS7Padding().Decrypt().ToString()
-					//数据暂存至缓存，过期时间为1小时
-					lock.Lock()
-					_, err = Conn.Do("Set", phone, result)
-					if err != nil {
-						fmt.Println("set phone result failed")
-					}
-					_, err = Conn.Do("expire", phone, 3600)
-					if err != nil {
-						fmt.Println("expore phone failed")
-					}
-					lock.Unlock()
-					var result_message []api.Message
-					err = json.Unmarshal([]byte(result), &result_message)
-					if err != nil {
-						fmt.Println("json转换错误")
-					}
-					ResponseChannel <- response.Resp().Json(gin.H{"status": 200, "data": result_message, "msg": "查询成功"})
-				}
-			}()
-			return <-ResponseChannel
-		} else {
-			return response.Resp().Json(gin.H{"status": 601, "data": "", "msg": "参数无效"})
-		}
-	} else {
-		return response.Resp().Json(gin.H{"status": 200, "data": r, "msg": "查询成功"})
-	}
+	messages, err := depositsByPhone(c.Request.Context(), c.Query("phone"))
+	return legacyResp(messages, err, "查询成功")
 }
 
 // 根据交易哈希查询
 func QueryByHash(c *gin.Context) *response.Response {
-	Db, _ := sqlx.Open("mysql", "root:123456@tcp(127.0.0.1:3306)/credite")
-	defer Db.Close()
-	hash := c.Query("hash")
-	//hash := c.PostForm("hash")
-	//先在缓存中查找数据
-	r, _ := redis.String(Conn.Do("Get", hash))
-	if r == "" {
-		if VerifyHashFormat(hash) {
-			go func() {
-				var deposit []Deposit
-				//先在本地数据库中寻找
-				Db.Select(&deposit, "select txhash,phone,timestamp,ismodify from deposit where txhash=?", hash)
-				//判断deposit中是否有值
-				if len(deposit) == 0 {
-					ResponseChannel <- response.Resp().Json(gin.H{"status": 603, "data": "", "msg": "交易哈希不存在"})
-				} else {
-					if deposit[0].IsModify {
-						ResponseChannel <- response.Resp().Json(gin.H{"status": 601, "data": "", "msg": "该数据已被修改，请使用新哈希查询"})
-					} else {
-						//结果解析，判断没有查询结果的情况
-						if VerifyHashFormat(hash) {
-							result, err := api.QueryByHash(hash)
-							if err != nil {
-								ResponseChannel <- response.Resp().Json(gin.H{"status": utils.GetCode(err), "data": "", "msg": utils.GetMsg(err)})
-							} else {
-								//数据解密
-								//crypderesult := crypto.FromBase64String(result).SetKey(Key).Aes().ECB().PKCS7Padding().Decrypt().ToString()
-								//数据暂存至缓存，过期时间为1小时
-								lock.Lock()
-								_, err = Conn.Do("Set", hash, result)
-								if err != nil {
-									fmt.Println("set hash result failed")
-								}
-								_, err = Conn.Do("expire", hash, 3600)
-								if err != nil {
-									fmt.Println("expire hash 3600 failed")
-								}
-								lock.Unlock()
-								// fmt.Println(JSON.parse(result))
-								var result_message api.Message
-								err = json.Unmarshal([]byte(result), &result_message)
-								if err != nil {
-									fmt.Println("json字符串转为对象错误!")
-								}
-								ResponseChannel <- response.Resp().Json(gin.H{"status": 200, "data": result_message, "msg": "查询成功"})
-							}
-						} else {
-							ResponseChannel <- response.Resp().Json(gin.H{"status": 601, "data": "", "msg": "参数无效"})
-						}
-					}
-				}
+	message, err := depositByHash(c.Request.Context(), c.Query("hash"))
+	return legacyResp(message, err, "查询成功")
+}
 
-			}()
-			return <-ResponseChannel
-		} else {
-			return response.Resp().Json(gin.H{"status": 601, "data": "", "msg": "参数无效"})
-		}
+// 修改上链数据
+func Modify(c *gin.Context) *response.Response {
+	txHash, _, err := modifyDeposit(c.Request.Context(), c.PostForm("phone"), c.PostForm("hash"), c.PostForm("data"), c.PostForm("callback_url"))
+	return legacyResp(txHash, err, "修改成功")
+}
 
-	} else {
-		return response.Resp().Json(gin.H{"status": 200, "data": r, "msg": "查询成功"})
+// 查询异步上链任务状态
+func QueryJob(c *gin.Context) *response.Response {
+	job, err := findJob(c.Param("id"))
+	return legacyResp(job, err, "查询成功")
+}
+
+// 旧接口的响应格式，HTTP状态码始终为200，错误码放在status字段中
+func legacyResp(data interface{}, err error, msg string) *response.Response {
+	if err != nil {
+		code := utils.GetCode(err)
+		//旧接口中已修改的存证按参数无效返回
+		if code == response.CodeAlreadyModified {
+			code = response.CodeInvalidParam
+		}
+		return response.Resp().Json(gin.H{"status": code, "data": "", "msg": utils.GetMsg(err)})
 	}
+	return response.Resp().Json(gin.H{"status": 200, "data": data, "msg": msg})
 }
 
-// 修改上链数据
-func Modify(c *gin.Context) *response.Response {
-	Db, _ := sqlx.Open("mysql", "root:123456@tcp(127.0.0.1:3306)/credite")
-	defer Db.Close()
-	//并发操作只能对副本进行
-	//cCp := c.Copy()
-	//校验数据有效性
-	// phone := cCp.Query("phone")
-	// hash := cCp.Query("hash")
-	// data := cCp.Query("data")
-	// bodyByts, err := ioutil.ReadAll(c.Request.Body)
-	// c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(bodyByts))
-	// if err != nil {
-	// // 返回错误信息
-	// c.String(http.StatusBadRequest, err.Error())
-	// // 执行退出
-	// c.Abort()
-	// }
-	phone := c.PostForm("phone")
-	hash := c.PostForm("hash")
-	data := c.PostForm("data")
+// 提交存证交易并等待落块，交易落块后（无论是否通过校验）按需回调
+func commitRecode(ctx context.Context, rec api.Recode, callbackURL string, failMsg string) (string, error) {
+	txHash, err := Chain.SubmitRecode(ctx, rec, nil)
+	if err == nil {
+		notify(callbackURL, txHash, store.JobValid)
+		return txHash, nil
+	}
+	if statusErr, ok := err.(*utils.TxStatusError); ok {
+		notify(callbackURL, statusErr.TxHash, statusErr.Status)
+	}
+	return "", utils.ErrorNew(response.CodeChainFailed, failMsg)
+}
 
-	if verifyMobileFormat(phone) && VerifyHashFormat(hash) && json.Valid([]byte(data)) {
-		go func() {
-			//数据加密
-			cyptdata := crypto.FromString(data).SetKey(Key).Aes().ECB().PKCS7Padding().Encrypt().ToBase64String()
-			//timestamp := time.Now().Unix()
+// 记录存证索引并删除对应缓存，同一手机号的索引更新与缓存失效需保持顺序
+func indexDeposit(phone string, txHash string, timestamp int64, datakey string, cyptdata string) {
+	unlock := keyLocks.Lock(phone)
+	defer unlock()
+	if err := Store.Insert(newDeposit(phone, txHash, timestamp, datakey, cyptdata)); err != nil {
+		fmt.Println(err)
+	}
+	cacheDel(phone, txHash)
+}
 
-			lock.Lock()
-			//先验证手机号和交易hash对应的东西是否存在
-			var deposit []Deposit
-			Db.Select(&deposit, "select txhash,phone,timestamp,ismodify from deposit where txhash=? and phone=?", hash, phone)
-			fmt.Println("---------------------------")
-			fmt.Println(deposit)
-			if len(deposit) == 0 {
-				ResponseChannel <- response.Resp().Json(gin.H{"status": 603, "data": "", "msg": "该条信息不存在"})
-			} else {
-				//数据修改
-				//TxHashID, err := api.ChangeRecode(phone, strconv.FormatInt(timestamp, 10), cyptdata)
+// 生成datakey，即手机号+时间戳的hash
+func newDataKey(phone string, timestamp int64) string {
+	timestampbyte := make([]byte, 8)
+	binary.BigEndian.PutUint64(timestampbyte, uint64(timestamp))
+	headers := bytes.Join([][]byte{[]byte(phone), timestampbyte}, []byte{})
+	datakeySha := sha256.Sum256(headers)
+	return hex.EncodeToString(datakeySha[:])
+}
 
-				//生成datakey，即手机号+时间戳的hash
-				timestamp := time.Now().Unix()
-				timestampbyte := make([]byte, 8)
-				binary.BigEndian.PutUint64(timestampbyte, uint64(timestamp))
-				headers := bytes.Join([][]byte{[]byte(phone), timestampbyte}, []byte{})
-				datakeysha := sha256.Sum256(headers)
-				datakey := hex.EncodeToString(datakeysha[:])
+// 撤销原因作为合约参数以分号分隔，不能包含分号
+func verifyRevokeReason(reason string) bool {
+	return strings.TrimSpace(reason) != "" && utf8.RuneCountInString(reason) <= 200 && !strings.Contains(reason, ";")
+}
 
-				TxHashID, err := api.ChangeRecode(datakey, cyptdata)
-				//本地数据更新
-				//Db.Exec("insert into deposit(txhash,phone,timestamp,ismodify)values(?,?,?,?)", TxHashID, phone, timestamp, false)
-				Db.Exec("insert into deposit(txhash,phone,timestamp,datakey,ismodify)values(?,?,?,?,?)", TxHashID, phone, timestamp, datakey, false)
-				Db.Exec("update deposit set ismodify=? where txhash=?", true, hash)
-				//删除对应缓存
-				Conn.Do("DEL", phone)
-				Conn.Do("DEL", hash)
-				lock.Unlock()
+// 所有者哈希，即手机号的sha256，链上按所有者建立索引，不保存手机号明文
+func ownerHash(phone string) string {
+	sum := sha256.Sum256([]byte(phone))
+	return hex.EncodeToString(sum[:])
+}
 
-				if err != nil {
-					ResponseChannel <- response.Resp().Json(gin.H{"status": utils.GetCode(err), "data": "", "msg": utils.GetMsg(err)})
-				} else {
-					ResponseChannel <- response.Resp().Json(gin.H{"status": 200, "data": TxHashID, "msg": "修改成功"})
-				}
-			}
-		}()
-		return <-ResponseChannel
-	} else {
-		return response.Resp().Json(gin.H{"status": 601, "data": "", "msg": "参数无效"})
-	}
+// 生成上链的结构化存证，data为明文，cyptdata为加密后的数据
+func newRecode(phone string, datakey string, data string, cyptdata string, timestamp int64) api.Recode {
+	sum := sha256.Sum256([]byte(data))
+	return api.Recode{ID: datakey, Owner: ownerHash(phone), Ciphertext: cyptdata, ContentHash: hex.EncodeToString(sum[:]), Timestamp: timestamp}
 }
 
 // 404页面
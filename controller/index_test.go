@@ -0,0 +1,313 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"git.huawei.com/goclient/api"
+	"git.huawei.com/goclient/envelope"
+	"git.huawei.com/goclient/response"
+	"git.huawei.com/goclient/store"
+	"git.huawei.com/goclient/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// fakeChain 模拟链上操作，交易哈希由datakey和数据计算得出，并随机延迟以制造并发交错
+type fakeChain struct{}
+
+func (fakeChain) SaveRecode(datakey string, data string) (string, error) {
+	time.Sleep(time.Duration(rand.Intn(5)) * time.Millisecond)
+	h := sha256.Sum256([]byte(datakey + ";" + data))
+	return hex.EncodeToString(h[:]), nil
+}
+
+func (fakeChain) QueryByPhone(ctx context.Context, txHashs []string) (string, error) {
+	return "[]", nil
+}
+
+func (fakeChain) QueryByHash(ctx context.Context, txHash string) (string, error) {
+	return "{}", nil
+}
+
+func (f fakeChain) SubmitRecode(ctx context.Context, rec api.Recode, progress utils.TxProgress) (string, error) {
+	txHash, _ := f.SaveRecode(rec.ID, rec.Ciphertext)
+	if progress != nil {
+		progress(utils.TxStageEndorsed, txHash)
+		progress(utils.TxStageSubmitted, txHash)
+	}
+	return txHash, nil
+}
+
+func (fakeChain) QueryTxStatus(ctx context.Context, txHash string) (string, error) {
+	return "", utils.ErrorNew(response.CodeNotFound, "交易哈希不存在")
+}
+
+func (fakeChain) QueryBlockHeight(ctx context.Context, txHash string) (uint64, error) {
+	return 1, nil
+}
+
+func (fakeChain) QueryCiphertext(ctx context.Context, txHash string) (string, error) {
+	return "", utils.ErrorNew(response.CodeChainFailed, "查询失败")
+}
+
+func (fakeChain) QueryHistory(ctx context.Context, recordID string) ([]api.Version, error) {
+	return nil, nil
+}
+
+func (fakeChain) QueryByOwner(ctx context.Context, owner string, limit int) (string, error) {
+	return "", utils.ErrorNew(response.CodePhoneNotFound, "手机号不存在")
+}
+
+func (fakeChain) RevokeRecode(ctx context.Context, recordID string, owner string, reason string, timestamp int64) (string, error) {
+	return "", utils.ErrorNew(response.CodeChainFailed, "撤销失败")
+}
+
+func (fakeChain) QueryRevocation(ctx context.Context, recordID string) (*api.Revocation, error) {
+	return nil, nil
+}
+
+func (fakeChain) QueryNodeStatus() (*api.NodeStatus, error) {
+	return &api.NodeStatus{}, nil
+}
+
+// ledgerChain 在fakeChain基础上记录每笔交易写入的密文、每个存证ID的历史版本、所有者索引和撤销记录
+type ledgerChain struct {
+	fakeChain
+	mu       sync.Mutex
+	data     map[string]string
+	versions map[string][]api.Version
+	owners   map[string][]string
+	revoked  map[string]api.Revocation
+}
+
+func newLedgerChain() *ledgerChain {
+	return &ledgerChain{data: map[string]string{}, versions: map[string][]api.Version{}, owners: map[string][]string{},
+		revoked: map[string]api.Revocation{}}
+}
+
+func (f *ledgerChain) SubmitRecode(ctx context.Context, rec api.Recode, progress utils.TxProgress) (string, error) {
+	txHash, err := f.fakeChain.SubmitRecode(ctx, rec, progress)
+	f.mu.Lock()
+	f.data[txHash] = rec.Ciphertext
+	if len(f.versions[rec.ID]) == 0 {
+		f.owners[rec.Owner] = append(f.owners[rec.Owner], rec.ID)
+	}
+	f.versions[rec.ID] = append(f.versions[rec.ID], api.Version{Value: rec.Ciphertext, TxHash: txHash})
+	f.mu.Unlock()
+	return txHash, err
+}
+
+// 返回所有者下各存证的最新版本，按写入顺序倒序
+func (f *ledgerChain) QueryByOwner(ctx context.Context, owner string, limit int) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ids := f.owners[owner]
+	if len(ids) == 0 {
+		return "", utils.ErrorNew(response.CodePhoneNotFound, "手机号不存在")
+	}
+	var messages []api.Message
+	for i := len(ids) - 1; i >= 0 && len(messages) < limit; i-- {
+		versions := f.versions[ids[i]]
+		latest := versions[len(versions)-1]
+		value, err := api.Decrypt(latest.Value)
+		if err != nil {
+			return "", err
+		}
+		messages = append(messages, api.Message{BlockHeight: 1, TxHash: latest.TxHash, Value: value})
+	}
+	result, _ := json.Marshal(messages)
+	return string(result), nil
+}
+
+func (f *ledgerChain) QueryCiphertext(ctx context.Context, txHash string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.data[txHash]
+	if !ok {
+		return "", utils.ErrorNew(response.CodeNotFound, "交易哈希不存在")
+	}
+	return data, nil
+}
+
+// 与合约一致：删除存证并移出所有者索引，历史中追加删除版本
+func (f *ledgerChain) RevokeRecode(ctx context.Context, recordID string, owner string, reason string, timestamp int64) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ids := f.owners[owner]
+	for i, id := range ids {
+		if id != recordID {
+			continue
+		}
+		if _, ok := f.revoked[recordID]; ok {
+			break
+		}
+		h := sha256.Sum256([]byte("revoke;" + recordID))
+		txHash := hex.EncodeToString(h[:])
+		f.owners[owner] = append(ids[:i:i], ids[i+1:]...)
+		f.versions[recordID] = append(f.versions[recordID], api.Version{TxHash: txHash, IsDeleted: true})
+		f.revoked[recordID] = api.Revocation{Reason: reason, RevokedAt: timestamp}
+		return txHash, nil
+	}
+	return "", utils.ErrorNew(response.CodeChainFailed, "撤销失败")
+}
+
+func (f *ledgerChain) QueryRevocation(ctx context.Context, recordID string) (*api.Revocation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if revocation, ok := f.revoked[recordID]; ok {
+		return &revocation, nil
+	}
+	return nil, nil
+}
+
+func (f *ledgerChain) QueryHistory(ctx context.Context, recordID string) ([]api.Version, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var history []api.Version
+	for _, v := range f.versions[recordID] {
+		if !v.IsDeleted {
+			value, err := api.Decrypt(v.Value)
+			if err != nil {
+				return nil, err
+			}
+			v.Value = value
+		}
+		history = append(history, v)
+	}
+	return history, nil
+}
+
+func setupTest(t *testing.T) *gin.Engine {
+	os.Setenv("CD_CHAIN_CONFIGFILEPATH", "../configuration/sdk.yaml")
+	os.Setenv("CD_STORE_DRIVER", "memory")
+	os.Setenv("CD_CRYPTO_KEY", "0123456789abcdef")
+	defer os.Unsetenv("CD_CHAIN_CONFIGFILEPATH")
+	defer os.Unsetenv("CD_STORE_DRIVER")
+	defer os.Unsetenv("CD_CRYPTO_KEY")
+	kekFile := filepath.Join(t.TempDir(), "kek.json")
+	if err := ioutil.WriteFile(kekFile, []byte(`{"current":"test","keys":{"test":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("CD_CRYPTO_KEKFILE", kekFile)
+	defer os.Unsetenv("CD_CRYPTO_KEKFILE")
+	if _, err := utils.LoadConfig("../configuration/server.yaml"); err != nil {
+		t.Fatalf("load config error: %v", err)
+	}
+	km, err := envelope.NewFileKeyManager("test", map[string][]byte{"test": make([]byte, 32)})
+	if err != nil {
+		t.Fatalf("init key manager error: %v", err)
+	}
+	dataCipher, err := envelope.New(km, envelope.AlgAESGCM, utils.ServerCfg().Crypto.Key)
+	if err != nil {
+		t.Fatalf("init cipher error: %v", err)
+	}
+	api.SetCipher(dataCipher)
+	Store = store.NewMemory()
+	Chain = fakeChain{}
+	Cache = nil
+	Webhook = nil
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	handle := func(f func(*gin.Context) *response.Response) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.JSON(http.StatusOK, f(c).GetData())
+		}
+	}
+	r.POST("/upchain", handle(UpChain))
+	r.POST("/modify", handle(Modify))
+	return r
+}
+
+func post(r http.Handler, path string, form url.Values) map[string]interface{} {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	body := map[string]interface{}{}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	return body
+}
+
+// 并发上链时每个请求都应拿到自己的交易哈希，运行时请加 -race
+func Test_UpChain_Parallel(t *testing.T) {
+	r := setupTest(t)
+	const n = 300
+	hashes := make([]string, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			form := url.Values{}
+			form.Set("phone", fmt.Sprintf("138%08d", i))
+			form.Set("data", fmt.Sprintf(`{"seq":%d}`, i))
+			body := post(r, "/upchain", form)
+			if body["status"] != float64(200) {
+				t.Errorf("request %d failed: %v", i, body)
+				return
+			}
+			hashes[i], _ = body["data"].(string)
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[string]int)
+	for i, hash := range hashes {
+		if prev, ok := seen[hash]; ok {
+			t.Errorf("request %d and %d got the same tx hash %s", prev, i, hash)
+		}
+		seen[hash] = i
+		deposit, err := Store.FindByTxHash(hash)
+		if err != nil {
+			t.Errorf("request %d tx hash %s not indexed: %v", i, hash, err)
+			continue
+		}
+		if want := fmt.Sprintf("138%08d", i); deposit.Phone != want {
+			t.Errorf("request %d got tx hash of phone %s, want %s", i, deposit.Phone, want)
+		}
+	}
+}
+
+// 同一条存证的并发修改只能成功一次
+func Test_Modify_SameRecord(t *testing.T) {
+	r := setupTest(t)
+	phone := "13900000000"
+	form := url.Values{"phone": {phone}, "data": {`{"v":0}`}}
+	hash, _ := post(r, "/upchain", form)["data"].(string)
+
+	const n = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			form := url.Values{"phone": {phone}, "hash": {hash}, "data": {fmt.Sprintf(`{"v":%d}`, i+1)}}
+			if post(r, "/modify", form)["status"] == float64(200) {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	if succeeded != 1 {
+		t.Errorf("expected exactly one successful modify, got %d", succeeded)
+	}
+}
@@ -0,0 +1,223 @@
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"git.huawei.com/goclient/api"
+	"git.huawei.com/goclient/response"
+	"git.huawei.com/goclient/store"
+	"git.huawei.com/goclient/utils"
+)
+
+// jobRunner 异步上链任务队列，队列中只保存任务ID，任务内容和进度均记录在Store中
+type jobRunner struct {
+	queue    chan string
+	quit     chan struct{}
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+// 异步上链任务队列，StartJobs之前为nil
+var jobs *jobRunner
+
+var jobIDReg = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// StartJobs 启动异步上链协程，并在后台恢复上次退出时未结束的任务
+func StartJobs(workers int, queueSize int) error {
+	unfinished, err := Store.ListUnfinishedJobs()
+	if err != nil {
+		return err
+	}
+	r := &jobRunner{queue: make(chan string, queueSize), quit: make(chan struct{})}
+	for i := 0; i < workers; i++ {
+		r.wg.Add(1)
+		go r.work()
+	}
+	jobs = r
+	if len(unfinished) > 0 {
+		r.wg.Add(1)
+		go r.recover(unfinished)
+	}
+	return nil
+}
+
+// StopJobs 停止处理任务并等待进行中的任务结束，ctx到期后直接返回；
+// 队列中和未完成的任务已记录在Store中，下次启动时恢复
+func StopJobs(ctx context.Context) error {
+	r := jobs
+	if r == nil {
+		return nil
+	}
+	r.stopOnce.Do(func() {
+		close(r.quit)
+	})
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// 保存任务并放入队列，返回任务ID；队列已满时任务记为失败
+func submitJob(phone string, rec api.Recode, callbackURL string) (string, error) {
+	r := jobs
+	if r == nil {
+		fmt.Println("job runner is not started")
+		return "", utils.ErrorNew(response.CodeChainFailed, "上链失败，建议重试")
+	}
+	id, err := newJobID()
+	if err != nil {
+		fmt.Println(err)
+		return "", utils.ErrorNew(response.CodeChainFailed, "上链失败，建议重试")
+	}
+	job := &store.Job{ID: id, Phone: phone, DataKey: rec.ID, Data: rec.Ciphertext, ContentHash: rec.ContentHash,
+		TimeStamp: rec.Timestamp, State: store.JobPending, CallbackURL: callbackURL}
+	if err := saveJob(job); err != nil {
+		return "", utils.ErrorNew(response.CodeChainFailed, "上链失败，建议重试")
+	}
+	select {
+	case r.queue <- id:
+		return id, nil
+	default:
+		job.State, job.Msg = store.JobFailed, "任务队列已满"
+		saveJob(job)
+		return "", utils.ErrorNew(response.CodeQueueFull, "任务队列已满，请稍后重试")
+	}
+}
+
+func (r *jobRunner) work() {
+	defer r.wg.Done()
+	for {
+		select {
+		case <-r.quit:
+			return
+		case id := <-r.queue:
+			runJob(id)
+		}
+	}
+}
+
+// 恢复未结束的任务：已确定交易哈希的先查询链上结果，链上没有该交易时重新提交
+func (r *jobRunner) recover(unfinished []store.Job) {
+	defer r.wg.Done()
+	for i := range unfinished {
+		job := &unfinished[i]
+		if job.State != store.JobPending && job.TxHash != "" {
+			status, err := Chain.QueryTxStatus(context.Background(), job.TxHash)
+			if err == nil {
+				finishJob(job, status)
+				continue
+			}
+			if utils.GetCode(err) != response.CodeNotFound {
+				//状态未知时不重新提交，避免重复上链，留待下次启动再确认
+				fmt.Printf("recover job %s error: %v\n", job.ID, err)
+				continue
+			}
+			job.State, job.TxHash = store.JobPending, ""
+			if saveJob(job) != nil {
+				continue
+			}
+		}
+		select {
+		case <-r.quit:
+			return
+		case r.queue <- job.ID:
+		}
+	}
+}
+
+// 提交任务并记录各阶段状态
+func runJob(id string) {
+	job, err := Store.FindJob(id)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if job.Finished() {
+		return
+	}
+	rec, err := jobRecode(job)
+	if err != nil {
+		job.State, job.Msg = store.JobFailed, utils.GetMsg(err)
+		saveJob(job)
+		return
+	}
+	txHash, err := Chain.SubmitRecode(context.Background(), rec, func(stage string, txHash string) {
+		job.State, job.TxHash = stage, txHash
+		saveJob(job)
+	})
+	if err == nil {
+		job.TxHash = txHash
+		finishJob(job, store.JobValid)
+		return
+	}
+	if statusErr, ok := err.(*utils.TxStatusError); ok {
+		job.TxHash = statusErr.TxHash
+		finishJob(job, statusErr.Status)
+		return
+	}
+	//已发送至共识节点但等待超时，交易仍可能落块，先查询一次链上结果
+	if job.State == store.JobSubmitted {
+		if status, qerr := Chain.QueryTxStatus(context.Background(), job.TxHash); qerr == nil {
+			finishJob(job, status)
+			return
+		}
+	}
+	job.State, job.Msg = store.JobFailed, utils.GetMsg(err)
+	saveJob(job)
+}
+
+// 记录任务的落块状态，交易有效时写入存证索引，并按需回调
+func finishJob(job *store.Job, status string) {
+	job.State = status
+	if status == store.JobValid {
+		indexDeposit(job.Phone, job.TxHash, job.TimeStamp, job.DataKey, job.Data)
+		job.Msg = "上链成功"
+	} else {
+		job.Msg = "交易校验失败"
+	}
+	notify(job.CallbackURL, job.TxHash, status)
+	saveJob(job)
+}
+
+// 任务对应的结构化存证，早期任务没有记录明文哈希，解密后计算
+func jobRecode(job *store.Job) (api.Recode, error) {
+	rec := api.Recode{ID: job.DataKey, Owner: ownerHash(job.Phone), Ciphertext: job.Data, ContentHash: job.ContentHash, Timestamp: job.TimeStamp}
+	if rec.ContentHash == "" {
+		data, err := api.Decrypt(job.Data)
+		if err != nil {
+			return rec, err
+		}
+		rec = newRecode(job.Phone, job.DataKey, data, job.Data, job.TimeStamp)
+	}
+	return rec, nil
+}
+
+func saveJob(job *store.Job) error {
+	job.UpdatedAt = time.Now().Unix()
+	err := Store.SaveJob(*job)
+	if err != nil {
+		fmt.Println(err)
+	}
+	return err
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,192 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"git.huawei.com/goclient/api"
+	"git.huawei.com/goclient/response"
+	"git.huawei.com/goclient/store"
+	"git.huawei.com/goclient/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// jobChain 在fakeChain基础上模拟落块校验失败和已落块交易的查询
+type jobChain struct {
+	fakeChain
+	invalid   map[string]bool   // 按datakey指定校验失败的交易
+	committed map[string]string // 已落块交易的状态
+}
+
+func (f jobChain) SubmitRecode(ctx context.Context, rec api.Recode, progress utils.TxProgress) (string, error) {
+	txHash, _ := f.SaveRecode(rec.ID, rec.Ciphertext)
+	if progress != nil {
+		progress(utils.TxStageEndorsed, txHash)
+		progress(utils.TxStageSubmitted, txHash)
+	}
+	if f.invalid[rec.ID] {
+		return "", &utils.TxStatusError{TxHash: txHash, Status: "INVALID_MVCC"}
+	}
+	return txHash, nil
+}
+
+func (f jobChain) QueryTxStatus(ctx context.Context, txHash string) (string, error) {
+	if status, ok := f.committed[txHash]; ok {
+		return status, nil
+	}
+	return "", utils.ErrorNew(response.CodeNotFound, "交易哈希不存在")
+}
+
+func startTestJobs(t *testing.T) *gin.Engine {
+	r := setupTest(t)
+	r.GET("/jobs/:id", func(c *gin.Context) {
+		c.JSON(http.StatusOK, QueryJob(c).GetData())
+	})
+	if err := StartJobs(2, 16); err != nil {
+		t.Fatalf("start jobs error: %v", err)
+	}
+	return r
+}
+
+func stopTestJobs(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := StopJobs(ctx); err != nil {
+		t.Errorf("stop jobs error: %v", err)
+	}
+	jobs = nil
+}
+
+// 轮询任务直到结束
+func waitJob(t *testing.T, r http.Handler, id string) store.Job {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		req := httptest.NewRequest(http.MethodGet, "/jobs/"+id, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var body struct {
+			Status int       `json:"status"`
+			Data   store.Job `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Status != 200 {
+			t.Fatalf("query job %s failed: %s", id, w.Body.String())
+		}
+		if body.Data.Finished() {
+			return body.Data
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s not finished in time", id)
+	return store.Job{}
+}
+
+func Test_UpChain_Async(t *testing.T) {
+	r := startTestJobs(t)
+	defer stopTestJobs(t)
+	chain := jobChain{invalid: map[string]bool{}}
+	Chain = chain
+
+	ids := make([]string, 5)
+	for i := range ids {
+		form := url.Values{"phone": {fmt.Sprintf("137%08d", i)}, "data": {`{"v":1}`}, "async": {"true"}}
+		body := post(r, "/upchain", form)
+		if body["status"] != float64(200) {
+			t.Fatalf("async upchain failed: %v", body)
+		}
+		ids[i], _ = body["data"].(string)
+	}
+	for i, id := range ids {
+		job := waitJob(t, r, id)
+		if job.State != store.JobValid || job.TxHash == "" {
+			t.Errorf("job %d unexpected result: %+v", i, job)
+			continue
+		}
+		deposit, err := Store.FindByTxHash(job.TxHash)
+		if err != nil || deposit.Phone != fmt.Sprintf("137%08d", i) {
+			t.Errorf("job %d deposit not indexed: %v %v", i, deposit, err)
+		}
+	}
+
+	//落块校验失败的交易记录链上状态，且不写入索引
+	timestamp := time.Now().Unix()
+	datakey := newDataKey("13600000000", timestamp)
+	chain.invalid[datakey] = true
+	id, err := submitJob("13600000000", newRecode("13600000000", datakey, "{}", "data", timestamp), "")
+	if err != nil {
+		t.Fatalf("submit job error: %v", err)
+	}
+	job := waitJob(t, r, id)
+	if job.State != "INVALID_MVCC" || job.TxHash == "" {
+		t.Errorf("unexpected invalid job result: %+v", job)
+	}
+	if _, err := Store.FindByTxHash(job.TxHash); err != store.ErrNotFound {
+		t.Errorf("invalid tx should not be indexed, got %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/jobs/00000000000000000000000000000000", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	body := map[string]interface{}{}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if body["status"] != float64(603) {
+		t.Errorf("unexpected response for missing job: %s", w.Body.String())
+	}
+}
+
+// 重启后恢复未结束的任务：已落块的直接记录结果，链上不存在的重新提交
+func Test_RecoverJobs(t *testing.T) {
+	setupTest(t)
+	committed := store.Job{ID: "committed", Phone: "13500000001", DataKey: "k1", Data: "d1", ContentHash: "c1",
+		TimeStamp: 1, State: store.JobSubmitted, TxHash: "h1"}
+	lost := store.Job{ID: "lost", Phone: "13500000002", DataKey: "k2", Data: "d2", ContentHash: "c2",
+		TimeStamp: 2, State: store.JobEndorsed, TxHash: "h2"}
+	pending := store.Job{ID: "pending", Phone: "13500000003", DataKey: "k3", Data: "d3", ContentHash: "c3",
+		TimeStamp: 3, State: store.JobPending}
+	for _, job := range []store.Job{committed, lost, pending} {
+		if err := Store.SaveJob(job); err != nil {
+			t.Fatalf("save job error: %v", err)
+		}
+	}
+	Chain = jobChain{committed: map[string]string{"h1": store.JobValid}}
+	if err := StartJobs(2, 16); err != nil {
+		t.Fatalf("start jobs error: %v", err)
+	}
+	defer stopTestJobs(t)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		unfinished, err := Store.ListUnfinishedJobs()
+		if err != nil {
+			t.Fatalf("list unfinished jobs error: %v", err)
+		}
+		if len(unfinished) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("jobs not recovered: %+v", unfinished)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for _, id := range []string{"committed", "lost", "pending"} {
+		job, _ := Store.FindJob(id)
+		if job.State != store.JobValid {
+			t.Errorf("job %s unexpected state: %+v", id, job)
+		}
+		if _, err := Store.FindByTxHash(job.TxHash); err != nil {
+			t.Errorf("job %s deposit not indexed: %v", id, err)
+		}
+	}
+	if job, _ := Store.FindJob("committed"); job.TxHash != "h1" {
+		t.Errorf("committed job should keep its tx hash, got %s", job.TxHash)
+	}
+	if job, _ := Store.FindJob("lost"); job.TxHash == "h2" {
+		t.Errorf("lost job should be resubmitted with a new tx hash")
+	}
+}
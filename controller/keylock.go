@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"sync"
+)
+
+// keyLock 按key加锁，不同key之间互不阻塞，锁在无人持有时自动回收
+type keyLock struct {
+	mu    sync.Mutex
+	locks map[string]*keyLockEntry
+}
+
+type keyLockEntry struct {
+	mu   sync.Mutex
+	refs int
+}
+
+func newKeyLock() *keyLock {
+	return &keyLock{locks: make(map[string]*keyLockEntry)}
+}
+
+// Lock 对key加锁，返回对应的解锁函数
+func (l *keyLock) Lock(key string) func() {
+	l.mu.Lock()
+	entry, ok := l.locks[key]
+	if !ok {
+		entry = &keyLockEntry{}
+		l.locks[key] = entry
+	}
+	entry.refs++
+	l.mu.Unlock()
+
+	entry.mu.Lock()
+	return func() {
+		entry.mu.Unlock()
+		l.mu.Lock()
+		entry.refs--
+		if entry.refs == 0 {
+			delete(l.locks, key)
+		}
+		l.mu.Unlock()
+	}
+}
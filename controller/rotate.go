@@ -0,0 +1,283 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"sync"
+	"time"
+
+	"git.huawei.com/goclient/api"
+	"git.huawei.com/goclient/response"
+	"git.huawei.com/goclient/store"
+	"git.huawei.com/goclient/utils"
+)
+
+// 每批从Store读取的存证数量
+const rotationBatch = 100
+
+// 单条存证的轮换结果
+const (
+	rotateRewrapped   = "rewrapped"
+	rotateReencrypted = "reencrypted"
+	rotateSkipped     = "skipped"
+)
+
+// keyRotator 后台执行的KEK轮换，同一时间只运行一个，进度记录在Store中
+type keyRotator struct {
+	quit chan struct{}
+	done chan struct{}
+}
+
+var (
+	rotatorMu sync.Mutex
+	rotator   *keyRotator
+)
+
+// ResumeRotation 继续上次退出时未完成的、轮换到当前KEK的任务
+func ResumeRotation() error {
+	rotation, err := Store.FindRotation(api.CurrentKeyID())
+	if err == store.ErrRotationNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if rotation.State != store.RotationRunning {
+		return nil
+	}
+	_, err = startRotation(rotation.Reencrypt)
+	return err
+}
+
+// StopRotation 停止轮换并等待当前存证处理完成，ctx到期后直接返回
+func StopRotation(ctx context.Context) error {
+	rotatorMu.Lock()
+	r := rotator
+	rotatorMu.Unlock()
+	if r == nil {
+		return nil
+	}
+	select {
+	case <-r.quit:
+	default:
+		close(r.quit)
+	}
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// LookupKey 查询本地索引中存证的KEK标识和包装后的数据密钥，供api包解密时使用
+func LookupKey(txHash string) (string, []byte, bool) {
+	deposit, err := Store.FindByTxHash(txHash)
+	if err != nil || deposit.KeyID == "" {
+		return "", nil, false
+	}
+	wrappedKey, err := base64.StdEncoding.DecodeString(deposit.WrappedKey)
+	if err != nil {
+		fmt.Println(err)
+		return "", nil, false
+	}
+	return deposit.KeyID, wrappedKey, true
+}
+
+// 开始轮换到当前KEK，上次未完成时从中断处继续，已完成时从头开始以重试失败的存证
+func startRotation(reencrypt bool) (*store.Rotation, error) {
+	keyID := api.CurrentKeyID()
+	if keyID == "" {
+		fmt.Println("cipher is not initialized")
+		return nil, utils.ErrorNew(response.CodeInternal, "服务内部错误")
+	}
+	rotatorMu.Lock()
+	defer rotatorMu.Unlock()
+	if rotator != nil {
+		select {
+		case <-rotator.done:
+		default:
+			return nil, utils.ErrorNew(response.CodeRotationRunning, "KEK轮换正在进行")
+		}
+	}
+
+	rotation, err := Store.FindRotation(keyID)
+	if err == store.ErrRotationNotFound || (err == nil && rotation.State == store.RotationDone) {
+		rotation, err = &store.Rotation{KeyID: keyID}, nil
+	}
+	if err != nil {
+		fmt.Println(err)
+		return nil, utils.ErrorNew(response.CodeInternal, "服务内部错误")
+	}
+	//待处理数量为已处理数量加上次中断后剩余的数量，跳过和失败的存证仍使用旧KEK，需从中扣除
+	stale, err := Store.CountStale(keyID)
+	if err != nil {
+		fmt.Println(err)
+		return nil, utils.ErrorNew(response.CodeInternal, "服务内部错误")
+	}
+	rotation.Reencrypt = reencrypt
+	rotation.State = store.RotationRunning
+	rotation.Total = rotation.Processed + stale - rotation.Skipped - rotation.Failed
+	if err := saveRotation(rotation); err != nil {
+		return nil, utils.ErrorNew(response.CodeInternal, "服务内部错误")
+	}
+
+	r := &keyRotator{quit: make(chan struct{}), done: make(chan struct{})}
+	rotator = r
+	go r.run(*rotation)
+	return rotation, nil
+}
+
+// 查询轮换进度
+func findRotation(keyID string) (*store.Rotation, error) {
+	rotation, err := Store.FindRotation(keyID)
+	if err != nil {
+		if err != store.ErrRotationNotFound {
+			fmt.Println(err)
+		}
+		return nil, utils.ErrorNew(response.CodeNotFound, "轮换记录不存在")
+	}
+	return rotation, nil
+}
+
+// 按交易哈希顺序分批处理存证，每处理一条记录一次进度
+func (r *keyRotator) run(rotation store.Rotation) {
+	defer close(r.done)
+	for {
+		deposits, err := Store.ListActive(rotation.LastTxHash, rotationBatch)
+		if err != nil {
+			//保持running状态，下次启动时继续
+			rotation.LastError = err.Error()
+			saveRotation(&rotation)
+			return
+		}
+		if len(deposits) == 0 {
+			rotation.State = store.RotationDone
+			saveRotation(&rotation)
+			return
+		}
+		for _, deposit := range deposits {
+			select {
+			case <-r.quit:
+				return
+			default:
+			}
+			rotation.LastTxHash = deposit.TxHash
+			//已使用当前KEK的存证，包括本次重新加密上链产生的新记录
+			if deposit.KeyID == rotation.KeyID {
+				continue
+			}
+			result, err := rotateDeposit(deposit, rotation.Reencrypt)
+			switch {
+			case err != nil:
+				rotation.Failed++
+				rotation.LastError = deposit.TxHash + ": " + utils.GetMsg(err)
+			case result == rotateRewrapped:
+				rotation.Rewrapped++
+			case result == rotateReencrypted:
+				rotation.Reencrypted++
+			case result == rotateSkipped:
+				rotation.Skipped++
+			}
+			rotation.Processed++
+			saveRotation(&rotation)
+		}
+	}
+}
+
+// 轮换单条存证：默认只用当前KEK重新包装本地索引中的数据密钥，不改动链上密文；
+// reencrypt为true或旧版AES-ECB存证时重新加密并上链，旧交易标记为已修改。
+// 两种方式都需校验新的密钥或密文解密后与原明文的哈希一致
+func rotateDeposit(deposit store.Deposit, reencrypt bool) (string, error) {
+	if deposit.KeyID == "" && !reencrypt {
+		return rotateSkipped, nil
+	}
+	//与修改存证互斥，并确认存证在等待期间未被修改
+	unlockHash := keyLocks.Lock(deposit.TxHash)
+	defer unlockHash()
+	if current, err := Store.FindByTxHash(deposit.TxHash); err != nil || current.IsModify || current.IsRevoked {
+		return rotateSkipped, nil
+	}
+
+	ciphertext, err := Chain.QueryCiphertext(context.Background(), deposit.TxHash)
+	if err != nil {
+		return "", err
+	}
+	wrappedKey, err := base64.StdEncoding.DecodeString(deposit.WrappedKey)
+	if err != nil {
+		return "", utils.ErrorNew(response.CodeInternal, "数据密钥格式错误")
+	}
+	plaintext, err := api.DecryptWithKey(ciphertext, deposit.KeyID, wrappedKey)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256([]byte(plaintext))
+
+	if !reencrypt {
+		keyID, rewrapped, err := api.Rewrap(deposit.KeyID, wrappedKey)
+		if err != nil {
+			return "", err
+		}
+		if err := verifyDigest(ciphertext, keyID, rewrapped, digest); err != nil {
+			return "", err
+		}
+		if err := Store.UpdateKey(deposit.TxHash, keyID, base64.StdEncoding.EncodeToString(rewrapped)); err != nil {
+			fmt.Println(err)
+			return "", utils.ErrorNew(response.CodeInternal, "更新本地索引失败")
+		}
+		return rotateRewrapped, nil
+	}
+
+	cyptdata, err := api.Encrypt(plaintext)
+	if err != nil {
+		return "", err
+	}
+	if err := verifyDigest(cyptdata, "", nil, digest); err != nil {
+		return "", err
+	}
+	//沿用原datakey，链上同一存证更新为新密文，本地索引沿用原时间戳；早期存证没有datakey时生成新的
+	datakey := deposit.DataKey
+	if datakey == "" {
+		datakey = newDataKey(deposit.Phone, deposit.TimeStamp)
+	}
+	txHash, err := commitRecode(context.Background(), newRecode(deposit.Phone, datakey, plaintext, cyptdata, time.Now().Unix()), "", "重新加密上链失败")
+	if err != nil {
+		return "", err
+	}
+	replaced := newDeposit(deposit.Phone, txHash, deposit.TimeStamp, datakey, cyptdata)
+	replaced.PrevHash = deposit.TxHash
+	unlockPhone := keyLocks.Lock(deposit.Phone)
+	defer unlockPhone()
+	if err := Store.Insert(replaced); err != nil {
+		fmt.Println(err)
+	}
+	if err := Store.MarkModified(deposit.TxHash); err != nil {
+		fmt.Println(err)
+	}
+	cacheDel(deposit.Phone, deposit.TxHash)
+	return rotateReencrypted, nil
+}
+
+// 校验密文使用给定数据密钥解密后的明文哈希
+func verifyDigest(ciphertext string, keyID string, wrappedKey []byte, digest [sha256.Size]byte) error {
+	plaintext, err := api.DecryptWithKey(ciphertext, keyID, wrappedKey)
+	if err != nil {
+		return err
+	}
+	if sum := sha256.Sum256([]byte(plaintext)); !bytes.Equal(sum[:], digest[:]) {
+		return utils.ErrorNew(response.CodeInternal, "校验失败，解密结果与原数据不一致")
+	}
+	return nil
+}
+
+func saveRotation(rotation *store.Rotation) error {
+	rotation.UpdatedAt = time.Now().Unix()
+	err := Store.SaveRotation(*rotation)
+	if err != nil {
+		fmt.Println(err)
+	}
+	return err
+}
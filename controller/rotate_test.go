@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"git.huawei.com/goclient/api"
+	"git.huawei.com/goclient/envelope"
+	"git.huawei.com/goclient/store"
+	"git.huawei.com/goclient/utils"
+	"github.com/deatil/go-cryptobin/cryptobin/crypto"
+)
+
+func setTestCipher(t *testing.T, current string, keyIDs ...string) {
+	keys := map[string][]byte{}
+	for _, id := range keyIDs {
+		keys[id] = []byte(fmt.Sprintf("%032s", id))
+	}
+	km, err := envelope.NewFileKeyManager(current, keys)
+	if err != nil {
+		t.Fatalf("init key manager error: %v", err)
+	}
+	c, err := envelope.New(km, envelope.AlgAESGCM, utils.ServerCfg().Crypto.Key)
+	if err != nil {
+		t.Fatalf("init cipher error: %v", err)
+	}
+	api.SetCipher(c)
+}
+
+// 等待轮换结束
+func waitRotation(t *testing.T, reencrypt bool) *store.Rotation {
+	if _, err := startRotation(reencrypt); err != nil {
+		t.Fatalf("start rotation error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	select {
+	case <-rotator.done:
+	case <-ctx.Done():
+		t.Fatal("rotation not finished")
+	}
+	rotation, err := findRotation(api.CurrentKeyID())
+	if err != nil || rotation.State != store.RotationDone {
+		t.Fatalf("unexpected rotation: %+v %v", rotation, err)
+	}
+	return rotation
+}
+
+// 使用本地索引中的数据密钥解密链上密文
+func readDeposit(t *testing.T, chain *ledgerChain, txHash string) string {
+	ciphertext, err := chain.QueryCiphertext(context.Background(), txHash)
+	if err != nil {
+		t.Fatalf("query ciphertext error: %v", err)
+	}
+	keyID, wrappedKey, _ := LookupKey(txHash)
+	plaintext, err := api.DecryptWithKey(ciphertext, keyID, wrappedKey)
+	if err != nil {
+		t.Fatalf("decrypt %s error: %v", txHash, err)
+	}
+	return plaintext
+}
+
+func Test_RotateKeys(t *testing.T) {
+	setupTest(t)
+	chain := newLedgerChain()
+	Chain = chain
+	setTestCipher(t, "k1", "k1", "k2")
+
+	phone := "13800000000"
+	payloads := map[string]string{}
+	for i := 0; i < 3; i++ {
+		data := fmt.Sprintf(`{"n":%d}`, i)
+		txHash, _, err := saveDeposit(context.Background(), phone, data, "")
+		if err != nil {
+			t.Fatalf("save deposit error: %v", err)
+		}
+		payloads[txHash] = data
+	}
+	//旧版AES-ECB存证
+	legacyHash := strings.Repeat("0", 64)
+	chain.data[legacyHash] = crypto.FromString(`{"legacy":true}`).SetKey(utils.ServerCfg().Crypto.Key).Aes().ECB().PKCS7Padding().Encrypt().ToBase64String()
+	Store.Insert(store.Deposit{TxHash: legacyHash, Phone: phone, TimeStamp: 1, DataKey: "legacy"})
+
+	//切换到k2后仅重新包装，链上密文不变，旧版存证跳过
+	setTestCipher(t, "k2", "k1", "k2")
+	rotation := waitRotation(t, false)
+	if rotation.Total != 4 || rotation.Processed != 4 || rotation.Rewrapped != 3 || rotation.Skipped != 1 || rotation.Failed != 0 {
+		t.Errorf("unexpected rewrap result: %+v", rotation)
+	}
+	//停用k1后仍可读取
+	setTestCipher(t, "k2", "k2")
+	for txHash, data := range payloads {
+		if got := readDeposit(t, chain, txHash); got != data {
+			t.Errorf("deposit %s decrypted to %s, want %s", txHash, got, data)
+		}
+	}
+
+	//重新加密旧版存证并上链，新旧交易哈希关联
+	rotation = waitRotation(t, true)
+	if rotation.Total != 1 || rotation.Reencrypted != 1 || rotation.Failed != 0 {
+		t.Errorf("unexpected reencrypt result: %+v", rotation)
+	}
+	if old, _ := Store.FindByTxHash(legacyHash); !old.IsModify {
+		t.Errorf("legacy deposit not marked modified: %+v", old)
+	}
+	latest, _ := Store.FindByPhone(phone, 0)
+	var replaced *store.Deposit
+	for i := range latest {
+		if latest[i].PrevHash == legacyHash {
+			replaced = &latest[i]
+		}
+	}
+	if replaced == nil || replaced.KeyID != "k2" || replaced.DataKey != "legacy" {
+		t.Fatalf("reencrypted deposit not linked: %+v", latest)
+	}
+	if got := readDeposit(t, chain, replaced.TxHash); got != `{"legacy":true}` {
+		t.Errorf("reencrypted deposit decrypted to %s", got)
+	}
+}
+
+// 中断后从最后处理的存证继续
+func Test_RotateKeys_Resume(t *testing.T) {
+	setupTest(t)
+	chain := newLedgerChain()
+	Chain = chain
+	setTestCipher(t, "k1", "k1", "k2")
+	for i := 0; i < 4; i++ {
+		if _, _, err := saveDeposit(context.Background(), "13800000000", `{"n":1}`, ""); err != nil {
+			t.Fatalf("save deposit error: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	deposits, _ := Store.ListActive("", 0)
+
+	//模拟处理两条后中断
+	setTestCipher(t, "k2", "k1", "k2")
+	for _, d := range deposits[:2] {
+		if _, err := rotateDeposit(d, false); err != nil {
+			t.Fatalf("rotate deposit error: %v", err)
+		}
+	}
+	Store.SaveRotation(store.Rotation{KeyID: "k2", State: store.RotationRunning, LastTxHash: deposits[1].TxHash,
+		Processed: 2, Rewrapped: 2})
+
+	rotator = nil
+	if err := ResumeRotation(); err != nil {
+		t.Fatalf("resume rotation error: %v", err)
+	}
+	<-rotator.done
+	rotation, _ := findRotation("k2")
+	if rotation.State != store.RotationDone || rotation.Total != 4 || rotation.Processed != 4 || rotation.Rewrapped != 4 {
+		t.Errorf("unexpected resumed rotation: %+v", rotation)
+	}
+	if stale, _ := Store.CountStale("k2"); stale != 0 {
+		t.Errorf("%d deposits still use old kek", stale)
+	}
+}
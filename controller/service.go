@@ -0,0 +1,357 @@
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"git.huawei.com/goclient/api"
+	"git.huawei.com/goclient/envelope"
+	"git.huawei.com/goclient/response"
+	"git.huawei.com/goclient/store"
+	"git.huawei.com/goclient/utils"
+)
+
+// 以下为旧接口与 /api/v1 共用的存证业务逻辑，返回的错误均由utils.ErrorNew创建，错误码见response.Errors
+
+// 校验参数、加密数据并上链，交易落块后返回交易哈希和存证ID
+func saveDeposit(ctx context.Context, phone string, data string, callbackURL string) (string, string, error) {
+	if !verifyMobileFormat(phone) || !json.Valid([]byte(data)) {
+		return "", "", utils.ErrorNew(response.CodeInvalidParam, "参数无效")
+	}
+	if err := checkCallback(callbackURL); err != nil {
+		return "", "", err
+	}
+	result, err := callWithContext(ctx, func() (interface{}, error) {
+		//数据加密
+		cyptdata, err := api.Encrypt(data)
+		if err != nil {
+			return nil, err
+		}
+
+		//生成datakey，即手机号+时间戳的hash，作为存证ID，后续修改沿用
+		timestamp := time.Now().Unix()
+		datakey := newDataKey(phone, timestamp)
+
+		//上链存储
+		hash, err := commitRecode(ctx, newRecode(phone, datakey, data, cyptdata, timestamp), callbackURL, "上链失败，建议重试")
+		if err != nil {
+			return nil, err
+		}
+
+		//本地存储并删除对应缓存
+		indexDeposit(phone, hash, timestamp, datakey, cyptdata)
+		return committed{txHash: hash, recordID: datakey}, nil
+	})
+	if err != nil {
+		return "", "", err
+	}
+	c := result.(committed)
+	return c.txHash, c.recordID, nil
+}
+
+// 校验参数并创建异步上链任务，返回任务ID
+func saveDepositAsync(phone string, data string, callbackURL string) (string, error) {
+	if !verifyMobileFormat(phone) || !json.Valid([]byte(data)) {
+		return "", utils.ErrorNew(response.CodeInvalidParam, "参数无效")
+	}
+	if err := checkCallback(callbackURL); err != nil {
+		return "", err
+	}
+	cyptdata, err := api.Encrypt(data)
+	if err != nil {
+		return "", err
+	}
+	timestamp := time.Now().Unix()
+	return submitJob(phone, newRecode(phone, newDataKey(phone, timestamp), data, cyptdata, timestamp), callbackURL)
+}
+
+// 查询手机号下最新的三条存证
+func depositsByPhone(ctx context.Context, phone string) ([]api.Message, error) {
+	if !verifyMobileFormat(phone) {
+		return nil, utils.ErrorNew(response.CodeInvalidParam, "参数无效")
+	}
+	var cached []api.Message
+	//先在缓存中查找数据
+	if r := cacheGet(phone); r != "" && json.Unmarshal([]byte(r), &cached) == nil {
+		return cached, nil
+	}
+	result, err := callWithContext(ctx, func() (interface{}, error) {
+		//先在本地数据库中找到对应交易哈希，本地索引缺失或读取失败时直接按所有者查询链上存证
+		hashlist := make([]string, 0, 3)
+		deposit, err := Store.FindByPhone(phone, 3)
+		if err != nil {
+			fmt.Println(err)
+		}
+		for i := 0; i < len(deposit); i++ {
+			hashlist = append(hashlist, deposit[i].TxHash)
+		}
+		var result string
+		if len(hashlist) > 0 {
+			result, err = Chain.QueryByPhone(ctx, hashlist)
+		} else {
+			result, err = Chain.QueryByOwner(ctx, ownerHash(phone), 3)
+		}
+		if err != nil {
+			return nil, err
+		}
+		//数据暂存至缓存
+		unlock := keyLocks.Lock(phone)
+		cacheSet(phone, result)
+		unlock()
+		var messages []api.Message
+		if err = json.Unmarshal([]byte(result), &messages); err != nil {
+			fmt.Println("json转换错误")
+		}
+		return messages, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]api.Message), nil
+}
+
+// 根据交易哈希查询存证，已被修改的存证需使用新哈希查询
+func depositByHash(ctx context.Context, hash string) (*api.Message, error) {
+	if !VerifyHashFormat(hash) {
+		return nil, utils.ErrorNew(response.CodeInvalidParam, "参数无效")
+	}
+	cached := &api.Message{}
+	//先在缓存中查找数据
+	if r := cacheGet(hash); r != "" && json.Unmarshal([]byte(r), cached) == nil {
+		return cached, nil
+	}
+	message, err := callWithContext(ctx, func() (interface{}, error) {
+		//先在本地数据库中寻找
+		deposit, err := Store.FindByTxHash(hash)
+		if err != nil {
+			if err != store.ErrNotFound {
+				fmt.Println(err)
+			}
+			return nil, utils.ErrorNew(response.CodeNotFound, "交易哈希不存在")
+		}
+		if deposit.IsModify {
+			return nil, utils.ErrorNew(response.CodeAlreadyModified, "该数据已被修改，请使用新哈希查询")
+		}
+		result, err := Chain.QueryByHash(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
+		//数据暂存至缓存
+		unlock := keyLocks.Lock(hash)
+		cacheSet(hash, result)
+		unlock()
+		message := &api.Message{}
+		if err = json.Unmarshal([]byte(result), message); err != nil {
+			fmt.Println("json字符串转为对象错误!")
+		}
+		return message, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return message.(*api.Message), nil
+}
+
+// 修改手机号下的一条存证，新版本写入同一存证ID，返回新版本的交易哈希和存证ID
+func modifyDeposit(ctx context.Context, phone string, hash string, data string, callbackURL string) (string, string, error) {
+	if !verifyMobileFormat(phone) || !VerifyHashFormat(hash) || !json.Valid([]byte(data)) {
+		return "", "", utils.ErrorNew(response.CodeInvalidParam, "参数无效")
+	}
+	if err := checkCallback(callbackURL); err != nil {
+		return "", "", err
+	}
+	result, err := callWithContext(ctx, func() (interface{}, error) {
+		//数据加密
+		cyptdata, err := api.Encrypt(data)
+		if err != nil {
+			return nil, err
+		}
+
+		//同一条存证的修改需串行执行，避免重复修改同一版本
+		unlockHash := keyLocks.Lock(hash)
+		defer unlockHash()
+
+		//先验证手机号和交易hash对应的东西是否存在
+		deposit, err := Store.FindByTxHash(hash)
+		if err != nil && err != store.ErrNotFound {
+			fmt.Println(err)
+		}
+		if err != nil || deposit.Phone != phone {
+			return nil, utils.ErrorNew(response.CodeNotFound, "该条信息不存在")
+		}
+		if deposit.IsModify {
+			return nil, utils.ErrorNew(response.CodeAlreadyModified, "该数据已被修改，请使用新哈希查询")
+		}
+		if deposit.IsRevoked {
+			return nil, utils.ErrorNew(response.CodeRevoked, "存证已撤销")
+		}
+
+		//数据修改，沿用原datakey，链上可通过存证ID查询全部历史版本；早期存证没有datakey时生成新的
+		timestamp := time.Now().Unix()
+		datakey := deposit.DataKey
+		if datakey == "" {
+			datakey = newDataKey(phone, timestamp)
+		}
+		hashID, err := commitRecode(ctx, newRecode(phone, datakey, data, cyptdata, timestamp), callbackURL, "修改失败")
+		if err != nil {
+			return nil, err
+		}
+
+		//本地数据更新并删除对应缓存
+		unlockPhone := keyLocks.Lock(phone)
+		modified := newDeposit(phone, hashID, timestamp, datakey, cyptdata)
+		modified.PrevHash = hash
+		if serr := Store.Insert(modified); serr != nil {
+			fmt.Println(serr)
+		}
+		if serr := Store.MarkModified(hash); serr != nil {
+			fmt.Println(serr)
+		}
+		cacheDel(phone, hash)
+		unlockPhone()
+		return committed{txHash: hashID, recordID: datakey}, nil
+	})
+	if err != nil {
+		return "", "", err
+	}
+	c := result.(committed)
+	return c.txHash, c.recordID, nil
+}
+
+// 查询存证的全部历史版本，id为存证ID或任一版本的交易哈希
+func depositHistory(ctx context.Context, id string) (*DepositHistory, error) {
+	if !VerifyHashFormat(id) {
+		return nil, utils.ErrorNew(response.CodeInvalidParam, "参数无效")
+	}
+	recordID := id
+	if deposit, err := Store.FindByTxHash(id); err == nil && deposit.DataKey != "" {
+		recordID = deposit.DataKey
+	} else if err != nil && err != store.ErrNotFound {
+		fmt.Println(err)
+	}
+	history, err := callWithContext(ctx, func() (interface{}, error) {
+		versions, err := Chain.QueryHistory(ctx, recordID)
+		if err != nil {
+			return nil, err
+		}
+		if len(versions) == 0 {
+			return nil, utils.ErrorNew(response.CodeNotFound, "存证不存在")
+		}
+		//撤销后最后一个版本为删除操作，撤销原因需单独查询
+		revocation, err := Chain.QueryRevocation(ctx, recordID)
+		if err != nil {
+			return nil, err
+		}
+		return &DepositHistory{RecordID: recordID, Versions: versions, Revocation: revocation}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return history.(*DepositHistory), nil
+}
+
+// 撤销手机号下的一条存证，只能撤销最新版本，撤销后存证ID下的全部版本均不再解密，返回撤销交易的哈希和存证ID
+func revokeDeposit(ctx context.Context, phone string, hash string, reason string) (string, string, error) {
+	if !verifyMobileFormat(phone) || !VerifyHashFormat(hash) || !verifyRevokeReason(reason) {
+		return "", "", utils.ErrorNew(response.CodeInvalidParam, "参数无效")
+	}
+	result, err := callWithContext(ctx, func() (interface{}, error) {
+		//与修改同一条存证互斥
+		unlockHash := keyLocks.Lock(hash)
+		defer unlockHash()
+
+		deposit, err := Store.FindByTxHash(hash)
+		if err != nil && err != store.ErrNotFound {
+			fmt.Println(err)
+		}
+		if err != nil || deposit.Phone != phone {
+			return nil, utils.ErrorNew(response.CodeNotFound, "该条信息不存在")
+		}
+		if deposit.IsModify {
+			return nil, utils.ErrorNew(response.CodeAlreadyModified, "该数据已被修改，请使用新哈希查询")
+		}
+		if deposit.IsRevoked {
+			return nil, utils.ErrorNew(response.CodeRevoked, "存证已撤销")
+		}
+		//早期存证没有datakey，无法定位链上的存证ID
+		if deposit.DataKey == "" {
+			return nil, utils.ErrorNew(response.CodeInvalidParam, "该存证不支持撤销")
+		}
+
+		hashID, err := Chain.RevokeRecode(ctx, deposit.DataKey, ownerHash(phone), reason, time.Now().Unix())
+		if err != nil {
+			return nil, utils.ErrorNew(response.CodeChainFailed, "撤销失败")
+		}
+
+		unlockPhone := keyLocks.Lock(phone)
+		if serr := Store.MarkRevoked(hash); serr != nil {
+			fmt.Println(serr)
+		}
+		cacheDel(phone, hash)
+		unlockPhone()
+		return committed{txHash: hashID, recordID: deposit.DataKey}, nil
+	})
+	if err != nil {
+		return "", "", err
+	}
+	c := result.(committed)
+	return c.txHash, c.recordID, nil
+}
+
+// 查询异步上链任务
+func findJob(id string) (*store.Job, error) {
+	if !jobIDReg.MatchString(id) {
+		return nil, utils.ErrorNew(response.CodeInvalidParam, "参数无效")
+	}
+	job, err := Store.FindJob(id)
+	if err != nil {
+		if err != store.ErrJobNotFound {
+			fmt.Println(err)
+		}
+		return nil, utils.ErrorNew(response.CodeNotFound, "任务不存在")
+	}
+	return job, nil
+}
+
+// 生成本地索引记录，信封密文的KEK标识和包装后的数据密钥一并保存，便于按KEK检索和轮换
+func newDeposit(phone string, txHash string, timestamp int64, datakey string, cyptdata string) store.Deposit {
+	deposit := store.Deposit{TxHash: txHash, Phone: phone, TimeStamp: timestamp, DataKey: datakey}
+	if sealed, err := envelope.Parse(cyptdata); err == nil {
+		deposit.KeyID = sealed.KeyID
+		deposit.WrappedKey = base64.StdEncoding.EncodeToString(sealed.WrappedKey)
+	}
+	return deposit
+}
+
+// committed 上链交易的哈希和存证ID
+type committed struct {
+	txHash   string
+	recordID string
+}
+
+// callResult 链操作协程的返回值和错误
+type callResult struct {
+	value interface{}
+	err   error
+}
+
+// 在独立协程中执行链操作，客户端断开或请求超时时立即返回。
+// ctx取消后执行fn的协程不会被中止，会一直运行到fn返回，fn需自行使用同一个ctx：
+// 链上查询和尚未提交的交易随ctx取消，已提交的链操作会继续执行完成，以保证本地索引与链上数据一致。
+// 协程的结果只通过channel返回，不与调用方共享变量，调用方在返回错误时不读取结果
+func callWithContext(ctx context.Context, fn func() (interface{}, error)) (interface{}, error) {
+	result := make(chan callResult, 1)
+	go func() {
+		value, err := fn()
+		result <- callResult{value: value, err: err}
+	}()
+	select {
+	case r := <-result:
+		return r.value, r.err
+	case <-ctx.Done():
+		return nil, utils.ErrorNew(response.CodeCanceled, "请求已取消")
+	}
+}
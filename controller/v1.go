@@ -0,0 +1,184 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"git.huawei.com/goclient/api"
+	"git.huawei.com/goclient/response"
+	"git.huawei.com/goclient/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// /api/v1 接口，请求体为JSON，响应为response.Envelope，错误码与HTTP状态码见response.Errors
+
+// CreateDepositRequest 新增存证请求
+type CreateDepositRequest struct {
+	Phone       string          `json:"phone" binding:"required"` // 手机号
+	Data        json.RawMessage `json:"data" binding:"required"`  // 存证内容，任意JSON
+	CallbackURL string          `json:"callbackUrl,omitempty"`    // 交易落块后的回调地址
+	Async       bool            `json:"async,omitempty"`          // 为true时立即返回任务ID
+}
+
+// ModifyDepositRequest 修改存证请求，原交易哈希在路径中
+type ModifyDepositRequest struct {
+	Phone       string          `json:"phone" binding:"required"`
+	Data        json.RawMessage `json:"data" binding:"required"`
+	CallbackURL string          `json:"callbackUrl,omitempty"`
+}
+
+// RevokeDepositRequest 撤销存证请求，待撤销存证的交易哈希在路径中
+type RevokeDepositRequest struct {
+	Phone  string `json:"phone" binding:"required"`
+	Reason string `json:"reason" binding:"required"` // 撤销原因，不超过200个字符且不能包含分号
+}
+
+// TxResult 同步上链结果
+type TxResult struct {
+	TxHash   string `json:"txHash"`
+	RecordID string `json:"recordId"` // 存证ID，修改后不变，用于查询历史版本
+}
+
+// DepositHistory 存证的全部历史版本，按写入顺序排列
+type DepositHistory struct {
+	RecordID   string          `json:"recordId"`
+	Versions   []api.Version   `json:"versions"`
+	Revocation *api.Revocation `json:"revocation,omitempty"` // 已撤销时不为空
+}
+
+// JobAccepted 异步上链受理结果
+type JobAccepted struct {
+	JobID string `json:"jobId"`
+}
+
+// CreateDeposit 新增存证，同步时返回201，异步时返回202
+func CreateDeposit(c *gin.Context) *response.Response {
+	var req CreateDepositRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return response.Fail(response.CodeInvalidParam, "参数无效: "+err.Error())
+	}
+	if req.Async {
+		jobID, err := saveDepositAsync(req.Phone, string(req.Data), req.CallbackURL)
+		if err != nil {
+			return v1Error(err)
+		}
+		return response.Ok(http.StatusAccepted, JobAccepted{JobID: jobID})
+	}
+	txHash, recordID, err := saveDeposit(c.Request.Context(), req.Phone, string(req.Data), req.CallbackURL)
+	if err != nil {
+		return v1Error(err)
+	}
+	return response.Ok(http.StatusCreated, TxResult{TxHash: txHash, RecordID: recordID})
+}
+
+// ListDeposits 查询手机号下最新的存证
+func ListDeposits(c *gin.Context) *response.Response {
+	messages, err := depositsByPhone(c.Request.Context(), c.Query("phone"))
+	if err != nil {
+		return v1Error(err)
+	}
+	if messages == nil {
+		messages = []api.Message{}
+	}
+	return response.Ok(http.StatusOK, messages)
+}
+
+// GetDeposit 根据交易哈希查询存证
+func GetDeposit(c *gin.Context) *response.Response {
+	message, err := depositByHash(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		return v1Error(err)
+	}
+	return response.Ok(http.StatusOK, message)
+}
+
+// ModifyDeposit 修改存证，返回新版本的交易哈希
+func ModifyDeposit(c *gin.Context) *response.Response {
+	var req ModifyDepositRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return response.Fail(response.CodeInvalidParam, "参数无效: "+err.Error())
+	}
+	txHash, recordID, err := modifyDeposit(c.Request.Context(), req.Phone, c.Param("id"), string(req.Data), req.CallbackURL)
+	if err != nil {
+		return v1Error(err)
+	}
+	return response.Ok(http.StatusOK, TxResult{TxHash: txHash, RecordID: recordID})
+}
+
+// RevokeDeposit 撤销存证，返回撤销交易的哈希
+func RevokeDeposit(c *gin.Context) *response.Response {
+	var req RevokeDepositRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return response.Fail(response.CodeInvalidParam, "参数无效: "+err.Error())
+	}
+	txHash, recordID, err := revokeDeposit(c.Request.Context(), req.Phone, c.Param("id"), req.Reason)
+	if err != nil {
+		return v1Error(err)
+	}
+	return response.Ok(http.StatusOK, TxResult{TxHash: txHash, RecordID: recordID})
+}
+
+// GetDepositHistory 查询存证的全部历史版本，路径中的id可以是存证ID或任一版本的交易哈希
+func GetDepositHistory(c *gin.Context) *response.Response {
+	history, err := depositHistory(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		return v1Error(err)
+	}
+	return response.Ok(http.StatusOK, history)
+}
+
+// GetJob 查询异步上链任务
+func GetJob(c *gin.Context) *response.Response {
+	job, err := findJob(c.Param("id"))
+	if err != nil {
+		return v1Error(err)
+	}
+	return response.Ok(http.StatusOK, job)
+}
+
+// RotateKeysRequest KEK轮换请求，轮换到KEK文件中的当前KEK
+type RotateKeysRequest struct {
+	Reencrypt bool `json:"reencrypt,omitempty"` // 为true时重新加密存证并上链，旧版AES-ECB存证只能以此方式轮换
+}
+
+// CreateKeyRotation 开始或继续KEK轮换，返回202及轮换进度
+func CreateKeyRotation(c *gin.Context) *response.Response {
+	var req RotateKeysRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			return response.Fail(response.CodeInvalidParam, "参数无效: "+err.Error())
+		}
+	}
+	rotation, err := startRotation(req.Reencrypt)
+	if err != nil {
+		return v1Error(err)
+	}
+	return response.Ok(http.StatusAccepted, rotation)
+}
+
+// GetKeyRotation 查询轮换到指定KEK的进度
+func GetKeyRotation(c *gin.Context) *response.Response {
+	rotation, err := findRotation(c.Param("keyId"))
+	if err != nil {
+		return v1Error(err)
+	}
+	return response.Ok(http.StatusOK, rotation)
+}
+
+// GetNodes 查询链节点的健康状态和当前使用的节点
+func GetNodes(c *gin.Context) *response.Response {
+	status, err := Chain.QueryNodeStatus()
+	if err != nil {
+		return v1Error(err)
+	}
+	return response.Ok(http.StatusOK, status)
+}
+
+// 将业务错误转换为统一响应，非utils.ErrorNew创建的错误按服务内部错误处理
+func v1Error(err error) *response.Response {
+	code := utils.GetCode(err)
+	if code < 0 {
+		return response.Fail(response.CodeInternal, "服务内部错误")
+	}
+	return response.Fail(code, utils.GetMsg(err))
+}
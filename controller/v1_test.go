@@ -0,0 +1,297 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"git.huawei.com/goclient/api"
+	"git.huawei.com/goclient/response"
+	"git.huawei.com/goclient/store"
+	"git.huawei.com/goclient/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func setupV1Test(t *testing.T) *gin.Engine {
+	r := setupTest(t)
+	handle := func(f func(*gin.Context) *response.Response) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			resp := f(c)
+			c.JSON(resp.GetStatus(), resp.GetData())
+		}
+	}
+	v1 := r.Group("/api/v1")
+	v1.POST("/deposits", handle(CreateDeposit))
+	v1.GET("/deposits", handle(ListDeposits))
+	v1.GET("/deposits/:id", handle(GetDeposit))
+	v1.PUT("/deposits/:id", handle(ModifyDeposit))
+	v1.GET("/deposits/:id/history", handle(GetDepositHistory))
+	v1.POST("/deposits/:id/revoke", handle(RevokeDeposit))
+	v1.GET("/jobs/:id", handle(GetJob))
+	return r
+}
+
+func doJSON(r http.Handler, method string, path string, body interface{}) (int, response.Envelope) {
+	var reader *bytes.Reader
+	if body != nil {
+		raw, _ := json.Marshal(body)
+		reader = bytes.NewReader(raw)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var envelope response.Envelope
+	json.Unmarshal(w.Body.Bytes(), &envelope)
+	return w.Code, envelope
+}
+
+func Test_V1_Deposits(t *testing.T) {
+	r := setupV1Test(t)
+
+	status, env := doJSON(r, http.MethodPost, "/api/v1/deposits", gin.H{"phone": "13800000000", "data": gin.H{"amount": 100}})
+	if status != http.StatusCreated || env.Code != response.CodeOK {
+		t.Fatalf("create deposit: %d %+v", status, env)
+	}
+	txHash, _ := env.Data.(map[string]interface{})["txHash"].(string)
+	//本地索引记录信封加密使用的KEK
+	if deposit, err := Store.FindByTxHash(txHash); err != nil || deposit.KeyID != "test" || deposit.WrappedKey == "" {
+		t.Errorf("deposit key metadata not indexed: %+v %v", deposit, err)
+	}
+
+	status, env = doJSON(r, http.MethodGet, "/api/v1/deposits/"+txHash, nil)
+	if status != http.StatusOK || env.Code != response.CodeOK {
+		t.Errorf("get deposit: %d %+v", status, env)
+	}
+
+	status, env = doJSON(r, http.MethodPut, "/api/v1/deposits/"+txHash, gin.H{"phone": "13800000000", "data": gin.H{"amount": 200}})
+	if status != http.StatusOK || env.Code != response.CodeOK {
+		t.Fatalf("modify deposit: %d %+v", status, env)
+	}
+
+	//已修改的存证返回409，旧接口仍按601返回
+	status, env = doJSON(r, http.MethodGet, "/api/v1/deposits/"+txHash, nil)
+	if status != http.StatusConflict || env.Code != response.CodeAlreadyModified {
+		t.Errorf("get modified deposit: %d %+v", status, env)
+	}
+	_, err := depositByHash(context.Background(), txHash)
+	if body := legacyResp(nil, err, "").GetData().(gin.H); body["status"] != response.CodeInvalidParam {
+		t.Errorf("legacy response for modified deposit: %v", body)
+	}
+
+	status, env = doJSON(r, http.MethodGet, "/api/v1/deposits?phone=13800000000", nil)
+	if status != http.StatusOK || env.Code != response.CodeOK {
+		t.Errorf("list deposits: %d %+v", status, env)
+	}
+}
+
+func Test_V1_Errors(t *testing.T) {
+	r := setupV1Test(t)
+	cases := []struct {
+		method string
+		path   string
+		body   interface{}
+		status int
+		code   int
+	}{
+		{http.MethodPost, "/api/v1/deposits", gin.H{"phone": "13800000000"}, http.StatusBadRequest, response.CodeInvalidParam},
+		{http.MethodPost, "/api/v1/deposits", gin.H{"phone": "123", "data": 1}, http.StatusBadRequest, response.CodeInvalidParam},
+		{http.MethodGet, "/api/v1/deposits/" + string(bytes.Repeat([]byte("a"), 64)), nil, http.StatusNotFound, response.CodeNotFound},
+		{http.MethodGet, "/api/v1/deposits/zz", nil, http.StatusBadRequest, response.CodeInvalidParam},
+		{http.MethodGet, "/api/v1/jobs/" + string(bytes.Repeat([]byte("0"), 32)), nil, http.StatusNotFound, response.CodeNotFound},
+		{http.MethodPost, "/api/v1/deposits", gin.H{"phone": "13800000000", "data": 1, "async": true}, http.StatusBadGateway, response.CodeChainFailed},
+	}
+	for _, c := range cases {
+		status, env := doJSON(r, c.method, c.path, c.body)
+		if status != c.status || env.Code != c.code || env.Message == "" {
+			t.Errorf("%s %s: got %d %+v, want %d code %d", c.method, c.path, status, env, c.status, c.code)
+		}
+	}
+}
+
+func Test_V1_History(t *testing.T) {
+	r := setupV1Test(t)
+	Chain = newLedgerChain()
+
+	_, env := doJSON(r, http.MethodPost, "/api/v1/deposits", gin.H{"phone": "13800000000", "data": gin.H{"v": 1}})
+	created, _ := env.Data.(map[string]interface{})
+	_, env = doJSON(r, http.MethodPut, "/api/v1/deposits/"+created["txHash"].(string), gin.H{"phone": "13800000000", "data": gin.H{"v": 2}})
+	modified, _ := env.Data.(map[string]interface{})
+	if modified["recordId"] != created["recordId"] {
+		t.Fatalf("record id changed after modify: %v -> %v", created, modified)
+	}
+	if d, _ := Store.FindByTxHash(modified["txHash"].(string)); d.PrevHash != created["txHash"] {
+		t.Errorf("modified deposit not linked to previous version: %+v", d)
+	}
+
+	//存证ID和任一版本的交易哈希都可查询
+	for _, id := range []interface{}{created["recordId"], created["txHash"], modified["txHash"]} {
+		status, env := doJSON(r, http.MethodGet, "/api/v1/deposits/"+id.(string)+"/history", nil)
+		if status != http.StatusOK {
+			t.Fatalf("history of %s: %d %+v", id, status, env)
+		}
+		history := env.Data.(map[string]interface{})
+		versions := history["versions"].([]interface{})
+		if history["recordId"] != created["recordId"] || len(versions) != 2 ||
+			versions[0].(map[string]interface{})["value"] != `{"v":1}` || versions[1].(map[string]interface{})["txHash"] != modified["txHash"] {
+			t.Errorf("unexpected history of %s: %+v", id, history)
+		}
+	}
+
+	status, env := doJSON(r, http.MethodGet, "/api/v1/deposits/"+string(bytes.Repeat([]byte("b"), 64))+"/history", nil)
+	if status != http.StatusNotFound || env.Code != response.CodeNotFound {
+		t.Errorf("history of unknown record: %d %+v", status, env)
+	}
+}
+
+// 本地索引缺失时按所有者从链上查询
+func Test_V1_ListFromLedger(t *testing.T) {
+	r := setupV1Test(t)
+	Chain = newLedgerChain()
+	_, env := doJSON(r, http.MethodPost, "/api/v1/deposits", gin.H{"phone": "13800000000", "data": gin.H{"v": 1}})
+	created, _ := env.Data.(map[string]interface{})
+
+	Store = store.NewMemory()
+	status, env := doJSON(r, http.MethodGet, "/api/v1/deposits?phone=13800000000", nil)
+	messages, _ := env.Data.([]interface{})
+	if status != http.StatusOK || len(messages) != 1 {
+		t.Fatalf("list from ledger: %d %+v", status, env)
+	}
+	if message := messages[0].(map[string]interface{}); message["TxHash"] != created["txHash"] || message["Value"] != `{"v":1}` {
+		t.Errorf("unexpected ledger message: %+v", message)
+	}
+
+	status, env = doJSON(r, http.MethodGet, "/api/v1/deposits?phone=13900000000", nil)
+	if status != http.StatusNotFound || env.Code != response.CodePhoneNotFound {
+		t.Errorf("unknown owner: %d %+v", status, env)
+	}
+}
+
+func Test_V1_Revoke(t *testing.T) {
+	r := setupV1Test(t)
+	Chain = newLedgerChain()
+	phone := "13800000000"
+	_, env := doJSON(r, http.MethodPost, "/api/v1/deposits", gin.H{"phone": phone, "data": gin.H{"v": 1}})
+	created, _ := env.Data.(map[string]interface{})
+	txHash := created["txHash"].(string)
+	revokePath := "/api/v1/deposits/" + txHash + "/revoke"
+
+	cases := []struct {
+		body   interface{}
+		status int
+		code   int
+	}{
+		{gin.H{"phone": phone}, http.StatusBadRequest, response.CodeInvalidParam},
+		{gin.H{"phone": phone, "reason": "a;b"}, http.StatusBadRequest, response.CodeInvalidParam},
+		{gin.H{"phone": "13900000000", "reason": "legal"}, http.StatusNotFound, response.CodeNotFound},
+	}
+	for _, c := range cases {
+		if status, env := doJSON(r, http.MethodPost, revokePath, c.body); status != c.status || env.Code != c.code {
+			t.Errorf("revoke with %v: got %d %+v, want %d code %d", c.body, status, env, c.status, c.code)
+		}
+	}
+
+	status, env := doJSON(r, http.MethodPost, revokePath, gin.H{"phone": phone, "reason": "legal"})
+	revoked, _ := env.Data.(map[string]interface{})
+	if status != http.StatusOK || revoked["recordId"] != created["recordId"] || revoked["txHash"] == "" {
+		t.Fatalf("revoke deposit: %d %+v", status, env)
+	}
+	if d, _ := Store.FindByTxHash(txHash); !d.IsRevoked {
+		t.Errorf("deposit not marked revoked: %+v", d)
+	}
+
+	//撤销后不能再修改或重复撤销
+	if status, env := doJSON(r, http.MethodPut, "/api/v1/deposits/"+txHash, gin.H{"phone": phone, "data": 1}); status != http.StatusGone || env.Code != response.CodeRevoked {
+		t.Errorf("modify revoked deposit: %d %+v", status, env)
+	}
+	if status, env := doJSON(r, http.MethodPost, revokePath, gin.H{"phone": phone, "reason": "legal"}); status != http.StatusGone || env.Code != response.CodeRevoked {
+		t.Errorf("revoke twice: %d %+v", status, env)
+	}
+
+	//历史版本以删除操作结束，并附带撤销原因
+	_, env = doJSON(r, http.MethodGet, "/api/v1/deposits/"+txHash+"/history", nil)
+	history, _ := env.Data.(map[string]interface{})
+	versions, _ := history["versions"].([]interface{})
+	revocation, _ := history["revocation"].(map[string]interface{})
+	if len(versions) != 2 || versions[1].(map[string]interface{})["isDeleted"] != true || revocation["reason"] != "legal" {
+		t.Errorf("unexpected history after revoke: %+v", history)
+	}
+
+	//本地索引和链上所有者索引均不再返回已撤销的存证
+	if status, env := doJSON(r, http.MethodGet, "/api/v1/deposits?phone="+phone, nil); status != http.StatusNotFound || env.Code != response.CodePhoneNotFound {
+		t.Errorf("list after revoke: %d %+v", status, env)
+	}
+}
+
+// blockingChain 在release关闭前阻塞交易提交，用于模拟请求取消后仍在执行的链操作
+type blockingChain struct {
+	fakeChain
+	release chan struct{}
+}
+
+func (c blockingChain) SubmitRecode(ctx context.Context, rec api.Recode, progress utils.TxProgress) (string, error) {
+	<-c.release
+	return c.fakeChain.SubmitRecode(ctx, rec, progress)
+}
+
+// checkCanceled 取消的请求返回CodeCanceled且不带结果，否则应为完整的结果
+func checkCanceled(op string, hash string, id string, err error) error {
+	if err == nil && hash != "" && id != "" || utils.GetCode(err) == response.CodeCanceled && hash == "" && id == "" {
+		return nil
+	}
+	return fmt.Errorf("%s after cancel: %q %q %v", op, hash, id, err)
+}
+
+// 请求取消后立即返回，协程中已提交的链操作继续完成并写入本地索引，使用-race运行时不应出现数据竞争
+func Test_V1_Canceled(t *testing.T) {
+	setupV1Test(t)
+	txHash, _, err := saveDeposit(context.Background(), "13800000000", `{"v":1}`, "")
+	if err != nil {
+		t.Fatalf("save deposit error: %v", err)
+	}
+	chain := blockingChain{release: make(chan struct{})}
+	Chain = chain
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	//调用方返回与链操作完成之间没有先后关系，链操作先完成时也可能返回结果
+	results := make(chan error, 2)
+	go func() {
+		hash, id, err := saveDeposit(ctx, "13900000000", `{"v":1}`, "")
+		results <- checkCanceled("save deposit", hash, id, err)
+	}()
+	go func() {
+		hash, id, err := modifyDeposit(ctx, "13800000000", txHash, `{"v":2}`, "")
+		results <- checkCanceled("modify deposit", hash, id, err)
+	}()
+	close(chain.release)
+	for i := 0; i < 2; i++ {
+		if err := <-results; err != nil {
+			t.Error(err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		saved, _ := Store.FindByPhone("13900000000", 0)
+		modified, _ := Store.FindByTxHash(txHash)
+		if len(saved) == 1 && modified.IsModify {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("canceled chain operations not completed: %+v %+v", saved, modified)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	//等待协程释放存证和手机号的锁，即本地索引和缓存处理完成
+	for _, key := range []string{txHash, "13800000000", "13900000000"} {
+		keyLocks.Lock(key)()
+	}
+}
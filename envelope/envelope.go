@@ -0,0 +1,258 @@
+// Package envelope 存证数据信封加密：每条存证使用随机生成的数据密钥加密，
+// 数据密钥由密钥加密密钥（KEK）包装后与密文保存在一起，KEK可来自本地文件或外部KMS
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+
+	"github.com/deatil/go-cryptobin/cryptobin/crypto"
+	"github.com/pkg/errors"
+)
+
+// 数据加密算法
+const (
+	AlgAESGCM = "AES-GCM" // AES-256-GCM
+	AlgSM4    = "SM4"     // 国密SM4，依赖gmssl，仅Linux可用
+)
+
+// prefix 信封密文前缀，不带此前缀的密文按旧版AES-ECB处理
+const prefix = "env1:"
+
+// KeyManager 密钥加密密钥管理接口，实现方负责保管KEK，可对接外部KMS
+type KeyManager interface {
+	// CurrentKeyID 当前用于包装新数据密钥的KEK标识
+	CurrentKeyID() string
+
+	// Wrap 使用keyID对应的KEK包装数据密钥
+	Wrap(keyID string, dataKey []byte) ([]byte, error)
+
+	// Unwrap 使用keyID对应的KEK解包数据密钥
+	Unwrap(keyID string, wrapped []byte) ([]byte, error)
+}
+
+// Sealed 信封密文，Ciphertext的格式由Alg决定
+type Sealed struct {
+	Alg        string
+	KeyID      string
+	WrappedKey []byte
+	Ciphertext []byte
+}
+
+// String 编码为 env1:算法:KEK标识:包装后的数据密钥:密文，后两段为base64
+func (s *Sealed) String() string {
+	return prefix + strings.Join([]string{s.Alg, s.KeyID,
+		base64.StdEncoding.EncodeToString(s.WrappedKey),
+		base64.StdEncoding.EncodeToString(s.Ciphertext)}, ":")
+}
+
+// IsEnvelope 判断密文是否为信封格式
+func IsEnvelope(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Parse 解析信封密文
+func Parse(value string) (*Sealed, error) {
+	if !IsEnvelope(value) {
+		return nil, errors.New("not an envelope ciphertext")
+	}
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 4 {
+		return nil, errors.Errorf("envelope ciphertext has %d fields, want 4", len(parts))
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.WithMessage(err, "decode wrapped key error")
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return nil, errors.WithMessage(err, "decode ciphertext error")
+	}
+	return &Sealed{Alg: parts[0], KeyID: parts[1], WrappedKey: wrapped, Ciphertext: ciphertext}, nil
+}
+
+// Cipher 信封加解密
+type Cipher struct {
+	km        KeyManager
+	alg       string
+	legacyKey string
+}
+
+// New 创建信封加解密，alg为新数据使用的算法，legacyKey为旧版AES-ECB密钥，为空时不支持解密旧数据
+func New(km KeyManager, alg string, legacyKey string) (*Cipher, error) {
+	if km == nil {
+		return nil, errors.New("key manager is required")
+	}
+	if _, err := newDataCipher(alg); err != nil {
+		return nil, err
+	}
+	if strings.Contains(km.CurrentKeyID(), ":") {
+		return nil, errors.Errorf("key id %q must not contain ':'", km.CurrentKeyID())
+	}
+	return &Cipher{km: km, alg: alg, legacyKey: legacyKey}, nil
+}
+
+// KeyManager 返回使用的KEK管理
+func (c *Cipher) KeyManager() KeyManager {
+	return c.km
+}
+
+// Seal 生成数据密钥加密明文，并用当前KEK包装数据密钥
+func (c *Cipher) Seal(plaintext []byte) (*Sealed, error) {
+	dc, err := newDataCipher(c.alg)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := dc.newKey()
+	if err != nil {
+		return nil, errors.WithMessage(err, "generate data key error")
+	}
+	ciphertext, err := dc.encrypt(dataKey, plaintext)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "%s encrypt error", c.alg)
+	}
+	keyID := c.km.CurrentKeyID()
+	wrapped, err := c.km.Wrap(keyID, dataKey)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "wrap data key with %s error", keyID)
+	}
+	return &Sealed{Alg: c.alg, KeyID: keyID, WrappedKey: wrapped, Ciphertext: ciphertext}, nil
+}
+
+// Open 解包数据密钥并解密
+func (c *Cipher) Open(s *Sealed) ([]byte, error) {
+	dc, err := newDataCipher(s.Alg)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := c.km.Unwrap(s.KeyID, s.WrappedKey)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "unwrap data key with %s error", s.KeyID)
+	}
+	plaintext, err := dc.decrypt(dataKey, s.Ciphertext)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "%s decrypt error", s.Alg)
+	}
+	return plaintext, nil
+}
+
+// Decrypt 解密链上保存的存证数据，兼容旧版AES-ECB密文
+func (c *Cipher) Decrypt(value string) ([]byte, error) {
+	if !IsEnvelope(value) {
+		return c.decryptLegacy(value)
+	}
+	s, err := Parse(value)
+	if err != nil {
+		return nil, err
+	}
+	return c.Open(s)
+}
+
+// DecryptWithKey 使用指定的KEK标识和包装后的数据密钥解密信封密文，用于KEK轮换后
+// 链上密文中的数据密钥仍由旧KEK包装的情况；keyID为空或密文为旧版格式时等同于Decrypt
+func (c *Cipher) DecryptWithKey(value string, keyID string, wrappedKey []byte) ([]byte, error) {
+	if keyID == "" || !IsEnvelope(value) {
+		return c.Decrypt(value)
+	}
+	s, err := Parse(value)
+	if err != nil {
+		return nil, err
+	}
+	s.KeyID = keyID
+	s.WrappedKey = wrappedKey
+	return c.Open(s)
+}
+
+// Rewrap 使用keyID对应的KEK解包数据密钥，再用当前KEK重新包装，返回当前KEK标识和新的包装结果
+func (c *Cipher) Rewrap(keyID string, wrappedKey []byte) (string, []byte, error) {
+	dataKey, err := c.km.Unwrap(keyID, wrappedKey)
+	if err != nil {
+		return "", nil, errors.WithMessagef(err, "unwrap data key with %s error", keyID)
+	}
+	current := c.km.CurrentKeyID()
+	wrapped, err := c.km.Wrap(current, dataKey)
+	if err != nil {
+		return "", nil, errors.WithMessagef(err, "wrap data key with %s error", current)
+	}
+	return current, wrapped, nil
+}
+
+func (c *Cipher) decryptLegacy(value string) ([]byte, error) {
+	if c.legacyKey == "" {
+		return nil, errors.New("legacy ciphertext but no legacy key configured")
+	}
+	result := crypto.FromBase64String(value).SetKey(c.legacyKey).Aes().ECB().PKCS7Padding().Decrypt()
+	if len(result.Errors) > 0 {
+		return nil, errors.WithMessage(result.Errors[0], "legacy AES-ECB decrypt error")
+	}
+	return result.ToBytes(), nil
+}
+
+// dataCipher 数据密钥使用的对称算法
+type dataCipher interface {
+	newKey() ([]byte, error)
+	encrypt(key []byte, plaintext []byte) ([]byte, error)
+	decrypt(key []byte, ciphertext []byte) ([]byte, error)
+}
+
+func newDataCipher(alg string) (dataCipher, error) {
+	switch alg {
+	case AlgAESGCM:
+		return aesGCM{}, nil
+	case AlgSM4:
+		return newSM4()
+	}
+	return nil, errors.Errorf("unsupported data encryption algorithm: %s", alg)
+}
+
+// aesGCM AES-256-GCM，密文为 nonce||ciphertext||tag
+type aesGCM struct{}
+
+func (aesGCM) newKey() ([]byte, error) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	return key, err
+}
+
+func (aesGCM) encrypt(key []byte, plaintext []byte) ([]byte, error) {
+	return gcmSeal(key, plaintext, nil)
+}
+
+func (aesGCM) decrypt(key []byte, ciphertext []byte) ([]byte, error) {
+	return gcmOpen(key, ciphertext, nil)
+}
+
+func gcmSeal(key []byte, plaintext []byte, aad []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func gcmOpen(key []byte, ciphertext []byte, aad []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce := ciphertext[:gcm.NonceSize()]
+	return gcm.Open(nil, nonce, ciphertext[gcm.NonceSize():], aad)
+}
@@ -0,0 +1,146 @@
+package envelope
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/deatil/go-cryptobin/cryptobin/crypto"
+)
+
+func testKeyManager(t *testing.T) *FileKeyManager {
+	km, err := NewFileKeyManager("k1", map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, 32),
+		"k2": bytes.Repeat([]byte{2}, 16),
+	})
+	if err != nil {
+		t.Fatalf("new key manager error: %v", err)
+	}
+	return km
+}
+
+func Test_Cipher_AESGCM(t *testing.T) {
+	c, err := New(testKeyManager(t), AlgAESGCM, "")
+	if err != nil {
+		t.Fatalf("new cipher error: %v", err)
+	}
+	plaintext := []byte(`{"amount":100}`)
+	sealed, err := c.Seal(plaintext)
+	if err != nil {
+		t.Fatalf("seal error: %v", err)
+	}
+	if sealed.KeyID != "k1" || sealed.Alg != AlgAESGCM {
+		t.Errorf("unexpected sealed header: %+v", sealed)
+	}
+	encoded := sealed.String()
+	if !IsEnvelope(encoded) {
+		t.Fatalf("encoded value is not an envelope: %s", encoded)
+	}
+	got, err := c.Decrypt(encoded)
+	if err != nil || !bytes.Equal(got, plaintext) {
+		t.Fatalf("decrypt got %q, %v", got, err)
+	}
+
+	//同一明文每次使用不同的数据密钥
+	other, _ := c.Seal(plaintext)
+	if bytes.Equal(other.Ciphertext, sealed.Ciphertext) || bytes.Equal(other.WrappedKey, sealed.WrappedKey) {
+		t.Error("sealing twice should use fresh data keys")
+	}
+
+	//篡改密文或KEK标识都无法解密
+	tampered, _ := Parse(encoded)
+	tampered.Ciphertext[len(tampered.Ciphertext)-1] ^= 1
+	if _, err := c.Open(tampered); err == nil {
+		t.Error("tampered ciphertext should not decrypt")
+	}
+	wrongKey, _ := Parse(encoded)
+	wrongKey.KeyID = "k2"
+	if _, err := c.Open(wrongKey); err == nil {
+		t.Error("data key wrapped by k1 should not unwrap with k2")
+	}
+}
+
+func Test_Cipher_Rewrap(t *testing.T) {
+	km := testKeyManager(t)
+	c, _ := New(km, AlgAESGCM, "")
+	sealed, _ := c.Seal([]byte("hello"))
+	encoded := sealed.String()
+
+	km.current = "k2"
+	keyID, wrapped, err := c.Rewrap(sealed.KeyID, sealed.WrappedKey)
+	if err != nil || keyID != "k2" {
+		t.Fatalf("rewrap got %s, %v", keyID, err)
+	}
+	//链上密文不变，使用重新包装的数据密钥解密
+	got, err := c.DecryptWithKey(encoded, keyID, wrapped)
+	if err != nil || string(got) != "hello" {
+		t.Errorf("decrypt with rewrapped key got %q, %v", got, err)
+	}
+	if _, err := c.DecryptWithKey(encoded, "k1", wrapped); err == nil {
+		t.Error("rewrapped key should not unwrap with k1")
+	}
+}
+
+func Test_Cipher_Legacy(t *testing.T) {
+	legacyKey := "dfertf12dfertf12"
+	legacy := crypto.FromString(`{"v":1}`).SetKey(legacyKey).Aes().ECB().PKCS7Padding().Encrypt().ToBase64String()
+
+	c, _ := New(testKeyManager(t), AlgAESGCM, legacyKey)
+	got, err := c.Decrypt(legacy)
+	if err != nil || string(got) != `{"v":1}` {
+		t.Errorf("legacy decrypt got %q, %v", got, err)
+	}
+
+	noLegacy, _ := New(testKeyManager(t), AlgAESGCM, "")
+	if _, err := noLegacy.Decrypt(legacy); err == nil {
+		t.Error("legacy ciphertext should fail without legacy key")
+	}
+}
+
+func Test_Cipher_SM4(t *testing.T) {
+	c, err := New(testKeyManager(t), AlgSM4, "")
+	if err != nil {
+		t.Skipf("SM4 unavailable: %v", err)
+	}
+	sealed, err := c.Seal([]byte("hello"))
+	if err != nil {
+		t.Skipf("SM4 unavailable: %v", err)
+	}
+	got, err := c.Decrypt(sealed.String())
+	if err != nil || string(got) != "hello" {
+		t.Errorf("sm4 decrypt got %q, %v", got, err)
+	}
+}
+
+func Test_LoadKeyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "envelope")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "kek.json")
+	content := `{"current":"2024","keys":{"2023":"AQEBAQEBAQEBAQEBAQEBAQ==","2024":"AgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgI="}}`
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	km, err := LoadKeyFile(path)
+	if err != nil {
+		t.Fatalf("load key file error: %v", err)
+	}
+	if km.CurrentKeyID() != "2024" || len(km.KeyIDs()) != 2 {
+		t.Errorf("unexpected key manager: %s %v", km.CurrentKeyID(), km.KeyIDs())
+	}
+
+	for _, bad := range []string{
+		`{"current":"x","keys":{"2024":"AgICAgICAgICAgICAgICAg=="}}`,
+		`{"current":"a","keys":{"a":"AQID"}}`,
+		`not json`,
+	} {
+		ioutil.WriteFile(path, []byte(bad), 0600)
+		if _, err := LoadKeyFile(path); err == nil {
+			t.Errorf("key file %s should be rejected", bad)
+		}
+	}
+}
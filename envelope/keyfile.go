@@ -0,0 +1,110 @@
+package envelope
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// keyFile KEK文件格式，keys中的密钥为base64编码的16、24或32字节AES密钥：
+//
+//	{"current": "k2", "keys": {"k1": "...", "k2": "..."}}
+type keyFile struct {
+	Current string            `json:"current"`
+	Keys    map[string]string `json:"keys"`
+}
+
+// FileKeyManager 从本地文件加载KEK，使用AES-GCM包装数据密钥，KEK标识作为附加认证数据
+type FileKeyManager struct {
+	mu      sync.RWMutex
+	current string
+	keys    map[string][]byte
+}
+
+// LoadKeyFile 从KEK文件创建密钥管理
+func LoadKeyFile(path string) (*FileKeyManager, error) {
+	raw, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "read key file %s error", path)
+	}
+	var kf keyFile
+	if err := json.Unmarshal(raw, &kf); err != nil {
+		return nil, errors.WithMessagef(err, "parse key file %s error", path)
+	}
+	keys := make(map[string][]byte, len(kf.Keys))
+	for id, encoded := range kf.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "decode key %s error", id)
+		}
+		keys[id] = key
+	}
+	return NewFileKeyManager(kf.Current, keys)
+}
+
+// NewFileKeyManager 使用给定的KEK创建密钥管理，current为包装新数据密钥使用的KEK标识
+func NewFileKeyManager(current string, keys map[string][]byte) (*FileKeyManager, error) {
+	for id, key := range keys {
+		switch len(key) {
+		case 16, 24, 32:
+		default:
+			return nil, errors.Errorf("key %s must be 16, 24 or 32 bytes", id)
+		}
+	}
+	if _, ok := keys[current]; !ok {
+		return nil, errors.Errorf("current key %q not found", current)
+	}
+	return &FileKeyManager{current: current, keys: keys}, nil
+}
+
+// CurrentKeyID 当前KEK标识
+func (m *FileKeyManager) CurrentKeyID() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.current
+}
+
+// KeyIDs 已加载的全部KEK标识
+func (m *FileKeyManager) KeyIDs() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ids := make([]string, 0, len(m.keys))
+	for id := range m.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Wrap 使用keyID对应的KEK包装数据密钥
+func (m *FileKeyManager) Wrap(keyID string, dataKey []byte) ([]byte, error) {
+	kek, err := m.key(keyID)
+	if err != nil {
+		return nil, err
+	}
+	return gcmSeal(kek, dataKey, []byte(keyID))
+}
+
+// Unwrap 使用keyID对应的KEK解包数据密钥
+func (m *FileKeyManager) Unwrap(keyID string, wrapped []byte) ([]byte, error) {
+	kek, err := m.key(keyID)
+	if err != nil {
+		return nil, err
+	}
+	return gcmOpen(kek, wrapped, []byte(keyID))
+}
+
+func (m *FileKeyManager) key(keyID string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	kek, ok := m.keys[keyID]
+	if !ok {
+		return nil, errors.Errorf("key %q not found", keyID)
+	}
+	return kek, nil
+}
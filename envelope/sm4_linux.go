@@ -0,0 +1,38 @@
+package envelope
+
+import (
+	"encoding/base64"
+
+	"git.huawei.com/huaweichain/common/cryptomgr/bccryptoutil"
+)
+
+// sm4 国密SM4，密钥生成和加解密均由gmssl完成，密文为 ciphertext||iv
+type sm4 struct{}
+
+func newSM4() (dataCipher, error) {
+	return sm4{}, nil
+}
+
+func (sm4) newKey() ([]byte, error) {
+	key, err := bccryptoutil.NewSm4Key()
+	if err != nil {
+		return nil, err
+	}
+	return key.GetKeyBytes(), nil
+}
+
+func (sm4) encrypt(key []byte, plaintext []byte) ([]byte, error) {
+	k, err := bccryptoutil.NewSm4KeyWithKeyBase64(base64.StdEncoding.EncodeToString(key))
+	if err != nil {
+		return nil, err
+	}
+	return k.Encrypt(plaintext)
+}
+
+func (sm4) decrypt(key []byte, ciphertext []byte) ([]byte, error) {
+	k, err := bccryptoutil.NewSm4KeyWithKeyBase64(base64.StdEncoding.EncodeToString(key))
+	if err != nil {
+		return nil, err
+	}
+	return k.Decrypt(ciphertext)
+}
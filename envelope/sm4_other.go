@@ -0,0 +1,12 @@
+// +build !linux
+
+package envelope
+
+import (
+	"github.com/pkg/errors"
+)
+
+// 非Linux平台没有gmssl，不支持SM4
+func newSM4() (dataCipher, error) {
+	return nil, errors.New("SM4 is only supported on linux")
+}
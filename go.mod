@@ -10,11 +10,14 @@ require (
 	github.com/deatil/go-cryptobin v1.0.1041
 	github.com/garyburd/redigo v1.6.4
 	github.com/gin-gonic/gin v1.8.1
-	github.com/go-sql-driver/mysql v1.6.0 // indirect
+	github.com/go-sql-driver/mysql v1.6.0
 	github.com/gogo/protobuf v1.3.2
-	github.com/gomodule/redigo v1.8.9 // indirect
-	github.com/jmoiron/sqlx v1.3.5 // indirect
+	github.com/gomodule/redigo v1.8.9
+	github.com/jmoiron/sqlx v1.3.5
+	github.com/mattn/go-sqlite3 v1.14.6
 	github.com/pkg/errors v0.9.1
+	github.com/spf13/viper v1.6.2
+	google.golang.org/grpc v1.31.0
 )
 
 replace (
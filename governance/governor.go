@@ -0,0 +1,251 @@
+package governance
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"git.huawei.com/goclient/utils"
+	"git.huawei.com/huaweichain/proto/common"
+	"git.huawei.com/huaweichain/proto/nodeservice"
+	"git.huawei.com/huaweichain/sdk/client"
+	"git.huawei.com/huaweichain/sdk/rawmessage"
+	"github.com/pkg/errors"
+)
+
+// Status 提案的投票状态
+type Status struct {
+	Subject   string    `json:"subject"`
+	Digest    string    `json:"digest"`
+	Summary   string    `json:"summary"`
+	Approvals []string  `json:"approvals"` // 已投票的组织，按名称排序
+	Missing   []string  `json:"missing"`   // 满足配置策略还需投票的最少组织，已满足或已生效时为空
+	Threshold string    `json:"threshold"` // 链配置的ConfigPolicy
+	Effective bool      `json:"effective"` // 更新已体现在链配置中，生效后投票记录被清除
+	Proposal  *Proposal `json:"-"`
+}
+
+// Satisfied 投票是否已满足配置策略
+func (s *Status) Satisfied() bool {
+	return len(s.Missing) == 0
+}
+
+// Member 参与审批的组织
+type Member struct {
+	Name          string                // 组织名，与投票记录中的投票者一致
+	Client        *client.GatewayClient // 以该组织身份签名的网关客户端
+	QueryNode     string                // 查询投票和链配置的节点，同时用于订阅交易事件
+	ConsensusNode string                // 提交投票交易的节点，为空时使用QueryNode
+}
+
+// Options 审批参数，零值字段使用默认值
+type Options struct {
+	PollInterval time.Duration // Wait查询投票和链配置的间隔，默认2s
+}
+
+// memberClient 组织在链上的查询和投票，sdkMember基于网关客户端实现
+type memberClient interface {
+	votes(ctx context.Context, chainID string, subject rawmessage.Subject) ([]*nodeservice.Vote, error)
+	chainConfig(ctx context.Context, chainID string) (*common.ChainConfig, error)
+	vote(ctx context.Context, p *Proposal) (string, error)
+}
+
+// Governor 以一个组织的身份审阅和会签配置更新提案
+type Governor struct {
+	name   string
+	client memberClient
+	opts   Options
+}
+
+// NewGovernor 创建组织的审批客户端
+func NewGovernor(m Member, opts Options) (*Governor, error) {
+	if m.Name == "" || m.Client == nil || m.QueryNode == "" {
+		return nil, errors.Errorf("member %q requires name, client and query node", m.Name)
+	}
+	for _, name := range []string{m.QueryNode, m.ConsensusNode} {
+		if _, ok := m.Client.Nodes[name]; name != "" && !ok {
+			return nil, errors.Errorf("node not exist： %v", name)
+		}
+	}
+	return newGovernor(m.Name, newSDKMember(m), opts), nil
+}
+
+func newGovernor(name string, c memberClient, opts Options) *Governor {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 2 * time.Second
+	}
+	return &Governor{name: name, client: c, opts: opts}
+}
+
+// Pending 查询subject下尚未生效的配置更新投票，每个不同的Payload为一个提案，
+// 返回状态中的Proposal可Encode后分发给其它组织审阅和会签
+func (g *Governor) Pending(ctx context.Context, chainID string, subject rawmessage.Subject) ([]*Status, error) {
+	config, err := g.client.chainConfig(ctx, chainID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "query chain config error")
+	}
+	votes, err := g.client.votes(ctx, chainID, subject)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "query %s votes error", subject)
+	}
+	var statuses []*Status
+	for _, vote := range votes {
+		p := &Proposal{ChainID: chainID, Handler: rawmessage.UpdateHandler, Payload: vote.Payload}
+		status, err := newStatus(p, subject, config, votes)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Review 查询提案的投票状态。最低平台版本和证书状态的变更没有投票主题，不能查询投票记录，Approvals为空
+func (g *Governor) Review(ctx context.Context, p *Proposal) (*Status, error) {
+	config, err := g.client.chainConfig(ctx, p.ChainID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "query chain config error")
+	}
+	var votes []*nodeservice.Vote
+	subject, err := p.Subject()
+	if err == nil {
+		// 该主题还没有投票记录时查询可能失败，按均未投票处理
+		votes, _ = g.client.votes(ctx, p.ChainID, subject)
+	}
+	return newStatus(p, subject, config, votes)
+}
+
+// Sign 以本组织身份对提案投票，发起和会签相同。本组织已投票或更新已生效时不再提交，返回空的交易哈希
+func (g *Governor) Sign(ctx context.Context, p *Proposal) (string, error) {
+	status, err := g.Review(ctx, p)
+	if err != nil {
+		return "", err
+	}
+	if status.Effective || containsString(status.Approvals, g.name) {
+		return "", nil
+	}
+	txHash, err := g.client.vote(ctx, p)
+	if err != nil {
+		return "", errors.WithMessagef(err, "vote of org %s error", g.name)
+	}
+	return txHash, nil
+}
+
+// Wait 按PollInterval查询直到更新生效，已投票的组织变化时调用progress。ctx取消或到期时返回最后的状态和错误，
+// 共识、网络和证书状态的变更不在链配置中，直接返回错误
+func (g *Governor) Wait(ctx context.Context, p *Proposal, progress func(status *Status)) (*Status, error) {
+	if _, err := p.effective(&common.ChainConfig{}); err != nil {
+		return nil, err
+	}
+	ticker := time.NewTicker(g.opts.PollInterval)
+	defer ticker.Stop()
+	var last *Status
+	for {
+		status, err := g.Review(ctx, p)
+		if err != nil {
+			return last, err
+		}
+		if progress != nil && (last == nil || strings.Join(status.Approvals, ",") != strings.Join(last.Approvals, ",") ||
+			status.Effective) {
+			progress(status)
+		}
+		last = status
+		if status.Effective {
+			return status, nil
+		}
+		select {
+		case <-ctx.Done():
+			return last, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// newStatus 按Payload匹配投票记录，根据链配置的ConfigPolicy计算还需投票的组织
+func newStatus(p *Proposal, subject rawmessage.Subject, config *common.ChainConfig, votes []*nodeservice.Vote) (*Status, error) {
+	status := &Status{Subject: string(subject), Digest: p.Digest(), Summary: p.Summary(), Threshold: config.ConfigPolicy,
+		Proposal: p}
+	for _, vote := range votes {
+		if !bytes.Equal(vote.Payload, p.Payload) {
+			continue
+		}
+		for _, voter := range vote.Voters {
+			if !containsString(status.Approvals, voter) {
+				status.Approvals = append(status.Approvals, voter)
+			}
+		}
+	}
+	sort.Strings(status.Approvals)
+
+	if effective, err := p.effective(config); err == nil && effective {
+		status.Effective = true
+		return status, nil
+	}
+	orgs := make([]string, len(config.Organizations))
+	for i, org := range config.Organizations {
+		orgs[i] = org.Name
+	}
+	t, err := newThreshold(config.ConfigPolicy, orgs)
+	if err != nil {
+		return nil, err
+	}
+	status.Missing = t.missing(status.Approvals)
+	return status, nil
+}
+
+// threshold 配置更新需要的投票组织。ConfigPolicy为ALL、ANY或MAJORITY（可带角色，如 "MAJORITY Admin"）时
+// 按链上组织数计算，否则按背书策略格式的组织表达式解析
+type threshold struct {
+	policy *common.Policy
+	orgs   []string
+	count  int
+}
+
+func newThreshold(expr string, orgs []string) (*threshold, error) {
+	fields := strings.Fields(expr)
+	if len(fields) > 0 {
+		switch strings.ToUpper(fields[0]) {
+		case "ALL":
+			return &threshold{orgs: orgs, count: len(orgs)}, nil
+		case "ANY":
+			return &threshold{orgs: orgs, count: 1}, nil
+		case "MAJORITY":
+			return &threshold{orgs: orgs, count: len(orgs)/2 + 1}, nil
+		}
+	}
+	policy, err := utils.ParsePolicy([]byte(expr))
+	if err != nil {
+		return nil, errors.WithMessage(err, "parse config policy error")
+	}
+	return &threshold{policy: policy}, nil
+}
+
+func (t *threshold) missing(approvals []string) []string {
+	if t.policy != nil {
+		return utils.MissingOrgs(t.policy, approvals)
+	}
+	var missing []string
+	need := t.count
+	for _, org := range t.orgs {
+		if containsString(approvals, org) {
+			need--
+		}
+	}
+	for _, org := range t.orgs {
+		if len(missing) < need && !containsString(approvals, org) {
+			missing = append(missing, org)
+		}
+	}
+	return missing
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,184 @@
+package governance
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"git.huawei.com/huaweichain/proto/common"
+	"git.huawei.com/huaweichain/proto/nodeservice"
+	"git.huawei.com/huaweichain/sdk/rawmessage"
+	"github.com/gogo/protobuf/proto"
+)
+
+// fakeChain 模拟链上的配置更新投票：投票满足ConfigPolicy时更新链配置，投票记录清除
+type fakeChain struct {
+	config *common.ChainConfig
+	votes  map[rawmessage.Subject][]*nodeservice.Vote
+}
+
+func newFakeChain(policy string) *fakeChain {
+	config := &common.ChainConfig{ChainId: "chain", ConfigPolicy: policy, LifecyclePolicy: "MAJORITY"}
+	for _, name := range []string{"org1", "org2", "org3"} {
+		config.Organizations = append(config.Organizations, &common.Organization{Name: name})
+	}
+	return &fakeChain{config: config, votes: map[rawmessage.Subject][]*nodeservice.Vote{}}
+}
+
+type fakeMember struct {
+	name  string
+	chain *fakeChain
+	voted int
+}
+
+func (m *fakeMember) votes(ctx context.Context, chainID string, subject rawmessage.Subject) ([]*nodeservice.Vote, error) {
+	return m.chain.votes[subject], nil
+}
+
+func (m *fakeMember) chainConfig(ctx context.Context, chainID string) (*common.ChainConfig, error) {
+	return m.chain.config, nil
+}
+
+func (m *fakeMember) vote(ctx context.Context, p *Proposal) (string, error) {
+	m.voted++
+	subject, _ := p.Subject()
+	var vote *nodeservice.Vote
+	for _, v := range m.chain.votes[subject] {
+		if bytes.Equal(v.Payload, p.Payload) {
+			vote = v
+		}
+	}
+	if vote == nil {
+		vote = &nodeservice.Vote{Payload: p.Payload}
+		m.chain.votes[subject] = append(m.chain.votes[subject], vote)
+	}
+	vote.Voters = append(vote.Voters, m.name)
+
+	status, _ := newStatus(p, subject, m.chain.config, m.chain.votes[subject])
+	if status.Satisfied() {
+		configSet, _ := p.configSet()
+		m.chain.config.LifecyclePolicy = configSet.GetLifecyclePolicy()
+		delete(m.chain.votes, subject)
+	}
+	return "tx-" + m.name, nil
+}
+
+func Test_Proposal(t *testing.T) {
+	p, err := NewProposal("chain", &common.ConfigSet{Value: &common.ConfigSet_LifecyclePolicy{LifecyclePolicy: "ALL"}})
+	if err != nil {
+		t.Fatalf("new proposal error: %v", err)
+	}
+	data, _ := p.Encode()
+	decoded, err := DecodeProposal(data)
+	if err != nil || !reflect.DeepEqual(decoded, p) || decoded.Digest() != p.Digest() {
+		t.Fatalf("decoded proposal %v %v, want %v", decoded, err, p)
+	}
+	if subject, _ := p.Subject(); subject != rawmessage.SubjectLifecycle || p.Summary() != "lifecycle policy: ALL" {
+		t.Errorf("unexpected subject %s, summary %s", subject, p.Summary())
+	}
+
+	orgs, _ := NewProposal("chain", &common.ConfigSet{Value: &common.ConfigSet_OrgUpdates_{OrgUpdates: &common.ConfigSet_OrgUpdates{
+		OrgUpdate: []*common.ConfigSet_OrgUpdate{
+			{Operation: common.OP_APPEND, Organization: &common.Organization{Name: "org4"}},
+			{Operation: common.OP_REMOVE, Organization: &common.Organization{Name: "org3"}},
+		}}}})
+	if subject, _ := orgs.Subject(); subject != rawmessage.SubjectOrg || orgs.Summary() != "org: append org4, remove org3" {
+		t.Errorf("unexpected subject %s, summary %s", subject, orgs.Summary())
+	}
+	config := newFakeChain("ALL").config
+	if effective, _ := orgs.effective(config); effective {
+		t.Error("org update should not be effective")
+	}
+	config.Organizations = append(config.Organizations[:2], &common.Organization{Name: "org4"})
+	if effective, _ := orgs.effective(config); !effective {
+		t.Error("org update should be effective")
+	}
+
+	cs, _ := proto.Marshal(&common.CertWithStatus{Status: common.CERT_FREEZE})
+	cert := &Proposal{ChainID: "chain", Handler: common.CERT_STATUS_CHANGE.String(), Payload: cs}
+	if _, err := cert.Subject(); err == nil || cert.Summary() != "cert status: CERT_FREEZE" {
+		t.Errorf("unexpected cert status proposal: %v %s", err, cert.Summary())
+	}
+	if _, err := DecodeProposal([]byte(`{"chainId":"chain"}`)); err == nil {
+		t.Error("expected error for proposal without payload")
+	}
+}
+
+func Test_Governance(t *testing.T) {
+	chain := newFakeChain("MAJORITY Admin")
+	var members []*fakeMember
+	var governors []*Governor
+	for _, name := range []string{"org1", "org2", "org3"} {
+		member := &fakeMember{name: name, chain: chain}
+		members = append(members, member)
+		governors = append(governors, newGovernor(name, member, Options{PollInterval: time.Millisecond}))
+	}
+	ctx := context.Background()
+
+	p, _ := NewProposal("chain", &common.ConfigSet{Value: &common.ConfigSet_LifecyclePolicy{LifecyclePolicy: "ALL"}})
+	if txHash, err := governors[0].Sign(ctx, p); err != nil || txHash != "tx-org1" {
+		t.Fatalf("org1 sign: %s %v", txHash, err)
+	}
+	// org2只拿到链上的投票记录，审阅后对相同的Payload会签
+	pending, err := governors[1].Pending(ctx, "chain", rawmessage.SubjectLifecycle)
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("pending: %v %v", pending, err)
+	}
+	status := pending[0]
+	if status.Digest != p.Digest() || status.Summary != "lifecycle policy: ALL" || status.Threshold != "MAJORITY Admin" ||
+		strings.Join(status.Approvals, ",") != "org1" || strings.Join(status.Missing, ",") != "org2" || status.Effective {
+		t.Errorf("unexpected pending status: %+v", status)
+	}
+	if txHash, _ := governors[0].Sign(ctx, p); txHash != "" || members[0].voted != 1 {
+		t.Errorf("org1 voted again: %s", txHash)
+	}
+
+	data, _ := status.Proposal.Encode()
+	decoded, _ := DecodeProposal(data)
+	if _, err := governors[1].Sign(ctx, decoded); err != nil {
+		t.Fatalf("org2 sign error: %v", err)
+	}
+	var progress []*Status
+	status, err = governors[0].Wait(ctx, p, func(status *Status) { progress = append(progress, status) })
+	if err != nil || !status.Effective || status.Missing != nil || chain.config.LifecyclePolicy != "ALL" {
+		t.Errorf("update not effective: %+v %v", status, err)
+	}
+	if len(progress) != 1 || progress[0] != status {
+		t.Errorf("unexpected progress: %v", progress)
+	}
+	cs, _ := proto.Marshal(&common.CertWithStatus{Status: common.CERT_FREEZE})
+	if _, err := governors[0].Wait(ctx, &Proposal{ChainID: "chain", Handler: common.CERT_STATUS_CHANGE.String(), Payload: cs}, nil); err == nil {
+		t.Error("expected error waiting for cert status change")
+	}
+	// 已生效的更新不再投票
+	if txHash, _ := governors[2].Sign(ctx, p); txHash != "" || members[2].voted != 0 {
+		t.Errorf("org3 voted for effective update: %s", txHash)
+	}
+}
+
+func Test_Threshold(t *testing.T) {
+	orgs := []string{"org1", "org2", "org3", "org4"}
+	tests := []struct {
+		policy    string
+		approvals []string
+		want      []string
+	}{
+		{"MAJORITY Admin", []string{"org2"}, []string{"org1", "org3"}},
+		{"ALL", []string{"org2"}, []string{"org1", "org3", "org4"}},
+		{"any", nil, []string{"org1"}},
+		{"org1 & (org2 | org3)", []string{"org3"}, []string{"org1"}},
+		{"MAJORITY", []string{"org1", "org2", "org4"}, nil},
+	}
+	for _, tt := range tests {
+		threshold, err := newThreshold(tt.policy, orgs)
+		if err != nil {
+			t.Fatalf("new threshold %q error: %v", tt.policy, err)
+		}
+		if missing := threshold.missing(tt.approvals); !reflect.DeepEqual(missing, tt.want) {
+			t.Errorf("policy %q with %v: got %v, want %v", tt.policy, tt.approvals, missing, tt.want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package governance
+
+import (
+	"context"
+
+	"git.huawei.com/goclient/utils"
+	"git.huawei.com/huaweichain/proto/common"
+	"git.huawei.com/huaweichain/proto/nodeservice"
+	"git.huawei.com/huaweichain/sdk/client"
+	"git.huawei.com/huaweichain/sdk/rawmessage"
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// sdkMember 通过组织的网关客户端查询投票、链配置并提交投票交易
+type sdkMember struct {
+	client    *client.GatewayClient
+	queryNode string
+	consensus string
+}
+
+func newSDKMember(m Member) *sdkMember {
+	consensus := m.ConsensusNode
+	if consensus == "" {
+		consensus = m.QueryNode
+	}
+	return &sdkMember{client: m.Client, queryNode: m.QueryNode, consensus: consensus}
+}
+
+func (m *sdkMember) votes(ctx context.Context, chainID string, subject rawmessage.Subject) ([]*nodeservice.Vote, error) {
+	rawMsg, err := m.client.QueryRawMessage.BuildQueryChainUpdateVoteRawMessage(chainID, string(subject))
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query chain update vote raw message error")
+	}
+	responseMsg, err := m.client.Nodes[m.queryNode].QueryAction.GetVoteCtx(ctx, rawMsg)
+	if err != nil {
+		return nil, errors.WithMessage(err, "query action get vote error")
+	}
+	payload, err := utils.GetPayloadWithResp(responseMsg)
+	if err != nil {
+		return nil, err
+	}
+	response := &nodeservice.VoteQueryResponse{}
+	if err := proto.Unmarshal(payload, response); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal vote query response error")
+	}
+	return response.Votes, nil
+}
+
+func (m *sdkMember) chainConfig(ctx context.Context, chainID string) (*common.ChainConfig, error) {
+	rawMsg, err := m.client.ChainRawMessage.BuildQueryChainRawMessage(chainID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query chain raw message error")
+	}
+	responseMsg, err := m.client.Nodes[m.queryNode].ChainAction.QueryChainCtx(ctx, rawMsg)
+	if err != nil {
+		return nil, errors.WithMessage(err, "chain action query chain error")
+	}
+	payload, err := utils.GetPayloadWithResp(responseMsg)
+	if err != nil {
+		return nil, err
+	}
+	info := &nodeservice.QueryInfoResponse{}
+	if err := proto.Unmarshal(payload, info); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal query info response error")
+	}
+	if info.ChainInfo == nil || info.ChainInfo.Config == nil || info.ChainInfo.Config.Config == nil {
+		return nil, errors.Errorf("chain %s has no config", chainID)
+	}
+	return info.ChainInfo.Config.Config, nil
+}
+
+// vote 对提案的Payload构造本组织签名的投票交易，提交后等待落块
+func (m *sdkMember) vote(ctx context.Context, p *Proposal) (string, error) {
+	txRawMsg, err := m.client.ConfigRawMessage.UpdateConfig.BuildVoteRawMessage(p.ChainID,
+		&common.VoteTxData{Handler: p.Handler, Payload: p.Payload})
+	if err != nil {
+		return "", errors.WithMessage(err, "build vote raw message error")
+	}
+	net, err := utils.NewNodes(m.client, []string{m.queryNode}, m.consensus)
+	if err != nil {
+		return "", err
+	}
+	config := utils.Config{ChainID: p.ChainID, QueryNode: m.queryNode, ConsensusNode: m.consensus}
+	txResult, err := utils.SendTxCtx(ctx, net, config, txRawMsg)
+	if err != nil {
+		return "", err
+	}
+	return utils.Hash2str(txResult.TxHash), nil
+}
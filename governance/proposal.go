@@ -0,0 +1,189 @@
+// Package governance 链配置更新的多组织审批：解析待生效的投票记录（提案摘要、已投票和仍需投票的组织、
+// 配置策略），各组织对同一提案的Payload会签，并通过链配置确认更新已生效
+package governance
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"git.huawei.com/huaweichain/proto/common"
+	"git.huawei.com/huaweichain/sdk/rawmessage"
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// Proposal 链配置更新提案，即投票交易的VoteTxData。发起组织通过Encode导出，其它组织DecodeProposal后
+// 审阅并对相同的Payload投票，链上按Payload归集投票
+type Proposal struct {
+	ChainID string `json:"chainId"`
+	Handler string `json:"handler"`
+	Payload []byte `json:"payload"`
+}
+
+// NewProposal 由配置变更创建提案，与UpdateConfig.BuildUpdateXxxRawMessage构造的投票内容一致
+func NewProposal(chainID string, configSet *common.ConfigSet) (*Proposal, error) {
+	if chainID == "" || configSet == nil || configSet.Value == nil {
+		return nil, errors.New("chain id and config set are required")
+	}
+	payload, err := proto.Marshal(configSet)
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal config set error")
+	}
+	return &Proposal{ChainID: chainID, Handler: rawmessage.UpdateHandler, Payload: payload}, nil
+}
+
+// DecodeProposal 解析Encode导出的提案
+func DecodeProposal(data []byte) (*Proposal, error) {
+	p := &Proposal{}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal proposal error")
+	}
+	if p.ChainID == "" || p.Handler == "" || len(p.Payload) == 0 {
+		return nil, errors.New("proposal chain id, handler and payload are required")
+	}
+	return p, nil
+}
+
+// Encode 导出提案，Payload以base64编码
+func (p *Proposal) Encode() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// Digest Payload的SHA-256，各组织据此确认审阅和投票的是同一提案
+func (p *Proposal) Digest() string {
+	sum := sha256.Sum256(p.Payload)
+	return hex.EncodeToString(sum[:])
+}
+
+func (p *Proposal) configSet() (*common.ConfigSet, error) {
+	if p.Handler != rawmessage.UpdateHandler {
+		return nil, errors.Errorf("proposal handler %s is not a config update", p.Handler)
+	}
+	configSet := &common.ConfigSet{}
+	if err := proto.Unmarshal(p.Payload, configSet); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal config set error")
+	}
+	return configSet, nil
+}
+
+// Subject 提案在链上投票记录中的主题，用于BuildQueryChainUpdateVoteRawMessage
+func (p *Proposal) Subject() (rawmessage.Subject, error) {
+	configSet, err := p.configSet()
+	if err != nil {
+		return "", err
+	}
+	switch value := configSet.Value.(type) {
+	case *common.ConfigSet_ConfigPolicy:
+		return rawmessage.SubjectPolicy, nil
+	case *common.ConfigSet_LifecyclePolicy:
+		return rawmessage.SubjectLifecycle, nil
+	case *common.ConfigSet_OrgUpdates_:
+		return rawmessage.SubjectOrg, nil
+	case *common.ConfigSet_ConsenterUpdate:
+		return rawmessage.SubjectConsensus, nil
+	case *common.ConfigSet_Net:
+		if _, ok := value.Net.GetUpdate().(*common.NetUpdate_Domain); ok {
+			return rawmessage.SubjectDomain, nil
+		}
+		return rawmessage.SubjectZone, nil
+	}
+	return "", errors.Errorf("no vote subject for config update %s", configSet.String())
+}
+
+// Summary 提案内容的可读描述
+func (p *Proposal) Summary() string {
+	if p.Handler == common.CERT_STATUS_CHANGE.String() {
+		cs := &common.CertWithStatus{}
+		if err := proto.Unmarshal(p.Payload, cs); err != nil {
+			return "invalid cert status change"
+		}
+		return fmt.Sprintf("cert status: %s", cs.Status.String())
+	}
+	configSet, err := p.configSet()
+	if err != nil {
+		return fmt.Sprintf("%s: %d bytes", p.Handler, len(p.Payload))
+	}
+	switch value := configSet.Value.(type) {
+	case *common.ConfigSet_ConfigPolicy:
+		return fmt.Sprintf("config policy: %s", value.ConfigPolicy)
+	case *common.ConfigSet_LifecyclePolicy:
+		return fmt.Sprintf("lifecycle policy: %s", value.LifecyclePolicy)
+	case *common.ConfigSet_MinPlatformVersion:
+		return fmt.Sprintf("min platform version: %s", value.MinPlatformVersion)
+	case *common.ConfigSet_OrgUpdates_:
+		var updates []string
+		for _, update := range orgUpdates(value) {
+			op := strings.ToLower(strings.TrimPrefix(update.Operation.String(), "OP_"))
+			updates = append(updates, op+" "+orgName(update))
+		}
+		return fmt.Sprintf("org: %s", strings.Join(updates, ", "))
+	case *common.ConfigSet_ConsenterUpdate:
+		return fmt.Sprintf("consenter update: %d bytes", len(value.ConsenterUpdate))
+	case *common.ConfigSet_Net:
+		return fmt.Sprintf("net: %s", value.Net.String())
+	}
+	return "empty config update"
+}
+
+// effective 更新是否已体现在链配置中，共识、网络和证书状态的变更不在链配置中，返回错误
+func (p *Proposal) effective(config *common.ChainConfig) (bool, error) {
+	configSet, err := p.configSet()
+	if err != nil {
+		return false, err
+	}
+	switch value := configSet.Value.(type) {
+	case *common.ConfigSet_ConfigPolicy:
+		return config.ConfigPolicy == value.ConfigPolicy, nil
+	case *common.ConfigSet_LifecyclePolicy:
+		return config.LifecyclePolicy == value.LifecyclePolicy, nil
+	case *common.ConfigSet_MinPlatformVersion:
+		return config.MinPlatformVersion == value.MinPlatformVersion, nil
+	case *common.ConfigSet_OrgUpdates_:
+		for _, update := range orgUpdates(value) {
+			if !orgUpdated(config, update) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+	return false, errors.Errorf("effect of %s cannot be observed in chain config", p.Summary())
+}
+
+func orgUpdates(value *common.ConfigSet_OrgUpdates_) []*common.ConfigSet_OrgUpdate {
+	if value.OrgUpdates == nil {
+		return nil
+	}
+	return value.OrgUpdates.OrgUpdate
+}
+
+func orgName(update *common.ConfigSet_OrgUpdate) string {
+	if update.Organization == nil {
+		return ""
+	}
+	return update.Organization.Name
+}
+
+func orgUpdated(config *common.ChainConfig, update *common.ConfigSet_OrgUpdate) bool {
+	var current *common.Organization
+	for _, org := range config.Organizations {
+		if org.Name == orgName(update) {
+			current = org
+		}
+	}
+	switch update.Operation {
+	case common.OP_REMOVE:
+		return current == nil
+	case common.OP_REPLACE:
+		if update.Organization == nil {
+			return false
+		}
+		return current != nil && bytes.Equal(current.RootCert, update.Organization.RootCert) &&
+			bytes.Equal(current.AdminCert, update.Organization.AdminCert) &&
+			bytes.Equal(current.TLSRootCert, update.Organization.TLSRootCert)
+	}
+	return current != nil
+}
@@ -7,6 +7,7 @@ package action
 import (
 	"context"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -43,12 +44,36 @@ func NewConfig(hostOverride string, host string, port int, tlsEnable bool,
 type action struct {
 	config *Config
 	conn   *grpc.ClientConn
+	// mu guards lazy creation and closing of conn, so that an action can be shared by goroutines.
+	mu sync.Mutex
 }
 
-func (a *action) newClientConn() (*grpc.ClientConn, error) {
+// Close is used to close the grpc client connection of the action. The connection is
+// recreated on the next call.
+func (a *action) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.conn == nil {
+		return nil
+	}
+	err := a.conn.Close()
+	a.conn = nil
+	return err
+}
+
+// callContext derives the context of a grpc call from ctx, with the sdk timeout if ctx has no deadline.
+func callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, utils.GetTimeout()*time.Second)
+}
+
+// newClientConn dials the node, the dial is canceled with ctx.
+func (a *action) newClientConn(ctx context.Context) (*grpc.ClientConn, error) {
 	ipAddr := a.config.host + ":" + strconv.Itoa(a.config.port)
 	options := a.getOpts()
-	ctx, cancel := context.WithTimeout(context.Background(), utils.GetTimeout()*time.Second)
+	ctx, cancel := callContext(ctx)
 	defer cancel()
 	cc, err := grpc.DialContext(ctx, ipAddr, options...)
 	if err != nil {
@@ -7,14 +7,12 @@ package action
 
 import (
 	"context"
-	"time"
 
 	"github.com/pkg/errors"
 	"google.golang.org/grpc/connectivity"
 
 	"git.huawei.com/huaweichain/proto/common"
 	"git.huawei.com/huaweichain/proto/nodeservice"
-	"git.huawei.com/huaweichain/sdk/utils"
 )
 
 // ChainAction is the action for chain operations.
@@ -31,59 +29,85 @@ func NewChainAction(config *Config) *ChainAction {
 
 // JoinChain is used to send join chain request raw message by grpc.
 func (action *ChainAction) JoinChain(rawMsg *common.RawMessage) (*common.RawMessage, error) {
-	client, err := action.getClient()
+	return action.JoinChainCtx(context.Background(), rawMsg)
+}
+
+// JoinChainCtx is the same as JoinChain but bound to ctx, the call is canceled with ctx.
+// The sdk timeout applies if ctx has no deadline.
+func (action *ChainAction) JoinChainCtx(ctx context.Context, rawMsg *common.RawMessage) (*common.RawMessage, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
+	client, err := action.getClient(ctx)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get client error")
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), utils.GetTimeout()*time.Second)
-	defer cancel()
 	return client.CreateChain(ctx, rawMsg)
 }
 
 // QuitChain is used to send quit chain request raw message by grpc.
 func (action *ChainAction) QuitChain(rawMsg *common.RawMessage) (*common.RawMessage, error) {
-	client, err := action.getClient()
+	return action.QuitChainCtx(context.Background(), rawMsg)
+}
+
+// QuitChainCtx is the same as QuitChain but bound to ctx, the call is canceled with ctx.
+// The sdk timeout applies if ctx has no deadline.
+func (action *ChainAction) QuitChainCtx(ctx context.Context, rawMsg *common.RawMessage) (*common.RawMessage, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
+	client, err := action.getClient(ctx)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get client error")
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), utils.GetTimeout()*time.Second)
-	defer cancel()
 	return client.DeleteChain(ctx, rawMsg)
 }
 
 // QueryChain is used to send query chain request raw message by grpc.
 func (action *ChainAction) QueryChain(rawMsg *common.RawMessage) (*common.RawMessage, error) {
-	client, err := action.getClient()
+	return action.QueryChainCtx(context.Background(), rawMsg)
+}
+
+// QueryChainCtx is the same as QueryChain but bound to ctx, the call is canceled with ctx.
+// The sdk timeout applies if ctx has no deadline.
+func (action *ChainAction) QueryChainCtx(ctx context.Context, rawMsg *common.RawMessage) (*common.RawMessage, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
+	client, err := action.getClient(ctx)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get client error")
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), utils.GetTimeout()*time.Second)
-	defer cancel()
 	return client.QueryChainInfo(ctx, rawMsg)
 }
 
 // QueryAllChains is used to send query all chains request raw message by grpc.
 func (action *ChainAction) QueryAllChains(rawMsg *common.RawMessage) (*common.RawMessage, error) {
-	client, err := action.getClient()
+	return action.QueryAllChainsCtx(context.Background(), rawMsg)
+}
+
+// QueryAllChainsCtx is the same as QueryAllChains but bound to ctx, the call is canceled with ctx.
+// The sdk timeout applies if ctx has no deadline.
+func (action *ChainAction) QueryAllChainsCtx(ctx context.Context, rawMsg *common.RawMessage) (*common.RawMessage, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
+	client, err := action.getClient(ctx)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get client error")
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), utils.GetTimeout()*time.Second)
-	defer cancel()
 	return client.QueryAllChainInfos(ctx, rawMsg)
 }
 
-func (action *ChainAction) getClient() (nodeservice.ChainManagerClient, error) {
+func (action *ChainAction) getClient(ctx context.Context) (nodeservice.ChainManagerClient, error) {
+	action.mu.Lock()
+	defer action.mu.Unlock()
 	if action.conn == nil || action.conn.GetState() == connectivity.Shutdown {
-		if err := action.newClient(); err != nil {
+		if err := action.newClient(ctx); err != nil {
 			return nil, errors.WithMessage(err, "new client error")
 		}
 	}
 	return action.client, nil
 }
 
-func (action *ChainAction) newClient() error {
-	cc, err := action.newClientConn()
+func (action *ChainAction) newClient(ctx context.Context) error {
+	cc, err := action.newClientConn(ctx)
 	if err != nil {
 		return errors.WithMessage(err, "get client connection error")
 	}
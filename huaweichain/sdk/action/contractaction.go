@@ -6,14 +6,12 @@ package action
 
 import (
 	"context"
-	"time"
 
 	"github.com/pkg/errors"
 	"google.golang.org/grpc/connectivity"
 
 	"git.huawei.com/huaweichain/proto/common"
 	"git.huawei.com/huaweichain/proto/nodeservice"
-	"git.huawei.com/huaweichain/sdk/utils"
 )
 
 // ContractAction is the action for contract operations.
@@ -31,93 +29,125 @@ func NewContractAction(config *Config) *ContractAction {
 
 // Invoke is used to send invoke request raw message to contract by grpc.
 func (action *ContractAction) Invoke(rawMsg *common.RawMessage) (*common.RawMessage, error) {
+	return action.InvokeCtx(context.Background(), rawMsg)
+}
+
+// InvokeCtx is the same as Invoke but bound to ctx, the call is canceled with ctx.
+// The sdk timeout applies if ctx has no deadline.
+func (action *ContractAction) InvokeCtx(ctx context.Context, rawMsg *common.RawMessage) (*common.RawMessage, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
 	if rawMsg == nil {
 		return nil, errors.New("raw message is nil")
 	}
-	client, err := action.getContractClient()
+	client, err := action.getContractClient(ctx)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get contract client error")
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), utils.GetTimeout()*time.Second)
-	defer cancel()
 	return client.Invoke(ctx, rawMsg)
 }
 
 // Query is used to send query request raw message to contract by grpc.
 func (action *ContractAction) Query(rawMsg *common.RawMessage) (*common.RawMessage, error) {
+	return action.QueryCtx(context.Background(), rawMsg)
+}
+
+// QueryCtx is the same as Query but bound to ctx, the call is canceled with ctx.
+// The sdk timeout applies if ctx has no deadline.
+func (action *ContractAction) QueryCtx(ctx context.Context, rawMsg *common.RawMessage) (*common.RawMessage, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
 	if rawMsg == nil {
 		return nil, errors.New("raw message is nil")
 	}
-	client, err := action.getContractClient()
+	client, err := action.getContractClient(ctx)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get contract client error")
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), utils.GetTimeout()*time.Second)
-	defer cancel()
 	return client.Query(ctx, rawMsg)
 }
 
 // ContractImport is used to send import contract request raw message by grpc.
 func (action *ContractAction) ContractImport(rawMsg *common.RawMessage) (*common.RawMessage, error) {
-	client, err := action.getContractClient()
+	return action.ContractImportCtx(context.Background(), rawMsg)
+}
+
+// ContractImportCtx is the same as ContractImport but bound to ctx, the call is canceled with ctx.
+// The sdk timeout applies if ctx has no deadline.
+func (action *ContractAction) ContractImportCtx(ctx context.Context, rawMsg *common.RawMessage) (*common.RawMessage, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
+	client, err := action.getContractClient(ctx)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get contract client error")
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), utils.GetTimeout()*time.Second)
-	defer cancel()
 	return client.Import(ctx, rawMsg)
 }
 
 // ContractUnImport is used to send unimport contract request raw message by grpc.
 func (action *ContractAction) ContractUnImport(rawMsg *common.RawMessage) (*common.RawMessage, error) {
-	client, err := action.getContractClient()
+	return action.ContractUnImportCtx(context.Background(), rawMsg)
+}
+
+// ContractUnImportCtx is the same as ContractUnImport but bound to ctx, the call is canceled with ctx.
+// The sdk timeout applies if ctx has no deadline.
+func (action *ContractAction) ContractUnImportCtx(ctx context.Context, rawMsg *common.RawMessage) (*common.RawMessage, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
+	client, err := action.getContractClient(ctx)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get contract client error")
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), utils.GetTimeout()*time.Second)
-	defer cancel()
 	return client.UnImport(ctx, rawMsg)
 }
 
 // Transaction is used to send transaction request raw message by grpc.
 func (action *ContractAction) Transaction(rawMsg *common.RawMessage) (*common.RawMessage, error) {
+	return action.TransactionCtx(context.Background(), rawMsg)
+}
+
+// TransactionCtx is the same as Transaction but bound to ctx, the call is canceled with ctx.
+// The sdk timeout applies if ctx has no deadline.
+func (action *ContractAction) TransactionCtx(ctx context.Context, rawMsg *common.RawMessage) (*common.RawMessage, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
 	if rawMsg == nil {
 		return nil, errors.New("raw message is nil")
 	}
-	client, err := action.getTransactionClient()
+	client, err := action.getTransactionClient(ctx)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get transaction client error")
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), utils.GetTimeout()*time.Second)
-	defer cancel()
 	return client.SendTransaction(ctx, rawMsg)
 }
 
-func (action *ContractAction) getContractClient() (nodeservice.ContractClient, error) {
-	if err := action.resetClients(); err != nil {
+func (action *ContractAction) getContractClient(ctx context.Context) (nodeservice.ContractClient, error) {
+	if err := action.resetClients(ctx); err != nil {
 		return nil, errors.WithMessage(err, "reset clients error")
 	}
 	return action.contractClient, nil
 }
 
-func (action *ContractAction) getTransactionClient() (nodeservice.TransactionSenderClient, error) {
-	if err := action.resetClients(); err != nil {
+func (action *ContractAction) getTransactionClient(ctx context.Context) (nodeservice.TransactionSenderClient, error) {
+	if err := action.resetClients(ctx); err != nil {
 		return nil, errors.WithMessage(err, "reset clients error")
 	}
 	return action.transactionClient, nil
 }
 
-func (action *ContractAction) resetClients() error {
+func (action *ContractAction) resetClients(ctx context.Context) error {
+	action.mu.Lock()
+	defer action.mu.Unlock()
 	if action.conn == nil || action.conn.GetState() == connectivity.Shutdown {
-		if err := action.newClients(); err != nil {
+		if err := action.newClients(ctx); err != nil {
 			return errors.WithMessage(err, "new client error")
 		}
 	}
 	return nil
 }
 
-func (action *ContractAction) newClients() error {
-	cc, err := action.newClientConn()
+func (action *ContractAction) newClients(ctx context.Context) error {
+	cc, err := action.newClientConn(ctx)
 	if err != nil {
 		return errors.WithMessage(err, "get client connection error")
 	}
@@ -129,11 +159,17 @@ func (action *ContractAction) newClients() error {
 
 // QueryState is used to send import contract request raw message by grpc.
 func (action *ContractAction) QueryState(rawMsg *common.RawMessage) (*common.RawMessage, error) {
-	client, err := action.getContractClient()
+	return action.QueryStateCtx(context.Background(), rawMsg)
+}
+
+// QueryStateCtx is the same as QueryState but bound to ctx, the call is canceled with ctx.
+// The sdk timeout applies if ctx has no deadline.
+func (action *ContractAction) QueryStateCtx(ctx context.Context, rawMsg *common.RawMessage) (*common.RawMessage, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
+	client, err := action.getContractClient(ctx)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get contract client error")
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), utils.GetTimeout()*time.Second)
-	defer cancel()
 	return client.QueryState(ctx, rawMsg)
 }
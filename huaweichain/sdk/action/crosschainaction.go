@@ -6,13 +6,11 @@ package action
 
 import (
 	"context"
-	"time"
 
 	"github.com/pkg/errors"
 	"google.golang.org/grpc/connectivity"
 
 	"git.huawei.com/huaweichain/proto/relayer"
-	"git.huawei.com/huaweichain/sdk/utils"
 )
 
 // CrossChainAction is the definition of cross chain action.
@@ -29,37 +27,51 @@ func NewCrossChainAction(config *Config) *CrossChainAction {
 
 // RegisterConfig is used to register config.
 func (action *CrossChainAction) RegisterConfig(rawMsg *relayer.RawMessage) (*relayer.RawMessage, error) {
-	client, err := action.getClient()
+	return action.RegisterConfigCtx(context.Background(), rawMsg)
+}
+
+// RegisterConfigCtx is the same as RegisterConfig but bound to ctx, the call is canceled with ctx.
+// The sdk timeout applies if ctx has no deadline.
+func (action *CrossChainAction) RegisterConfigCtx(ctx context.Context, rawMsg *relayer.RawMessage) (*relayer.RawMessage, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
+	client, err := action.getClient(ctx)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get client error")
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), utils.GetTimeout()*time.Second)
-	defer cancel()
 	return client.RegisterConfig(ctx, rawMsg)
 }
 
 // QueryConfig is used to send query cross chain config.
 func (action *CrossChainAction) QueryConfig(rawMsg *relayer.RawMessage) (*relayer.RawMessage, error) {
-	client, err := action.getClient()
+	return action.QueryConfigCtx(context.Background(), rawMsg)
+}
+
+// QueryConfigCtx is the same as QueryConfig but bound to ctx, the call is canceled with ctx.
+// The sdk timeout applies if ctx has no deadline.
+func (action *CrossChainAction) QueryConfigCtx(ctx context.Context, rawMsg *relayer.RawMessage) (*relayer.RawMessage, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
+	client, err := action.getClient(ctx)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get client error")
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), utils.GetTimeout()*time.Second)
-	defer cancel()
 	return client.QueryConfig(ctx, rawMsg)
 }
 
-func (action *CrossChainAction) getClient() (relayer.RelayerClient, error) {
+func (action *CrossChainAction) getClient(ctx context.Context) (relayer.RelayerClient, error) {
+	action.mu.Lock()
+	defer action.mu.Unlock()
 	if action.conn == nil || action.conn.GetState() == connectivity.Shutdown {
-		if err := action.newClient(); err != nil {
+		if err := action.newClient(ctx); err != nil {
 			return nil, errors.WithMessage(err, "new client error")
 		}
 	}
 	return action.relayerClient, nil
 }
 
-func (action *CrossChainAction) newClient() error {
-	cc, err := action.newClientConn()
+func (action *CrossChainAction) newClient(ctx context.Context) error {
+	cc, err := action.newClientConn(ctx)
 	if err != nil {
 		return errors.WithMessage(err, "get client connection error")
 	}
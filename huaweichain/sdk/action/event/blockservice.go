@@ -7,6 +7,7 @@ package event
 
 import (
 	"context"
+	"sync"
 
 	"github.com/pkg/errors"
 
@@ -24,16 +25,26 @@ type BlockEventService struct {
 	client  nodeservice.EventServiceClient
 	ctx     context.Context
 	cancel  context.CancelFunc
+
+	mu     sync.Mutex
+	policy ReconnectPolicy
 }
 
 // NewBlockEventService is used to create an instance of block event service.
 func NewBlockEventService(client nodeservice.EventServiceClient, chainID string) *BlockEventService {
-	ctx, cancel := context.WithCancel(context.Background())
+	return NewBlockEventServiceCtx(context.Background(), client, chainID)
+}
+
+// NewBlockEventServiceCtx is the same as NewBlockEventService, the service is closed when ctx is done.
+func NewBlockEventServiceCtx(ctx context.Context, client nodeservice.EventServiceClient,
+	chainID string) *BlockEventService {
+	ctx, cancel := context.WithCancel(ctx)
 	return &BlockEventService{
 		chainID: chainID,
 		client:  client,
 		ctx:     ctx,
 		cancel:  cancel,
+		policy:  DefaultReconnectPolicy,
 	}
 }
 
@@ -49,18 +60,17 @@ func (s *BlockEventService) RegisterBlockEventFrom(startNum uint64) (*BlockItera
 
 func (s *BlockEventService) registerBlockEventFrom(startPointType nodeservice.StartPointType,
 	startNum uint64) (*BlockIterator, error) {
-	rawMsg, err := s.buildRawMsg(startPointType, startNum)
-	if err != nil {
-		return nil, errors.WithMessage(err, "build raw message error")
-	}
-	blockEventClient, err := s.client.RegisterBlockEvent(s.ctx, rawMsg)
+	stream, err := s.newBlockStream("block event", startPointType, startNum, func(rawMsg *common.RawMessage) (recvFunc, error) {
+		blockEventClient, err := s.client.RegisterBlockEvent(s.ctx, rawMsg)
+		if err != nil {
+			return nil, err
+		}
+		return blockEventClient.Recv, nil
+	})
 	if err != nil {
-		return nil, errors.WithMessage(err, "block event service RegisterEvent failed")
+		return nil, err
 	}
-	return &BlockIterator{
-		client: blockEventClient,
-		ctx:    s.ctx,
-	}, nil
+	return &BlockIterator{stream: stream}, nil
 }
 
 // RegisterBlockResultEvent is used to register block result event from latest block.
@@ -75,18 +85,17 @@ func (s *BlockEventService) RegisterBlockResultEventFrom(startNum uint64) (*Bloc
 
 func (s *BlockEventService) registerBlockResultEventFrom(startPointType nodeservice.StartPointType,
 	startNum uint64) (*BlockResultIterator, error) {
-	rawMsg, err := s.buildRawMsg(startPointType, startNum)
-	if err != nil {
-		return nil, errors.WithMessage(err, "build raw message error")
-	}
-	resultEventClient, err := s.client.RegisterResultEvent(s.ctx, rawMsg)
+	stream, err := s.newBlockStream("block result event", startPointType, startNum, func(rawMsg *common.RawMessage) (recvFunc, error) {
+		resultEventClient, err := s.client.RegisterResultEvent(s.ctx, rawMsg)
+		if err != nil {
+			return nil, err
+		}
+		return resultEventClient.Recv, nil
+	})
 	if err != nil {
-		return nil, errors.WithMessage(err, "block event service RegisterEvent failed")
+		return nil, err
 	}
-	return &BlockResultIterator{
-		client: resultEventClient,
-		ctx:    s.ctx,
-	}, nil
+	return &BlockResultIterator{stream: stream}, nil
 }
 
 // RegisterBlockAndResultEvent is used to register block and result event from latest block.
@@ -101,18 +110,61 @@ func (s *BlockEventService) RegisterBlockAndResultEventFrom(startNum uint64) (*B
 
 func (s *BlockEventService) registerBlockAndResultEventFrom(startPointType nodeservice.StartPointType,
 	startNum uint64) (*BlockAndResultIterator, error) {
-	rawMsg, err := s.buildRawMsg(startPointType, startNum)
+	stream, err := s.newBlockStream("block and result event", startPointType, startNum,
+		func(rawMsg *common.RawMessage) (recvFunc, error) {
+			blockAndResultEventClient, err := s.client.RegisterBlockAndResultEvent(s.ctx, rawMsg)
+			if err != nil {
+				return nil, err
+			}
+			return blockAndResultEventClient.Recv, nil
+		})
 	if err != nil {
-		return nil, errors.WithMessage(err, "build raw message error")
+		return nil, err
 	}
-	blockAndResultEventClient, err := s.client.RegisterBlockAndResultEvent(s.ctx, rawMsg)
+	return &BlockAndResultIterator{stream: stream}, nil
+}
+
+// newBlockStream 订阅事件流，断开时按重连策略从下一个待接收的区块重新订阅
+func (s *BlockEventService) newBlockStream(name string, startPointType nodeservice.StartPointType, startNum uint64,
+	register func(rawMsg *common.RawMessage) (recvFunc, error)) (*blockStream, error) {
+	stream := &blockStream{
+		name:    name,
+		ctx:     s.ctx,
+		policy:  s.reconnectPolicy(),
+		next:    startNum,
+		started: startPointType == nodeservice.SPECIFIC,
+		open: func(startPointType nodeservice.StartPointType, startNum uint64) (recvFunc, error) {
+			rawMsg, err := s.buildRawMsg(startPointType, startNum)
+			if err != nil {
+				return nil, errors.WithMessage(err, "build raw message error")
+			}
+			recv, err := register(rawMsg)
+			if err != nil {
+				return nil, errors.WithMessage(err, "block event service RegisterEvent failed")
+			}
+			return recv, nil
+		},
+	}
+	recv, err := stream.open(startPointType, startNum)
 	if err != nil {
-		return nil, errors.WithMessage(err, "block event service RegisterEvent failed")
+		return nil, err
 	}
-	return &BlockAndResultIterator{
-		client: blockAndResultEventClient,
-		ctx:    s.ctx,
-	}, nil
+	stream.recv = recv
+	return stream, nil
+}
+
+// SetReconnectPolicy is used to set the policy of re-establishing the broken event stream for the iterators
+// registered afterwards.
+func (s *BlockEventService) SetReconnectPolicy(policy ReconnectPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policy = policy
+}
+
+func (s *BlockEventService) reconnectPolicy() ReconnectPolicy {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.policy
 }
 
 func (s *BlockEventService) buildRawMsg(startPointType nodeservice.StartPointType,
@@ -136,77 +188,126 @@ func (s *BlockEventService) Close() {
 	s.cancel()
 }
 
+type recvFunc func() (*common.RawMessage, error)
+
+// blockStream 可恢复的区块事件流，记录下一个待接收的区块号，断开时从该区块重新订阅，
+// 无法恢复时返回StreamLostError
+type blockStream struct {
+	name   string
+	ctx    context.Context
+	policy ReconnectPolicy
+	open   func(startPointType nodeservice.StartPointType, startNum uint64) (recvFunc, error)
+	recv   recvFunc
+	// next 为下一个待接收的区块号，started 表示next有效，从最新区块订阅且尚未收到区块时为false
+	next    uint64
+	started bool
+	err     error
+}
+
+func (st *blockStream) receive() (*common.RawMessage, error) {
+	if st.err != nil {
+		return nil, st.err
+	}
+	for {
+		rawMsg, err := st.recv()
+		if st.ctx.Err() != nil {
+			return nil, errors.New("grpc stream has closed")
+		}
+		if err == nil {
+			return rawMsg, nil
+		}
+		log.Warnf("%s stream broken: %v", st.name, err)
+		err = st.policy.reconnect(st.ctx, st.name, err, func() error {
+			startPointType, startNum := nodeservice.LATEST, uint64(0)
+			if st.started {
+				startPointType, startNum = nodeservice.SPECIFIC, st.next
+			}
+			recv, err := st.open(startPointType, startNum)
+			if err != nil {
+				return err
+			}
+			st.recv = recv
+			return nil
+		})
+		if err != nil {
+			if st.ctx.Err() == nil {
+				st.err = err
+			}
+			return nil, err
+		}
+	}
+}
+
+// advance 记录已收到的区块号
+func (st *blockStream) advance(blockNum uint64) {
+	st.next, st.started = blockNum+1, true
+}
+
 // BlockIterator is the definition of block iterator.
 type BlockIterator struct {
-	client nodeservice.EventService_RegisterBlockEventClient
-	ctx    context.Context
+	stream *blockStream
 }
 
-// Next is used to get the next element of iterator.
+// Next is used to get the next element of iterator. The broken stream is resumed from the next block,
+// a StreamLostError is returned if it can not be resumed.
 func (itr *BlockIterator) Next() (*common.Block, error) {
-	rawMsg, err := itr.client.Recv()
+	rawMsg, err := itr.stream.receive()
 	if err != nil {
 		return nil, errors.WithMessage(err, "recv block error")
 	}
-	select {
-	case <-itr.ctx.Done():
-		return nil, errors.New("grpc stream has closed")
-	default:
-		block := &common.Block{}
-		err = proto.Unmarshal(rawMsg.Payload, block)
-		if err != nil {
-			return nil, errors.WithMessage(err, "unmarshal block error")
-		}
-		return block, nil
+	block := &common.Block{}
+	err = proto.Unmarshal(rawMsg.Payload, block)
+	if err != nil {
+		return nil, errors.WithMessage(err, "unmarshal block error")
+	}
+	if block.Header != nil {
+		itr.stream.advance(block.Header.Number)
 	}
+	return block, nil
 }
 
 // BlockResultIterator is the definition of block result iterator.
 type BlockResultIterator struct {
-	client nodeservice.EventService_RegisterResultEventClient
-	ctx    context.Context
+	stream *blockStream
 }
 
-// Next is used to get the next element of iterator.
+// Next is used to get the next element of iterator. The broken stream is resumed from the next block,
+// a StreamLostError is returned if it can not be resumed.
 func (itr *BlockResultIterator) Next() (*common.BlockResult, error) {
-	rawMsg, err := itr.client.Recv()
+	rawMsg, err := itr.stream.receive()
 	if err != nil {
 		return nil, errors.WithMessage(err, "recv block result error")
 	}
-	select {
-	case <-itr.ctx.Done():
-		return nil, errors.New("grpc stream has closed")
-	default:
-		blockResult := &common.BlockResult{}
-		err = proto.Unmarshal(rawMsg.Payload, blockResult)
-		if err != nil {
-			return nil, errors.WithMessage(err, "unmarshal block result error")
-		}
-		return blockResult, nil
+	blockResult := &common.BlockResult{}
+	err = proto.Unmarshal(rawMsg.Payload, blockResult)
+	if err != nil {
+		return nil, errors.WithMessage(err, "unmarshal block result error")
 	}
+	itr.stream.advance(blockResult.BlockNum)
+	return blockResult, nil
 }
 
 // BlockAndResultIterator is the definition of block and result iterator.
 type BlockAndResultIterator struct {
-	client nodeservice.EventService_RegisterBlockAndResultEventClient
-	ctx    context.Context
+	stream *blockStream
 }
 
-// Next is used to get the next element of iterator.
+// Next is used to get the next element of iterator. The broken stream is resumed from the next block,
+// a StreamLostError is returned if it can not be resumed.
 func (itr *BlockAndResultIterator) Next() (*common.BlockAndResult, error) {
-	rawMsg, err := itr.client.Recv()
+	rawMsg, err := itr.stream.receive()
 	if err != nil {
 		return nil, errors.WithMessage(err, "recv block result error")
 	}
-	select {
-	case <-itr.ctx.Done():
-		return nil, errors.New("grpc stream has closed")
-	default:
-		br := &common.BlockAndResult{}
-		err = proto.Unmarshal(rawMsg.Payload, br)
-		if err != nil {
-			return nil, errors.WithMessage(err, "unmarshal block and result error")
-		}
-		return br, nil
+	br := &common.BlockAndResult{}
+	err = proto.Unmarshal(rawMsg.Payload, br)
+	if err != nil {
+		return nil, errors.WithMessage(err, "unmarshal block and result error")
+	}
+	if br.Result != nil {
+		itr.stream.advance(br.Result.BlockNum)
+	} else if br.Block != nil && br.Block.Header != nil {
+		itr.stream.advance(br.Block.Header.Number)
 	}
+	return br, nil
 }
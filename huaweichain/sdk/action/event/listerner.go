@@ -14,18 +14,21 @@ import (
 )
 
 type eventListener interface {
-	registerTx(txHash []byte) (chan *common.TxResult, error)
-	listen()
+	registerTx(txHash []byte) error
+	// listen 接收交易结果直到事件流断开，返回断开的原因
+	listen(deliver func(txResult *common.TxResult)) error
+	// reconnect 建立新的事件流，接续当前事件流的位置
+	reconnect() (eventListener, error)
 }
 
 type txListener struct {
-	ch     chan msg
-	client nodeservice.EventService_RegisterTxEventClient
-	ctx    context.Context
+	client  nodeservice.EventServiceClient
+	chainID string
+	stream  nodeservice.EventService_RegisterTxEventClient
+	ctx     context.Context
 }
 
-func newTxListener(ctx context.Context, client nodeservice.EventServiceClient, chainID string,
-	ch chan msg) (*txListener, error) {
+func newTxListener(ctx context.Context, client nodeservice.EventServiceClient, chainID string) (*txListener, error) {
 	txEventClient, err := client.RegisterTxEvent(ctx)
 	if err != nil {
 		return nil, errors.WithMessage(err, "client action RegisterTxEvent failed")
@@ -50,35 +53,30 @@ func newTxListener(ctx context.Context, client nodeservice.EventServiceClient, c
 		return nil, errors.WithMessage(err, "RegisterTxEvent error: unmarshal tx event response error")
 	}
 	if res.Status != nodeservice.SUCCESS {
-		return nil, errors.WithMessage(err, "RegisterTxEvent error")
+		return nil, errors.Errorf("RegisterTxEvent error: %s", res.Info)
 	}
 	return &txListener{
-		ch:     ch,
-		client: txEventClient,
-		ctx:    ctx,
+		client:  client,
+		chainID: chainID,
+		stream:  txEventClient,
+		ctx:     ctx,
 	}, nil
 }
 
-func (l *txListener) registerTx(txHash []byte) (chan *common.TxResult, error) {
-	ch := make(chan *common.TxResult, 1)
-	msg := &registerMsg{
-		txHash: txHash,
-		ch:     ch,
-	}
-	l.ch <- msg
+func (l *txListener) registerTx(txHash []byte) error {
 	txEvent := &nodeservice.TxEvent{TxHash: txHash, Type: nodeservice.REGISTER_TX_HASH}
 	bytes, err := txEvent.Marshal()
 	if err != nil {
-		return nil, errors.WithMessage(err, "marshal tx event error: %v")
+		return errors.WithMessage(err, "marshal tx event error: %v")
 	}
 	rawMsg := &common.RawMessage{Payload: bytes}
-	if err := l.client.Send(rawMsg); err != nil {
-		return nil, errors.WithMessage(err, "send register txid info error")
+	if err := l.stream.Send(rawMsg); err != nil {
+		return errors.WithMessage(err, "send register txid info error")
 	}
-	return ch, nil
+	return nil
 }
 
-func (l *txListener) listen() {
+func (l *txListener) listen(deliver func(txResult *common.TxResult)) error {
 	processor := func(rawMsg *common.RawMessage) {
 		resp := &nodeservice.TxEventRes{}
 		err := proto.Unmarshal(rawMsg.Payload, resp)
@@ -92,23 +90,32 @@ func (l *txListener) listen() {
 			log.Errorf("unmarshal tx result error: %v", err)
 			return
 		}
-		msg := &resultMsg{txResult: txResult}
-		l.ch <- msg
+		deliver(txResult)
 	}
-	listen(l.client.Recv, processor, l.ctx.Done())
+	return listen(l.stream.Recv, processor, l.ctx.Done())
+}
+
+// reconnect 新的事件流需由调用方重新注册等待中的交易，节点对已落块的交易直接返回结果
+func (l *txListener) reconnect() (eventListener, error) {
+	return newTxListener(l.ctx, l.client, l.chainID)
 }
 
 type blockListener struct {
-	ch     chan msg
-	client nodeservice.EventService_RegisterResultEventClient
-	ctx    context.Context
+	client  nodeservice.EventServiceClient
+	chainID string
+	stream  nodeservice.EventService_RegisterResultEventClient
+	ctx     context.Context
+	// next 为下一个待接收的区块号，started 表示已收到过区块
+	next    uint64
+	started bool
 }
 
 func newBlockListener(ctx context.Context, client nodeservice.EventServiceClient, chainID string,
-	ch chan msg) (*blockListener, error) {
+	startPointType nodeservice.StartPointType, startNum uint64) (*blockListener, error) {
 	startPoint := &nodeservice.EventStartPoint{}
 	startPoint.ChainId = chainID
-	startPoint.Type = nodeservice.LATEST
+	startPoint.Type = startPointType
+	startPoint.BlockNum = startNum
 	bytes, err := startPoint.Marshal()
 	if err != nil {
 		return nil, errors.WithMessage(err, "marshal EventStartPoint error")
@@ -121,23 +128,20 @@ func newBlockListener(ctx context.Context, client nodeservice.EventServiceClient
 		return nil, errors.WithMessage(err, "block listener RegisterResultEvent failed")
 	}
 	return &blockListener{
-		ch:     ch,
-		client: blockResultClient,
-		ctx:    ctx,
+		client:  client,
+		chainID: chainID,
+		stream:  blockResultClient,
+		ctx:     ctx,
+		next:    startNum,
+		started: startPointType == nodeservice.SPECIFIC,
 	}, nil
 }
 
-func (l *blockListener) registerTx(txHash []byte) (chan *common.TxResult, error) {
-	ch := make(chan *common.TxResult, 1)
-	msg := &registerMsg{
-		txHash: txHash,
-		ch:     ch,
-	}
-	l.ch <- msg
-	return ch, nil
+func (l *blockListener) registerTx(txHash []byte) error {
+	return nil
 }
 
-func (l *blockListener) listen() {
+func (l *blockListener) listen(deliver func(txResult *common.TxResult)) error {
 	processor := func(rawMsg *common.RawMessage) {
 		res := &common.BlockResult{}
 		err := proto.Unmarshal(rawMsg.Payload, res)
@@ -145,29 +149,35 @@ func (l *blockListener) listen() {
 			log.Errorf("unmarshal block result error: %v", err)
 			return
 		}
+		l.next, l.started = res.BlockNum+1, true
 		for _, txResult := range res.TxResults {
-			msg := &resultMsg{txResult: txResult}
-			l.ch <- msg
+			deliver(txResult)
 		}
 	}
-	listen(l.client.Recv, processor, l.ctx.Done())
+	return listen(l.stream.Recv, processor, l.ctx.Done())
+}
+
+// reconnect 从下一个待接收的区块开始订阅，尚未收到过区块时从最新区块开始
+func (l *blockListener) reconnect() (eventListener, error) {
+	if !l.started {
+		return newBlockListener(l.ctx, l.client, l.chainID, nodeservice.LATEST, 0)
+	}
+	return newBlockListener(l.ctx, l.client, l.chainID, nodeservice.SPECIFIC, l.next)
 }
 
 func listen(recv func() (*common.RawMessage, error), processor func(rawMsg *common.RawMessage),
-	done <-chan struct{}) {
+	done <-chan struct{}) error {
 	if done == nil {
-		log.Error(" context done chan is nil")
-		return
+		return errors.New("context done chan is nil")
 	}
 	for {
 		responseMsg, err := recv()
 		if err != nil {
-			log.Errorf("event receive response message error: %v", err)
-			return
+			return errors.WithMessage(err, "event receive response message error")
 		}
 		select {
 		case <-done:
-			return
+			return errors.New("grpc stream has closed")
 		default:
 			processor(responseMsg)
 		}
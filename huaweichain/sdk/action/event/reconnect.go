@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) Huawei Technologies Co., Ltd. 2021-2021. All rights reserved.
+ */
+
+package event
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrStreamLost is matched by errors.Is for every StreamLostError.
+var ErrStreamLost = errors.New("event stream lost")
+
+// StreamLostError is returned when an event stream broke and could not be re-established.
+type StreamLostError struct {
+	Stream   string // the stream type, such as "tx event" or "block event"
+	Attempts int    // the number of reconnect attempts made
+	Err      error  // the last error
+}
+
+func (e *StreamLostError) Error() string {
+	return fmt.Sprintf("%s stream lost after %d reconnect attempts: %v", e.Stream, e.Attempts, e.Err)
+}
+
+// Unwrap returns the last error.
+func (e *StreamLostError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrStreamLost.
+func (e *StreamLostError) Is(target error) bool {
+	return target == ErrStreamLost
+}
+
+// ReconnectPolicy controls how a broken event stream is re-established.
+type ReconnectPolicy struct {
+	MaxAttempts    int           // the max number of reconnect attempts, 0 disables reconnect
+	InitialBackoff time.Duration // the wait before the first attempt, doubled after each failure
+	MaxBackoff     time.Duration // the upper limit of the wait between attempts
+}
+
+// DefaultReconnectPolicy is the reconnect policy of newly created event services.
+var DefaultReconnectPolicy = ReconnectPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: 200 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+}
+
+// reconnect calls connect with backoff until it succeeds, the attempts run out or ctx is done.
+func (p ReconnectPolicy) reconnect(ctx context.Context, stream string, cause error, connect func() error) error {
+	backoff := p.InitialBackoff
+	err := cause
+	for attempt := 1; attempt <= p.MaxAttempts; attempt++ {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.New("grpc stream has closed")
+		case <-timer.C:
+		}
+		if err = connect(); err == nil {
+			log.Infof("%s stream reconnected after %d attempts", stream, attempt)
+			return nil
+		}
+		log.Warnf("reconnect %s stream error, attempt %d: %v", stream, attempt, err)
+		if backoff *= 2; p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+	return &StreamLostError{Stream: stream, Attempts: p.MaxAttempts, Err: err}
+}
@@ -0,0 +1,253 @@
+/*
+ * Copyright (c) Huawei Technologies Co., Ltd. 2021-2021. All rights reserved.
+ */
+
+package event
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+
+	"git.huawei.com/huaweichain/proto"
+	"git.huawei.com/huaweichain/proto/common"
+	"git.huawei.com/huaweichain/proto/nodeservice"
+)
+
+var testPolicy = ReconnectPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+
+// fakeStream 模拟事件流，closed后Recv返回io.EOF
+type fakeStream struct {
+	grpc.ClientStream
+	client *fakeEventClient
+	msgs   chan *common.RawMessage
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newFakeStream(client *fakeEventClient) *fakeStream {
+	return &fakeStream{client: client, msgs: make(chan *common.RawMessage, 16), closed: make(chan struct{})}
+}
+
+func (s *fakeStream) Recv() (*common.RawMessage, error) {
+	select {
+	case msg := <-s.msgs:
+		return msg, nil
+	case <-s.closed:
+		return nil, io.EOF
+	}
+}
+
+func (s *fakeStream) Send(rawMsg *common.RawMessage) error {
+	select {
+	case <-s.closed:
+		return io.EOF
+	default:
+	}
+	txEvent := &nodeservice.TxEvent{}
+	if err := proto.Unmarshal(rawMsg.Payload, txEvent); err != nil {
+		return err
+	}
+	res := &nodeservice.TxEventRes{Status: nodeservice.SUCCESS, Type: txEvent.Type}
+	if txEvent.Type == nodeservice.REGISTER_TX_HASH {
+		s.client.mu.Lock()
+		txResult, ok := s.client.committed[hex.EncodeToString(txEvent.TxHash)]
+		s.client.mu.Unlock()
+		if !ok {
+			return nil
+		}
+		res.Payload, _ = proto.Marshal(txResult)
+	}
+	payload, _ := proto.Marshal(res)
+	s.msgs <- &common.RawMessage{Payload: payload}
+	return nil
+}
+
+func (s *fakeStream) breakStream() {
+	s.once.Do(func() { close(s.closed) })
+}
+
+// fakeEventClient 模拟节点的事件服务，down为true时拒绝新的订阅
+type fakeEventClient struct {
+	nodeservice.EventServiceClient
+	mu        sync.Mutex
+	down      bool
+	streams   []*fakeStream
+	starts    []*nodeservice.EventStartPoint
+	committed map[string]*common.TxResult
+	// perStream 为每个区块事件流发送的区块数，之后事件流断开
+	perStream uint64
+}
+
+func (c *fakeEventClient) RegisterTxEvent(ctx context.Context,
+	opts ...grpc.CallOption) (nodeservice.EventService_RegisterTxEventClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.down {
+		return nil, errors.New("connection refused")
+	}
+	stream := newFakeStream(c)
+	c.streams = append(c.streams, stream)
+	return stream, nil
+}
+
+func (c *fakeEventClient) RegisterResultEvent(ctx context.Context, in *common.RawMessage,
+	opts ...grpc.CallOption) (nodeservice.EventService_RegisterResultEventClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.down {
+		return nil, errors.New("connection refused")
+	}
+	startPoint := &nodeservice.EventStartPoint{}
+	if err := proto.Unmarshal(in.Payload, startPoint); err != nil {
+		return nil, err
+	}
+	c.starts = append(c.starts, startPoint)
+	stream := newFakeStream(c)
+	for num := startPoint.BlockNum; num < startPoint.BlockNum+c.perStream; num++ {
+		payload, _ := proto.Marshal(&common.BlockResult{BlockNum: num})
+		stream.msgs <- &common.RawMessage{Payload: payload}
+	}
+	go func() {
+		// 区块发送完后断开
+		for len(stream.msgs) > 0 {
+			time.Sleep(time.Millisecond)
+		}
+		stream.breakStream()
+	}()
+	return stream, nil
+}
+
+func (c *fakeEventClient) lastStream() *fakeStream {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.streams[len(c.streams)-1]
+}
+
+func Test_TxEventService_Reconnect(t *testing.T) {
+	client := &fakeEventClient{committed: map[string]*common.TxResult{}}
+	s, err := NewTxEventService(client, "chain")
+	if err != nil {
+		t.Fatalf("new tx event service error: %v", err)
+	}
+	defer s.Close()
+	s.SetReconnectPolicy(testPolicy)
+
+	txHash := []byte{1, 2, 3}
+	ch, err := s.RegisterTx(txHash)
+	if err != nil {
+		t.Fatalf("register tx error: %v", err)
+	}
+	// 事件流断开期间交易落块，重连后重新注册的交易由节点直接返回结果
+	client.mu.Lock()
+	client.committed[hex.EncodeToString(txHash)] = &common.TxResult{TxHash: txHash, Status: common.VALID}
+	client.mu.Unlock()
+	client.lastStream().breakStream()
+
+	select {
+	case txResult, ok := <-ch:
+		if !ok || txResult.Status != common.VALID {
+			t.Fatalf("unexpected tx result: %v, %v", txResult, ok)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("tx result not replayed after reconnect")
+	}
+	if s.Err() != nil {
+		t.Errorf("unexpected error: %v", s.Err())
+	}
+}
+
+func Test_TxEventService_StreamLost(t *testing.T) {
+	client := &fakeEventClient{committed: map[string]*common.TxResult{}}
+	s, err := NewTxEventService(client, "chain")
+	if err != nil {
+		t.Fatalf("new tx event service error: %v", err)
+	}
+	defer s.Close()
+	s.SetReconnectPolicy(testPolicy)
+
+	ch, err := s.RegisterTx([]byte{1})
+	if err != nil {
+		t.Fatalf("register tx error: %v", err)
+	}
+	client.mu.Lock()
+	client.down = true
+	client.mu.Unlock()
+	client.lastStream().breakStream()
+
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatal("tx result chan should be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("tx result chan not closed after stream lost")
+	}
+	var lost *StreamLostError
+	if !errors.As(s.Err(), &lost) || lost.Attempts != testPolicy.MaxAttempts {
+		t.Errorf("unexpected error: %v", s.Err())
+	}
+	if _, err := s.RegisterTx([]byte{2}); !errors.Is(err, ErrStreamLost) {
+		t.Errorf("register tx after stream lost, error: %v", err)
+	}
+}
+
+func Test_TxEventService_UnregisterTx(t *testing.T) {
+	client := &fakeEventClient{committed: map[string]*common.TxResult{}}
+	s, err := NewTxEventService(client, "chain")
+	if err != nil {
+		t.Fatalf("new tx event service error: %v", err)
+	}
+	defer s.Close()
+
+	if _, err := s.RegisterTx([]byte{1}); err != nil {
+		t.Fatalf("register tx error: %v", err)
+	}
+	s.UnregisterTx([]byte{1})
+	s.mu.Lock()
+	pending := len(s.txMap)
+	s.mu.Unlock()
+	if pending != 0 {
+		t.Errorf("%d txs still waiting after unregister", pending)
+	}
+}
+
+func Test_BlockResultIterator_Resume(t *testing.T) {
+	client := &fakeEventClient{perStream: 2}
+	s := NewBlockEventService(client, "chain")
+	defer s.Close()
+	s.SetReconnectPolicy(testPolicy)
+
+	itr, err := s.RegisterBlockResultEventFrom(3)
+	if err != nil {
+		t.Fatalf("register block result event error: %v", err)
+	}
+	for want := uint64(3); want < 8; want++ {
+		blockResult, err := itr.Next()
+		if err != nil {
+			t.Fatalf("next error: %v", err)
+		}
+		if blockResult.BlockNum != want {
+			t.Fatalf("block number = %d, want %d", blockResult.BlockNum, want)
+		}
+	}
+	client.mu.Lock()
+	for i, start := range client.starts {
+		if start.Type != nodeservice.SPECIFIC || start.BlockNum != uint64(3+2*i) {
+			t.Errorf("start point %d: %v", i, start)
+		}
+	}
+	client.down = true
+	client.mu.Unlock()
+
+	itr.Next()
+	if _, err := itr.Next(); !errors.Is(err, ErrStreamLost) {
+		t.Errorf("unexpected error after stream lost: %v", err)
+	}
+}
@@ -7,7 +7,7 @@ package event
 import (
 	"context"
 	"encoding/hex"
-	"reflect"
+	"sync"
 
 	"github.com/pkg/errors"
 
@@ -25,14 +25,20 @@ const (
 	Tx SourceType = 1
 )
 
-// TxEventService 支持Block和Tx两种不同的Event来源
+// TxEventService 支持Block和Tx两种不同的Event来源。
+// 事件流断开时按重连策略重新建立：Tx来源重新注册全部未返回结果的交易，已落块的交易由节点重新推送结果；
+// Block来源从最后收到的区块的下一个区块开始订阅，补发断开期间的交易结果。
+// 无法重连时关闭全部等待中的结果通道，Err返回StreamLostError
 type TxEventService struct {
+	chainID string
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	mu       sync.Mutex
 	txMap    map[string]chan *common.TxResult
-	chainID  string
-	ch       chan msg
 	listener eventListener
-	ctx      context.Context
-	cancel   context.CancelFunc
+	policy   ReconnectPolicy
+	err      error
 }
 
 // NewTxEventService is used to create an instance of tx event service, default with tx event data source.
@@ -43,18 +49,24 @@ func NewTxEventService(client nodeservice.EventServiceClient, chainID string) (*
 // NewTxEventServiceWithSourceType is used to create an instance of tx event service with specified event data source.
 func NewTxEventServiceWithSourceType(client nodeservice.EventServiceClient, chainID string,
 	source SourceType) (*TxEventService, error) {
-	ctx, cancel := context.WithCancel(context.Background())
-	ch := make(chan msg)
+	return NewTxEventServiceWithSourceTypeCtx(context.Background(), client, chainID, source)
+}
+
+// NewTxEventServiceWithSourceTypeCtx is the same as NewTxEventServiceWithSourceType, the service is closed
+// when ctx is done.
+func NewTxEventServiceWithSourceTypeCtx(ctx context.Context, client nodeservice.EventServiceClient, chainID string,
+	source SourceType) (*TxEventService, error) {
+	ctx, cancel := context.WithCancel(ctx)
 	var listener eventListener
 	var err error
 	if source == Block {
-		listener, err = newBlockListener(ctx, client, chainID, ch)
+		listener, err = newBlockListener(ctx, client, chainID, nodeservice.LATEST, 0)
 		if err != nil {
 			cancel()
 			return nil, errors.WithMessage(err, "new block listener error")
 		}
 	} else {
-		listener, err = newTxListener(ctx, client, chainID, ch)
+		listener, err = newTxListener(ctx, client, chainID)
 		if err != nil {
 			cancel()
 			return nil, errors.WithMessage(err, "new tx listener error")
@@ -62,66 +74,168 @@ func NewTxEventServiceWithSourceType(client nodeservice.EventServiceClient, chai
 	}
 	es := &TxEventService{
 		chainID:  chainID,
-		ch:       ch,
 		txMap:    make(map[string]chan *common.TxResult),
 		listener: listener,
+		policy:   DefaultReconnectPolicy,
 		ctx:      ctx,
 		cancel:   cancel,
 	}
-	go listener.listen()
-	go es.dispatch()
+	go es.run(listener)
 	return es, nil
 }
 
-// RegisterTx is used to register transaction.
+// SetReconnectPolicy is used to set the policy of re-establishing the broken event stream.
+func (s *TxEventService) SetReconnectPolicy(policy ReconnectPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policy = policy
+}
+
+// RegisterTx is used to register transaction. The returned chan is closed without result if the event stream is lost.
 func (s *TxEventService) RegisterTx(txHash []byte) (chan *common.TxResult, error) {
-	return s.listener.registerTx(txHash)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	ch := make(chan *common.TxResult, 1)
+	s.txMap[hex.EncodeToString(txHash)] = ch
+	if err := s.listener.registerTx(txHash); err != nil {
+		// 事件流已断开，保留注册，重连后重新注册
+		log.Warnf("register tx error, retry after reconnect: %v", err)
+	}
+	return ch, nil
+}
+
+// UnregisterTx is used to stop waiting for the result of the transaction, e.g. when it failed to be submitted or
+// the caller gave up waiting. The chan returned by RegisterTx is not closed.
+func (s *TxEventService) UnregisterTx(txHash []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.txMap, hex.EncodeToString(txHash))
+}
+
+// Err is used to get the error of tx event service, it is a StreamLostError if the event stream is lost.
+func (s *TxEventService) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Handover is used to move the transactions waiting for results to the next service, which registers them on its
+// event stream and delivers the results to the same chans. It is used before closing the service when switching
+// to another node, the chans are closed without result if next has failed.
+func (s *TxEventService) Handover(next *TxEventService) {
+	s.mu.Lock()
+	pending := s.txMap
+	s.txMap = make(map[string]chan *common.TxResult)
+	s.mu.Unlock()
+
+	next.mu.Lock()
+	defer next.mu.Unlock()
+	for txID, ch := range pending {
+		if next.err != nil {
+			close(ch)
+			continue
+		}
+		next.txMap[txID] = ch
+		txHash, err := hex.DecodeString(txID)
+		if err != nil {
+			continue
+		}
+		if err := next.listener.registerTx(txHash); err != nil {
+			// 事件流已断开，保留注册，重连后重新注册
+			log.Warnf("register tx error, retry after reconnect: %v", err)
+		}
+	}
 }
 
-// Close is used to close tx event service.
+// Close is used to close tx event service. The chans still waiting for results are closed without result.
 func (s *TxEventService) Close() {
 	s.cancel()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for txID, ch := range s.txMap {
+		close(ch)
+		delete(s.txMap, txID)
+	}
 }
 
-func (s *TxEventService) dispatch() {
+// run 接收事件直到服务关闭，事件流断开时重连
+func (s *TxEventService) run(listener eventListener) {
 	for {
-		select {
-		case m := <-s.ch:
-			v, ok := m.(*registerMsg)
-			if ok {
-				txID := hex.EncodeToString(v.txHash)
-				s.txMap[txID] = v.ch
-				continue
-			}
-			rm, ok := m.(*resultMsg)
-			if !ok {
-				log.Errorf("unsupported message type: %v", reflect.TypeOf(m))
-				continue
-			}
-			txID := hex.EncodeToString(rm.txResult.TxHash)
-			ch, ok := s.txMap[txID]
-			if !ok {
-				continue
+		err := listener.listen(s.deliver)
+		if s.ctx.Err() != nil {
+			return
+		}
+		log.Warnf("tx event stream broken: %v", err)
+		s.mu.Lock()
+		policy := s.policy
+		s.mu.Unlock()
+		err = policy.reconnect(s.ctx, "tx event", err, func() error {
+			next, err := listener.reconnect()
+			if err != nil {
+				return err
 			}
-			if ch == nil {
-				log.Error("tx result chan is nil")
-				continue
+			return s.resume(next)
+		})
+		if err != nil {
+			if s.ctx.Err() == nil {
+				s.fail(err)
 			}
-			ch <- rm.txResult
-			delete(s.txMap, txID)
-		case <-s.ctx.Done():
 			return
 		}
+		listener = s.currentListener()
+	}
+}
+
+// resume 在新的事件流上重新注册全部等待中的交易
+func (s *TxEventService) resume(listener eventListener) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for txID := range s.txMap {
+		txHash, err := hex.DecodeString(txID)
+		if err != nil {
+			return errors.WithMessage(err, "decode tx hash error")
+		}
+		if err := listener.registerTx(txHash); err != nil {
+			return err
+		}
 	}
+	s.listener = listener
+	return nil
 }
 
-type msg interface{}
+func (s *TxEventService) currentListener() eventListener {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.listener
+}
 
-type registerMsg struct {
-	ch     chan *common.TxResult
-	txHash []byte
+// fail 无法重连时关闭全部等待中的结果通道
+func (s *TxEventService) fail(err error) {
+	log.Errorf("tx event service stopped: %v", err)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+	for txID, ch := range s.txMap {
+		close(ch)
+		delete(s.txMap, txID)
+	}
 }
 
-type resultMsg struct {
-	txResult *common.TxResult
+func (s *TxEventService) deliver(txResult *common.TxResult) {
+	txID := hex.EncodeToString(txResult.TxHash)
+	s.mu.Lock()
+	ch, ok := s.txMap[txID]
+	delete(s.txMap, txID)
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+	if ch == nil {
+		log.Error("tx result chan is nil")
+		return
+	}
+	ch <- txResult
 }
@@ -34,34 +34,54 @@ func NewEventAction(config *Config) *EventAction {
 
 // GetBlockEventService is used to get block event service.
 func (action *EventAction) GetBlockEventService(chainID string) (*event.BlockEventService, error) {
-	client, err := action.getClient()
+	return action.GetBlockEventServiceCtx(context.Background(), chainID)
+}
+
+// GetBlockEventServiceCtx is the same as GetBlockEventService, the event streams of the service are closed
+// when ctx is done.
+func (action *EventAction) GetBlockEventServiceCtx(ctx context.Context, chainID string) (*event.BlockEventService, error) {
+	client, err := action.getClient(ctx)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get client error")
 	}
-	return event.NewBlockEventService(client, chainID), nil
+	return event.NewBlockEventServiceCtx(ctx, client, chainID), nil
 }
 
 // GetTxEventService is used to get tx event service by default event source type. default tx event.
 func (action *EventAction) GetTxEventService(chainID string) (*event.TxEventService, error) {
-	client, err := action.getClient()
-	if err != nil {
-		return nil, errors.WithMessage(err, "get client error")
-	}
-	return event.NewTxEventService(client, chainID)
+	return action.GetTxEventServiceWithSourceTypeCtx(context.Background(), chainID, event.Tx)
+}
+
+// GetTxEventServiceCtx is the same as GetTxEventService, the service is closed when ctx is done.
+func (action *EventAction) GetTxEventServiceCtx(ctx context.Context, chainID string) (*event.TxEventService, error) {
+	return action.GetTxEventServiceWithSourceTypeCtx(ctx, chainID, event.Tx)
 }
 
 // GetTxEventServiceWithSourceType is used to get tx event service with specified event source type.
 func (action *EventAction) GetTxEventServiceWithSourceType(chainID string,
 	source event.SourceType) (*event.TxEventService, error) {
-	client, err := action.getClient()
+	return action.GetTxEventServiceWithSourceTypeCtx(context.Background(), chainID, source)
+}
+
+// GetTxEventServiceWithSourceTypeCtx is the same as GetTxEventServiceWithSourceType, the service is closed
+// when ctx is done.
+func (action *EventAction) GetTxEventServiceWithSourceTypeCtx(ctx context.Context, chainID string,
+	source event.SourceType) (*event.TxEventService, error) {
+	client, err := action.getClient(ctx)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get client error")
 	}
-	return event.NewTxEventServiceWithSourceType(client, chainID, source)
+	return event.NewTxEventServiceWithSourceTypeCtx(ctx, client, chainID, source)
 }
 
 // Listen is used to register result event to server and get the register result client.
 func (action *EventAction) Listen(chainID string) (nodeservice.EventService_RegisterResultEventClient, error) {
+	return action.ListenCtx(context.Background(), chainID)
+}
+
+// ListenCtx is the same as Listen, the stream is closed when ctx is done.
+func (action *EventAction) ListenCtx(ctx context.Context,
+	chainID string) (nodeservice.EventService_RegisterResultEventClient, error) {
 	in := &common.RawMessage{}
 	startPoint := &nodeservice.EventStartPoint{}
 	startPoint.ChainId = chainID
@@ -72,11 +92,11 @@ func (action *EventAction) Listen(chainID string) (nodeservice.EventService_Regi
 	}
 	in.Payload = bytes
 
-	client, err := action.getClient()
+	client, err := action.getClient(ctx)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get client error")
 	}
-	event, err := client.RegisterResultEvent(context.Background(), in)
+	event, err := client.RegisterResultEvent(ctx, in)
 	if err != nil {
 		return nil, errors.WithMessage(err, "event action RegisterResultEvent failed")
 	}
@@ -85,6 +105,12 @@ func (action *EventAction) Listen(chainID string) (nodeservice.EventService_Regi
 
 // ListenBlockAndResult is used to register block and result event to server and get the register result client.
 func (action *EventAction) ListenBlockAndResult(chainID string,
+	number uint64) (nodeservice.EventService_RegisterBlockAndResultEventClient, error) {
+	return action.ListenBlockAndResultCtx(context.Background(), chainID, number)
+}
+
+// ListenBlockAndResultCtx is the same as ListenBlockAndResult, the stream is closed when ctx is done.
+func (action *EventAction) ListenBlockAndResultCtx(ctx context.Context, chainID string,
 	number uint64) (nodeservice.EventService_RegisterBlockAndResultEventClient, error) {
 	in := &common.RawMessage{}
 	startPoint := &nodeservice.EventStartPoint{
@@ -98,11 +124,11 @@ func (action *EventAction) ListenBlockAndResult(chainID string,
 	}
 	in.Payload = bytes
 
-	client, err := action.getClient()
+	client, err := action.getClient(ctx)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get client error")
 	}
-	event, err := client.RegisterBlockAndResultEvent(context.Background(), in)
+	event, err := client.RegisterBlockAndResultEvent(ctx, in)
 	if err != nil {
 		return nil, errors.WithMessage(err, "event action RegisterBlockAndResultEvent failed")
 	}
@@ -111,11 +137,16 @@ func (action *EventAction) ListenBlockAndResult(chainID string,
 
 // RegisterTxEvent is used to register tx event to server and get the register tx event client.
 func (action *EventAction) RegisterTxEvent(chainID string) (*TxEvent, error) {
-	client, err := action.getClient()
+	return action.RegisterTxEventCtx(context.Background(), chainID)
+}
+
+// RegisterTxEventCtx is the same as RegisterTxEvent, the stream is closed when ctx is done.
+func (action *EventAction) RegisterTxEventCtx(ctx context.Context, chainID string) (*TxEvent, error) {
+	client, err := action.getClient(ctx)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get client error")
 	}
-	txEventClient, err := client.RegisterTxEvent(context.Background())
+	txEventClient, err := client.RegisterTxEvent(ctx)
 	if err != nil {
 		return nil, errors.WithMessage(err, "client action RegisterTxEvent failed")
 	}
@@ -144,17 +175,19 @@ func (action *EventAction) RegisterTxEvent(chainID string) (*TxEvent, error) {
 	return nil, errors.WithMessage(err, "RegisterTxEvent error")
 }
 
-func (action *EventAction) getClient() (nodeservice.EventServiceClient, error) {
+func (action *EventAction) getClient(ctx context.Context) (nodeservice.EventServiceClient, error) {
+	action.mu.Lock()
+	defer action.mu.Unlock()
 	if action.conn == nil || action.conn.GetState() == connectivity.Shutdown {
-		if err := action.newClient(); err != nil {
+		if err := action.newClient(ctx); err != nil {
 			return nil, errors.WithMessage(err, "new client error: %v")
 		}
 	}
 	return action.client, nil
 }
 
-func (action *EventAction) newClient() error {
-	cc, err := action.newClientConn()
+func (action *EventAction) newClient(ctx context.Context) error {
+	cc, err := action.newClientConn(ctx)
 	if err != nil {
 		return errors.WithMessage(err, "get client connection error: %v")
 	}
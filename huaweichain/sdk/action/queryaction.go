@@ -6,7 +6,6 @@ package action
 
 import (
 	"context"
-	"time"
 
 	"github.com/pkg/errors"
 
@@ -14,7 +13,6 @@ import (
 
 	"git.huawei.com/huaweichain/proto/common"
 	"git.huawei.com/huaweichain/proto/nodeservice"
-	"git.huawei.com/huaweichain/sdk/utils"
 )
 
 // QueryAction is the action for query operations.
@@ -33,123 +31,173 @@ func NewQueryAction(config *Config) *QueryAction {
 
 // GetLatestChainState is used to send get latest chain request raw message by grpc.
 func (action *QueryAction) GetLatestChainState(rawMsg *common.RawMessage) (*common.RawMessage, error) {
-	client, err := action.getChainServiceClient()
+	return action.GetLatestChainStateCtx(context.Background(), rawMsg)
+}
+
+// GetLatestChainStateCtx is the same as GetLatestChainState but bound to ctx, the call is canceled with ctx.
+// The sdk timeout applies if ctx has no deadline.
+func (action *QueryAction) GetLatestChainStateCtx(ctx context.Context, rawMsg *common.RawMessage) (*common.RawMessage, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
+	client, err := action.getChainServiceClient(ctx)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get chain service client error: %v")
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), utils.GetTimeout()*time.Second)
-	defer cancel()
 	return client.GetLatestChainState(ctx, rawMsg)
 }
 
 // GetBlockByNum is used to send get block by number request raw message by grpc.
 func (action *QueryAction) GetBlockByNum(rawMsg *common.RawMessage) (*common.RawMessage, error) {
-	client, err := action.getChainServiceClient()
+	return action.GetBlockByNumCtx(context.Background(), rawMsg)
+}
+
+// GetBlockByNumCtx is the same as GetBlockByNum but bound to ctx, the call is canceled with ctx.
+// The sdk timeout applies if ctx has no deadline.
+func (action *QueryAction) GetBlockByNumCtx(ctx context.Context, rawMsg *common.RawMessage) (*common.RawMessage, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
+	client, err := action.getChainServiceClient(ctx)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get chain service client error: %v")
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), utils.GetTimeout()*time.Second)
-	defer cancel()
 	return client.GetBlockByNum(ctx, rawMsg)
 }
 
 // GetBlockAndResultByNum is used to send get block and block result by number request raw message by grpc.
 func (action *QueryAction) GetBlockAndResultByNum(rawMsg *common.RawMessage) (*common.RawMessage, error) {
-	client, err := action.getChainServiceClient()
+	return action.GetBlockAndResultByNumCtx(context.Background(), rawMsg)
+}
+
+// GetBlockAndResultByNumCtx is the same as GetBlockAndResultByNum but bound to ctx, the call is canceled with ctx.
+// The sdk timeout applies if ctx has no deadline.
+func (action *QueryAction) GetBlockAndResultByNumCtx(ctx context.Context, rawMsg *common.RawMessage) (*common.RawMessage, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
+	client, err := action.getChainServiceClient(ctx)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get chain service client error: %v")
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), utils.GetTimeout()*time.Second)
-	defer cancel()
 	return client.GetBlockAndResultByNum(ctx, rawMsg)
 }
 
 // GetBlockByTxHash is used to send get block by transaction id request raw message by grpc.
 func (action *QueryAction) GetBlockByTxHash(rawMsg *common.RawMessage) (*common.RawMessage, error) {
-	client, err := action.getChainServiceClient()
+	return action.GetBlockByTxHashCtx(context.Background(), rawMsg)
+}
+
+// GetBlockByTxHashCtx is the same as GetBlockByTxHash but bound to ctx, the call is canceled with ctx.
+// The sdk timeout applies if ctx has no deadline.
+func (action *QueryAction) GetBlockByTxHashCtx(ctx context.Context, rawMsg *common.RawMessage) (*common.RawMessage, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
+	client, err := action.getChainServiceClient(ctx)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get chain service client error: %v")
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), utils.GetTimeout()*time.Second)
-	defer cancel()
 	return client.GetBlockByTxHash(ctx, rawMsg)
 }
 
 // GetTxByHash is used to send get transaction by transaction id request raw message by grpc.
 func (action *QueryAction) GetTxByHash(rawMsg *common.RawMessage) (*common.RawMessage, error) {
+	return action.GetTxByHashCtx(context.Background(), rawMsg)
+}
+
+// GetTxByHashCtx is the same as GetTxByHash but bound to ctx, the call is canceled with ctx.
+// The sdk timeout applies if ctx has no deadline.
+func (action *QueryAction) GetTxByHashCtx(ctx context.Context, rawMsg *common.RawMessage) (*common.RawMessage, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
 	if rawMsg == nil {
 		return nil, errors.New("raw message is nil")
 	}
-	client, err := action.getChainServiceClient()
+	client, err := action.getChainServiceClient(ctx)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get chain service client error: %v")
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), utils.GetTimeout()*time.Second)
-	defer cancel()
 	return client.GetTxByHash(ctx, rawMsg)
 }
 
 // GetTxResultByTxHash is used to send get tx result by transaction id request raw message by grpc.
 func (action *QueryAction) GetTxResultByTxHash(rawMsg *common.RawMessage) (*common.RawMessage, error) {
+	return action.GetTxResultByTxHashCtx(context.Background(), rawMsg)
+}
+
+// GetTxResultByTxHashCtx is the same as GetTxResultByTxHash but bound to ctx, the call is canceled with ctx.
+// The sdk timeout applies if ctx has no deadline.
+func (action *QueryAction) GetTxResultByTxHashCtx(ctx context.Context, rawMsg *common.RawMessage) (*common.RawMessage, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
 	if rawMsg == nil {
 		return nil, errors.New("raw message is nil")
 	}
-	client, err := action.getChainServiceClient()
+	client, err := action.getChainServiceClient(ctx)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get chain service client error: %v")
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), utils.GetTimeout()*time.Second)
-	defer cancel()
 	return client.GetTxResultByTxHash(ctx, rawMsg)
 }
 
 // GetContractInfo is used to send get contract info request raw message by grpc.
 func (action *QueryAction) GetContractInfo(rawMsg *common.RawMessage) (*common.RawMessage, error) {
-	client, err := action.getChainServiceClient()
+	return action.GetContractInfoCtx(context.Background(), rawMsg)
+}
+
+// GetContractInfoCtx is the same as GetContractInfo but bound to ctx, the call is canceled with ctx.
+// The sdk timeout applies if ctx has no deadline.
+func (action *QueryAction) GetContractInfoCtx(ctx context.Context, rawMsg *common.RawMessage) (*common.RawMessage, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
+	client, err := action.getChainServiceClient(ctx)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get chain service client error: %v")
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), utils.GetTimeout()*time.Second)
-	defer cancel()
 	return client.GetContractInfo(ctx, rawMsg)
 }
 
 // GetVote is used to send get vote request raw message by grpc.
 func (action *QueryAction) GetVote(rawMsg *common.RawMessage) (*common.RawMessage, error) {
-	client, err := action.getVoteManagerClient()
+	return action.GetVoteCtx(context.Background(), rawMsg)
+}
+
+// GetVoteCtx is the same as GetVote but bound to ctx, the call is canceled with ctx.
+// The sdk timeout applies if ctx has no deadline.
+func (action *QueryAction) GetVoteCtx(ctx context.Context, rawMsg *common.RawMessage) (*common.RawMessage, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
+	client, err := action.getVoteManagerClient(ctx)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get vote manager client error: %v")
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), utils.GetTimeout()*time.Second)
-	defer cancel()
 	return client.Query(ctx, rawMsg)
 }
 
-func (action *QueryAction) getChainServiceClient() (nodeservice.ChainServiceClient, error) {
-	if err := action.resetClients(); err != nil {
+func (action *QueryAction) getChainServiceClient(ctx context.Context) (nodeservice.ChainServiceClient, error) {
+	if err := action.resetClients(ctx); err != nil {
 		return nil, errors.WithMessage(err, "reset clients error: %v")
 	}
 	return action.chainServiceClient, nil
 }
 
-func (action *QueryAction) getVoteManagerClient() (nodeservice.VoteManagerClient, error) {
-	if err := action.resetClients(); err != nil {
+func (action *QueryAction) getVoteManagerClient(ctx context.Context) (nodeservice.VoteManagerClient, error) {
+	if err := action.resetClients(ctx); err != nil {
 		return nil, errors.WithMessage(err, "reset clients error: %v")
 	}
 	return action.voteManagerClient, nil
 }
 
-func (action *QueryAction) resetClients() error {
+func (action *QueryAction) resetClients(ctx context.Context) error {
+	action.mu.Lock()
+	defer action.mu.Unlock()
 	if action.conn == nil || action.conn.GetState() == connectivity.Shutdown {
-		if err := action.newClients(); err != nil {
+		if err := action.newClients(ctx); err != nil {
 			return errors.WithMessage(err, "new client error: %v")
 		}
 	}
 	return nil
 }
 
-func (action *QueryAction) newClients() error {
-	cc, err := action.newClientConn()
+func (action *QueryAction) newClients(ctx context.Context) error {
+	cc, err := action.newClientConn(ctx)
 	if err != nil {
 		return errors.WithMessage(err, "get client connection error: %v")
 	}
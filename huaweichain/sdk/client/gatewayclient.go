@@ -37,6 +37,30 @@ func NewEmptyGatewayClient() *GatewayClient {
 	return &GatewayClient{Nodes: make(map[string]*node.WNode)}
 }
 
+// Option is the definition of the option to create the gateway client.
+type Option func(*options)
+
+type options struct {
+	decrypt config.DecryptFunc
+	signer  crypto.Signer
+}
+
+// WithDecrypt is used to specify the function to decrypt the pem files in config, such as the key and certificates.
+func WithDecrypt(decrypt func(bytes []byte) ([]byte, error)) Option {
+	return func(o *options) {
+		o.decrypt = decrypt
+	}
+}
+
+// WithSigner is used to specify the signer of the identity, such as the encrypted key or the remote signer. The key
+// path in config is not used when the signer is specified. The remote signer ignores the algorithm in config, the
+// signing service chooses the algorithm of its key, which should match the certificate.
+func WithSigner(signer crypto.Signer) Option {
+	return func(o *options) {
+		o.signer = signer
+	}
+}
+
 // NewGatewayClient is used to create an instance of gateway client by specifying the config file path.
 func NewGatewayClient(configPath string, decrypts ...func(bytes []byte) ([]byte, error)) (*GatewayClient, error) {
 	cfg, err := config.NewClientConfig(configPath)
@@ -50,15 +74,25 @@ func NewGatewayClient(configPath string, decrypts ...func(bytes []byte) ([]byte,
 // NewGatewayClientWithCfg is used to create an instance of gateway client by specifying the config data.
 func NewGatewayClientWithCfg(cfg *config.ClientConfig, decrypts ...func(bytes []byte) ([]byte,
 	error)) (*GatewayClient, error) {
+	var opts []Option
+	if len(decrypts) > 0 {
+		opts = append(opts, WithDecrypt(decrypts[0]))
+	}
+	return NewGatewayClientWithOptions(cfg, opts...)
+}
+
+// NewGatewayClientWithOptions is used to create an instance of gateway client by specifying the config data and
+// the options, such as the decrypt function and the signer.
+func NewGatewayClientWithOptions(cfg *config.ClientConfig, opts ...Option) (*GatewayClient, error) {
 	client := NewEmptyGatewayClient()
-	decrypt := func(bytes []byte) ([]byte, error) {
+	o := &options{decrypt: func(bytes []byte) ([]byte, error) {
 		return bytes, nil
-	}
-	if len(decrypts) > 0 {
-		decrypt = decrypts[0]
+	}}
+	for _, opt := range opts {
+		opt(o)
 	}
 
-	c, err := getCrypto(cfg.Client, decrypt)
+	c, err := getCrypto(cfg.Client, o)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get crypto error")
 	}
@@ -68,7 +102,7 @@ func NewGatewayClientWithCfg(cfg *config.ClientConfig, decrypts ...func(bytes []
 	}
 	client.setCrypto(c)
 
-	client.Nodes, err = getNodes(cfg, decrypt)
+	client.Nodes, err = getNodes(cfg, o.decrypt)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get nodes error")
 	}
@@ -115,6 +149,17 @@ func (client *GatewayClient) SetTimeout(seconds int64) {
 	utils.SetTimeout(time.Duration(seconds))
 }
 
+// Close is used to close the grpc connections of all nodes of the client.
+func (client *GatewayClient) Close() error {
+	var firstErr error
+	for name, n := range client.Nodes {
+		if err := n.Close(); err != nil && firstErr == nil {
+			firstErr = errors.WithMessagef(err, "close node %s error", name)
+		}
+	}
+	return firstErr
+}
+
 // GetSingleApplier provides func to get latest block number when build txHeader.
 func (client *GatewayClient) GetSingleApplier(addr, chainID string) func() (uint64, error) {
 	return func() (uint64, error) {
@@ -191,9 +236,12 @@ func getNodes(c *config.ClientConfig, decrypt config.DecryptFunc) (map[string]*n
 	return nodes, nil
 }
 
-func getCrypto(c *config.Client, decrypt config.DecryptFunc) (crypto.Crypto, error) {
+func getCrypto(c *config.Client, o *options) (crypto.Crypto, error) {
 	if c == nil {
 		return nil, errors.New("the client config is nil")
 	}
-	return crypto.NewCrypto(c.Type, c.Identity.CertPath, c.Identity.KeyPath, decrypt)
+	if o.signer != nil {
+		return crypto.NewCryptoWithCertPath(c.Type, c.Identity.CertPath, o.signer, o.decrypt)
+	}
+	return crypto.NewCrypto(c.Type, c.Identity.CertPath, c.Identity.KeyPath, o.decrypt)
 }
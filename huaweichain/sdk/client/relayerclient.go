@@ -35,7 +35,7 @@ func NewRelayerClient(configPath string, decrypts ...func(bytes []byte) ([]byte,
 		decrypt = decrypts[0]
 	}
 
-	crypto, err := getCrypto(config.Client, decrypt)
+	crypto, err := getCrypto(config.Client, &options{decrypt: decrypt})
 	if err != nil {
 		return nil, errors.WithMessage(err, "get crypto error")
 	}
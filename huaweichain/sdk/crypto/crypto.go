@@ -21,13 +21,13 @@ import (
 type Info struct {
 	alg    string
 	cert   cryptomgr.Cert
-	key    cryptomgr.Key
+	signer Signer
 	hashFn func([]byte) []byte
 }
 
-// Sign is used to sign for a message.
+// Sign is used to sign for a message by the signer of the identity.
 func (c *Info) Sign(message []byte) ([]byte, error) {
-	return c.key.Sign(message, c.alg)
+	return c.signer.Sign(message)
 }
 
 // GetCertificate is used to get certificate.
@@ -77,40 +77,106 @@ func NewCrypto(alg string, certPath string, keyPath string,
 	return NewCryptoWithIdentity(alg, certPem, keyPem)
 }
 
+// NewCryptoWithCertPath is used to new an instance of crypto by specifying the certificate file and the signer.
+func NewCryptoWithCertPath(alg string, certPath string, signer Signer,
+	decrypt func(bytes []byte) ([]byte, error)) (Crypto, error) {
+	certPem, err := getPemInfoFromPath(certPath, decrypt)
+	if err != nil {
+		return nil, errors.WithMessage(err, "getPemInfoFromPath error")
+	}
+	return NewCryptoWithSigner(alg, certPem, signer)
+}
+
 // NewCryptoWithIdentity is used to create an instance by specify certificate and key byte array.
 func NewCryptoWithIdentity(alg string, certPem []byte, keyPem []byte) (Crypto, error) {
-	var certFactory cryptoimpl.CertFactory
-	var keyFactory cryptoimpl.KeyFactory
-	var hashFn func([]byte) []byte
+	signer, err := NewKeySigner(alg, keyPem)
+	if err != nil {
+		return nil, err
+	}
+	return NewCryptoWithSigner(alg, certPem, signer)
+}
 
-	switch alg {
-	case cryptomgr.EcdsaWithSha256:
-		certFactory = &cryptoimpl.EcdsaCertFactory{}
-		keyFactory = &cryptoimpl.EcdsaP256KeyFactory{}
-		hashFn = utils.HashSha256
-	case cryptomgr.Sm2WithSm3:
-		certFactory = &cryptoimpl.GmCertFactory{}
-		keyFactory = &cryptoimpl.GmKeyFactory{}
-		hashFn = utils.HashSM3
-	default:
-		return nil, errors.New("not support crypto algorithm")
+// NewCryptoWithSigner is used to create an instance by specifying certificate and the signer holding the key
+// of the certificate, the key may live outside the process.
+func NewCryptoWithSigner(alg string, certPem []byte, signer Signer) (Crypto, error) {
+	if signer == nil {
+		return nil, errors.New("the signer should not be nil")
 	}
-	cert, err := certFactory.GetCertFromPem(certPem)
+	certFactory, _, hashFn, err := getFactories(alg)
 	if err != nil {
-		return nil, errors.WithMessage(err, "GetCertFromPem error")
+		return nil, err
 	}
-	key, err := keyFactory.GetKeyFromPem(keyPem)
+	cert, err := certFactory.GetCertFromPem(certPem)
 	if err != nil {
-		return nil, errors.WithMessage(err, "GetKeyFromPem error")
+		return nil, errors.WithMessage(err, "GetCertFromPem error")
 	}
 	return &Info{
 		alg:    alg,
 		cert:   cert,
-		key:    key,
+		signer: signer,
 		hashFn: hashFn,
 	}, nil
 }
 
+// Identity is a certificate without the private key. It is used to build messages which are signed
+// on another machine and to verify the signatures of them.
+type Identity struct {
+	alg    string
+	cert   cryptomgr.Cert
+	hashFn func([]byte) []byte
+}
+
+// NewIdentity is used to create an identity by specifying the algorithm and certificate.
+func NewIdentity(alg string, certPem []byte) (*Identity, error) {
+	certFactory, _, hashFn, err := getFactories(alg)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := certFactory.GetCertFromPem(certPem)
+	if err != nil {
+		return nil, errors.WithMessage(err, "GetCertFromPem error")
+	}
+	return &Identity{alg: alg, cert: cert, hashFn: hashFn}, nil
+}
+
+// GetCertificate is used to get certificate.
+func (i *Identity) GetCertificate() ([]byte, error) {
+	return i.cert.GetPemCertBytes(), nil
+}
+
+// GetCommonName is used to get common name from certificate.
+func (i *Identity) GetCommonName() string {
+	return i.cert.GetCommonName()
+}
+
+// GetOrg is used to get organization from certificate.
+func (i *Identity) GetOrg() string {
+	if len(i.cert.GetOrganization()) == 0 {
+		return ""
+	}
+	return i.cert.GetOrganization()[0]
+}
+
+// Hash is the function to compute hash with the algorithm of the identity.
+func (i *Identity) Hash(data []byte) []byte {
+	return i.hashFn(data)
+}
+
+// Verify is used to verify that the signature of the message is signed by the key of the certificate.
+func (i *Identity) Verify(message []byte, sign []byte) error {
+	return i.cert.Verify(message, sign, i.alg)
+}
+
+func getFactories(alg string) (cryptoimpl.CertFactory, cryptoimpl.KeyFactory, func([]byte) []byte, error) {
+	switch alg {
+	case cryptomgr.EcdsaWithSha256:
+		return &cryptoimpl.EcdsaCertFactory{}, &cryptoimpl.EcdsaP256KeyFactory{}, utils.HashSha256, nil
+	case cryptomgr.Sm2WithSm3:
+		return &cryptoimpl.GmCertFactory{}, &cryptoimpl.GmKeyFactory{}, utils.HashSM3, nil
+	}
+	return nil, nil, nil, errors.New("not support crypto algorithm")
+}
+
 func getPemInfoFromPath(path string, decrypt func(bytes []byte) ([]byte, error)) ([]byte, error) {
 	info, err := ioutil.ReadFile(filepath.Clean(path))
 	if err != nil {
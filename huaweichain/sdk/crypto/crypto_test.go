@@ -5,7 +5,28 @@
 package crypto
 
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"hash"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/pkg/errors"
 
 	"git.huawei.com/huaweichain/common/cryptomgr"
 )
@@ -37,3 +58,180 @@ func Test_Crypto_GM(t *testing.T) {
 	t.Logf("gm common name: %v\n", crypto.GetCommonName())
 	t.Logf("gm organization: %v\n", crypto.GetOrg())
 }
+
+// newTestIdentity generates a self-signed ecdsa certificate and the PKCS#8 private key.
+func newTestIdentity(t *testing.T) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key error: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{Organization: []string{"org1"}, CommonName: "user1"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate error: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key error: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}),
+		pem.EncodeToMemory(&pem.Block{Type: privateKeyType, Bytes: der})
+}
+
+func readTestdata(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("read testdata error: %v", err)
+	}
+	return data
+}
+
+func Test_PBKDF2(t *testing.T) {
+	// RFC 6070 for hmacWithSHA1 and RFC 7914 section 11 for hmacWithSHA256
+	cases := []struct {
+		password   string
+		salt       string
+		iterations int
+		keyLen     int
+		h          func() hash.Hash
+		want       string
+	}{
+		{"password", "salt", 1, 20, sha1.New, "0c60c80f961f0e71f3a9b524af6012062fe037a6"},
+		{"password", "salt", 2, 20, sha1.New, "ea6c014dc72d6f8ccd1ed92ace1d41f0d8de8957"},
+		{"password", "salt", 4096, 20, sha1.New, "4b007901b765489abead49d926f721d065a429c1"},
+		{"passwordPASSWORDpassword", "saltSALTsaltSALTsaltSALTsaltSALTsalt", 4096, 25, sha1.New,
+			"3d2eec4fe41c849b80c8d83662c0e44a8b291a964cf2f07038"},
+		{"pass\x00word", "sa\x00lt", 4096, 16, sha1.New, "56fa6aa75548099dcc37d7f03425e0c3"},
+		{"passwd", "salt", 1, 64, sha256.New, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc" +
+			"49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+		{"Password", "NaCl", 80000, 64, sha256.New, "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56" +
+			"a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d"},
+	}
+	for _, c := range cases {
+		got := hex.EncodeToString(pbkdf2([]byte(c.password), []byte(c.salt), c.iterations, c.keyLen, c.h))
+		if got != c.want {
+			t.Errorf("pbkdf2(%q, %q, %d): got %s, want %s", c.password, c.salt, c.iterations, got, c.want)
+		}
+	}
+}
+
+func Test_DecryptPKCS8(t *testing.T) {
+	keyPem := readTestdata(t, "key.pem")
+	// generated by "openssl pkcs8 -topk8 -v2 <cipher> -v2prf <prf>", the sha1 keys omit the default prf
+	for _, name := range []string{"key_aes256_sha256.pem", "key_aes128_sha1.pem", "key_aes192_sha1.pem"} {
+		decrypted, err := DecryptPKCS8(readTestdata(t, name), []byte("passphrase"))
+		if err != nil || !bytes.Equal(decrypted, keyPem) {
+			t.Errorf("decrypt %s: %v", name, err)
+		}
+	}
+
+	encrypted := readTestdata(t, "key_aes256_sha256.pem")
+	if _, err := DecryptPKCS8(encrypted, []byte("wrong")); err == nil {
+		t.Error("expected error with wrong passphrase")
+	}
+	if _, err := DecryptPKCS8(keyPem, []byte("passphrase")); err == nil {
+		t.Error("expected error with unencrypted key")
+	}
+
+	block, _ := pem.Decode(encrypted)
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(block.Bytes, &info); err != nil {
+		t.Fatalf("unmarshal encrypted key error: %v", err)
+	}
+	info.EncryptedData = info.EncryptedData[:len(info.EncryptedData)-1]
+	truncated, err := asn1.Marshal(info)
+	if err != nil {
+		t.Fatalf("marshal truncated key error: %v", err)
+	}
+	if _, err := DecryptPKCS8(pem.EncodeToMemory(&pem.Block{Type: encryptedKeyType, Bytes: truncated}),
+		[]byte("passphrase")); err == nil {
+		t.Error("expected error with truncated ciphertext")
+	}
+	if _, err := DecryptPKCS8(pem.EncodeToMemory(&pem.Block{Type: encryptedKeyType, Bytes: block.Bytes[:len(block.Bytes)/2]}),
+		[]byte("passphrase")); err == nil {
+		t.Error("expected error with truncated key")
+	}
+}
+
+func Test_EncryptedKeySigner(t *testing.T) {
+	certPem, keyPem := newTestIdentity(t)
+	encrypted, err := EncryptPKCS8(keyPem, []byte("passphrase"))
+	if err != nil {
+		t.Fatalf("encrypt key error: %v", err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "key.pem")
+	if err := ioutil.WriteFile(keyPath, encrypted, 0600); err != nil {
+		t.Fatalf("write key error: %v", err)
+	}
+	if _, err := NewEncryptedKeySigner(cryptomgr.EcdsaWithSha256, keyPath, []byte("wrong")); err == nil {
+		t.Error("expected error with wrong passphrase")
+	}
+	signer, err := NewEncryptedKeySigner(cryptomgr.EcdsaWithSha256, keyPath, []byte("passphrase"))
+	if err != nil {
+		t.Fatalf("new encrypted key signer error: %v", err)
+	}
+	identity, err := NewIdentity(cryptomgr.EcdsaWithSha256, certPem)
+	if err != nil {
+		t.Fatalf("new identity error: %v", err)
+	}
+	sign, err := signer.Sign([]byte("message"))
+	if err != nil || identity.Verify([]byte("message"), sign) != nil {
+		t.Errorf("sign with encrypted key: %v", err)
+	}
+}
+
+type failingSigner struct{}
+
+func (failingSigner) Sign(message []byte) ([]byte, error) {
+	return nil, errors.New("hsm unavailable")
+}
+
+func Test_RemoteSigner(t *testing.T) {
+	certPem, keyPem := newTestIdentity(t)
+	keySigner, err := NewKeySigner(cryptomgr.EcdsaWithSha256, keyPem)
+	if err != nil {
+		t.Fatalf("new key signer error: %v", err)
+	}
+	server := httptest.NewServer(NewSignerHandler(map[string]Signer{"user1": keySigner, "broken": failingSigner{}}))
+	defer server.Close()
+
+	c, err := NewCryptoWithSigner(cryptomgr.EcdsaWithSha256, certPem, NewRemoteSigner(server.URL, "user1", nil))
+	if err != nil {
+		t.Fatalf("new crypto with remote signer error: %v", err)
+	}
+	identity, err := NewIdentity(cryptomgr.EcdsaWithSha256, certPem)
+	if err != nil {
+		t.Fatalf("new identity error: %v", err)
+	}
+	sign, err := c.Sign([]byte("message"))
+	if err != nil || identity.Verify([]byte("message"), sign) != nil {
+		t.Errorf("remote sign: %v", err)
+	}
+
+	for keyID, want := range map[string]string{"user2": "unknown key id", "broken": "hsm unavailable"} {
+		if _, err := NewRemoteSigner(server.URL, keyID, nil).Sign([]byte("message")); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("sign with key %s: %v", keyID, err)
+		}
+	}
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("get signer error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("get signer: status %d", resp.StatusCode)
+	}
+	resp, err = http.Post(server.URL, "application/json", strings.NewReader("{"))
+	if err != nil {
+		t.Fatalf("post invalid request error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("post invalid request: status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright (c) Huawei Technologies Co., Ltd. 2021-2021. All rights reserved.
+ */
+
+package crypto
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	remoteSignTimeout = 10 * time.Second
+	maxSignRequestLen = 1 << 20
+)
+
+// SignRequest is the request of the remote signing protocol, posted as json to the signing service.
+type SignRequest struct {
+	KeyID   string `json:"keyId"`
+	Message []byte `json:"message"`
+}
+
+// SignResponse is the response of the remote signing protocol. Error is set when the status code is not 200.
+type SignResponse struct {
+	Signature []byte `json:"signature,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// remoteSigner signs by the remote signing service, such as a signing proxy in front of the HSM or KMS.
+type remoteSigner struct {
+	url    string
+	keyID  string
+	client *http.Client
+}
+
+// NewRemoteSigner is used to create a signer which posts the message to the signing service of url and signs with
+// the key of keyID. The default http client with a timeout of 10 seconds is used when client is nil.
+func NewRemoteSigner(url string, keyID string, client *http.Client) Signer {
+	if client == nil {
+		client = &http.Client{Timeout: remoteSignTimeout}
+	}
+	return &remoteSigner{url: url, keyID: keyID, client: client}
+}
+
+// Sign is used to sign for a message.
+func (s *remoteSigner) Sign(message []byte) ([]byte, error) {
+	body, err := json.Marshal(&SignRequest{KeyID: s.keyID, Message: message})
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal sign request error")
+	}
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.WithMessage(err, "remote sign error")
+	}
+	defer resp.Body.Close()
+	var signResp SignResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxSignRequestLen)).Decode(&signResp); err != nil {
+		return nil, errors.Errorf("remote sign error: status %s, invalid response: %v", resp.Status, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("remote sign error: status %s, %s", resp.Status, signResp.Error)
+	}
+	if len(signResp.Signature) == 0 {
+		return nil, errors.New("remote sign error: empty signature")
+	}
+	return signResp.Signature, nil
+}
+
+// NewSignerHandler is used to create the http handler of the remote signing protocol, which signs with the signer of
+// the key id. It is a local stand-in of the signing service for development and tests.
+func NewSignerHandler(signers map[string]Signer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeSignResponse(w, http.StatusMethodNotAllowed, &SignResponse{Error: "method not allowed"})
+			return
+		}
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxSignRequestLen))
+		var req SignRequest
+		if err == nil {
+			err = json.Unmarshal(body, &req)
+		}
+		if err != nil {
+			writeSignResponse(w, http.StatusBadRequest, &SignResponse{Error: "invalid sign request: " + err.Error()})
+			return
+		}
+		signer, ok := signers[req.KeyID]
+		if !ok {
+			writeSignResponse(w, http.StatusNotFound, &SignResponse{Error: "unknown key id " + req.KeyID})
+			return
+		}
+		sign, err := signer.Sign(req.Message)
+		if err != nil {
+			writeSignResponse(w, http.StatusInternalServerError, &SignResponse{Error: err.Error()})
+			return
+		}
+		writeSignResponse(w, http.StatusOK, &SignResponse{Signature: sign})
+	})
+}
+
+func writeSignResponse(w http.ResponseWriter, status int, resp *SignResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
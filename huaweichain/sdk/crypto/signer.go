@@ -0,0 +1,272 @@
+/*
+ * Copyright (c) Huawei Technologies Co., Ltd. 2021-2021. All rights reserved.
+ */
+
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/pem"
+	"hash"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"git.huawei.com/huaweichain/common/cryptomgr"
+)
+
+const (
+	encryptedKeyType = "ENCRYPTED PRIVATE KEY"
+	privateKeyType   = "PRIVATE KEY"
+	pbkdf2Iterations = 10000
+	pbkdf2SaltLen    = 16
+)
+
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidAES128CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+// Signer is the definition of signer interface, which signs messages with the private key of the identity.
+type Signer interface {
+	Sign(message []byte) ([]byte, error)
+}
+
+// keySigner signs with the private key in memory.
+type keySigner struct {
+	alg string
+	key cryptomgr.Key
+}
+
+// NewKeySigner is used to create a signer with the pem private key in memory.
+func NewKeySigner(alg string, keyPem []byte) (Signer, error) {
+	_, keyFactory, _, err := getFactories(alg)
+	if err != nil {
+		return nil, err
+	}
+	key, err := keyFactory.GetKeyFromPem(keyPem)
+	if err != nil {
+		return nil, errors.WithMessage(err, "GetKeyFromPem error")
+	}
+	return &keySigner{alg: alg, key: key}, nil
+}
+
+// Sign is used to sign for a message.
+func (s *keySigner) Sign(message []byte) ([]byte, error) {
+	return s.key.Sign(message, s.alg)
+}
+
+// NewEncryptedKeySigner is used to create a signer with the encrypted PKCS#8 private key file and the passphrase.
+// The key is encrypted by PBES2 with PBKDF2 and AES-CBC, such as the output of "openssl pkcs8 -topk8 -v2 aes256".
+func NewEncryptedKeySigner(alg string, keyPath string, passphrase []byte) (Signer, error) {
+	encrypted, err := ioutil.ReadFile(filepath.Clean(keyPath))
+	if err != nil {
+		return nil, errors.WithMessage(err, "read key file error")
+	}
+	keyPem, err := DecryptPKCS8(encrypted, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeySigner(alg, keyPem)
+}
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// DecryptPKCS8 is used to decrypt the pem encrypted PKCS#8 private key, returns the pem PKCS#8 private key.
+func DecryptPKCS8(encryptedPem []byte, passphrase []byte) ([]byte, error) {
+	block, _ := pem.Decode(encryptedPem)
+	if block == nil || block.Type != encryptedKeyType {
+		return nil, errors.New("the key is not a pem encrypted private key")
+	}
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(block.Bytes, &info); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal encrypted private key info error")
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, errors.Errorf("unsupported key encryption algorithm %v", info.Algorithm.Algorithm)
+	}
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal pbes2 params error")
+	}
+	blockCipher, keyLen, err := aesKeyLen(params.EncryptionScheme.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal iv error")
+	}
+	key, err := deriveKey(params.KeyDerivationFunc, passphrase, keyLen)
+	if err != nil {
+		return nil, err
+	}
+	c, err := blockCipher(key)
+	if err != nil {
+		return nil, errors.WithMessage(err, "new cipher error")
+	}
+	if len(iv) != c.BlockSize() || len(info.EncryptedData) == 0 || len(info.EncryptedData)%c.BlockSize() != 0 {
+		return nil, errors.New("invalid encrypted key data")
+	}
+	der := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(c, iv).CryptBlocks(der, info.EncryptedData)
+	der, err = unpad(der, c.BlockSize())
+	if err != nil {
+		return nil, errors.WithMessage(err, "decrypt key error, the passphrase may be wrong")
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: privateKeyType, Bytes: der}), nil
+}
+
+// EncryptPKCS8 is used to encrypt the pem PKCS#8 private key with the passphrase by PBES2 with PBKDF2-HMAC-SHA256
+// and AES-256-CBC, returns the pem encrypted private key.
+func EncryptPKCS8(keyPem []byte, passphrase []byte) ([]byte, error) {
+	block, _ := pem.Decode(keyPem)
+	if block == nil || block.Type != privateKeyType {
+		return nil, errors.New("the key is not a pem PKCS#8 private key")
+	}
+	salt := make([]byte, pbkdf2SaltLen)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, errors.WithMessage(err, "generate salt error")
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return nil, errors.WithMessage(err, "generate iv error")
+	}
+	c, err := aes.NewCipher(pbkdf2(passphrase, salt, pbkdf2Iterations, 32, sha256.New))
+	if err != nil {
+		return nil, errors.WithMessage(err, "new cipher error")
+	}
+	padLen := aes.BlockSize - len(block.Bytes)%aes.BlockSize
+	data := append(append([]byte{}, block.Bytes...), bytes.Repeat([]byte{byte(padLen)}, padLen)...)
+	cipher.NewCBCEncrypter(c, iv).CryptBlocks(data, data)
+
+	kdf, err := asn1.Marshal(pbkdf2Params{Salt: salt, IterationCount: pbkdf2Iterations,
+		PRF: pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA256, Parameters: asn1.NullRawValue}})
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal pbkdf2 params error")
+	}
+	ivBytes, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal iv error")
+	}
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdf}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivBytes}},
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal pbes2 params error")
+	}
+	der, err := asn1.Marshal(encryptedPrivateKeyInfo{
+		Algorithm:     pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: params}},
+		EncryptedData: data,
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal encrypted private key info error")
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: encryptedKeyType, Bytes: der}), nil
+}
+
+func aesKeyLen(oid asn1.ObjectIdentifier) (func([]byte) (cipher.Block, error), int, error) {
+	switch {
+	case oid.Equal(oidAES128CBC):
+		return aes.NewCipher, 16, nil
+	case oid.Equal(oidAES192CBC):
+		return aes.NewCipher, 24, nil
+	case oid.Equal(oidAES256CBC):
+		return aes.NewCipher, 32, nil
+	}
+	return nil, 0, errors.Errorf("unsupported key encryption scheme %v", oid)
+}
+
+func deriveKey(kdf pkix.AlgorithmIdentifier, passphrase []byte, keyLen int) ([]byte, error) {
+	if !kdf.Algorithm.Equal(oidPBKDF2) {
+		return nil, errors.Errorf("unsupported key derivation function %v", kdf.Algorithm)
+	}
+	var params pbkdf2Params
+	if _, err := asn1.Unmarshal(kdf.Parameters.FullBytes, &params); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal pbkdf2 params error")
+	}
+	if params.IterationCount <= 0 {
+		return nil, errors.New("invalid pbkdf2 iteration count")
+	}
+	h := sha1.New
+	switch {
+	case params.PRF.Algorithm == nil || params.PRF.Algorithm.Equal(oidHMACWithSHA1):
+	case params.PRF.Algorithm.Equal(oidHMACWithSHA256):
+		h = sha256.New
+	default:
+		return nil, errors.Errorf("unsupported pbkdf2 prf %v", params.PRF.Algorithm)
+	}
+	return pbkdf2(passphrase, params.Salt, params.IterationCount, keyLen, h), nil
+}
+
+// pbkdf2 is the key derivation function defined in RFC 8018.
+func pbkdf2(password []byte, salt []byte, iterations int, keyLen int, h func() hash.Hash) []byte {
+	prf := hmac.New(h, password)
+	hashLen := prf.Size()
+	blocks := (keyLen + hashLen - 1) / hashLen
+	var counter [4]byte
+	derived := make([]byte, 0, blocks*hashLen)
+	u := make([]byte, hashLen)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(counter[:], uint32(block))
+		prf.Write(counter[:])
+		derived = prf.Sum(derived)
+		t := derived[len(derived)-hashLen:]
+		copy(u, t)
+		for n := 2; n <= iterations; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for i := range u {
+				t[i] ^= u[i]
+			}
+		}
+	}
+	return derived[:keyLen]
+}
+
+func unpad(data []byte, blockSize int) ([]byte, error) {
+	padLen := int(data[len(data)-1])
+	if padLen == 0 || padLen > blockSize || padLen > len(data) {
+		return nil, errors.New("invalid padding")
+	}
+	for _, b := range data[len(data)-padLen:] {
+		if int(b) != padLen {
+			return nil, errors.New("invalid padding")
+		}
+	}
+	return data[:len(data)-padLen], nil
+}
@@ -52,6 +52,18 @@ func (n *WNode) GetNodeAddr() string {
 	return fmt.Sprintf("%s:%d", n.Host, n.Port)
 }
 
+// Close is used to close all grpc connections held by the node proxy.
+func (n *WNode) Close() error {
+	var firstErr error
+	for _, closer := range []func() error{n.ChainAction.Close, n.ContractAction.Close, n.EventAction.Close,
+		n.QueryAction.Close} {
+		if err := closer(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
 // TLS is the definition of TLS.
 type TLS struct {
 	certPEMBlock []byte
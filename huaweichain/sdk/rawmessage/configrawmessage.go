@@ -360,6 +360,22 @@ func (u *UpdateConfig) BuildChangeCertStatusRawMessage(chainID string, pemCert [
 	return u.builder.GetTxRawMsg(tx)
 }
 
+// BuildVoteRawMessage build raw message voting for an encoded proposal, so that every org signs the same payload
+func (u *UpdateConfig) BuildVoteRawMessage(chainID string, voteTxData *common.VoteTxData) (*TxRawMsg, error) {
+	err := checkChainID(chainID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "the chainID is not correct")
+	}
+	if voteTxData == nil || voteTxData.Handler == "" || len(voteTxData.Payload) == 0 {
+		return nil, errors.New("the vote handler and payload should not be empty")
+	}
+	transaction, txErr := u.builder.BuildVoteTx(chainID, voteTxData)
+	if txErr != nil {
+		return nil, errors.WithMessage(txErr, "build vote tx error")
+	}
+	return u.builder.GetTxRawMsg(transaction)
+}
+
 func (u *UpdateConfig) buildUpdateChain(chainID string, configSet proto.Marshaler) (*TxRawMsg, error) {
 	bytes, marshalErr := proto.Marshal(configSet)
 	if marshalErr != nil {
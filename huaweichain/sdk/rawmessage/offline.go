@@ -0,0 +1,425 @@
+/*
+ * Copyright (c) Huawei Technologies Co., Ltd. 2021-2021. All rights reserved.
+ */
+
+package rawmessage
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"git.huawei.com/huaweichain/common/version"
+	"git.huawei.com/huaweichain/proto/common"
+	"git.huawei.com/huaweichain/proto/consensus"
+	"git.huawei.com/huaweichain/proto/contract"
+	"git.huawei.com/huaweichain/proto/relayer"
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+
+	"git.huawei.com/huaweichain/sdk/crypto"
+	"git.huawei.com/huaweichain/sdk/utils"
+)
+
+// kinds of offline message
+const (
+	// OfflineKindTx is a transaction, the approvals of the signer and the raw message are signed.
+	OfflineKindTx = "tx"
+	// OfflineKindMessage is a request raw message, only the raw message is signed.
+	OfflineKindMessage = "message"
+)
+
+const offlineFileMode = 0600
+
+// UnsignedBuilder is the message builder which builds messages with the identity of a certificate and leaves
+// the signatures empty. The messages are exported by NewOfflineTx or NewOfflineMessage and signed offline.
+type UnsignedBuilder struct {
+	identity *crypto.Identity
+}
+
+// NewUnsignedBuilder is used to create an instance of UnsignedBuilder by specifying the signer identity.
+func NewUnsignedBuilder(identity *crypto.Identity) *UnsignedBuilder {
+	return &UnsignedBuilder{identity: identity}
+}
+
+// GetRawMessage is used to generate raw message for the payload without signature.
+func (builder *UnsignedBuilder) GetRawMessage(payload []byte) (*common.RawMessage, error) {
+	cert, err := builder.identity.GetCertificate()
+	if err != nil {
+		return nil, errors.WithMessage(err, "get certificate error")
+	}
+	return &common.RawMessage{
+		Signature:       &common.RawMessage_Signature{Cert: cert},
+		Payload:         payload,
+		PlatformVersion: version.PlatformVersion,
+	}, nil
+}
+
+// GetCrossChainRawMessage is not supported by the unsigned builder.
+func (builder *UnsignedBuilder) GetCrossChainRawMessage(payload []byte) (*relayer.RawMessage, error) {
+	return nil, errors.New("cross chain raw message can not be signed offline")
+}
+
+// BuildVoteTx is used to build vote transaction with an empty approval of the signer.
+func (builder *UnsignedBuilder) BuildVoteTx(chainID string, voteTxData *common.VoteTxData) (*common.Transaction,
+	error) {
+	voteTxDataByte, err := proto.Marshal(voteTxData)
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal vote tx data error")
+	}
+	txPayload := &common.TxPayload{
+		Header: &common.TxHeader{
+			ChainId:   chainID,
+			Type:      common.VOTE_TRANSACTION,
+			Timestamp: utils.GenerateTimestamp(),
+			Nonce:     utils.GenerateNonce(),
+			Creator:   builder.BuildIdentity(),
+			Version:   version.PlatformVersion,
+		},
+		Data: voteTxDataByte,
+	}
+	txPayloadBytes, err := proto.Marshal(txPayload)
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal tx payload error")
+	}
+	cert, err := builder.identity.GetCertificate()
+	if err != nil {
+		return nil, errors.WithMessage(err, "get certificate error")
+	}
+	return &common.Transaction{Payload: txPayloadBytes, Approvals: []*common.Approval{{Identity: cert}}}, nil
+}
+
+// GetTxRawMsg is used to get tx raw message without signature.
+func (builder *UnsignedBuilder) GetTxRawMsg(tx *common.Transaction) (*TxRawMsg, error) {
+	return builder.GetTxRawMsgWithTarget(tx, nil)
+}
+
+// GetTxRawMsgWithTarget is used to get tx raw message with propose target without signature.
+func (builder *UnsignedBuilder) GetTxRawMsgWithTarget(tx *common.Transaction,
+	target *common.ProposeTarget) (*TxRawMsg, error) {
+	payload, err := proto.Marshal(tx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal transaction error")
+	}
+	rawMsg, err := builder.GetRawMessage(payload)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get raw message error")
+	}
+	rawMsg.Type = common.DIRECT
+	if target != nil {
+		bytes, err := target.Marshal()
+		if err != nil {
+			return nil, errors.WithMessage(err, "common.ProposeTarget marshal error")
+		}
+		rawMsg.ProxyInfo = bytes
+		rawMsg.Type = common.PROXY
+	}
+	return &TxRawMsg{Hash: builder.identity.Hash(tx.Payload), Msg: rawMsg}, nil
+}
+
+// BuildIdentity is used to build identity.
+func (builder *UnsignedBuilder) BuildIdentity() *common.Identity {
+	return &common.Identity{
+		Org:  builder.identity.GetOrg(),
+		Type: common.COMMON_NAME,
+		Id:   []byte(builder.identity.GetCommonName()),
+	}
+}
+
+// Hash is the function to compute hash with the algorithm of the signer identity.
+func (builder *UnsignedBuilder) Hash(data []byte) []byte {
+	return builder.identity.Hash(data)
+}
+
+// OfflineMessage is a portable message to be signed on another machine. The message is stored as protobuf,
+// the other fields are metadata for the signer to review.
+type OfflineMessage struct {
+	Kind        string `json:"kind"`
+	Description string `json:"description,omitempty"`
+	Signer      string `json:"signer"` // pem certificate of the expected signer
+	Hash        string `json:"hash"`   // hex hash preview, which is the tx hash for transactions
+	Message     []byte `json:"message"`
+	Signed      bool   `json:"signed"`
+}
+
+// NewOfflineTx is used to export a transaction built by UnsignedBuilder.
+func NewOfflineTx(txRawMsg *TxRawMsg, description string) (*OfflineMessage, error) {
+	if txRawMsg == nil || txRawMsg.Msg == nil || txRawMsg.Msg.Signature == nil {
+		return nil, errors.New("the tx raw message should not be empty")
+	}
+	msg, err := proto.Marshal(txRawMsg.Msg)
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal raw message error")
+	}
+	return &OfflineMessage{
+		Kind:        OfflineKindTx,
+		Description: description,
+		Signer:      string(txRawMsg.Msg.Signature.Cert),
+		Hash:        hex.EncodeToString(txRawMsg.Hash),
+		Message:     msg,
+	}, nil
+}
+
+// NewOfflineMessage is used to export a request raw message built by UnsignedBuilder.
+func NewOfflineMessage(rawMsg *common.RawMessage, identity *crypto.Identity,
+	description string) (*OfflineMessage, error) {
+	if rawMsg == nil || rawMsg.Signature == nil {
+		return nil, errors.New("the raw message should not be empty")
+	}
+	msg, err := proto.Marshal(rawMsg)
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal raw message error")
+	}
+	return &OfflineMessage{
+		Kind:        OfflineKindMessage,
+		Description: description,
+		Signer:      string(rawMsg.Signature.Cert),
+		Hash:        hex.EncodeToString(identity.Hash(rawMsg.Payload)),
+		Message:     msg,
+	}, nil
+}
+
+// LoadOfflineMessage is used to load an offline message from file.
+func LoadOfflineMessage(path string) (*OfflineMessage, error) {
+	data, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, errors.WithMessage(err, "read offline message file error")
+	}
+	m := &OfflineMessage{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal offline message error")
+	}
+	if m.Kind != OfflineKindTx && m.Kind != OfflineKindMessage {
+		return nil, errors.Errorf("unknown offline message kind: %s", m.Kind)
+	}
+	return m, nil
+}
+
+// Save is used to save the offline message to file.
+func (m *OfflineMessage) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return errors.WithMessage(err, "marshal offline message error")
+	}
+	return ioutil.WriteFile(filepath.Clean(path), data, offlineFileMode)
+}
+
+// OfflineContent is the content decoded from the message to be signed, the signer reviews it instead of the
+// description, which is written by the exporter and not bound to the message.
+type OfflineContent struct {
+	ChainID string // chain id of the transaction
+	TxType  string // type of the transaction
+	Creator string // creator of the transaction, common name@org
+	Handler string // handler of the vote transaction
+	Subject string // subject of the vote transaction
+	Detail  string // decoded vote payload, such as the contract definition or the config update
+}
+
+// Content is used to decode the content to be signed after checking the hash preview with the hash function of
+// the signer. Only transactions are decoded, the content of a request message is empty.
+func (m *OfflineMessage) Content(hash func([]byte) []byte) (*OfflineContent, error) {
+	_, tx, err := m.decode(hash)
+	if err != nil || tx == nil {
+		return &OfflineContent{}, err
+	}
+	txPayload := &common.TxPayload{}
+	if err := proto.Unmarshal(tx.Payload, txPayload); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal tx payload error")
+	}
+	if txPayload.Header == nil {
+		return nil, errors.New("the tx header is empty")
+	}
+	content := &OfflineContent{ChainID: txPayload.Header.ChainId, TxType: txPayload.Header.Type.String()}
+	if creator := txPayload.Header.Creator; creator != nil {
+		content.Creator = string(creator.Id) + "@" + creator.Org
+	}
+	if txPayload.Header.Type != common.VOTE_TRANSACTION {
+		return content, nil
+	}
+	voteTxData := &common.VoteTxData{}
+	if err := proto.Unmarshal(txPayload.Data, voteTxData); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal vote tx data error")
+	}
+	content.Handler, content.Subject = voteTxData.Handler, voteTxData.Subject
+	if content.Detail, err = voteDetail(voteTxData); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// voteDetail is used to decode the vote payload by the handler.
+func voteDetail(voteTxData *common.VoteTxData) (string, error) {
+	var msg proto.Message
+	switch voteTxData.Handler {
+	case "lifecycle":
+		if voteTxData.Subject != "start" {
+			return "contract " + string(voteTxData.Payload), nil
+		}
+		definition := &contract.ContractDefinition{}
+		if err := proto.Unmarshal(voteTxData.Payload, definition); err != nil {
+			return "", errors.WithMessage(err, "unmarshal contract definition error")
+		}
+		return fmt.Sprintf("contract %s, version %s, policy %s, init required %v", definition.ContractName,
+			definition.SchemaVersion, definition.ValidatorExtensions["policy"], definition.RequireInit), nil
+	case updateHandler:
+		msg = &common.ConfigSet{}
+	case voteHandler, completeHandler:
+		msg = &consensus.ConfigChange{}
+	case common.CERT_STATUS_CHANGE.String():
+		certStatus := &common.CertWithStatus{}
+		if err := proto.Unmarshal(voteTxData.Payload, certStatus); err != nil {
+			return "", errors.WithMessage(err, "unmarshal cert status error")
+		}
+		return fmt.Sprintf("cert %s, status %s", certSubject(certStatus.Cert), certStatus.Status), nil
+	case common.RECORD.String():
+		return "record cert " + certSubject(voteTxData.Payload), nil
+	default:
+		return fmt.Sprintf("payload of %d bytes", len(voteTxData.Payload)), nil
+	}
+	if err := proto.Unmarshal(voteTxData.Payload, msg); err != nil {
+		return "", errors.WithMessagef(err, "unmarshal %s vote payload error", voteTxData.Handler)
+	}
+	return proto.CompactTextString(msg), nil
+}
+
+// certSubject is used to get the subject of the pem certificate for review.
+func certSubject(certPem []byte) string {
+	block, _ := pem.Decode(certPem)
+	if block == nil {
+		return fmt.Sprintf("<%d bytes>", len(certPem))
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Sprintf("<%d bytes>", len(certPem))
+	}
+	return cert.Subject.String()
+}
+
+// Sign is used to sign the offline message with the key of the signer. The certificate of the crypto should be
+// the expected signer and the hash preview should match the message. The signed message can not be signed again.
+func (m *OfflineMessage) Sign(signer crypto.Crypto) error {
+	if m.Signed {
+		return errors.New("the message has already been signed")
+	}
+	cert, err := signer.GetCertificate()
+	if err != nil {
+		return errors.WithMessage(err, "get certificate error")
+	}
+	if !bytes.Equal(bytes.TrimSpace(cert), bytes.TrimSpace([]byte(m.Signer))) {
+		return errors.New("the certificate does not match the expected signer")
+	}
+	rawMsg, tx, err := m.decode(signer.Hash)
+	if err != nil {
+		return err
+	}
+	if tx != nil {
+		signed := false
+		for _, approval := range tx.Approvals {
+			if len(approval.Sign) != 0 || !bytes.Equal(approval.Identity, rawMsg.Signature.Cert) {
+				continue
+			}
+			if approval.Sign, err = signer.Sign(tx.Payload); err != nil {
+				return errors.WithMessage(err, "sign tx payload error")
+			}
+			signed = true
+		}
+		if !signed {
+			return errors.New("the transaction has no approval of the signer to sign")
+		}
+		if rawMsg.Payload, err = proto.Marshal(tx); err != nil {
+			return errors.WithMessage(err, "marshal transaction error")
+		}
+	}
+	if rawMsg.Signature.Sign, err = signer.Sign(rawMsg.Payload); err != nil {
+		return errors.WithMessage(err, "sign payload error")
+	}
+	if m.Message, err = proto.Marshal(rawMsg); err != nil {
+		return errors.WithMessage(err, "marshal raw message error")
+	}
+	m.Signed = true
+	return nil
+}
+
+// TxRawMsg is used to assemble the signed transaction after verifying the signatures with the expected identity.
+func (m *OfflineMessage) TxRawMsg(identity *crypto.Identity) (*TxRawMsg, error) {
+	if m.Kind != OfflineKindTx {
+		return nil, errors.Errorf("offline message of kind %s is not a transaction", m.Kind)
+	}
+	rawMsg, tx, err := m.verify(identity)
+	if err != nil {
+		return nil, err
+	}
+	return &TxRawMsg{Hash: identity.Hash(tx.Payload), Msg: rawMsg}, nil
+}
+
+// RawMessage is used to assemble the signed request raw message after verifying the signature with the expected
+// identity.
+func (m *OfflineMessage) RawMessage(identity *crypto.Identity) (*common.RawMessage, error) {
+	if m.Kind != OfflineKindMessage {
+		return nil, errors.Errorf("offline message of kind %s is not a request message", m.Kind)
+	}
+	rawMsg, _, err := m.verify(identity)
+	return rawMsg, err
+}
+
+func (m *OfflineMessage) verify(identity *crypto.Identity) (*common.RawMessage, *common.Transaction, error) {
+	rawMsg, tx, err := m.decode(identity.Hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := identity.GetCertificate()
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "get certificate error")
+	}
+	if !bytes.Equal(bytes.TrimSpace(cert), bytes.TrimSpace(rawMsg.Signature.Cert)) {
+		return nil, nil, errors.New("the message is not signed by the expected identity")
+	}
+	if err := identity.Verify(rawMsg.Payload, rawMsg.Signature.Sign); err != nil {
+		return nil, nil, errors.WithMessage(err, "verify raw message signature error")
+	}
+	if tx != nil {
+		approved := false
+		for _, approval := range tx.Approvals {
+			if !bytes.Equal(approval.Identity, rawMsg.Signature.Cert) {
+				continue
+			}
+			if err := identity.Verify(tx.Payload, approval.Sign); err != nil {
+				return nil, nil, errors.WithMessage(err, "verify approval signature error")
+			}
+			approved = true
+		}
+		if !approved {
+			return nil, nil, errors.New("the transaction has no approval of the expected identity")
+		}
+	}
+	return rawMsg, tx, nil
+}
+
+// decode is used to decode the raw message and transaction and check the hash preview.
+func (m *OfflineMessage) decode(hash func([]byte) []byte) (*common.RawMessage, *common.Transaction, error) {
+	rawMsg := &common.RawMessage{}
+	if err := proto.Unmarshal(m.Message, rawMsg); err != nil {
+		return nil, nil, errors.WithMessage(err, "unmarshal raw message error")
+	}
+	if rawMsg.Signature == nil {
+		return nil, nil, errors.New("the raw message has no signature")
+	}
+	hashed := rawMsg.Payload
+	var tx *common.Transaction
+	if m.Kind == OfflineKindTx {
+		tx = &common.Transaction{}
+		if err := proto.Unmarshal(rawMsg.Payload, tx); err != nil {
+			return nil, nil, errors.WithMessage(err, "unmarshal transaction error")
+		}
+		hashed = tx.Payload
+	}
+	if hex.EncodeToString(hash(hashed)) != m.Hash {
+		return nil, nil, errors.New("the hash preview does not match the message")
+	}
+	return rawMsg, tx, nil
+}
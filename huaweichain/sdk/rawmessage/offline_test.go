@@ -0,0 +1,223 @@
+/*
+ * Copyright (c) Huawei Technologies Co., Ltd. 2021-2021. All rights reserved.
+ */
+
+package rawmessage
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"git.huawei.com/huaweichain/common/cryptomgr"
+	"git.huawei.com/huaweichain/proto/common"
+	"github.com/gogo/protobuf/proto"
+
+	"git.huawei.com/huaweichain/sdk/crypto"
+)
+
+// newOfflineSigner generates a self-signed ecdsa identity, returns the crypto with the key and the identity
+// with the certificate only.
+func newOfflineSigner(t *testing.T, name string) (crypto.Crypto, *crypto.Identity) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key error: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{Organization: []string{"org1"}, CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate error: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key error: %v", err)
+	}
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	signer, err := crypto.NewCryptoWithIdentity(cryptomgr.EcdsaWithSha256, certPem, keyPem)
+	if err != nil {
+		t.Fatalf("new crypto error: %v", err)
+	}
+	identity, err := crypto.NewIdentity(cryptomgr.EcdsaWithSha256, certPem)
+	if err != nil {
+		t.Fatalf("new identity error: %v", err)
+	}
+	return signer, identity
+}
+
+// newOfflineVote exports an unsigned contract vote of the identity.
+func newOfflineVote(t *testing.T, signer crypto.Crypto, identity *crypto.Identity) (*OfflineMessage, *TxRawMsg) {
+	lifecycle := NewLifecycleRawMessage(NewUnsignedBuilder(identity), signer)
+	txRawMsg, err := lifecycle.BuildVoteRawMessage(NewContract("chain", "finance", "1.0"), "", "org1 & org2", false, true)
+	if err != nil {
+		t.Fatalf("build unsigned vote error: %v", err)
+	}
+	m, err := NewOfflineTx(txRawMsg, "vote finance 1.0")
+	if err != nil {
+		t.Fatalf("export tx error: %v", err)
+	}
+	return m, txRawMsg
+}
+
+func Test_OfflineTx(t *testing.T) {
+	signer, identity := newOfflineSigner(t, "admin")
+	m, txRawMsg := newOfflineVote(t, signer, identity)
+	if _, err := m.TxRawMsg(identity); err == nil {
+		t.Error("expected error assembling unsigned tx")
+	}
+
+	path := filepath.Join(t.TempDir(), "vote.json")
+	if err := m.Save(path); err != nil {
+		t.Fatalf("save offline tx error: %v", err)
+	}
+	loaded, err := LoadOfflineMessage(path)
+	if err != nil {
+		t.Fatalf("load offline tx error: %v", err)
+	}
+	content, err := loaded.Content(signer.Hash)
+	if err != nil {
+		t.Fatalf("decode content error: %v", err)
+	}
+	if content.ChainID != "chain" || content.TxType != common.VOTE_TRANSACTION.String() || content.Creator != "admin@org1" ||
+		content.Handler != "lifecycle" || content.Subject != "start" ||
+		content.Detail != "contract finance, version 1.0, policy org1 & org2, init required true" {
+		t.Errorf("unexpected content: %+v", content)
+	}
+
+	if err := loaded.Sign(signer); err != nil {
+		t.Fatalf("sign offline tx error: %v", err)
+	}
+	if err := loaded.Sign(signer); err == nil || !strings.Contains(err.Error(), "already been signed") {
+		t.Errorf("sign twice: %v", err)
+	}
+	assembled, err := loaded.TxRawMsg(identity)
+	if err != nil {
+		t.Fatalf("assemble signed tx error: %v", err)
+	}
+	if string(assembled.Hash) != string(txRawMsg.Hash) {
+		t.Error("the hash of the assembled tx changed")
+	}
+	if _, err := loaded.RawMessage(identity); err == nil {
+		t.Error("expected error assembling tx as request message")
+	}
+	_, other := newOfflineSigner(t, "other")
+	if _, err := loaded.TxRawMsg(other); err == nil {
+		t.Error("expected error verifying with other identity")
+	}
+}
+
+func Test_OfflineTx_Tampered(t *testing.T) {
+	signer, identity := newOfflineSigner(t, "admin")
+	otherSigner, otherIdentity := newOfflineSigner(t, "other")
+
+	// 签名者证书与导出的预期签名者不一致
+	m, _ := newOfflineVote(t, signer, identity)
+	if err := m.Sign(otherSigner); err == nil || !strings.Contains(err.Error(), "expected signer") {
+		t.Errorf("sign with other cert: %v", err)
+	}
+
+	// 交易内容被修改后哈希预览不再匹配
+	rawMsg, tx, err := m.decode(signer.Hash)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	tx.Payload = append(tx.Payload, 0)
+	if rawMsg.Payload, err = proto.Marshal(tx); err != nil {
+		t.Fatalf("marshal tx error: %v", err)
+	}
+	tampered := *m
+	if tampered.Message, err = proto.Marshal(rawMsg); err != nil {
+		t.Fatalf("marshal raw message error: %v", err)
+	}
+	if err := tampered.Sign(signer); err == nil || !strings.Contains(err.Error(), "hash preview") {
+		t.Errorf("sign tampered tx: %v", err)
+	}
+	if _, err := tampered.Content(signer.Hash); err == nil {
+		t.Error("expected error decoding tampered tx")
+	}
+
+	// 交易签名有效，但审批由其它证书签出
+	if err := m.Sign(signer); err != nil {
+		t.Fatalf("sign offline tx error: %v", err)
+	}
+	rawMsg, tx, err = m.decode(signer.Hash)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	otherCert, err := otherIdentity.GetCertificate()
+	if err != nil {
+		t.Fatalf("get certificate error: %v", err)
+	}
+	tx.Approvals[0].Identity = otherCert
+	if tx.Approvals[0].Sign, err = otherSigner.Sign(tx.Payload); err != nil {
+		t.Fatalf("sign approval error: %v", err)
+	}
+	if rawMsg.Payload, err = proto.Marshal(tx); err != nil {
+		t.Fatalf("marshal tx error: %v", err)
+	}
+	if rawMsg.Signature.Sign, err = signer.Sign(rawMsg.Payload); err != nil {
+		t.Fatalf("sign raw message error: %v", err)
+	}
+	if m.Message, err = proto.Marshal(rawMsg); err != nil {
+		t.Fatalf("marshal raw message error: %v", err)
+	}
+	if _, err := m.TxRawMsg(identity); err == nil || !strings.Contains(err.Error(), "no approval of the expected identity") {
+		t.Errorf("assemble tx approved by other cert: %v", err)
+	}
+}
+
+func Test_OfflineMessage(t *testing.T) {
+	signer, identity := newOfflineSigner(t, "admin")
+	rawMsg, err := NewQueryRawMessage(NewUnsignedBuilder(identity)).BuildQueryLifecycleVoteRawMessage("chain", "finance", "start")
+	if err != nil {
+		t.Fatalf("build unsigned request error: %v", err)
+	}
+	m, err := NewOfflineMessage(rawMsg, identity, "query votes of finance")
+	if err != nil {
+		t.Fatalf("export request error: %v", err)
+	}
+	if m.Kind != OfflineKindMessage {
+		t.Errorf("unexpected kind: %s", m.Kind)
+	}
+	if _, err := m.RawMessage(identity); err == nil {
+		t.Error("expected error assembling unsigned request")
+	}
+	if content, err := m.Content(signer.Hash); err != nil || content.TxType != "" {
+		t.Errorf("unexpected content of request: %+v %v", content, err)
+	}
+
+	if err := m.Sign(signer); err != nil {
+		t.Fatalf("sign request error: %v", err)
+	}
+	signed, err := m.RawMessage(identity)
+	if err != nil {
+		t.Fatalf("assemble signed request error: %v", err)
+	}
+	if string(signed.Payload) != string(rawMsg.Payload) || identity.Verify(signed.Payload, signed.Signature.Sign) != nil {
+		t.Error("the signed request does not match the exported one")
+	}
+	if _, err := m.TxRawMsg(identity); err == nil {
+		t.Error("expected error assembling request as tx")
+	}
+
+	signed.Payload = append(signed.Payload, 0)
+	if m.Message, err = proto.Marshal(signed); err != nil {
+		t.Fatalf("marshal raw message error: %v", err)
+	}
+	if _, err := m.RawMessage(identity); err == nil || !strings.Contains(err.Error(), "hash preview") {
+		t.Errorf("assemble tampered request: %v", err)
+	}
+}
@@ -42,6 +42,22 @@ type Stub interface {
 	// 入参：无
 	//返回值：智能合约名称
 	ContractName() string
+
+	// Creator 功能：获取发起本次交易的身份信息，即交易签名证书对应的身份
+	// 入参：无
+	// 返回值：交易发起者的证书、所属组织和通用名称
+	// error：无法解析交易发起者的证书时返回error信息
+	Creator() (*Identity, error)
+}
+
+// Identity 交易发起者的身份信息
+type Identity struct {
+	// Cert 交易发起者的证书，PEM格式
+	Cert []byte
+	// Org 证书所属组织，即证书Subject中的O
+	Org string
+	// CommonName 证书通用名称，即证书Subject中的CN
+	CommonName string
 }
 
 // ContractStub key-value数据库的stub接口
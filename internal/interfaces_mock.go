@@ -113,6 +113,21 @@ func (mr *MockStubMockRecorder) ContractName() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ContractName", reflect.TypeOf((*MockStub)(nil).ContractName))
 }
 
+// Creator mocks base method.
+func (m *MockStub) Creator() (*Identity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Creator")
+	ret0, _ := ret[0].(*Identity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Creator indicates an expected call of Creator.
+func (mr *MockStubMockRecorder) Creator() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Creator", reflect.TypeOf((*MockStub)(nil).Creator))
+}
+
 // FuncName mocks base method.
 func (m *MockStub) FuncName() string {
 	m.ctrl.T.Helper()
@@ -192,6 +207,21 @@ func (mr *MockContractStubMockRecorder) ContractName() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ContractName", reflect.TypeOf((*MockContractStub)(nil).ContractName))
 }
 
+// Creator mocks base method.
+func (m *MockContractStub) Creator() (*Identity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Creator")
+	ret0, _ := ret[0].(*Identity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Creator indicates an expected call of Creator.
+func (mr *MockContractStubMockRecorder) Creator() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Creator", reflect.TypeOf((*MockContractStub)(nil).Creator))
+}
+
 // DelComIndexOneRow mocks base method.
 func (m *MockContractStub) DelComIndexOneRow(indexName string, attributes []string, objectKey string) error {
 	m.ctrl.T.Helper()
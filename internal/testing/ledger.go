@@ -0,0 +1,236 @@
+/*
+ * Copyright (c) Huawei Technologies Co., Ltd. 2021-2021. All rights reserved.
+ */
+
+// Package testing 提供进程内的合约测试环境：Ledger为内存状态数据库，Stub实现contractapi.ContractStub。
+// 合约执行时的写操作只缓存在Stub的写集中，提交到Ledger后需调用CutBlock模拟出块才会写入状态数据库，
+// 出块时按交易顺序校验读集版本，读取的key在此期间被其它交易修改时交易无效，与链上的MVCC校验一致。
+// 为避免与标准库重名，使用时建议起别名，例如 ctesting
+package testing
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"git.huawei.com/poissonsearch/wienerchain/contract/docker-container/contract-go/contractapi"
+)
+
+// 组合索引在状态数据库中的key前缀，GetIterator不返回此类key
+const indexPrefix = "\x00"
+
+// Version 状态数据写入时的区块号和交易序号
+type Version struct {
+	BlockNum uint64
+	TxNum    int32
+}
+
+// HistoryEntry key的一个历史版本
+type HistoryEntry struct {
+	Value     []byte
+	TxHash    []byte
+	Version   Version
+	Timestamp uint64 // 交易时间戳，Unix纳秒
+	IsDeleted bool
+}
+
+// TxResult 出块时一笔交易的校验结果
+type TxResult struct {
+	TxHash []byte
+	Valid  bool
+}
+
+// Block 模拟出块的结果
+type Block struct {
+	Number uint64
+	Txs    []TxResult
+}
+
+type stateValue struct {
+	value   []byte
+	version Version
+}
+
+// Ledger 内存状态数据库，并发安全
+type Ledger struct {
+	// Now 出块时间，默认为time.Now，测试中可替换以固定时间戳
+	Now func() time.Time
+
+	mu           sync.Mutex
+	chainID      string
+	contractName string
+	state        map[string]stateValue
+	history      map[string][]HistoryEntry
+	pending      []*Stub
+	height       uint64
+	seq          uint64
+}
+
+// NewLedger 创建空的状态数据库，当前区块高度为0
+func NewLedger(chainID string, contractName string) *Ledger {
+	return &Ledger{
+		Now:          time.Now,
+		chainID:      chainID,
+		contractName: contractName,
+		state:        map[string]stateValue{},
+		history:      map[string][]HistoryEntry{},
+	}
+}
+
+// NewStub 创建一笔交易的执行上下文，creator为nil时Creator()返回错误
+func (l *Ledger) NewStub(creator *contractapi.Identity, funcName string, args ...string) *Stub {
+	l.mu.Lock()
+	l.seq++
+	seq := l.seq
+	l.mu.Unlock()
+
+	params := make([][]byte, len(args))
+	h := sha256.New()
+	seqBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(seqBytes, seq)
+	h.Write(seqBytes)
+	h.Write([]byte(funcName))
+	for i, arg := range args {
+		params[i] = []byte(arg)
+		h.Write([]byte(arg))
+	}
+	return &Stub{
+		ledger:   l,
+		funcName: funcName,
+		params:   params,
+		creator:  creator,
+		txHash:   h.Sum(nil),
+		reads:    map[string]Version{},
+		writes:   map[string]*[]byte{},
+	}
+}
+
+// Init 调用合约的Init接口，成功时提交写集，需CutBlock后生效
+func (l *Ledger) Init(contract contractapi.Contract, creator *contractapi.Identity, args ...string) ([]byte, error) {
+	stub := l.NewStub(creator, "init", args...)
+	result, err := contract.Init(stub)
+	if err == nil {
+		l.Submit(stub)
+	}
+	return result, err
+}
+
+// Invoke 调用合约的Invoke接口，成功时提交写集，需CutBlock后生效
+func (l *Ledger) Invoke(contract contractapi.Contract, creator *contractapi.Identity, funcName string, args ...string) ([]byte, error) {
+	stub := l.NewStub(creator, funcName, args...)
+	result, err := contract.Invoke(stub)
+	if err == nil {
+		l.Submit(stub)
+	}
+	return result, err
+}
+
+// Query 调用合约的Invoke接口但不提交，用于只读的查询函数
+func (l *Ledger) Query(contract contractapi.Contract, creator *contractapi.Identity, funcName string, args ...string) ([]byte, error) {
+	return contract.Invoke(l.NewStub(creator, funcName, args...))
+}
+
+// Submit 将交易的写集加入待出块队列
+func (l *Ledger) Submit(stub *Stub) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pending = append(l.pending, stub)
+}
+
+// CutBlock 将待出块的交易打包为新区块，按顺序校验读集并写入状态数据库
+func (l *Ledger) CutBlock() *Block {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.height++
+	block := &Block{Number: l.height}
+	timestamp := uint64(l.Now().UnixNano())
+	for i, stub := range l.pending {
+		version := Version{BlockNum: l.height, TxNum: int32(i)}
+		valid := l.validate(stub)
+		if valid {
+			l.commit(stub, version, timestamp)
+		}
+		block.Txs = append(block.Txs, TxResult{TxHash: stub.txHash, Valid: valid})
+	}
+	l.pending = nil
+	return block
+}
+
+// Height 当前区块高度
+func (l *Ledger) Height() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.height
+}
+
+// Get 读取已提交的状态数据，key不存在时返回nil
+func (l *Ledger) Get(key string) []byte {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.state[key].value
+}
+
+// History 读取key已提交的全部历史版本
+func (l *Ledger) History(key string) []HistoryEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]HistoryEntry(nil), l.history[key]...)
+}
+
+// 读集中每个key的版本需与当前状态一致
+func (l *Ledger) validate(stub *Stub) bool {
+	for key, version := range stub.reads {
+		if l.state[key].version != version {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *Ledger) commit(stub *Stub, version Version, timestamp uint64) {
+	keys := make([]string, 0, len(stub.writes))
+	for key := range stub.writes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := stub.writes[key]
+		entry := HistoryEntry{TxHash: stub.txHash, Version: version, Timestamp: timestamp}
+		if value == nil {
+			delete(l.state, key)
+			entry.IsDeleted = true
+		} else {
+			l.state[key] = stateValue{value: *value, version: version}
+			entry.Value = *value
+		}
+		if !strings.HasPrefix(key, indexPrefix) {
+			l.history[key] = append(l.history[key], entry)
+		}
+	}
+}
+
+// 读取已提交的状态数据
+func (l *Ledger) read(key string) stateValue {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.state[key]
+}
+
+// 按key排序返回match为true的已提交状态数据
+func (l *Ledger) scan(match func(key string) bool) []kv {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var result []kv
+	for key, v := range l.state {
+		if match(key) {
+			result = append(result, kv{key: key, value: v.value})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].key < result[j].key
+	})
+	return result
+}
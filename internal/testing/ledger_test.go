@@ -0,0 +1,91 @@
+package testing_test
+
+import (
+	"testing"
+	"time"
+
+	"git.huawei.com/poissonsearch/wienerchain/contract/docker-container/contract-go/contractapi"
+	ctesting "git.huawei.com/poissonsearch/wienerchain/contract/docker-container/contract-go/contractapi/testing"
+)
+
+var org1 = &contractapi.Identity{Org: "org1", CommonName: "user1"}
+
+func Test_WriteSetCommittedOnCut(t *testing.T) {
+	l := ctesting.NewLedger("chain", "contract")
+	stub := l.NewStub(org1, "put")
+	stub.PutKV("b", []byte("2"))
+	stub.PutKV("a", []byte("1"))
+	stub.SaveComIndex("color", []string{"red"}, "a")
+	if v, _ := stub.GetKV("a"); v != nil {
+		t.Errorf("uncommitted write visible: %s", v)
+	}
+	l.Submit(stub)
+	if l.Get("a") != nil {
+		t.Error("write committed before block cut")
+	}
+	block := l.CutBlock()
+	if block.Number != 1 || len(block.Txs) != 1 || !block.Txs[0].Valid {
+		t.Fatalf("unexpected block: %+v", block)
+	}
+
+	reader := l.NewStub(org1, "get")
+	iter, _ := reader.GetIterator("a", "c")
+	var keys []string
+	for iter.Next() {
+		keys = append(keys, iter.Key())
+	}
+	if len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Errorf("unexpected iterator keys: %v", keys)
+	}
+	iter, _ = reader.GetKVByComIndex("color", []string{"red"})
+	if !iter.Next() || iter.Key() != "a" || string(iter.Value()) != "1" || iter.Next() {
+		t.Error("composite index not committed")
+	}
+}
+
+func Test_MVCCConflict(t *testing.T) {
+	l := ctesting.NewLedger("chain", "contract")
+	seed := l.NewStub(org1, "put")
+	seed.PutKV("k", []byte("0"))
+	l.Submit(seed)
+	l.CutBlock()
+
+	//两笔交易读取同一版本后各自写入，后出块的交易无效
+	first, second := l.NewStub(org1, "inc"), l.NewStub(org1, "inc")
+	for _, stub := range []*ctesting.Stub{first, second} {
+		stub.GetKV("k")
+		stub.PutKV("k", []byte("1"))
+		l.Submit(stub)
+	}
+	block := l.CutBlock()
+	if !block.Txs[0].Valid || block.Txs[1].Valid {
+		t.Errorf("unexpected validation result: %+v", block.Txs)
+	}
+}
+
+func Test_History(t *testing.T) {
+	l := ctesting.NewLedger("chain", "contract")
+	now := time.Unix(100, 0)
+	l.Now = func() time.Time { return now }
+	put := l.NewStub(org1, "put")
+	put.PutKV("k", []byte("v1"))
+	l.Submit(put)
+	l.CutBlock()
+	del := l.NewStub(org1, "del")
+	del.DelKV("k")
+	l.Submit(del)
+	l.CutBlock()
+
+	iter, _ := l.NewStub(org1, "history").GetKeyHistoryIterator("k")
+	var deleted []bool
+	for iter.Next() {
+		blockNum, txNum := iter.Version()
+		if blockNum != uint64(len(deleted)+1) || txNum != 0 || iter.Timestamp() != uint64(now.UnixNano()) {
+			t.Errorf("unexpected version %d/%d at %d", blockNum, txNum, iter.Timestamp())
+		}
+		deleted = append(deleted, iter.IsDeleted())
+	}
+	if len(deleted) != 2 || deleted[0] || !deleted[1] {
+		t.Errorf("unexpected history: %v", deleted)
+	}
+}
@@ -0,0 +1,273 @@
+/*
+ * Copyright (c) Huawei Technologies Co., Ltd. 2021-2021. All rights reserved.
+ */
+
+package testing
+
+import (
+	"errors"
+	"strings"
+
+	"git.huawei.com/poissonsearch/wienerchain/contract/docker-container/contract-go/contractapi"
+)
+
+// Stub 一笔交易的执行上下文，读操作读取已提交的状态，写操作缓存在写集中
+type Stub struct {
+	ledger   *Ledger
+	funcName string
+	params   [][]byte
+	creator  *contractapi.Identity
+	txHash   []byte
+	reads    map[string]Version
+	writes   map[string]*[]byte // nil表示删除
+}
+
+var _ contractapi.ContractStub = (*Stub)(nil)
+
+// FuncName 合约函数名称
+func (s *Stub) FuncName() string {
+	return s.funcName
+}
+
+// Parameters 合约函数参数
+func (s *Stub) Parameters() [][]byte {
+	return s.params
+}
+
+// ChainID 链ID
+func (s *Stub) ChainID() string {
+	return s.ledger.chainID
+}
+
+// ContractName 合约名称
+func (s *Stub) ContractName() string {
+	return s.ledger.contractName
+}
+
+// Creator 交易发起者身份
+func (s *Stub) Creator() (*contractapi.Identity, error) {
+	if s.creator == nil {
+		return nil, errors.New("creator is not set")
+	}
+	return s.creator, nil
+}
+
+// TxHash 交易哈希
+func (s *Stub) TxHash() []byte {
+	return s.txHash
+}
+
+// SetTxHash 替换交易哈希，需在提交到Ledger前调用，用于与链上按交易内容计算的哈希保持一致
+func (s *Stub) SetTxHash(txHash []byte) {
+	s.txHash = append([]byte(nil), txHash...)
+}
+
+// ReadSet 读集中的key及其读取时的版本，key不存在时版本为零值
+func (s *Stub) ReadSet() map[string]Version {
+	result := make(map[string]Version, len(s.reads))
+	for key, version := range s.reads {
+		result[key] = version
+	}
+	return result
+}
+
+// WriteSet 写集中的key及其值，删除的key值为nil
+func (s *Stub) WriteSet() map[string][]byte {
+	result := make(map[string][]byte, len(s.writes))
+	for key, value := range s.writes {
+		if value == nil {
+			result[key] = nil
+		} else {
+			result[key] = *value
+		}
+	}
+	return result
+}
+
+// GetKV 读取已提交的值并记录读集，不读取本交易的写集
+func (s *Stub) GetKV(key string) ([]byte, error) {
+	if key == "" {
+		return nil, errors.New("key is empty")
+	}
+	v := s.ledger.read(key)
+	s.reads[key] = v.version
+	return v.value, nil
+}
+
+// PutKV 写入写集
+func (s *Stub) PutKV(key string, value []byte) error {
+	if key == "" || value == nil {
+		return errors.New("key is empty or value is nil")
+	}
+	v := append([]byte(nil), value...)
+	s.writes[key] = &v
+	return nil
+}
+
+// PutKVCommon value需实现contractapi.ValueSerialization
+func (s *Stub) PutKVCommon(key string, value interface{}) error {
+	serializable, ok := value.(contractapi.ValueSerialization)
+	if !ok {
+		return errors.New("value does not implement ValueSerialization")
+	}
+	raw, err := serializable.Marshal()
+	if err != nil {
+		return err
+	}
+	return s.PutKV(key, raw)
+}
+
+// DelKV 在写集中标记删除
+func (s *Stub) DelKV(key string) error {
+	if key == "" {
+		return errors.New("key is empty")
+	}
+	s.writes[key] = nil
+	return nil
+}
+
+// GetIterator 按字典序查询[startKey, endKey)内已提交的状态数据
+func (s *Stub) GetIterator(startKey, endKey string) (contractapi.Iterator, error) {
+	if startKey == "" || endKey == "" {
+		return nil, errors.New("startKey or endKey is empty")
+	}
+	kvs := s.ledger.scan(func(key string) bool {
+		return key >= startKey && key < endKey && !strings.HasPrefix(key, indexPrefix)
+	})
+	return &iterator{kvs: kvs, pos: -1}, nil
+}
+
+// GetKeyHistoryIterator 按提交顺序查询key的全部历史版本
+func (s *Stub) GetKeyHistoryIterator(key string) (contractapi.HistoryIterator, error) {
+	if key == "" {
+		return nil, errors.New("key is empty")
+	}
+	return &historyIterator{key: key, entries: s.ledger.History(key), pos: -1}, nil
+}
+
+// 组合索引key为 indexName_attributes_objectKey，属性之间以"_"连接，值为objectKey
+func indexKey(indexName string, attributes []string, objectKey string) string {
+	parts := append(append([]string{indexPrefix + indexName}, attributes...), objectKey)
+	return strings.Join(parts, "_")
+}
+
+func checkIndex(indexName string, attributes []string) error {
+	if indexName == "" || len(attributes) == 0 {
+		return errors.New("indexName is empty or attributes is empty")
+	}
+	return nil
+}
+
+// SaveComIndex 在写集中保存组合索引
+func (s *Stub) SaveComIndex(indexName string, attributes []string, objectKey string) error {
+	if err := checkIndex(indexName, attributes); err != nil {
+		return err
+	}
+	if objectKey == "" {
+		return errors.New("objectKey is empty")
+	}
+	return s.PutKV(indexKey(indexName, attributes, objectKey), []byte(objectKey))
+}
+
+// GetKVByComIndex 查询属性前缀匹配的组合索引，返回索引指向的key及其已提交的值
+func (s *Stub) GetKVByComIndex(indexName string, attributes []string) (contractapi.Iterator, error) {
+	if err := checkIndex(indexName, attributes); err != nil {
+		return nil, err
+	}
+	prefix := indexKey(indexName, attributes, "")
+	entries := s.ledger.scan(func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+	var kvs []kv
+	for _, entry := range entries {
+		objectKey := string(entry.value)
+		if v := s.ledger.read(objectKey); v.value != nil {
+			kvs = append(kvs, kv{key: objectKey, value: v.value})
+		}
+	}
+	return &iterator{kvs: kvs, pos: -1}, nil
+}
+
+// DelComIndexOneRow 在写集中删除组合索引
+func (s *Stub) DelComIndexOneRow(indexName string, attributes []string, objectKey string) error {
+	if err := checkIndex(indexName, attributes); err != nil {
+		return err
+	}
+	if objectKey == "" {
+		return errors.New("objectKey is empty")
+	}
+	return s.DelKV(indexKey(indexName, attributes, objectKey))
+}
+
+type kv struct {
+	key   string
+	value []byte
+}
+
+type iterator struct {
+	kvs []kv
+	pos int
+}
+
+func (it *iterator) Next() bool {
+	if it.pos+1 >= len(it.kvs) {
+		return false
+	}
+	it.pos++
+	return true
+}
+
+func (it *iterator) Key() string {
+	return it.kvs[it.pos].key
+}
+
+func (it *iterator) Value() []byte {
+	return it.kvs[it.pos].value
+}
+
+func (it *iterator) Close() {
+	it.kvs = nil
+}
+
+type historyIterator struct {
+	key     string
+	entries []HistoryEntry
+	pos     int
+}
+
+func (it *historyIterator) Next() bool {
+	if it.pos+1 >= len(it.entries) {
+		return false
+	}
+	it.pos++
+	return true
+}
+
+func (it *historyIterator) Key() string {
+	return it.key
+}
+
+func (it *historyIterator) Value() []byte {
+	return it.entries[it.pos].Value
+}
+
+func (it *historyIterator) Close() {
+	it.entries = nil
+}
+
+func (it *historyIterator) Version() (uint64, int32) {
+	v := it.entries[it.pos].Version
+	return v.BlockNum, v.TxNum
+}
+
+func (it *historyIterator) TxHash() []byte {
+	return it.entries[it.pos].TxHash
+}
+
+func (it *historyIterator) IsDeleted() bool {
+	return it.entries[it.pos].IsDeleted
+}
+
+func (it *historyIterator) Timestamp() uint64 {
+	return it.entries[it.pos].Timestamp
+}
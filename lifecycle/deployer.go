@@ -0,0 +1,287 @@
+// Package lifecycle 合约生命周期编排：各组织导入合约并投票，等待投票满足策略，
+// 合约定义生效后按需调用Init，并通过GetContractInfo确认新版本可用
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"git.huawei.com/goclient/utils"
+	"git.huawei.com/huaweichain/proto/contract"
+	"git.huawei.com/huaweichain/proto/nodeservice"
+	"git.huawei.com/huaweichain/sdk/client"
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// 部署进度
+const (
+	StageImported    = "imported"    // 合约包已导入组织的一个节点
+	StageVoted       = "voted"       // 组织的投票交易已落块，或组织此前已投票
+	StageApproval    = "approval"    // 已投票的组织发生变化
+	StageApproved    = "approved"    // 投票满足策略
+	StageActive      = "active"      // GetContractInfo返回新版本
+	StageInitialized = "initialized" // Init交易已落块
+)
+
+// Event 部署进度事件
+type Event struct {
+	Stage     string
+	Org       string   // imported、voted时为对应的组织
+	Node      string   // imported时为导入的节点
+	TxHash    string   // voted、initialized时为交易哈希，组织此前已投票时为空
+	Approvals []string // approval、approved时为已投票的组织
+}
+
+// Progress 进度回调，在Deploy的协程中调用
+type Progress func(event Event)
+
+// Contract 待部署的合约版本
+type Contract struct {
+	ChainID        string
+	Name           string
+	Version        string
+	Path           string // 合约包路径
+	Sandbox        string // docker、native、nativewasm或teewasm，默认docker
+	Language       string // 默认go
+	Description    string
+	Policy         string // 背书策略，如 "org1 & (org2 | org3)"
+	HistorySupport bool
+	RequireInit    bool
+	InitFunc       string   // RequireInit时调用的函数，默认init
+	InitArgs       []string // Init的参数
+}
+
+// Org 参与部署的组织
+type Org struct {
+	Name          string                // 组织名，与投票记录中的投票者一致
+	Client        *client.GatewayClient // 以该组织身份签名的网关客户端
+	Nodes         []string              // 需要导入合约的节点，第一个节点同时用于查询和订阅交易事件
+	ConsensusNode string                // 提交投票交易的节点，为空时使用Nodes[0]
+}
+
+// Options 部署参数，零值字段使用默认值
+type Options struct {
+	ApprovalPolicy string        // 新版本生效所需的投票组织，格式同背书策略，默认需全部组织投票
+	PollInterval   time.Duration // 查询投票和合约信息的间隔，默认2s
+	Progress       Progress
+}
+
+// orgClient 组织在链上的操作，sdkOrg基于网关客户端实现
+type orgClient interface {
+	importContract(ctx context.Context, node string, c Contract) error
+	vote(ctx context.Context, c Contract) (string, error)
+	votes(ctx context.Context, c Contract) ([]*nodeservice.Vote, error)
+	contractInfo(ctx context.Context, c Contract) (*nodeservice.ContractInfoQueryResponse, error)
+	invoke(ctx context.Context, c Contract, funcName string, args []string) (string, error)
+}
+
+type deployOrg struct {
+	name   string
+	nodes  []string
+	client orgClient
+}
+
+// Deployer 在多个组织间编排合约部署，可重复调用Deploy部署不同版本
+type Deployer struct {
+	orgs []deployOrg
+	opts Options
+}
+
+// NewDeployer 创建部署器，每个组织至少需要一个节点
+func NewDeployer(orgs []Org, opts Options) (*Deployer, error) {
+	deployOrgs := make([]deployOrg, 0, len(orgs))
+	for _, org := range orgs {
+		if org.Name == "" || org.Client == nil || len(org.Nodes) == 0 {
+			return nil, errors.Errorf("org %q requires name, client and nodes", org.Name)
+		}
+		for _, name := range append([]string{org.ConsensusNode}, org.Nodes...) {
+			if _, ok := org.Client.Nodes[name]; name != "" && !ok {
+				return nil, errors.Errorf("node not exist： %v", name)
+			}
+		}
+		deployOrgs = append(deployOrgs, deployOrg{name: org.Name, nodes: org.Nodes, client: newSDKOrg(org)})
+	}
+	return newDeployer(deployOrgs, opts)
+}
+
+func newDeployer(orgs []deployOrg, opts Options) (*Deployer, error) {
+	if len(orgs) == 0 {
+		return nil, errors.New("no org to deploy")
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 2 * time.Second
+	}
+	if opts.ApprovalPolicy == "" {
+		names := make([]string, len(orgs))
+		for i, org := range orgs {
+			names[i] = org.name
+		}
+		opts.ApprovalPolicy = strings.Join(names, " & ")
+	}
+	if _, err := utils.ParsePolicy([]byte(opts.ApprovalPolicy)); err != nil {
+		return nil, errors.WithMessage(err, "parse approval policy error")
+	}
+	return &Deployer{orgs: orgs, opts: opts}, nil
+}
+
+// Deploy 部署合约：依次在各组织的节点导入合约、由未投票的组织投票，等待投票满足ApprovalPolicy且
+// GetContractInfo返回新版本，RequireInit时再调用Init。ctx取消或到期时返回，已提交的交易不会撤回，
+// 再次调用会跳过已投票的组织
+func (d *Deployer) Deploy(ctx context.Context, c Contract) error {
+	if c.ChainID == "" || c.Name == "" || c.Version == "" {
+		return errors.New("contract chain id, name and version are required")
+	}
+	approvalPolicy, _ := utils.ParsePolicy([]byte(d.opts.ApprovalPolicy))
+	lead := d.orgs[0].client
+
+	for _, org := range d.orgs {
+		for _, node := range org.nodes {
+			if err := org.client.importContract(ctx, node, c); err != nil {
+				return errors.WithMessagef(err, "import contract on node %s of org %s error", node, org.name)
+			}
+			d.report(Event{Stage: StageImported, Org: org.name, Node: node})
+		}
+	}
+
+	// 首次部署时链上可能还没有投票记录，查询失败时按均未投票处理
+	approvals, _ := d.approvals(ctx, lead, c)
+	for _, org := range d.orgs {
+		if containsString(approvals, org.name) {
+			d.report(Event{Stage: StageVoted, Org: org.name})
+			continue
+		}
+		txHash, err := org.client.vote(ctx, c)
+		if err != nil {
+			return errors.WithMessagef(err, "vote of org %s error", org.name)
+		}
+		d.report(Event{Stage: StageVoted, Org: org.name, TxHash: txHash})
+	}
+
+	// 投票满足策略后投票记录可能被清除，合约信息已是新版本时同样视为满足
+	var reported []string
+	err := d.poll(ctx, func() (bool, error) {
+		if active(ctx, lead, c) {
+			return true, nil
+		}
+		approvals, err := d.approvals(ctx, lead, c)
+		if err != nil {
+			return false, err
+		}
+		if strings.Join(approvals, ",") != strings.Join(reported, ",") {
+			reported = approvals
+			d.report(Event{Stage: StageApproval, Approvals: approvals})
+		}
+		return utils.PolicySatisfied(approvalPolicy, approvals), nil
+	})
+	if err != nil {
+		return errors.WithMessage(err, "wait for approval error")
+	}
+	d.report(Event{Stage: StageApproved, Approvals: reported})
+
+	if err := d.poll(ctx, func() (bool, error) { return active(ctx, lead, c), nil }); err != nil {
+		return errors.WithMessagef(err, "wait for contract %s version %s to be active error", c.Name, c.Version)
+	}
+	d.report(Event{Stage: StageActive})
+
+	if c.RequireInit {
+		initFunc := c.InitFunc
+		if initFunc == "" {
+			initFunc = "init"
+		}
+		txHash, err := lead.invoke(ctx, c, initFunc, c.InitArgs)
+		if err != nil {
+			return errors.WithMessage(err, "init contract error")
+		}
+		d.report(Event{Stage: StageInitialized, TxHash: txHash})
+	}
+	return nil
+}
+
+func (d *Deployer) report(event Event) {
+	if d.opts.Progress != nil {
+		d.opts.Progress(event)
+	}
+}
+
+// poll 按PollInterval调用check直到返回true、出错或ctx结束
+func (d *Deployer) poll(ctx context.Context, check func() (bool, error)) error {
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		done, err := check()
+		if err != nil || done {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// approvals 对合约该版本定义投票的组织，按名称排序
+func (d *Deployer) approvals(ctx context.Context, org orgClient, c Contract) ([]string, error) {
+	votes, err := org.votes(ctx, c)
+	if err != nil {
+		return nil, errors.WithMessage(err, "query votes error")
+	}
+	var approvals []string
+	for _, vote := range votes {
+		definition := &contract.ContractDefinition{}
+		if err := proto.Unmarshal(vote.Payload, definition); err != nil {
+			continue
+		}
+		if definition.ContractName != c.Name || definition.SchemaVersion != c.Version {
+			continue
+		}
+		for _, voter := range vote.Voters {
+			if !containsString(approvals, voter) {
+				approvals = append(approvals, voter)
+			}
+		}
+	}
+	sort.Strings(approvals)
+	return approvals, nil
+}
+
+// active 合约信息是否已是该版本，合约首次部署时定义生效前查询会失败
+func active(ctx context.Context, org orgClient, c Contract) bool {
+	info, err := org.contractInfo(ctx, c)
+	if err != nil {
+		return false
+	}
+	version := info.Version
+	if info.Definition != nil && info.Definition.SchemaVersion != "" {
+		version = info.Definition.SchemaVersion
+	}
+	return version == c.Version && info.Status == nodeservice.OK
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// String 便于日志输出
+func (e Event) String() string {
+	switch e.Stage {
+	case StageImported:
+		return fmt.Sprintf("%s: org %s node %s", e.Stage, e.Org, e.Node)
+	case StageVoted:
+		return fmt.Sprintf("%s: org %s tx %s", e.Stage, e.Org, e.TxHash)
+	case StageApproval, StageApproved:
+		return fmt.Sprintf("%s: %s", e.Stage, strings.Join(e.Approvals, ","))
+	case StageInitialized:
+		return fmt.Sprintf("%s: tx %s", e.Stage, e.TxHash)
+	}
+	return e.Stage
+}
@@ -0,0 +1,172 @@
+package lifecycle
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"git.huawei.com/goclient/utils"
+	"git.huawei.com/huaweichain/proto/common"
+	"git.huawei.com/huaweichain/proto/contract"
+	"git.huawei.com/huaweichain/proto/nodeservice"
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// fakeChain 模拟链上的投票记录：投票组织满足policy时合约定义生效，投票记录清除
+type fakeChain struct {
+	policy  *common.Policy
+	voters  map[string][]string // 版本 -> 投票组织
+	version string              // 已生效的版本，为空时查询合约信息失败
+	imports []string
+	inits   []string
+}
+
+func newFakeChain(t *testing.T, policy string) *fakeChain {
+	p, err := utils.ParsePolicy([]byte(policy))
+	if err != nil {
+		t.Fatalf("parse policy error: %v", err)
+	}
+	return &fakeChain{policy: p, voters: map[string][]string{}}
+}
+
+type fakeOrg struct {
+	name  string
+	chain *fakeChain
+	voted int
+}
+
+func (o *fakeOrg) importContract(ctx context.Context, node string, c Contract) error {
+	o.chain.imports = append(o.chain.imports, node+"@"+c.Version)
+	return nil
+}
+
+func (o *fakeOrg) vote(ctx context.Context, c Contract) (string, error) {
+	o.voted++
+	voters := append(o.chain.voters[c.Version], o.name)
+	o.chain.voters[c.Version] = voters
+	if utils.PolicySatisfied(o.chain.policy, voters) {
+		o.chain.version = c.Version
+		delete(o.chain.voters, c.Version)
+	}
+	return "tx-" + o.name, nil
+}
+
+func (o *fakeOrg) votes(ctx context.Context, c Contract) ([]*nodeservice.Vote, error) {
+	voters, ok := o.chain.voters[c.Version]
+	if !ok {
+		return nil, nil
+	}
+	payload, _ := proto.Marshal(&contract.ContractDefinition{ContractName: c.Name, SchemaVersion: c.Version})
+	// 其它版本的投票不计入
+	other, _ := proto.Marshal(&contract.ContractDefinition{ContractName: c.Name, SchemaVersion: "0.9"})
+	return []*nodeservice.Vote{{Voters: voters, Payload: payload}, {Voters: []string{"org3"}, Payload: other}}, nil
+}
+
+func (o *fakeOrg) contractInfo(ctx context.Context, c Contract) (*nodeservice.ContractInfoQueryResponse, error) {
+	if o.chain.version == "" {
+		return nil, errors.Errorf("contract %s not found", c.Name)
+	}
+	return &nodeservice.ContractInfoQueryResponse{Name: c.Name, Version: o.chain.version, Status: nodeservice.OK}, nil
+}
+
+func (o *fakeOrg) invoke(ctx context.Context, c Contract, funcName string, args []string) (string, error) {
+	if o.chain.version != c.Version {
+		return "", errors.New("contract definition not committed")
+	}
+	o.chain.inits = append(o.chain.inits, o.name+":"+funcName+":"+strings.Join(args, ";"))
+	return "tx-init", nil
+}
+
+func newTestDeployer(t *testing.T, chain *fakeChain, approvalPolicy string, events *[]Event) (*Deployer, []*fakeOrg) {
+	var orgs []deployOrg
+	var fakes []*fakeOrg
+	for _, name := range []string{"org1", "org2", "org3"} {
+		fake := &fakeOrg{name: name, chain: chain}
+		fakes = append(fakes, fake)
+		orgs = append(orgs, deployOrg{name: name, nodes: []string{"node-0." + name}, client: fake})
+	}
+	d, err := newDeployer(orgs, Options{
+		ApprovalPolicy: approvalPolicy,
+		PollInterval:   time.Millisecond,
+		Progress:       func(event Event) { *events = append(*events, event) },
+	})
+	if err != nil {
+		t.Fatalf("new deployer error: %v", err)
+	}
+	return d, fakes
+}
+
+func stages(events []Event) string {
+	var result []string
+	for _, event := range events {
+		result = append(result, event.Stage)
+	}
+	return strings.Join(result, ",")
+}
+
+func Test_Deploy(t *testing.T) {
+	chain := newFakeChain(t, "org1 & org2 & org3")
+	var events []Event
+	d, _ := newTestDeployer(t, chain, "", &events)
+
+	err := d.Deploy(context.Background(), Contract{ChainID: "chain", Name: "finance", Version: "1.0", RequireInit: true, InitArgs: []string{"a", "b"}})
+	if err != nil {
+		t.Fatalf("deploy error: %v", err)
+	}
+	if want := "imported,imported,imported,voted,voted,voted,approved,active,initialized"; stages(events) != want {
+		t.Errorf("stages = %s, want %s", stages(events), want)
+	}
+	if strings.Join(chain.imports, ",") != "node-0.org1@1.0,node-0.org2@1.0,node-0.org3@1.0" {
+		t.Errorf("unexpected imports: %v", chain.imports)
+	}
+	if len(chain.inits) != 1 || chain.inits[0] != "org1:init:a;b" {
+		t.Errorf("unexpected init: %v", chain.inits)
+	}
+}
+
+func Test_Deploy_Resume(t *testing.T) {
+	chain := newFakeChain(t, "org1 & org2 & org3")
+	chain.voters["2.0"] = []string{"org2"}
+	var events []Event
+	d, fakes := newTestDeployer(t, chain, "", &events)
+
+	if err := d.Deploy(context.Background(), Contract{ChainID: "chain", Name: "finance", Version: "2.0"}); err != nil {
+		t.Fatalf("deploy error: %v", err)
+	}
+	// 已投票的组织不再投票，未要求初始化时不调用Init
+	if fakes[1].voted != 0 || fakes[0].voted != 1 || fakes[2].voted != 1 {
+		t.Errorf("unexpected votes: %d %d %d", fakes[0].voted, fakes[1].voted, fakes[2].voted)
+	}
+	for _, event := range events {
+		if event.Stage == StageVoted && event.Org == "org2" && event.TxHash != "" {
+			t.Errorf("org2 voted again: %v", event)
+		}
+	}
+	if len(chain.inits) != 0 {
+		t.Errorf("unexpected init: %v", chain.inits)
+	}
+}
+
+func Test_Deploy_WaitApproval(t *testing.T) {
+	// 链上要求的投票组织多于参与部署的组织，新版本不会生效
+	chain := newFakeChain(t, "org1 & org2 & org3 & org4")
+	var events []Event
+	d, _ := newTestDeployer(t, chain, "org1 & org4", &events)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := d.Deploy(ctx, Contract{ChainID: "chain", Name: "finance", Version: "1.0"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	last := events[len(events)-1]
+	if last.Stage != StageApproval || strings.Join(last.Approvals, ",") != "org1,org2,org3" {
+		t.Errorf("unexpected last event: %v", last)
+	}
+
+	if _, err := newDeployer([]deployOrg{{name: "org1", client: &fakeOrg{}}}, Options{ApprovalPolicy: "org1 &"}); err == nil {
+		t.Error("expected error for invalid approval policy")
+	}
+}
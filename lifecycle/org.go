@@ -0,0 +1,131 @@
+package lifecycle
+
+import (
+	"context"
+	"strings"
+
+	"git.huawei.com/goclient/utils"
+	"git.huawei.com/huaweichain/proto/nodeservice"
+	"git.huawei.com/huaweichain/sdk/client"
+	"git.huawei.com/huaweichain/sdk/rawmessage"
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// sdkOrg 通过组织的网关客户端执行导入、投票和查询
+type sdkOrg struct {
+	client    *client.GatewayClient
+	queryNode string
+	consensus string
+}
+
+func newSDKOrg(org Org) *sdkOrg {
+	consensus := org.ConsensusNode
+	if consensus == "" {
+		consensus = org.Nodes[0]
+	}
+	return &sdkOrg{client: org.Client, queryNode: org.Nodes[0], consensus: consensus}
+}
+
+func (o *sdkOrg) config(c Contract) utils.Config {
+	return utils.Config{
+		ChainID:       c.ChainID,
+		ContractName:  c.Name,
+		QueryNode:     o.queryNode,
+		ConsensusNode: o.consensus,
+	}
+}
+
+func (o *sdkOrg) importContract(ctx context.Context, node string, c Contract) error {
+	sandbox, language := c.Sandbox, c.Language
+	if sandbox == "" {
+		sandbox = "docker"
+	}
+	if language == "" {
+		language = "go"
+	}
+	rawMsg, err := o.client.LifecycleRawMessage.BuildImportRawMessage(
+		rawmessage.NewContract(c.ChainID, c.Name, c.Version), c.Path, sandbox, language)
+	if err != nil {
+		return errors.WithMessage(err, "build import raw message error")
+	}
+	responseMsg, err := o.client.Nodes[node].ContractAction.ContractImportCtx(ctx, rawMsg)
+	if err != nil {
+		return errors.WithMessage(err, "contract action import error")
+	}
+	_, err = utils.GetPayloadWithResp(responseMsg)
+	return err
+}
+
+func (o *sdkOrg) vote(ctx context.Context, c Contract) (string, error) {
+	txRawMsg, err := o.client.LifecycleRawMessage.BuildVoteRawMessage(rawmessage.NewContract(c.ChainID, c.Name, c.Version),
+		c.Description, c.Policy, c.HistorySupport, c.RequireInit)
+	if err != nil {
+		return "", errors.WithMessage(err, "build vote raw message error")
+	}
+	net, err := utils.NewNodes(o.client, []string{o.queryNode}, o.consensus)
+	if err != nil {
+		return "", err
+	}
+	txResult, err := utils.SendTxCtx(ctx, net, o.config(c), txRawMsg)
+	if err != nil {
+		return "", err
+	}
+	return utils.Hash2str(txResult.TxHash), nil
+}
+
+func (o *sdkOrg) votes(ctx context.Context, c Contract) ([]*nodeservice.Vote, error) {
+	rawMsg, err := o.client.QueryRawMessage.BuildQueryLifecycleVoteRawMessage(c.ChainID, c.Name, "start")
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query lifecycle vote raw message error")
+	}
+	responseMsg, err := o.client.Nodes[o.queryNode].QueryAction.GetVoteCtx(ctx, rawMsg)
+	if err != nil {
+		return nil, errors.WithMessage(err, "query action get vote error")
+	}
+	payload, err := utils.GetPayloadWithResp(responseMsg)
+	if err != nil {
+		return nil, err
+	}
+	response := &nodeservice.VoteQueryResponse{}
+	if err := proto.Unmarshal(payload, response); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal vote query response error")
+	}
+	return response.Votes, nil
+}
+
+func (o *sdkOrg) contractInfo(ctx context.Context, c Contract) (*nodeservice.ContractInfoQueryResponse, error) {
+	rawMsg, err := o.client.QueryRawMessage.BuildContractRawMessage(c.ChainID, c.Name)
+	if err != nil {
+		return nil, errors.WithMessage(err, "build contract raw message error")
+	}
+	responseMsg, err := o.client.Nodes[o.queryNode].QueryAction.GetContractInfoCtx(ctx, rawMsg)
+	if err != nil {
+		return nil, errors.WithMessage(err, "query action get contract info error")
+	}
+	payload, err := utils.GetPayloadWithResp(responseMsg)
+	if err != nil {
+		return nil, err
+	}
+	info := &nodeservice.ContractInfoQueryResponse{}
+	if err := proto.Unmarshal(payload, info); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal contract info error")
+	}
+	return info, nil
+}
+
+// invoke 按新版本的背书策略选择背书节点后调用合约并等待落块
+func (o *sdkOrg) invoke(ctx context.Context, c Contract, funcName string, args []string) (string, error) {
+	config := o.config(c)
+	endorsers, err := utils.SelectEndorsers(o.client, config)
+	if err != nil {
+		return "", errors.WithMessage(err, "select endorsers error")
+	}
+	config.EndorserNodes = strings.Join(endorsers, ",")
+	net, err := utils.NewNodes(o.client, endorsers, o.consensus)
+	if err != nil {
+		return "", err
+	}
+	_, txHash, err := utils.SendCtx(ctx, o.client, net, config, funcName, strings.Join(args, ";"))
+	return txHash, err
+}
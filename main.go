@@ -2,15 +2,155 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"git.huawei.com/goclient/api"
 	"git.huawei.com/goclient/controller"
+	"git.huawei.com/goclient/envelope"
 	"git.huawei.com/goclient/routes"
+	"git.huawei.com/goclient/store"
+	"git.huawei.com/goclient/utils"
+	"git.huawei.com/goclient/webhook"
 	"github.com/gin-gonic/gin"
+	"github.com/gomodule/redigo/redis"
 )
 
 func main() {
+	defaultConfig := "configuration/server.yaml"
+	if path := os.Getenv("CD_CONFIG"); path != "" {
+		defaultConfig = path
+	}
+	configPath := flag.String("config", defaultConfig, "server config file path")
+	flag.Parse()
+
+	cfg, err := utils.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("load config error: %v", err)
+	}
+
+	depositStore, err := store.New(cfg.Store.Driver, cfg.Store.DSN)
+	if err != nil {
+		log.Fatalf("init deposit store error: %v", err)
+	}
+	defer depositStore.Close()
+	controller.Store = depositStore
+
+	if cfg.Cache.Address != "" {
+		address := cfg.Cache.Address
+		pool := &redis.Pool{
+			MaxIdle:     16,
+			IdleTimeout: 5 * time.Minute,
+			Dial: func() (redis.Conn, error) {
+				return redis.Dial("tcp", address, redis.DialConnectTimeout(5*time.Second))
+			},
+		}
+		conn := pool.Get()
+		_, err := conn.Do("PING")
+		conn.Close()
+		if err != nil {
+			log.Fatalf("connect cache %s error: %v", address, err)
+		}
+		defer pool.Close()
+		controller.Cache = pool
+	}
+
+	session, err := utils.NewSession(utils.AppConfig())
+	if err != nil {
+		log.Fatalf("init chain session error: %v", err)
+	}
+	defer session.Close()
+	api.SetSession(session)
+
+	keyManager, err := envelope.LoadKeyFile(cfg.Crypto.KEKFile)
+	if err != nil {
+		log.Fatalf("load kek file error: %v", err)
+	}
+	dataCipher, err := envelope.New(keyManager, cfg.Crypto.Algorithm, cfg.Crypto.Key)
+	if err != nil {
+		log.Fatalf("init data cipher error: %v", err)
+	}
+	api.SetCipher(dataCipher)
+	api.SetKeyLookup(controller.LookupKey)
+
+	if cfg.Webhook.Secret != "" {
+		dispatcher := webhook.New(depositStore, cfg.Webhook.Secret, webhook.Options{
+			Timeout:      cfg.Webhook.Timeout,
+			MaxAttempts:  cfg.Webhook.MaxAttempts,
+			Backoff:      cfg.Webhook.Backoff,
+			MaxBackoff:   cfg.Webhook.MaxBackoff,
+			AllowedHosts: cfg.Webhook.AllowedHosts,
+		})
+		dispatcher.Start()
+		controller.Webhook = dispatcher
+	}
+
+	if err := controller.StartJobs(cfg.Job.Workers, cfg.Job.QueueSize); err != nil {
+		log.Fatalf("start jobs error: %v", err)
+	}
+	if err := controller.ResumeRotation(); err != nil {
+		log.Fatalf("resume key rotation error: %v", err)
+	}
+
 	r := gin.Default()
 	routes.Load(r)
-	r.Run(":8000")
-	defer controller.Conn.Close()
-	close(controller.ResponseChannel)
+	server := &http.Server{
+		Addr:         cfg.Server.Listen,
+		Handler:      r,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("server stopped: %v", err)
+		}
+	}()
+	//KEK轮换等管理接口只在本机回环地址上提供
+	var adminServer *http.Server
+	if cfg.Server.AdminListen != "" {
+		admin := gin.Default()
+		routes.LoadAdmin(admin)
+		adminServer = &http.Server{Addr: cfg.Server.AdminListen, Handler: admin, ReadTimeout: cfg.Server.ReadTimeout}
+		go func() {
+			if err := adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("admin server stopped: %v", err)
+			}
+		}()
+	}
+
+	//收到退出信号后等待进行中的请求、上链任务和KEK轮换结束，再关闭链会话、缓存和数据库连接
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	shutdownTimeout := cfg.Server.WriteTimeout
+	if shutdownTimeout == 0 {
+		shutdownTimeout = cfg.Chain.CommitTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("server shutdown error: %v", err)
+	}
+	if adminServer != nil {
+		if err := adminServer.Shutdown(ctx); err != nil {
+			log.Printf("admin server shutdown error: %v", err)
+		}
+	}
+	if err := controller.StopJobs(ctx); err != nil {
+		log.Printf("stop jobs error: %v", err)
+	}
+	if err := controller.StopRotation(ctx); err != nil {
+		log.Printf("stop key rotation error: %v", err)
+	}
+	if controller.Webhook != nil {
+		if err := controller.Webhook.Stop(ctx); err != nil {
+			log.Printf("stop webhook error: %v", err)
+		}
+	}
 }
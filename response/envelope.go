@@ -0,0 +1,68 @@
+package response
+
+import (
+	"net/http"
+)
+
+// Envelope /api/v1 统一响应结构，成功时code为0
+type Envelope struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// ErrorCode 错误码目录中的一项
+type ErrorCode struct {
+	Code       int    `json:"code"`
+	HTTPStatus int    `json:"httpStatus"`
+	Message    string `json:"message"`
+}
+
+// 错误码，601~604沿用旧接口中utils.ErrorNew使用的取值
+const (
+	CodeOK              = 0
+	CodeInternal        = 600
+	CodeInvalidParam    = 601
+	CodePhoneNotFound   = 602
+	CodeNotFound        = 603
+	CodeChainFailed     = 604
+	CodeCanceled        = 605
+	CodeQueueFull       = 606
+	CodeAlreadyModified = 607
+	CodeRotationRunning = 608
+	CodeRevoked         = 609
+)
+
+// Errors 错误码目录，按错误码排序，会写入OpenAPI文档
+var Errors = []ErrorCode{
+	{CodeInternal, http.StatusInternalServerError, "服务内部错误"},
+	{CodeInvalidParam, http.StatusBadRequest, "参数无效"},
+	{CodePhoneNotFound, http.StatusNotFound, "手机号不存在"},
+	{CodeNotFound, http.StatusNotFound, "记录不存在"},
+	{CodeChainFailed, http.StatusBadGateway, "链上操作失败，建议重试"},
+	{CodeCanceled, http.StatusRequestTimeout, "请求已取消"},
+	{CodeQueueFull, http.StatusServiceUnavailable, "任务队列已满，请稍后重试"},
+	{CodeAlreadyModified, http.StatusConflict, "该数据已被修改，请使用新哈希查询"},
+	{CodeRotationRunning, http.StatusConflict, "KEK轮换正在进行"},
+	{CodeRevoked, http.StatusGone, "存证已撤销"},
+}
+
+// HTTPStatus 错误码对应的HTTP状态码，未登记的错误码按服务内部错误处理
+func HTTPStatus(code int) int {
+	for _, e := range Errors {
+		if e.Code == code {
+			return e.HTTPStatus
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// Ok 成功响应
+func Ok(status int, data interface{}) *Response {
+	return Resp().Status(status).Envelope(Envelope{Code: CodeOK, Message: "ok", Data: data})
+}
+
+// Fail 失败响应，HTTP状态码由错误码目录确定
+func Fail(code int, msg string) *Response {
+	return Resp().Status(HTTPStatus(code)).Envelope(Envelope{Code: code, Message: msg})
+}
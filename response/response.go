@@ -1,9 +1,14 @@
 package response
 
-import "github.com/gin-gonic/gin"
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
 
 type Response struct {
-	data interface{}
+	status int
+	data   interface{}
 }
 
 func Resp() *Response {
@@ -25,6 +30,26 @@ func (r *Response) Byte(data []byte) *Response {
 	return r
 }
 
+// Envelope 设置 /api/v1 统一响应结构
+func (r *Response) Envelope(data Envelope) *Response {
+	r.data = data
+	return r
+}
+
+// Status 设置HTTP状态码，未设置时为200
+func (r *Response) Status(status int) *Response {
+	r.status = status
+	return r
+}
+
 func (r *Response) GetData() interface{} {
 	return r.data
 }
+
+// GetStatus 获取HTTP状态码
+func (r *Response) GetStatus() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
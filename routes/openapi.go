@@ -0,0 +1,202 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"git.huawei.com/goclient/response"
+)
+
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// OpenAPI 根据路由表生成OpenAPI 3文档，请求体和data字段的结构由Go类型反射得到
+func OpenAPI(routes []Route) map[string]interface{} {
+	g := &schemaGen{schemas: map[string]interface{}{}}
+	paths := map[string]interface{}{}
+	for _, route := range routes {
+		path := V1Prefix + openAPIPath(route.Path)
+		item, ok := paths[path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[path] = item
+		}
+		op := map[string]interface{}{
+			"summary":     route.Summary,
+			"operationId": operationID(route),
+			"responses":   g.responses(route),
+		}
+		var params []interface{}
+		for _, p := range route.Params {
+			params = append(params, map[string]interface{}{
+				"name":        p.Name,
+				"in":          p.In,
+				"description": p.Description,
+				"required":    true,
+				"schema":      map[string]interface{}{"type": "string"},
+			})
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+		if route.Body != nil {
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  jsonContent(g.schema(reflect.TypeOf(route.Body))),
+			}
+		}
+		item[strings.ToLower(route.Method)] = op
+	}
+
+	g.schemas["Error"] = map[string]interface{}{
+		"type":     "object",
+		"required": []string{"code", "message"},
+		"properties": map[string]interface{}{
+			"code":    map[string]interface{}{"type": "integer", "description": "错误码，见 x-error-codes"},
+			"message": map[string]interface{}{"type": "string"},
+		},
+	}
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "存证服务接口",
+			"version":     "v1",
+			"description": "所有响应均为 {code, message, data} 结构，code为0表示成功，其它取值见 x-error-codes。",
+		},
+		"paths":         paths,
+		"components":    map[string]interface{}{"schemas": g.schemas},
+		"x-error-codes": response.Errors,
+	}
+}
+
+// gin路径参数 :name 转换为 {name}
+func openAPIPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") {
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// 以处理函数名作为operationId
+func operationID(route Route) string {
+	name := runtime.FuncForPC(reflect.ValueOf(route.Handler).Pointer()).Name()
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+func jsonContent(schema interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": schema},
+	}
+}
+
+type schemaGen struct {
+	schemas map[string]interface{}
+}
+
+// 成功响应及按HTTP状态码分组的错误响应
+func (g *schemaGen) responses(route Route) map[string]interface{} {
+	responses := map[string]interface{}{}
+	for status, result := range route.Results {
+		envelope := map[string]interface{}{
+			"type":     "object",
+			"required": []string{"code", "message"},
+			"properties": map[string]interface{}{
+				"code":    map[string]interface{}{"type": "integer", "enum": []int{response.CodeOK}},
+				"message": map[string]interface{}{"type": "string"},
+				"data":    g.schema(reflect.TypeOf(result)),
+			},
+		}
+		responses[strconv.Itoa(status)] = map[string]interface{}{
+			"description": http.StatusText(status),
+			"content":     jsonContent(envelope),
+		}
+	}
+
+	byStatus := map[int][]string{}
+	for _, code := range route.Errors {
+		for _, e := range response.Errors {
+			if e.Code == code {
+				byStatus[e.HTTPStatus] = append(byStatus[e.HTTPStatus], fmt.Sprintf("%d %s", e.Code, e.Message))
+			}
+		}
+	}
+	for status, codes := range byStatus {
+		sort.Strings(codes)
+		responses[strconv.Itoa(status)] = map[string]interface{}{
+			"description": strings.Join(codes, "; "),
+			"content":     jsonContent(map[string]interface{}{"$ref": "#/components/schemas/Error"}),
+		}
+	}
+	return responses
+}
+
+// Go类型转换为JSON Schema，具名结构体放入components并返回引用
+func (g *schemaGen) schema(t reflect.Type) interface{} {
+	if t == nil {
+		return map[string]interface{}{}
+	}
+	if t == rawMessageType {
+		return map[string]interface{}{"description": "任意JSON"}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.schema(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		if _, ok := g.schemas[t.Name()]; !ok {
+			g.schemas[t.Name()] = map[string]interface{}{} // 先占位，避免自引用时无限递归
+			g.schemas[t.Name()] = g.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	}
+	return map[string]interface{}{}
+}
+
+func (g *schemaGen) object(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Name
+		if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+		properties[name] = g.schema(field.Type)
+		if strings.Contains(field.Tag.Get("binding"), "required") {
+			required = append(required, name)
+		}
+	}
+	object := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		object["required"] = required
+	}
+	return object
+}
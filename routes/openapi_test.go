@@ -0,0 +1,53 @@
+package routes
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func Test_OpenAPI(t *testing.T) {
+	raw, err := json.Marshal(OpenAPI(V1Routes))
+	if err != nil {
+		t.Fatalf("marshal openapi error: %v", err)
+	}
+	var doc struct {
+		OpenAPI    string                                       `json:"openapi"`
+		Paths      map[string]map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+		ErrorCodes []map[string]interface{} `json:"x-error-codes"`
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatalf("unmarshal openapi error: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Errorf("unexpected openapi version: %s", doc.OpenAPI)
+	}
+	for _, route := range V1Routes {
+		path := V1Prefix + openAPIPath(route.Path)
+		if _, ok := doc.Paths[path][strings.ToLower(route.Method)]; !ok {
+			t.Errorf("route %s %s missing from openapi paths", route.Method, path)
+		}
+	}
+	if _, ok := doc.Paths["/api/v1/deposits/{id}"]["put"]["requestBody"]; !ok {
+		t.Error("modify deposit should document its request body")
+	}
+	if len(doc.ErrorCodes) == 0 {
+		t.Error("error code catalogue missing")
+	}
+
+	//所有引用都应能在components中找到
+	for _, ref := range strings.Split(string(raw), `"$ref":"#/components/schemas/`)[1:] {
+		name := ref[:strings.Index(ref, `"`)]
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("dangling schema reference %s", name)
+		}
+	}
+	for _, name := range []string{"CreateDepositRequest", "Message", "Job", "Error"} {
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("schema %s missing", name)
+		}
+	}
+}
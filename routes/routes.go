@@ -1,18 +1,133 @@
 package routes
 
 import (
-	// "Gin/controller"
-	// "response"
+	"net/http"
+
+	"git.huawei.com/goclient/api"
 	"git.huawei.com/goclient/controller"
 	"git.huawei.com/goclient/response"
+	"git.huawei.com/goclient/store"
 	"github.com/gin-gonic/gin"
 )
 
+// V1Prefix /api/v1 接口的路径前缀
+const V1Prefix = "/api/v1"
+
+// Route 路由定义，注册路由和生成OpenAPI文档共用
+type Route struct {
+	Method  string
+	Path    string // gin路径，相对于V1Prefix，如 /deposits/:id
+	Summary string
+	Handler func(ctx *gin.Context) *response.Response
+	Params  []Param             // 路径参数和查询参数
+	Body    interface{}         // 请求体类型的零值，nil表示无请求体
+	Results map[int]interface{} // 成功时的HTTP状态码及对应data字段类型的零值
+	Errors  []int               // 可能返回的错误码
+}
+
+// Param 路径参数或查询参数
+type Param struct {
+	Name        string
+	In          string // path 或 query
+	Description string
+}
+
+// V1Routes /api/v1 路由表
+var V1Routes = []Route{
+	{
+		Method: http.MethodPost, Path: "/deposits", Summary: "新增存证，async为true时返回任务ID",
+		Handler: controller.CreateDeposit, Body: controller.CreateDepositRequest{},
+		Results: map[int]interface{}{http.StatusCreated: controller.TxResult{}, http.StatusAccepted: controller.JobAccepted{}},
+		Errors:  []int{response.CodeInvalidParam, response.CodeChainFailed, response.CodeCanceled, response.CodeQueueFull},
+	},
+	{
+		Method: http.MethodGet, Path: "/deposits", Summary: "查询手机号下最新的三条存证",
+		Handler: controller.ListDeposits, Params: []Param{{"phone", "query", "手机号"}},
+		Results: map[int]interface{}{http.StatusOK: []api.Message{}},
+		Errors:  []int{response.CodeInvalidParam, response.CodePhoneNotFound, response.CodeChainFailed, response.CodeCanceled},
+	},
+	{
+		Method: http.MethodGet, Path: "/deposits/:id", Summary: "根据交易哈希查询存证",
+		Handler: controller.GetDeposit, Params: []Param{{"id", "path", "交易哈希"}},
+		Results: map[int]interface{}{http.StatusOK: api.Message{}},
+		Errors:  []int{response.CodeInvalidParam, response.CodeNotFound, response.CodeAlreadyModified, response.CodeChainFailed, response.CodeCanceled},
+	},
+	{
+		Method: http.MethodPut, Path: "/deposits/:id", Summary: "修改存证，新版本沿用存证ID，返回新版本的交易哈希",
+		Handler: controller.ModifyDeposit, Params: []Param{{"id", "path", "待修改存证的交易哈希"}},
+		Body: controller.ModifyDepositRequest{}, Results: map[int]interface{}{http.StatusOK: controller.TxResult{}},
+		Errors: []int{response.CodeInvalidParam, response.CodeNotFound, response.CodeAlreadyModified, response.CodeChainFailed, response.CodeCanceled},
+	},
+	{
+		Method: http.MethodGet, Path: "/deposits/:id/history", Summary: "查询存证在链上的全部历史版本",
+		Handler: controller.GetDepositHistory, Params: []Param{{"id", "path", "存证ID或任一版本的交易哈希"}},
+		Results: map[int]interface{}{http.StatusOK: controller.DepositHistory{}},
+		Errors:  []int{response.CodeInvalidParam, response.CodeNotFound, response.CodeInternal, response.CodeChainFailed, response.CodeCanceled},
+	},
+	{
+		Method: http.MethodPost, Path: "/deposits/:id/revoke", Summary: "撤销存证，撤销后查询只返回撤销状态，历史版本中最后一个为删除操作",
+		Handler: controller.RevokeDeposit, Params: []Param{{"id", "path", "待撤销存证最新版本的交易哈希"}},
+		Body: controller.RevokeDepositRequest{}, Results: map[int]interface{}{http.StatusOK: controller.TxResult{}},
+		Errors: []int{response.CodeInvalidParam, response.CodeNotFound, response.CodeAlreadyModified, response.CodeRevoked, response.CodeChainFailed, response.CodeCanceled},
+	},
+	{
+		Method: http.MethodGet, Path: "/jobs/:id", Summary: "查询异步上链任务",
+		Handler: controller.GetJob, Params: []Param{{"id", "path", "任务ID"}},
+		Results: map[int]interface{}{http.StatusOK: store.Job{}},
+		Errors:  []int{response.CodeInvalidParam, response.CodeNotFound},
+	},
+	{
+		Method: http.MethodGet, Path: "/nodes", Summary: "查询链节点的健康状态，以及当前使用的背书、共识和事件节点",
+		Handler: controller.GetNodes, Results: map[int]interface{}{http.StatusOK: api.NodeStatus{}},
+		Errors: []int{response.CodeChainFailed},
+	},
+}
+
+// AdminRoutes 管理接口路由表，只在本机的管理监听地址（server.adminListen）上提供，路径同样以V1Prefix开头。
+// KEK轮换会重新加密存证并提交新的链上交易，不能暴露在对外的服务地址上
+var AdminRoutes = []Route{
+	{
+		Method: http.MethodPost, Path: "/key-rotations", Summary: "将存证的数据密钥轮换到当前KEK，未完成时从中断处继续",
+		Handler: controller.CreateKeyRotation, Body: controller.RotateKeysRequest{},
+		Results: map[int]interface{}{http.StatusAccepted: store.Rotation{}},
+		Errors:  []int{response.CodeInvalidParam, response.CodeRotationRunning, response.CodeInternal},
+	},
+	{
+		Method: http.MethodGet, Path: "/key-rotations/:keyId", Summary: "查询KEK轮换进度",
+		Handler: controller.GetKeyRotation, Params: []Param{{"keyId", "path", "目标KEK标识"}},
+		Results: map[int]interface{}{http.StatusOK: store.Rotation{}},
+		Errors:  []int{response.CodeNotFound},
+	},
+}
+
 func Load(r *gin.Engine) {
 	r.POST("/upchain", convert(controller.UpChain))
 	r.GET("/querybyphone", convert(controller.QueryByPhone))
 	r.GET("/querybyhash", convert(controller.QueryByHash))
 	r.POST("/modify", convert(controller.Modify))
+	r.GET("/jobs/:id", convert(controller.QueryJob))
+
+	v1 := r.Group(V1Prefix)
+	for _, route := range V1Routes {
+		v1.Handle(route.Method, route.Path, convert(route.Handler))
+	}
+	spec := OpenAPI(V1Routes)
+	v1.GET("/openapi.json", func(c *gin.Context) {
+		c.JSON(http.StatusOK, spec)
+	})
+	r.NoRoute(controller.NoRoute)
+}
+
+// LoadAdmin 注册管理接口，r为管理监听地址上的路由
+func LoadAdmin(r *gin.Engine) {
+	admin := r.Group(V1Prefix)
+	for _, route := range AdminRoutes {
+		admin.Handle(route.Method, route.Path, convert(route.Handler))
+	}
+	spec := OpenAPI(AdminRoutes)
+	admin.GET("/openapi.json", func(c *gin.Context) {
+		c.JSON(http.StatusOK, spec)
+	})
 	r.NoRoute(controller.NoRoute)
 }
 
@@ -22,9 +137,13 @@ func convert(f func(ctx *gin.Context) *response.Response) gin.HandlerFunc {
 		data := resp.GetData()
 		switch item := data.(type) {
 		case string:
-			c.String(200, item)
+			c.String(resp.GetStatus(), item)
+		case []byte:
+			c.Data(resp.GetStatus(), "application/octet-stream", item)
 		case gin.H:
-			c.JSON(200, item)
+			c.JSON(resp.GetStatus(), item)
+		default:
+			c.JSON(resp.GetStatus(), item)
 		}
 	}
 }
@@ -0,0 +1,40 @@
+package routes
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// KEK轮换只在管理监听地址上注册，对外的服务地址上没有该路由
+func Test_Load_AdminRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registered := func(load func(r *gin.Engine)) map[string]bool {
+		r := gin.New()
+		load(r)
+		paths := map[string]bool{}
+		for _, route := range r.Routes() {
+			paths[route.Method+" "+route.Path] = true
+		}
+		return paths
+	}
+	public, admin := registered(Load), registered(LoadAdmin)
+	for _, route := range AdminRoutes {
+		key := route.Method + " " + V1Prefix + route.Path
+		if public[key] {
+			t.Errorf("admin route %s registered on the public router", key)
+		}
+		if !admin[key] {
+			t.Errorf("admin route %s missing from the admin router", key)
+		}
+	}
+	for key := range public {
+		if strings.Contains(key, "key-rotations") {
+			t.Errorf("key rotation route %s registered on the public router", key)
+		}
+	}
+	if !public["POST "+V1Prefix+"/deposits"] || admin["POST "+V1Prefix+"/deposits"] {
+		t.Error("deposit routes should only be registered on the public router")
+	}
+}
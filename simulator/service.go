@@ -0,0 +1,351 @@
+package simulator
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"git.huawei.com/huaweichain/proto/common"
+	"git.huawei.com/huaweichain/proto/contract"
+	"git.huawei.com/huaweichain/proto/nodeservice"
+	"git.huawei.com/poissonsearch/wienerchain/contract/docker-container/contract-go/contractapi"
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// 将结果封装为Response，与节点返回的消息结构一致
+func response(status common.Status, info string, payload []byte) (*common.RawMessage, error) {
+	bytes, err := proto.Marshal(&common.Response{Status: status, StatusInfo: info, Payload: payload})
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal response error")
+	}
+	return &common.RawMessage{Payload: bytes}, nil
+}
+
+// 查询成功时返回序列化的message，查询失败时返回NOT_FOUND
+func reply(message proto.Message, err error) (*common.RawMessage, error) {
+	if err != nil {
+		return response(common.NOT_FOUND, err.Error(), nil)
+	}
+	payload, err := proto.Marshal(message)
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal payload error")
+	}
+	return response(common.SUCCESS, "", payload)
+}
+
+// contractService 背书服务，Invoke和Query都在当前状态上执行合约并返回背书后的交易
+type contractService struct {
+	nodeservice.UnimplementedContractServer
+	s *Simulator
+}
+
+func (c *contractService) Invoke(ctx context.Context, msg *common.RawMessage) (*common.RawMessage, error) {
+	return c.s.endorse(msg, true)
+}
+
+func (c *contractService) Query(ctx context.Context, msg *common.RawMessage) (*common.RawMessage, error) {
+	return c.s.endorse(msg, false)
+}
+
+// 执行背书请求，keep为true且交易有写集时保留执行上下文，等待交易提交后出块
+func (s *Simulator) endorse(msg *common.RawMessage, keep bool) (*common.RawMessage, error) {
+	invocation := &nodeservice.Invocation{}
+	if err := proto.Unmarshal(msg.Payload, invocation); err != nil {
+		return response(common.BAD_REQUEST, "unmarshal invocation error: "+err.Error(), nil)
+	}
+	header, params := invocation.Header, invocation.Parameters
+	if header == nil || header.Creator == nil || params == nil {
+		return response(common.BAD_REQUEST, "invocation header or parameters is empty", nil)
+	}
+	if header.ChainId != s.opts.ChainID {
+		return response(common.NOT_FOUND, fmt.Sprintf("chain %s not found", header.ChainId), nil)
+	}
+	if params.ContractName != s.opts.ContractName {
+		return response(common.NOT_FOUND, fmt.Sprintf("contract %s not found", params.ContractName), nil)
+	}
+	creator := &contractapi.Identity{Org: header.Creator.Org, CommonName: string(header.Creator.Id)}
+	if msg.Signature != nil {
+		creator.Cert = msg.Signature.Cert
+	}
+	tx, hash, stub, err := s.execute(header, params, creator, false)
+	if err != nil {
+		return response(common.CONTRACT_RUNTIME_ERR, err.Error(), nil)
+	}
+	if keep && len(stub.WriteSet()) > 0 {
+		now := time.Now()
+		s.mu.Lock()
+		// 客户端背书后可能不再提交，保存前清理过期的执行上下文
+		s.evictEndorsed(now)
+		s.endorsed[hex.EncodeToString(hash)] = &endorsedTx{stub: stub, expire: now.Add(s.opts.EndorsedTTL)}
+		s.mu.Unlock()
+	}
+	payload, err := proto.Marshal(tx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal transaction error")
+	}
+	return response(common.SUCCESS, "", payload)
+}
+
+// transactionService 接收背书后的交易，出块结果通过事件服务推送
+type transactionService struct {
+	nodeservice.UnimplementedTransactionSenderServer
+	s *Simulator
+}
+
+func (t *transactionService) SendTransaction(ctx context.Context, msg *common.RawMessage) (*common.RawMessage, error) {
+	tx := &common.Transaction{}
+	if err := proto.Unmarshal(msg.Payload, tx); err != nil {
+		return response(common.BAD_REQUEST, "unmarshal transaction error: "+err.Error(), nil)
+	}
+	if err := t.s.submit(tx); err != nil {
+		return response(common.BAD_REQUEST, err.Error(), nil)
+	}
+	payload, err := proto.Marshal(&common.RawMessage{})
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal transaction response error")
+	}
+	return response(common.SUCCESS, "", payload)
+}
+
+// chainService 区块和交易查询服务
+type chainService struct {
+	nodeservice.UnimplementedChainServiceServer
+	s *Simulator
+}
+
+func (c *chainService) request(msg *common.RawMessage) (*nodeservice.ChainServiceRequest, error) {
+	request := &nodeservice.ChainServiceRequest{}
+	if err := proto.Unmarshal(msg.Payload, request); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal chain service request error")
+	}
+	if request.ChainId != c.s.opts.ChainID {
+		return nil, errors.Errorf("chain %s not found", request.ChainId)
+	}
+	return request, nil
+}
+
+func (c *chainService) GetLatestChainState(ctx context.Context, msg *common.RawMessage) (*common.RawMessage, error) {
+	if _, err := c.request(msg); err != nil {
+		return reply(nil, err)
+	}
+	return reply(&nodeservice.LatestChainState{Height: c.s.Height()}, nil)
+}
+
+func (c *chainService) GetBlockByNum(ctx context.Context, msg *common.RawMessage) (*common.RawMessage, error) {
+	block, _, err := c.blockByNum(msg)
+	return reply(block, err)
+}
+
+func (c *chainService) GetBlockAndResultByNum(ctx context.Context, msg *common.RawMessage) (*common.RawMessage, error) {
+	block, result, err := c.blockByNum(msg)
+	return reply(&common.BlockAndResult{Block: block, Result: result}, err)
+}
+
+func (c *chainService) GetTxByHash(ctx context.Context, msg *common.RawMessage) (*common.RawMessage, error) {
+	_, tx, _, err := c.txByHash(msg)
+	return reply(tx, err)
+}
+
+func (c *chainService) GetTxResultByTxHash(ctx context.Context, msg *common.RawMessage) (*common.RawMessage, error) {
+	_, _, result, err := c.txByHash(msg)
+	return reply(result, err)
+}
+
+func (c *chainService) GetBlockByTxHash(ctx context.Context, msg *common.RawMessage) (*common.RawMessage, error) {
+	block, _, _, err := c.txByHash(msg)
+	return reply(block, err)
+}
+
+func (c *chainService) GetContractInfo(ctx context.Context, msg *common.RawMessage) (*common.RawMessage, error) {
+	request, err := c.request(msg)
+	if err != nil {
+		return reply(nil, err)
+	}
+	if request.GetContract() != c.s.opts.ContractName {
+		return reply(nil, errors.Errorf("contract %s not found", request.GetContract()))
+	}
+	definition := &contract.ContractDefinition{ContractName: c.s.opts.ContractName, ApprovalValidator: "default"}
+	if c.s.opts.Policy != "" {
+		definition.ValidatorExtensions = map[string][]byte{"policy": []byte(c.s.opts.Policy)}
+	}
+	return reply(&nodeservice.ContractInfoQueryResponse{Name: c.s.opts.ContractName, Definition: definition, Status: nodeservice.OK}, nil)
+}
+
+func (c *chainService) blockByNum(msg *common.RawMessage) (*common.Block, *common.BlockResult, error) {
+	request, err := c.request(msg)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	number := request.GetBlockNum()
+	if number >= uint64(len(c.s.blocks)) {
+		return nil, nil, errors.Errorf("block %d not found", number)
+	}
+	return c.s.blocks[number], c.s.results[number], nil
+}
+
+func (c *chainService) txByHash(msg *common.RawMessage) (*common.Block, *common.Tx, *common.TxResult, error) {
+	request, err := c.request(msg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	block, tx, result := c.s.findTx(request.GetTxHash())
+	if tx == nil {
+		return nil, nil, nil, errors.Errorf("transaction %x not found", request.GetTxHash())
+	}
+	return block, tx, result, nil
+}
+
+// eventService 区块和交易事件服务，区块事件按区块号顺序推送，交易事件只推送客户端注册过的交易
+type eventService struct {
+	nodeservice.UnimplementedEventServiceServer
+	s *Simulator
+}
+
+func (e *eventService) RegisterBlockEvent(msg *common.RawMessage, stream nodeservice.EventService_RegisterBlockEventServer) error {
+	return e.streamBlocks(msg, stream.Context(), stream.Send, func(block *common.Block, _ *common.BlockResult) proto.Message {
+		return block
+	})
+}
+
+func (e *eventService) RegisterResultEvent(msg *common.RawMessage, stream nodeservice.EventService_RegisterResultEventServer) error {
+	return e.streamBlocks(msg, stream.Context(), stream.Send, func(_ *common.Block, result *common.BlockResult) proto.Message {
+		return result
+	})
+}
+
+func (e *eventService) RegisterBlockAndResultEvent(msg *common.RawMessage,
+	stream nodeservice.EventService_RegisterBlockAndResultEventServer) error {
+	return e.streamBlocks(msg, stream.Context(), stream.Send, func(block *common.Block, result *common.BlockResult) proto.Message {
+		return &common.BlockAndResult{Block: block, Result: result}
+	})
+}
+
+// 从起始区块开始逐个推送区块事件，LATEST从下一个新区块开始
+func (e *eventService) streamBlocks(msg *common.RawMessage, ctx context.Context, send func(*common.RawMessage) error,
+	event func(*common.Block, *common.BlockResult) proto.Message) error {
+	startPoint := &nodeservice.EventStartPoint{}
+	if err := proto.Unmarshal(msg.Payload, startPoint); err != nil {
+		return errors.WithMessage(err, "unmarshal event start point error")
+	}
+	if startPoint.ChainId != e.s.opts.ChainID {
+		return errors.Errorf("chain %s not found", startPoint.ChainId)
+	}
+	next := e.s.Height()
+	if startPoint.Type == nodeservice.SPECIFIC {
+		next = startPoint.BlockNum
+	}
+	for ; ; next++ {
+		block, result, err := e.s.waitBlock(ctx.Done(), next)
+		if err != nil {
+			return err
+		}
+		payload, err := proto.Marshal(event(block, result))
+		if err != nil {
+			return errors.WithMessage(err, "marshal event error")
+		}
+		if err := send(&common.RawMessage{Payload: payload}); err != nil {
+			return err
+		}
+	}
+}
+
+// RegisterTxEvent 首条消息注册客户端，之后每条消息注册一个交易哈希；注册时交易已出块则立即推送结果
+func (e *eventService) RegisterTxEvent(stream nodeservice.EventService_RegisterTxEventServer) error {
+	s := e.s
+	msg, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	register := &nodeservice.TxEvent{}
+	if err := proto.Unmarshal(msg.Payload, register); err != nil || register.Type != nodeservice.REGISTER_CLIENT ||
+		register.ChainId != s.opts.ChainID {
+		return sendTxEvent(stream, &nodeservice.TxEventRes{Status: nodeservice.FAILED, Type: nodeservice.REGISTER_CLIENT,
+			Info: "invalid register client message"})
+	}
+	next := s.Height()
+	if err := sendTxEvent(stream, &nodeservice.TxEventRes{Status: nodeservice.SUCCESS, Type: nodeservice.REGISTER_CLIENT}); err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	hashes := make(chan []byte)
+	go func() {
+		defer close(hashes)
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			event := &nodeservice.TxEvent{}
+			if err := proto.Unmarshal(msg.Payload, event); err != nil || event.Type != nodeservice.REGISTER_TX_HASH {
+				continue
+			}
+			select {
+			case hashes <- event.TxHash:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	registered := map[string]bool{}
+	for {
+		s.mu.Lock()
+		height, notify := uint64(len(s.blocks)), s.notify
+		results := s.results[next:height]
+		s.mu.Unlock()
+		next = height
+		for _, result := range results {
+			for _, txResult := range result.TxResults {
+				key := hex.EncodeToString(txResult.TxHash)
+				if !registered[key] {
+					continue
+				}
+				delete(registered, key)
+				if err := sendTxResult(stream, txResult); err != nil {
+					return err
+				}
+			}
+		}
+
+		select {
+		case hash, ok := <-hashes:
+			if !ok {
+				return nil
+			}
+			if _, _, txResult := s.findTx(hash); txResult != nil {
+				if err := sendTxResult(stream, txResult); err != nil {
+					return err
+				}
+			} else {
+				registered[hex.EncodeToString(hash)] = true
+			}
+		case <-notify:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.done:
+			return errors.New("simulator closed")
+		}
+	}
+}
+
+func sendTxResult(stream nodeservice.EventService_RegisterTxEventServer, txResult *common.TxResult) error {
+	payload, err := proto.Marshal(txResult)
+	if err != nil {
+		return errors.WithMessage(err, "marshal tx result error")
+	}
+	return sendTxEvent(stream, &nodeservice.TxEventRes{Status: nodeservice.SUCCESS, Type: nodeservice.REGISTER_TX_HASH,
+		Payload: payload})
+}
+
+func sendTxEvent(stream nodeservice.EventService_RegisterTxEventServer, res *nodeservice.TxEventRes) error {
+	payload, err := proto.Marshal(res)
+	if err != nil {
+		return errors.WithMessage(err, "marshal tx event response error")
+	}
+	return stream.Send(&common.RawMessage{Payload: payload})
+}
@@ -0,0 +1,427 @@
+// Package simulator 本地链模拟器：在本机提供nodeservice的合约、交易、链查询和事件gRPC服务，
+// 背书时在进程内执行contractapi.Contract，提交交易后出块并推送交易和区块事件，
+// 用于在没有华为链网络时端到端验证GatewayClient和存证服务。
+// 模拟器不校验签名和背书策略，状态数据库由contractapi/testing.Ledger提供，出块时同样进行MVCC校验
+package simulator
+
+import (
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"git.huawei.com/huaweichain/common/cryptomgr"
+	"git.huawei.com/huaweichain/proto/common"
+	"git.huawei.com/huaweichain/proto/nodeservice"
+	sdkutils "git.huawei.com/huaweichain/sdk/utils"
+	"git.huawei.com/poissonsearch/wienerchain/contract/docker-container/contract-go/contractapi"
+	ctesting "git.huawei.com/poissonsearch/wienerchain/contract/docker-container/contract-go/contractapi/testing"
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// DefaultNodeName 默认的背书节点名称
+const DefaultNodeName = "simulator"
+
+// DefaultEndorsedTTL 已背书未提交交易执行上下文的默认保留时间
+const DefaultEndorsedTTL = 10 * time.Minute
+
+// Options 模拟器配置
+type Options struct {
+	ChainID       string        // 链ID
+	ContractName  string        // 合约名称，调用其它合约时返回NOT_FOUND
+	Algorithm     string        // 客户端签名算法，决定交易哈希算法，ecdsa_with_sha256（默认）或 sm2_with_sm3
+	NodeName      string        // 背书节点名称，写入交易的背书信息
+	BlockInterval time.Duration // 出块间隔，为0时每笔交易提交后立即单独出块
+	TLSConfig     *tls.Config   // 为nil时不启用TLS，sdk.yaml中需关闭tls
+	Policy        string        // 合约背书策略，通过GetContractInfo返回，如 "org1 | org2"
+	EndorsedTTL   time.Duration // 已背书未提交交易的保留时间，过期后提交时以空写集出块，为0时使用DefaultEndorsedTTL
+}
+
+// Simulator 单节点的本地链，并发安全
+type Simulator struct {
+	opts     Options
+	contract contractapi.Contract
+	ledger   *ctesting.Ledger
+	hash     func(data []byte) []byte
+	server   *grpc.Server
+	listener net.Listener
+
+	mu       sync.Mutex
+	blocks   []*common.Block
+	results  []*common.BlockResult
+	txs      map[string]txLocation  // 已出块的交易，key为十六进制交易哈希
+	endorsed map[string]*endorsedTx // 已背书且有写集、等待提交的交易，提交时或过期后删除
+	pending  []*pendingTx           // 已提交、等待出块的交易
+	notify   chan struct{}          // 出块时关闭并替换，用于唤醒事件流
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type txLocation struct {
+	block uint64
+	index int
+}
+
+type endorsedTx struct {
+	stub   *ctesting.Stub
+	expire time.Time
+}
+
+type pendingTx struct {
+	hash []byte
+	tx   *common.Transaction
+	stub *ctesting.Stub
+}
+
+// New 创建模拟器并生成0号创世区块，合约需调用Init初始化后才能写入
+func New(contract contractapi.Contract, opts Options) (*Simulator, error) {
+	if contract == nil || opts.ChainID == "" || opts.ContractName == "" {
+		return nil, errors.New("contract, chain id and contract name are required")
+	}
+	if opts.NodeName == "" {
+		opts.NodeName = DefaultNodeName
+	}
+	if opts.EndorsedTTL <= 0 {
+		opts.EndorsedTTL = DefaultEndorsedTTL
+	}
+	s := &Simulator{
+		opts:     opts,
+		contract: contract,
+		ledger:   ctesting.NewLedger(opts.ChainID, opts.ContractName),
+		txs:      map[string]txLocation{},
+		endorsed: map[string]*endorsedTx{},
+		notify:   make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	switch opts.Algorithm {
+	case "", cryptomgr.EcdsaWithSha256:
+		s.hash = sdkutils.HashSha256
+	case cryptomgr.Sm2WithSm3:
+		s.hash = sdkutils.HashSM3
+	default:
+		return nil, errors.Errorf("unsupported algorithm: %s", opts.Algorithm)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.appendBlock(nil, nil); err != nil {
+		return nil, errors.WithMessage(err, "create genesis block error")
+	}
+	return s, nil
+}
+
+// Ledger 模拟器使用的状态数据库，可用于在测试中检查已提交的状态
+func (s *Simulator) Ledger() *ctesting.Ledger {
+	return s.ledger
+}
+
+// Init 以creator身份调用合约的Init接口并单独出块，相当于链上部署合约后的初始化交易
+func (s *Simulator) Init(creator *contractapi.Identity, args ...string) error {
+	if creator == nil {
+		return errors.New("creator is nil")
+	}
+	header := &common.TxHeader{
+		Type:      common.COMMON_TRANSACTION,
+		ChainId:   s.opts.ChainID,
+		Creator:   &common.Identity{Org: creator.Org, Type: common.COMMON_NAME, Id: []byte(creator.CommonName)},
+		Timestamp: uint64(s.ledger.Now().UnixNano()),
+	}
+	params := &common.ContractInvocation{ContractName: s.opts.ContractName, FuncName: "init"}
+	for _, arg := range args {
+		params.Args = append(params.Args, []byte(arg))
+	}
+	tx, hash, stub, err := s.execute(header, params, creator, true)
+	if err != nil {
+		return errors.WithMessage(err, "init contract error")
+	}
+	s.mu.Lock()
+	s.pending = append(s.pending, &pendingTx{hash: hash, tx: tx, stub: stub})
+	s.mu.Unlock()
+	return s.CutBlock()
+}
+
+// Start 在address上监听并提供gRPC服务，address为"127.0.0.1:0"时使用随机端口，实际地址见Addr
+func (s *Simulator) Start(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return errors.WithMessage(err, "listen error")
+	}
+	var options []grpc.ServerOption
+	if s.opts.TLSConfig != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(s.opts.TLSConfig)))
+	}
+	s.listener = listener
+	s.server = grpc.NewServer(options...)
+	nodeservice.RegisterContractServer(s.server, &contractService{s: s})
+	nodeservice.RegisterTransactionSenderServer(s.server, &transactionService{s: s})
+	nodeservice.RegisterChainServiceServer(s.server, &chainService{s: s})
+	nodeservice.RegisterEventServiceServer(s.server, &eventService{s: s})
+	go s.server.Serve(listener)
+	if s.opts.BlockInterval > 0 {
+		go s.cutBlocks()
+	}
+	return nil
+}
+
+// Addr 实际监听的地址
+func (s *Simulator) Addr() string {
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Close 停止gRPC服务并结束所有事件流，可重复调用
+func (s *Simulator) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		if s.server != nil {
+			s.server.Stop()
+		}
+	})
+}
+
+// Endorsed 已背书、等待提交且未过期的交易数
+func (s *Simulator) Endorsed() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evictEndorsed(time.Now())
+	return len(s.endorsed)
+}
+
+// 删除过期的已背书交易，调用方需持有s.mu
+func (s *Simulator) evictEndorsed(now time.Time) {
+	for key, endorsed := range s.endorsed {
+		if !now.Before(endorsed.expire) {
+			delete(s.endorsed, key)
+		}
+	}
+}
+
+// Height 当前区块高度，即包括创世区块在内的区块数
+func (s *Simulator) Height() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return uint64(len(s.blocks))
+}
+
+// CutBlock 将已提交的交易打包出块，没有待出块交易时不出块
+func (s *Simulator) CutBlock() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.pending) == 0 {
+		return nil
+	}
+	for _, p := range s.pending {
+		s.ledger.Submit(p.stub)
+	}
+	validated := s.ledger.CutBlock()
+	number := uint64(len(s.blocks))
+	var txList []*common.Tx
+	var results []*common.TxResult
+	for i, p := range s.pending {
+		status := common.VALID
+		if !validated.Txs[i].Valid {
+			status = common.INVALID_MVCC
+		}
+		txList = append(txList, &common.Tx{Hash: p.hash, Data: &common.Tx_Full{Full: p.tx}})
+		results = append(results, &common.TxResult{TxHash: p.hash, Status: status})
+		s.txs[hex.EncodeToString(p.hash)] = txLocation{block: number, index: i}
+	}
+	s.pending = nil
+	return s.appendBlock(txList, results)
+}
+
+// 按出块间隔定时出块
+func (s *Simulator) cutBlocks() {
+	ticker := time.NewTicker(s.opts.BlockInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.CutBlock(); err != nil {
+				fmt.Println("cut block error:", err)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// 构建区块并追加到链上，唤醒等待新区块的事件流，调用方需持有mu
+func (s *Simulator) appendBlock(txList []*common.Tx, results []*common.TxResult) error {
+	body, err := proto.Marshal(&common.BlockBody{TxList: txList})
+	if err != nil {
+		return errors.WithMessage(err, "marshal block body error")
+	}
+	number := uint64(len(s.blocks))
+	header := &common.BlockHeader{Number: number, BodyHash: s.hash(body), Timestamp: s.ledger.Now().UnixNano()}
+	if number > 0 {
+		parent, err := proto.Marshal(s.blocks[number-1].Header)
+		if err != nil {
+			return errors.WithMessage(err, "marshal parent block header error")
+		}
+		header.ParentHash = s.hash(parent)
+	}
+	s.blocks = append(s.blocks, &common.Block{Header: header, Body: body})
+	s.results = append(s.results, &common.BlockResult{BlockNum: number, TxResults: results})
+	close(s.notify)
+	s.notify = make(chan struct{})
+	return nil
+}
+
+// 在当前状态上执行合约，返回背书后的交易、交易哈希和执行上下文
+func (s *Simulator) execute(header *common.TxHeader, params *common.ContractInvocation, creator *contractapi.Identity,
+	init bool) (*common.Transaction, []byte, *ctesting.Stub, error) {
+	args := make([]string, len(params.Args))
+	for i, arg := range params.Args {
+		args[i] = string(arg)
+	}
+	stub := s.ledger.NewStub(creator, params.FuncName, args...)
+	var result []byte
+	var err error
+	if init {
+		result, err = s.contract.Init(stub)
+	} else {
+		result, err = s.contract.Invoke(stub)
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	payload, err := txPayload(header, params, result, stub)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	hash := s.hash(payload)
+	stub.SetTxHash(hash)
+	tx := &common.Transaction{
+		Payload:   payload,
+		Approvals: []*common.Approval{{OrgName: creator.Org, NodeName: s.opts.NodeName}},
+	}
+	return tx, hash, stub, nil
+}
+
+// 提交已背书的交易，等待出块；交易未经本节点背书、只读或背书已过期时以空写集出块
+func (s *Simulator) submit(tx *common.Transaction) error {
+	txPayload := &common.TxPayload{}
+	if err := proto.Unmarshal(tx.Payload, txPayload); err != nil {
+		return errors.WithMessage(err, "unmarshal tx payload error")
+	}
+	if txPayload.Header == nil || txPayload.Header.ChainId != s.opts.ChainID {
+		return errors.New("transaction chain id does not match")
+	}
+	hash := s.hash(tx.Payload)
+	key := hex.EncodeToString(hash)
+
+	s.mu.Lock()
+	if _, ok := s.txs[key]; ok {
+		s.mu.Unlock()
+		return errors.Errorf("duplicate transaction %s", key)
+	}
+	var stub *ctesting.Stub
+	if endorsed, ok := s.endorsed[key]; ok && time.Now().Before(endorsed.expire) {
+		stub = endorsed.stub
+	} else {
+		stub = s.ledger.NewStub(nil, "")
+		stub.SetTxHash(hash)
+	}
+	delete(s.endorsed, key)
+	s.pending = append(s.pending, &pendingTx{hash: hash, tx: tx, stub: stub})
+	s.mu.Unlock()
+
+	if s.opts.BlockInterval > 0 {
+		return nil
+	}
+	return s.CutBlock()
+}
+
+// 等待并返回指定区块号的区块，ctx结束或模拟器关闭时返回错误
+func (s *Simulator) waitBlock(done <-chan struct{}, number uint64) (*common.Block, *common.BlockResult, error) {
+	for {
+		s.mu.Lock()
+		if number < uint64(len(s.blocks)) {
+			block, result := s.blocks[number], s.results[number]
+			s.mu.Unlock()
+			return block, result, nil
+		}
+		notify := s.notify
+		s.mu.Unlock()
+		select {
+		case <-notify:
+		case <-done:
+			return nil, nil, errors.New("event stream closed")
+		case <-s.done:
+			return nil, nil, errors.New("simulator closed")
+		}
+	}
+}
+
+// 查询已出块的交易，不存在时返回nil
+func (s *Simulator) findTx(hash []byte) (*common.Block, *common.Tx, *common.TxResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	location, ok := s.txs[hex.EncodeToString(hash)]
+	if !ok {
+		return nil, nil, nil
+	}
+	block := s.blocks[location.block]
+	body := &common.BlockBody{}
+	if err := proto.Unmarshal(block.Body, body); err != nil {
+		return nil, nil, nil
+	}
+	return block, body.TxList[location.index], s.results[location.block].TxResults[location.index]
+}
+
+// 构建交易内容：交易头和包含合约调用、执行结果及读写集的CommonTxData，读写集按key排序以保证多次背书结果一致
+func txPayload(header *common.TxHeader, params *common.ContractInvocation, result []byte,
+	stub *ctesting.Stub) ([]byte, error) {
+	invocation, err := proto.Marshal(params)
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal contract invocation error")
+	}
+	updates := &common.KvStateUpdates{}
+	reads := stub.ReadSet()
+	readKeys := make([]string, 0, len(reads))
+	for key := range reads {
+		readKeys = append(readKeys, key)
+	}
+	sort.Strings(readKeys)
+	for _, key := range readKeys {
+		version := reads[key]
+		updates.KeyVersions = append(updates.KeyVersions, &common.KeyVersion{
+			Key:     key,
+			Version: &common.Version{BlockNum: version.BlockNum, TxNum: version.TxNum},
+		})
+	}
+	writes := stub.WriteSet()
+	writeKeys := make([]string, 0, len(writes))
+	for key := range writes {
+		writeKeys = append(writeKeys, key)
+	}
+	sort.Strings(writeKeys)
+	for _, key := range writeKeys {
+		if writes[key] == nil {
+			updates.Deletes = append(updates.Deletes, key)
+		} else {
+			updates.Updates = append(updates.Updates, &common.KeyValue{Key: key, Value: writes[key]})
+		}
+	}
+	data, err := proto.Marshal(&common.CommonTxData{
+		ContractInvocation: invocation,
+		Response:           &common.InvocationResponse{Status: common.SUCCESS, Payload: result},
+		StateUpdates: []*common.StateUpdates{{
+			Namespace: params.ContractName,
+			Updates:   &common.StateUpdates_KvUpdates{KvUpdates: updates},
+		}},
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal common tx data error")
+	}
+	return proto.Marshal(&common.TxPayload{Header: header, Data: data})
+}
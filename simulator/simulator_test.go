@@ -0,0 +1,504 @@
+package simulator_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"git.huawei.com/goclient/api"
+	"git.huawei.com/goclient/controller"
+	"git.huawei.com/goclient/envelope"
+	"git.huawei.com/goclient/response"
+	"git.huawei.com/goclient/routes"
+	"git.huawei.com/goclient/simulator"
+	"git.huawei.com/goclient/store"
+	"git.huawei.com/goclient/usercontract"
+	"git.huawei.com/goclient/utils"
+	"git.huawei.com/huaweichain/common/cryptomgr"
+	"git.huawei.com/huaweichain/proto/common"
+	"git.huawei.com/huaweichain/sdk/client"
+	"git.huawei.com/huaweichain/sdk/config"
+	"git.huawei.com/huaweichain/sdk/crypto"
+	sdkutils "git.huawei.com/huaweichain/sdk/utils"
+	"git.huawei.com/poissonsearch/wienerchain/contract/docker-container/contract-go/contractapi"
+	"github.com/gin-gonic/gin"
+)
+
+// 存证ID为64位十六进制
+var record1, record2 = strings.Repeat("1", 64), strings.Repeat("2", 64)
+
+const sdkConfig = `client:
+  type: ecdsa_with_sha256
+  identity:
+    keyPath: %s
+    certPath: %s
+  tls:
+    enable: false
+nodes:
+`
+
+const sdkNode = `  %s:
+    hostOverride: 127.0.0.1
+    host: 127.0.0.1
+    port: %s
+`
+
+// 启动运行存证合约的模拟器，生成org1的客户端身份并写入sdk.yaml，返回sdk.yaml路径。
+// sdk.yaml中node-0.org1指向模拟器，unreachable中的节点指向没有监听的端口
+func startSimulator(t *testing.T, unreachable ...string) string {
+	_, configPath := startSimulatorWith(t, simulator.Options{ChainID: "chain", ContractName: "finance", Policy: "org2 | org1"}, nil, unreachable)
+	return configPath
+}
+
+// startSimulatorWith 按opts启动模拟器，sdk.yaml中node-0.org1和reachable中的节点指向模拟器，unreachable中的节点指向没有监听的端口
+func startSimulatorWith(t *testing.T, opts simulator.Options, reachable []string, unreachable []string) (*simulator.Simulator, string) {
+	sim, err := simulator.New(usercontract.NewSmartContract(), opts)
+	if err != nil {
+		t.Fatalf("new simulator error: %v", err)
+	}
+	if err := sim.Init(&contractapi.Identity{Org: "org1"}); err != nil {
+		t.Fatalf("init contract error: %v", err)
+	}
+	if err := sim.Start("127.0.0.1:0"); err != nil {
+		t.Fatalf("start simulator error: %v", err)
+	}
+	t.Cleanup(sim.Close)
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{Organization: []string{"org1"}, CommonName: "user1"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, _ := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	dir := t.TempDir()
+	certPath, keyPath, configPath := filepath.Join(dir, "user.crt"), filepath.Join(dir, "user.key"), filepath.Join(dir, "sdk.yaml")
+	ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), 0600)
+	ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	_, port, _ := net.SplitHostPort(sim.Addr())
+	config := fmt.Sprintf(sdkConfig, keyPath, certPath)
+	for _, name := range append([]string{"node-0.org1"}, reachable...) {
+		config += fmt.Sprintf(sdkNode, name, port)
+	}
+	if len(unreachable) > 0 {
+		listener, _ := net.Listen("tcp", "127.0.0.1:0")
+		_, port, _ := net.SplitHostPort(listener.Addr().String())
+		listener.Close()
+		for _, name := range unreachable {
+			config += fmt.Sprintf(sdkNode, name, port)
+		}
+	}
+	ioutil.WriteFile(configPath, []byte(config), 0600)
+	return sim, configPath
+}
+
+func newSession(t *testing.T, configPath string) (*utils.Session, utils.Config) {
+	config := utils.Config{
+		ConfigFilePath: configPath,
+		ContractName:   "finance",
+		ConsensusNode:  "node-0.org1",
+		QueryNode:      "node-0.org1",
+		ChainID:        "chain",
+		CommitTimeout:  5 * time.Second,
+	}
+	session, err := utils.NewSession(config)
+	if err != nil {
+		t.Fatalf("new session error: %v", err)
+	}
+	t.Cleanup(session.Close)
+	// 未配置背书节点，按背书策略选择org1的节点
+	if session.Config.EndorserNodes != "node-0.org1" {
+		t.Fatalf("unexpected endorsers: %s", session.Config.EndorserNodes)
+	}
+	return session, session.Config
+}
+
+func Test_Session(t *testing.T) {
+	session, config := newSession(t, startSimulator(t))
+
+	_, txHash, err := session.Send("putRecord", record1+";o1;c1;h1;100")
+	if err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	if result, err := session.Query("getRecord", record1); err != nil || !strings.Contains(result, `"ciphertext":"c1"`) {
+		t.Errorf("query record: %s %v", result, err)
+	}
+	if _, _, err := session.Send("putRecord", record1+";o2;c2;h2;200"); err == nil || !strings.Contains(err.Error(), "another owner") {
+		t.Errorf("contract error not returned: %v", err)
+	}
+
+	txResult, err := session.QueryTxResult(txHash)
+	if err != nil || txResult.Status != common.VALID {
+		t.Errorf("query tx result: %+v %v", txResult, err)
+	}
+	txTool, blockTool := utils.TxTool{}, utils.BlockTool{}
+	tx, err := txTool.QueryTxByTxID(session.Client, config, txHash)
+	if err != nil {
+		t.Fatalf("query tx error: %v", err)
+	}
+	keyValues, _ := txTool.GetTxKeyValues(*tx)
+	if org, _ := txTool.GetCreateOrg(*tx); org != "org1" || len(keyValues) == 0 {
+		t.Errorf("unexpected tx: org %s, key values %v", org, keyValues)
+	}
+
+	//0号为创世区块，1号为合约初始化交易
+	height, err := blockTool.QueryLastBlockNumber(session.Client, config)
+	if err != nil || height != 2 {
+		t.Fatalf("last block number: %d %v", height, err)
+	}
+	block, err := blockTool.QueryBlockByTxID(session.Client, config, txHash)
+	if err != nil || block.Header.Number != 2 {
+		t.Fatalf("query block by tx: %+v %v", block, err)
+	}
+	parent, err := blockTool.QueryBlockByNumber(session.Client, config, "1")
+	if err != nil || len(block.Header.ParentHash) == 0 {
+		t.Fatalf("query block by number: %+v %v", parent, err)
+	}
+	if ids, _ := blockTool.GetTxIdList(block); len(ids) != 1 || ids[0] != txHash {
+		t.Errorf("unexpected tx ids in block: %v", ids)
+	}
+
+	events, err := session.Nodes.EventListener.EventAction.GetBlockEventService(config.ChainID)
+	if err != nil {
+		t.Fatalf("get block event service error: %v", err)
+	}
+	defer events.Close()
+	iter, err := events.RegisterBlockEventFrom(1)
+	if err != nil {
+		t.Fatalf("register block event error: %v", err)
+	}
+	for _, want := range []uint64{1, 2} {
+		if block, err := iter.Next(); err != nil || block.Header.Number != want {
+			t.Errorf("block event: %+v %v, want block %d", block, err, want)
+		}
+	}
+}
+
+// 配置的背书、共识和事件节点不可用时切换到同组织的node-0.org1，失败次数达到阈值后熔断
+func Test_Failover(t *testing.T) {
+	sdkutils.SetTimeout(1)
+	defer sdkutils.SetTimeout(utils.WaitTime)
+	session, err := utils.NewSession(utils.Config{
+		ConfigFilePath: startSimulator(t, "node-1.org1"),
+		ContractName:   "finance",
+		EndorserNodes:  "node-1.org1",
+		ConsensusNode:  "node-1.org1",
+		QueryNode:      "node-0.org1",
+		ChainID:        "chain",
+		CommitTimeout:  5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("new session error: %v", err)
+	}
+	defer session.Close()
+	if listener := session.EventListener(); listener != "node-0.org1" {
+		t.Errorf("event listener not failed over: %s", listener)
+	}
+
+	for i, args := range []string{record1 + ";o1;c1;h1;100", record2 + ";o1;c2;h2;100"} {
+		if _, _, err := session.Send("putRecord", args); err != nil {
+			t.Fatalf("send %d error: %v", i, err)
+		}
+	}
+	health := session.NodeHealth()
+	if len(health) != 2 || health[0].State != utils.NodeHealthy || health[1].Name != "node-1.org1" ||
+		health[1].State != utils.NodeOpen || health[1].TotalFailures != 3 || health[1].LastError == "" {
+		t.Errorf("unexpected node health: %+v", health)
+	}
+}
+
+// 等待落块期间事件节点熔断，交易事件切换到同组织的其它节点，等待中的交易在新节点上收到结果而不是超时
+func Test_ListenerSwitch(t *testing.T) {
+	sim, configPath := startSimulatorWith(t, simulator.Options{ChainID: "chain", ContractName: "finance",
+		Policy: "org2 | org1", BlockInterval: time.Hour}, []string{"node-1.org1"}, nil)
+	session, err := utils.NewSession(utils.Config{
+		ConfigFilePath: configPath,
+		ContractName:   "finance",
+		EndorserNodes:  "node-0.org1",
+		ConsensusNode:  "node-0.org1",
+		QueryNode:      "node-0.org1",
+		ChainID:        "chain",
+		CommitTimeout:  5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("new session error: %v", err)
+	}
+	defer session.Close()
+
+	submitted := make(chan struct{})
+	sent := make(chan error, 1)
+	start := time.Now()
+	go func() {
+		_, _, err := session.SendWithProgress("putRecord", record1+";o1;c1;h1;100", func(stage string, txHash string) {
+			if stage == utils.TxStageSubmitted {
+				close(submitted)
+			}
+		})
+		sent <- err
+	}()
+	select {
+	case <-submitted:
+	case err := <-sent:
+		t.Fatalf("send error before submitted: %v", err)
+	}
+
+	// 事件节点熔断后，下一次注册在node-1.org1上重新订阅
+	for i := 0; i < 3; i++ {
+		session.Nodes.Pool.Failure("node-0.org1", errors.New("event stream broken"))
+	}
+	if _, err := session.RegisterTx([]byte("other")); err != nil {
+		t.Fatalf("register tx error: %v", err)
+	}
+	if listener := session.EventListener(); listener != "node-1.org1" {
+		t.Fatalf("event listener not switched: %s", listener)
+	}
+	if err := sim.CutBlock(); err != nil {
+		t.Fatalf("cut block error: %v", err)
+	}
+	if err := <-sent; err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 5*time.Second {
+		t.Errorf("tx result delivered after commit timeout: %v", elapsed)
+	}
+}
+
+// 背书后未提交的交易在过期后清理，提交的交易在提交时清理
+func Test_EndorsedTTL(t *testing.T) {
+	sim, configPath := startSimulatorWith(t, simulator.Options{ChainID: "chain", ContractName: "finance",
+		Policy: "org2 | org1", EndorsedTTL: 200 * time.Millisecond}, nil, nil)
+	session, config := newSession(t, configPath)
+
+	rawMsg, err := session.Client.ContractRawMessage.BuildInvokeMessage(config.ChainID, config.ContractName, "putRecord",
+		[]string{record1, "o1", "c1", "h1", "100"})
+	if err != nil {
+		t.Fatalf("build invoke message error: %v", err)
+	}
+	if _, err := session.Nodes.Endorsers[0].ContractAction.Invoke(rawMsg); err != nil {
+		t.Fatalf("endorse error: %v", err)
+	}
+	if n := sim.Endorsed(); n != 1 {
+		t.Fatalf("endorsed txs: %d, want 1", n)
+	}
+	if _, _, err := session.Send("putRecord", record2+";o1;c2;h2;100"); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	if n := sim.Endorsed(); n != 1 {
+		t.Errorf("endorsed txs after submit: %d, want 1", n)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if n := sim.Endorsed(); n != 0 {
+		t.Errorf("endorsed txs after ttl: %d, want 0", n)
+	}
+	if result, err := session.Query("getRecord", record2); err != nil || !strings.Contains(result, `"ciphertext":"c2"`) {
+		t.Errorf("query record: %s %v", result, err)
+	}
+}
+
+const serverConfig = `server:
+  listen: ":0"
+chain:
+  configFilePath: %s
+  contractName: finance
+  consensusNode: node-0.org1
+  queryNode: node-0.org1
+  chainID: chain
+  commitTimeout: 5s
+store:
+  driver: memory
+crypto:
+  kekFile: %s
+  key: 0123456789abcdef
+`
+
+func Test_Context(t *testing.T) {
+	session, config := newSession(t, startSimulator(t))
+	blockTool := utils.BlockTool{}
+	height, err := blockTool.QueryLastBlockNumber(session.Client, config)
+	if err != nil {
+		t.Fatalf("query last block number error: %v", err)
+	}
+
+	// 已取消的请求不背书也不提交交易，且不计为节点失败
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := session.SendCtx(ctx, "putRecord", record1+";o1;c1;h1;100"); err == nil {
+		t.Error("send with canceled context should fail")
+	}
+	if _, err := session.QueryCtx(ctx, "history", record1); err == nil {
+		t.Error("query with canceled context should fail")
+	}
+	if _, err := blockTool.QueryLastBlockNumberCtx(ctx, session.Client, config); err == nil {
+		t.Error("query last block number with canceled context should fail")
+	}
+	if latest, err := blockTool.QueryLastBlockNumber(session.Client, config); err != nil || latest != height {
+		t.Errorf("block number = %d, error %v, want %d", latest, err, height)
+	}
+	for _, health := range session.NodeHealth() {
+		if health.TotalFailures != 0 {
+			t.Errorf("canceled calls counted as node failure: %+v", health)
+		}
+	}
+
+	// 调用方的截止时间优先于SDK的超时时间
+	ctx, cancel = context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	time.Sleep(time.Millisecond)
+	if _, err := session.QueryCtx(ctx, "history", record1); err == nil || !strings.Contains(err.Error(), "deadline exceeded") {
+		t.Errorf("unexpected error with expired deadline: %v", err)
+	}
+	if _, _, err := session.SendCtx(context.Background(), "putRecord", record1+";o1;c1;h1;100"); err != nil {
+		t.Errorf("send error: %v", err)
+	}
+}
+
+func doJSON(r http.Handler, method string, path string, body interface{}) (int, response.Envelope) {
+	raw, _ := json.Marshal(body)
+	if body == nil {
+		raw = nil
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(raw))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var envelope response.Envelope
+	json.Unmarshal(w.Body.Bytes(), &envelope)
+	return w.Code, envelope
+}
+
+// 存证服务经由GatewayClient和模拟器完成新增、查询、修改、历史和撤销
+func Test_DepositService(t *testing.T) {
+	dir := t.TempDir()
+	serverPath, kekFile := filepath.Join(dir, "server.yaml"), filepath.Join(dir, "kek.json")
+	if err := ioutil.WriteFile(kekFile, []byte(`{"current":"k1","keys":{"k1":"AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE="}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(serverPath, []byte(fmt.Sprintf(serverConfig, startSimulator(t), kekFile)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := utils.LoadConfig(serverPath)
+	if err != nil {
+		t.Fatalf("load config error: %v", err)
+	}
+	session, _ := newSession(t, cfg.Chain.ConfigFilePath)
+	api.SetSession(session)
+	keyManager, err := envelope.LoadKeyFile(cfg.Crypto.KEKFile)
+	if err != nil {
+		t.Fatalf("load kek file error: %v", err)
+	}
+	dataCipher, err := envelope.New(keyManager, cfg.Crypto.Algorithm, cfg.Crypto.Key)
+	if err != nil {
+		t.Fatalf("init cipher error: %v", err)
+	}
+	api.SetCipher(dataCipher)
+	api.SetKeyLookup(controller.LookupKey)
+	controller.Store = store.NewMemory()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	routes.Load(r)
+
+	status, env := doJSON(r, http.MethodPost, "/api/v1/deposits", gin.H{"phone": "13800000000", "data": gin.H{"v": 1}})
+	if status != http.StatusCreated {
+		t.Fatalf("create deposit: %d %+v", status, env)
+	}
+	created := env.Data.(map[string]interface{})
+	status, env = doJSON(r, http.MethodGet, "/api/v1/deposits/"+created["txHash"].(string), nil)
+	if status != http.StatusOK || env.Data.(map[string]interface{})["Value"] != `{"v":1}` {
+		t.Errorf("get deposit: %d %+v", status, env)
+	}
+
+	status, env = doJSON(r, http.MethodPut, "/api/v1/deposits/"+created["txHash"].(string), gin.H{"phone": "13800000000", "data": gin.H{"v": 2}})
+	if status != http.StatusOK {
+		t.Fatalf("modify deposit: %d %+v", status, env)
+	}
+	modified := env.Data.(map[string]interface{})
+	status, env = doJSON(r, http.MethodGet, "/api/v1/deposits/"+modified["txHash"].(string)+"/history", nil)
+	if versions, _ := env.Data.(map[string]interface{})["versions"].([]interface{}); status != http.StatusOK || len(versions) != 2 ||
+		versions[1].(map[string]interface{})["txHash"] != modified["txHash"] {
+		t.Errorf("deposit history: %d %+v", status, env)
+	}
+
+	status, env = doJSON(r, http.MethodPost, "/api/v1/deposits/"+modified["txHash"].(string)+"/revoke", gin.H{"phone": "13800000000", "reason": "legal"})
+	if status != http.StatusOK {
+		t.Fatalf("revoke deposit: %d %+v", status, env)
+	}
+	status, env = doJSON(r, http.MethodGet, "/api/v1/deposits/"+modified["txHash"].(string), nil)
+	if status != http.StatusOK || env.Data.(map[string]interface{})["Revoked"] != true {
+		t.Errorf("get revoked deposit: %d %+v", status, env)
+	}
+	status, env = doJSON(r, http.MethodPut, "/api/v1/deposits/"+modified["txHash"].(string), gin.H{"phone": "13800000000", "data": gin.H{"v": 3}})
+	if status != http.StatusGone || env.Code != response.CodeRevoked {
+		t.Errorf("modify revoked deposit: %d %+v", status, env)
+	}
+}
+
+// 客户端身份的私钥加密存储或由远程签名服务持有时，通过WithSigner创建网关客户端，sdk.yaml中的私钥不再读取
+func Test_Signer(t *testing.T) {
+	configPath := startSimulator(t)
+	dir := filepath.Dir(configPath)
+	keyPath := filepath.Join(dir, "user.key")
+	keyPem, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		t.Fatalf("read key error: %v", err)
+	}
+	encrypted, err := crypto.EncryptPKCS8(keyPem, []byte("passphrase"))
+	if err != nil {
+		t.Fatalf("encrypt key error: %v", err)
+	}
+	encryptedPath := filepath.Join(dir, "user.enc.key")
+	if err := ioutil.WriteFile(encryptedPath, encrypted, 0600); err != nil {
+		t.Fatalf("write encrypted key error: %v", err)
+	}
+	if err := ioutil.WriteFile(keyPath, []byte("removed"), 0600); err != nil {
+		t.Fatalf("remove plain key error: %v", err)
+	}
+	signer, err := crypto.NewEncryptedKeySigner(cryptomgr.EcdsaWithSha256, encryptedPath, []byte("passphrase"))
+	if err != nil {
+		t.Fatalf("new encrypted key signer error: %v", err)
+	}
+
+	cfg, err := config.NewClientConfig(configPath)
+	if err != nil {
+		t.Fatalf("load sdk config error: %v", err)
+	}
+	if _, err := client.NewGatewayClientWithOptions(cfg); err == nil {
+		t.Error("expected error loading the removed key without signer")
+	}
+	gatewayClient, err := client.NewGatewayClientWithOptions(cfg, client.WithSigner(signer))
+	if err != nil {
+		t.Fatalf("new gateway client error: %v", err)
+	}
+	defer gatewayClient.Close()
+
+	appConfig := utils.Config{ContractName: "finance", ChainID: "chain", CommitTimeout: 5 * time.Second}
+	nodes, err := utils.NewNodes(gatewayClient, []string{"node-0.org1"}, "node-0.org1")
+	if err != nil {
+		t.Fatalf("new nodes error: %v", err)
+	}
+	if _, _, err := utils.Send(gatewayClient, nodes, appConfig, "putRecord", record1+";o1;c1;h1;100"); err != nil {
+		t.Fatalf("send with signer error: %v", err)
+	}
+	if result, err := utils.Query(gatewayClient, nodes, appConfig, "getRecord", record1); err != nil || !strings.Contains(result, `"ciphertext":"c1"`) {
+		t.Errorf("query record: %s %v", result, err)
+	}
+}
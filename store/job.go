@@ -0,0 +1,43 @@
+package store
+
+import (
+	"github.com/pkg/errors"
+)
+
+// 上链任务状态，落块后的状态直接使用common.TxStatus的名称，如 VALID、INVALID_MVCC
+const (
+	JobPending   = "pending"   // 已受理，等待提交
+	JobEndorsed  = "endorsed"  // 背书完成，已确定交易哈希
+	JobSubmitted = "submitted" // 已发送至共识节点，等待落块
+	JobValid     = "VALID"     // 落块且校验通过
+	JobFailed    = "failed"    // 落块前失败，如背书失败、等待超时
+)
+
+// ErrJobNotFound 上链任务不存在
+var ErrJobNotFound = errors.New("job not found")
+
+// Job 异步上链任务，Data为加密后的存证数据
+type Job struct {
+	ID      string `db:"id" json:"id"`
+	Phone   string `db:"phone" json:"phone"`
+	DataKey string `db:"datakey" json:"recordId"` // 存证ID
+	Data    string `db:"data" json:"-"`
+	// ContentHash 明文的SHA-256，随存证一起上链
+	ContentHash string `db:"contenthash" json:"-"`
+	TimeStamp   int64  `db:"timestamp" json:"timestamp"`
+	State       string `db:"state" json:"state"`
+	TxHash      string `db:"txhash" json:"txHash"`
+	Msg         string `db:"msg" json:"msg"`
+	// CallbackURL 任务结束后回调的地址，为空时不回调
+	CallbackURL string `db:"callbackurl" json:"callbackUrl,omitempty"`
+	UpdatedAt   int64  `db:"updatedat" json:"updatedAt"`
+}
+
+// Finished 任务是否已结束，结束的任务不会再被重试
+func (j *Job) Finished() bool {
+	switch j.State {
+	case JobPending, JobEndorsed, JobSubmitted:
+		return false
+	}
+	return true
+}
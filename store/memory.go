@@ -0,0 +1,215 @@
+package store
+
+import (
+	"sort"
+	"sync"
+)
+
+// memoryStore 内存存证存储，进程退出后数据丢失，用于本地调试和测试
+type memoryStore struct {
+	mu       sync.RWMutex
+	deposits []Deposit
+	index    map[string]int
+	jobs     map[string]Job
+	jobOrder []string
+	outbox   map[string]Delivery
+	rotation map[string]Rotation
+}
+
+// NewMemory 创建内存存证存储
+func NewMemory() DepositStore {
+	return &memoryStore{index: make(map[string]int), jobs: make(map[string]Job), outbox: make(map[string]Delivery),
+		rotation: make(map[string]Rotation)}
+}
+
+func (s *memoryStore) Insert(deposit Deposit) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i, ok := s.index[deposit.TxHash]; ok {
+		s.deposits[i] = deposit
+		return nil
+	}
+	s.index[deposit.TxHash] = len(s.deposits)
+	s.deposits = append(s.deposits, deposit)
+	return nil
+}
+
+func (s *memoryStore) FindByPhone(phone string, limit int) ([]Deposit, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var deposits []Deposit
+	for i := len(s.deposits) - 1; i >= 0; i-- {
+		if s.deposits[i].Phone == phone && !s.deposits[i].IsModify && !s.deposits[i].IsRevoked {
+			deposits = append(deposits, s.deposits[i])
+		}
+	}
+	sort.SliceStable(deposits, func(i, j int) bool {
+		return deposits[i].TimeStamp > deposits[j].TimeStamp
+	})
+	if limit > 0 && len(deposits) > limit {
+		deposits = deposits[:limit]
+	}
+	return deposits, nil
+}
+
+func (s *memoryStore) FindByTxHash(txHash string) (*Deposit, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i, ok := s.index[txHash]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	deposit := s.deposits[i]
+	return &deposit, nil
+}
+
+func (s *memoryStore) MarkModified(txHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i, ok := s.index[txHash]; ok {
+		s.deposits[i].IsModify = true
+	}
+	return nil
+}
+
+func (s *memoryStore) MarkRevoked(txHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i, ok := s.index[txHash]; ok {
+		s.deposits[i].IsRevoked = true
+	}
+	return nil
+}
+
+func (s *memoryStore) ListVersions(phone string) ([]Deposit, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var deposits []Deposit
+	for _, deposit := range s.deposits {
+		if deposit.Phone == phone {
+			deposits = append(deposits, deposit)
+		}
+	}
+	sort.SliceStable(deposits, func(i, j int) bool {
+		return deposits[i].TimeStamp < deposits[j].TimeStamp
+	})
+	return deposits, nil
+}
+
+func (s *memoryStore) ListActive(after string, limit int) ([]Deposit, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var deposits []Deposit
+	for _, deposit := range s.deposits {
+		if deposit.TxHash > after && !deposit.IsModify && !deposit.IsRevoked {
+			deposits = append(deposits, deposit)
+		}
+	}
+	sort.Slice(deposits, func(i, j int) bool {
+		return deposits[i].TxHash < deposits[j].TxHash
+	})
+	if limit > 0 && len(deposits) > limit {
+		deposits = deposits[:limit]
+	}
+	return deposits, nil
+}
+
+func (s *memoryStore) CountStale(keyID string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	count := 0
+	for _, deposit := range s.deposits {
+		if !deposit.IsModify && !deposit.IsRevoked && deposit.KeyID != keyID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *memoryStore) UpdateKey(txHash string, keyID string, wrappedKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i, ok := s.index[txHash]; ok {
+		s.deposits[i].KeyID = keyID
+		s.deposits[i].WrappedKey = wrappedKey
+	}
+	return nil
+}
+
+func (s *memoryStore) SaveJob(job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[job.ID]; !ok {
+		s.jobOrder = append(s.jobOrder, job.ID)
+	}
+	s.jobs[job.ID] = job
+	return nil
+}
+
+func (s *memoryStore) FindJob(id string) (*Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return &job, nil
+}
+
+func (s *memoryStore) ListUnfinishedJobs() ([]Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var jobs []Job
+	for _, id := range s.jobOrder {
+		if job := s.jobs[id]; !job.Finished() {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+func (s *memoryStore) SaveDelivery(delivery Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.outbox[delivery.ID] = delivery
+	return nil
+}
+
+func (s *memoryStore) ListDueDeliveries(now int64, limit int) ([]Delivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var deliveries []Delivery
+	for _, delivery := range s.outbox {
+		if delivery.State == DeliveryPending && delivery.NextAttempt <= now {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].NextAttempt < deliveries[j].NextAttempt
+	})
+	if limit > 0 && len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+func (s *memoryStore) SaveRotation(rotation Rotation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rotation[rotation.KeyID] = rotation
+	return nil
+}
+
+func (s *memoryStore) FindRotation(keyID string) (*Rotation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rotation, ok := s.rotation[keyID]
+	if !ok {
+		return nil, ErrRotationNotFound
+	}
+	return &rotation, nil
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
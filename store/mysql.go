@@ -0,0 +1,73 @@
+package store
+
+import (
+	// MySQL驱动
+	_ "github.com/go-sql-driver/mysql"
+)
+
+var mysqlSchema = []string{
+	`create table if not exists deposit(
+		txhash varchar(64) not null primary key,
+		phone varchar(20) not null,
+		timestamp bigint not null,
+		datakey varchar(64) not null default '',
+		ismodify tinyint(1) not null default 0,
+		keyid varchar(64) not null default '',
+		wrappedkey varchar(255) not null default '',
+		prevhash varchar(64) not null default '',
+		isrevoked tinyint(1) not null default 0,
+		index idx_deposit_phone(phone)
+	)`,
+	`create table if not exists job(
+		id varchar(32) not null primary key,
+		phone varchar(20) not null,
+		datakey varchar(64) not null,
+		data text not null,
+		contenthash varchar(64) not null default '',
+		timestamp bigint not null,
+		state varchar(32) not null,
+		txhash varchar(64) not null default '',
+		msg varchar(255) not null default '',
+		callbackurl varchar(1024) not null default '',
+		updatedat bigint not null,
+		index idx_job_state(state)
+	)`,
+	`create table if not exists outbox(
+		id varchar(32) not null primary key,
+		url varchar(1024) not null,
+		payload text not null,
+		state varchar(16) not null,
+		attempts int not null default 0,
+		nextattempt bigint not null,
+		lasterror varchar(255) not null default '',
+		updatedat bigint not null,
+		index idx_outbox_due(state,nextattempt)
+	)`,
+	`create table if not exists rotation(
+		keyid varchar(64) not null primary key,
+		reencrypt tinyint(1) not null default 0,
+		state varchar(16) not null,
+		lasttxhash varchar(64) not null default '',
+		total int not null default 0,
+		processed int not null default 0,
+		rewrapped int not null default 0,
+		reencrypted int not null default 0,
+		skipped int not null default 0,
+		failed int not null default 0,
+		lasterror varchar(255) not null default '',
+		updatedat bigint not null
+	)`,
+}
+
+var mysqlMigrations = []string{
+	`alter table deposit add column keyid varchar(64) not null default ''`,
+	`alter table deposit add column wrappedkey varchar(255) not null default ''`,
+	`alter table deposit add column prevhash varchar(64) not null default ''`,
+	`alter table job add column contenthash varchar(64) not null default ''`,
+	`alter table deposit add column isrevoked tinyint(1) not null default 0`,
+}
+
+// NewMySQL 创建MySQL存证存储，dsn形如 root:123456@tcp(127.0.0.1:3306)/credite
+func NewMySQL(dsn string) (DepositStore, error) {
+	return newSQLStore(DriverMySQL, dsn, mysqlSchema, mysqlMigrations, 0)
+}
@@ -0,0 +1,20 @@
+package store
+
+// 回调投递状态
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed" // 重试次数用尽
+)
+
+// Delivery 待投递的回调，NextAttempt为下次投递时间（Unix毫秒）
+type Delivery struct {
+	ID          string `db:"id"`
+	URL         string `db:"url"`
+	Payload     string `db:"payload"`
+	State       string `db:"state"`
+	Attempts    int    `db:"attempts"`
+	NextAttempt int64  `db:"nextattempt"`
+	LastError   string `db:"lasterror"`
+	UpdatedAt   int64  `db:"updatedat"`
+}
@@ -0,0 +1,32 @@
+package store
+
+import (
+	"github.com/pkg/errors"
+)
+
+// KEK轮换状态
+const (
+	RotationRunning = "running"
+	RotationDone    = "done"
+)
+
+// ErrRotationNotFound KEK轮换记录不存在
+var ErrRotationNotFound = errors.New("rotation not found")
+
+// Rotation 轮换到KeyID对应KEK的进度，按交易哈希顺序处理存证，LastTxHash为最后处理完成的交易哈希，中断后从其后继续
+type Rotation struct {
+	KeyID string `db:"keyid" json:"keyId"`
+	// Reencrypt 为true时重新加密存证并上链，否则仅重新包装本地索引中的数据密钥
+	Reencrypt   bool   `db:"reencrypt" json:"reencrypt"`
+	State       string `db:"state" json:"state"`
+	LastTxHash  string `db:"lasttxhash" json:"lastTxHash"`
+	Total       int    `db:"total" json:"total"`
+	Processed   int    `db:"processed" json:"processed"`
+	Rewrapped   int    `db:"rewrapped" json:"rewrapped"`
+	Reencrypted int    `db:"reencrypted" json:"reencrypted"`
+	// Skipped 旧版AES-ECB存证在仅重新包装时无法处理，需使用Reencrypt
+	Skipped   int    `db:"skipped" json:"skipped"`
+	Failed    int    `db:"failed" json:"failed"`
+	LastError string `db:"lasterror" json:"lastError,omitempty"`
+	UpdatedAt int64  `db:"updatedat" json:"updatedAt"`
+}
@@ -0,0 +1,218 @@
+package store
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// deposit表查询的字段
+const depositColumns = "txhash,phone,timestamp,datakey,ismodify,keyid,wrappedkey,prevhash,isrevoked"
+
+// job表查询的字段
+const jobColumns = "id,phone,datakey,data,contenthash,timestamp,state,txhash,msg,callbackurl,updatedat"
+
+// sqlStore 基于database/sql的存证存储，MySQL与SQLite共用
+type sqlStore struct {
+	db *sqlx.DB
+}
+
+// migrations为升级旧表结构的语句，字段已存在时忽略
+func newSQLStore(driver string, dsn string, schema []string, migrations []string, maxOpenConns int) (*sqlStore, error) {
+	db, err := sqlx.Open(driver, dsn)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "open %s deposit store error", driver)
+	}
+	db.SetMaxOpenConns(maxOpenConns)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, errors.WithMessagef(err, "ping %s deposit store error", driver)
+	}
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, errors.WithMessage(err, "init deposit tables error")
+		}
+	}
+	for _, stmt := range migrations {
+		if _, err := db.Exec(stmt); err != nil && !strings.Contains(strings.ToLower(err.Error()), "duplicate column") {
+			db.Close()
+			return nil, errors.WithMessage(err, "migrate deposit tables error")
+		}
+	}
+	return &sqlStore{db: db}, nil
+}
+
+func (s *sqlStore) Insert(deposit Deposit) error {
+	_, err := s.db.Exec("insert into deposit("+depositColumns+")values(?,?,?,?,?,?,?,?,?)",
+		deposit.TxHash, deposit.Phone, deposit.TimeStamp, deposit.DataKey, deposit.IsModify, deposit.KeyID, deposit.WrappedKey, deposit.PrevHash,
+		deposit.IsRevoked)
+	if err != nil {
+		return errors.WithMessage(err, "insert deposit error")
+	}
+	return nil
+}
+
+func (s *sqlStore) FindByPhone(phone string, limit int) ([]Deposit, error) {
+	query := "select " + depositColumns + " from deposit where phone=? AND ismodify=? AND isrevoked=? order by timestamp Desc"
+	args := []interface{}{phone, false, false}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+	var deposits []Deposit
+	if err := s.db.Select(&deposits, query, args...); err != nil {
+		return nil, errors.WithMessage(err, "select deposit by phone error")
+	}
+	return deposits, nil
+}
+
+func (s *sqlStore) FindByTxHash(txHash string) (*Deposit, error) {
+	deposit := &Deposit{}
+	err := s.db.Get(deposit, "select "+depositColumns+" from deposit where txhash=?", txHash)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.WithMessage(err, "select deposit by txhash error")
+	}
+	return deposit, nil
+}
+
+func (s *sqlStore) MarkModified(txHash string) error {
+	if _, err := s.db.Exec("update deposit set ismodify=? where txhash=?", true, txHash); err != nil {
+		return errors.WithMessage(err, "update deposit error")
+	}
+	return nil
+}
+
+func (s *sqlStore) MarkRevoked(txHash string) error {
+	if _, err := s.db.Exec("update deposit set isrevoked=? where txhash=?", true, txHash); err != nil {
+		return errors.WithMessage(err, "update deposit error")
+	}
+	return nil
+}
+
+func (s *sqlStore) ListVersions(phone string) ([]Deposit, error) {
+	var deposits []Deposit
+	err := s.db.Select(&deposits,
+		"select "+depositColumns+" from deposit where phone=? order by timestamp Asc", phone)
+	if err != nil {
+		return nil, errors.WithMessage(err, "select deposit versions error")
+	}
+	return deposits, nil
+}
+
+func (s *sqlStore) ListActive(after string, limit int) ([]Deposit, error) {
+	query := "select " + depositColumns + " from deposit where txhash>? AND ismodify=? AND isrevoked=? order by txhash Asc"
+	args := []interface{}{after, false, false}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+	var deposits []Deposit
+	if err := s.db.Select(&deposits, query, args...); err != nil {
+		return nil, errors.WithMessage(err, "select active deposits error")
+	}
+	return deposits, nil
+}
+
+func (s *sqlStore) CountStale(keyID string) (int, error) {
+	var count int
+	if err := s.db.Get(&count, "select count(*) from deposit where ismodify=? AND isrevoked=? AND keyid<>?", false, false, keyID); err != nil {
+		return 0, errors.WithMessage(err, "count stale deposits error")
+	}
+	return count, nil
+}
+
+func (s *sqlStore) UpdateKey(txHash string, keyID string, wrappedKey string) error {
+	if _, err := s.db.Exec("update deposit set keyid=?,wrappedkey=? where txhash=?", keyID, wrappedKey, txHash); err != nil {
+		return errors.WithMessage(err, "update deposit key error")
+	}
+	return nil
+}
+
+func (s *sqlStore) SaveJob(job Job) error {
+	_, err := s.db.Exec("replace into job("+jobColumns+")values(?,?,?,?,?,?,?,?,?,?,?)",
+		job.ID, job.Phone, job.DataKey, job.Data, job.ContentHash, job.TimeStamp, job.State, job.TxHash, job.Msg, job.CallbackURL, job.UpdatedAt)
+	if err != nil {
+		return errors.WithMessage(err, "save job error")
+	}
+	return nil
+}
+
+func (s *sqlStore) FindJob(id string) (*Job, error) {
+	job := &Job{}
+	err := s.db.Get(job, "select "+jobColumns+" from job where id=?", id)
+	if err == sql.ErrNoRows {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, errors.WithMessage(err, "select job error")
+	}
+	return job, nil
+}
+
+func (s *sqlStore) ListUnfinishedJobs() ([]Job, error) {
+	var jobs []Job
+	err := s.db.Select(&jobs, "select "+jobColumns+" from job where state in (?,?,?) order by timestamp Asc",
+		JobPending, JobEndorsed, JobSubmitted)
+	if err != nil {
+		return nil, errors.WithMessage(err, "select unfinished jobs error")
+	}
+	return jobs, nil
+}
+
+func (s *sqlStore) SaveDelivery(delivery Delivery) error {
+	_, err := s.db.Exec("replace into outbox(id,url,payload,state,attempts,nextattempt,lasterror,updatedat)values(?,?,?,?,?,?,?,?)",
+		delivery.ID, delivery.URL, delivery.Payload, delivery.State, delivery.Attempts, delivery.NextAttempt, delivery.LastError, delivery.UpdatedAt)
+	if err != nil {
+		return errors.WithMessage(err, "save delivery error")
+	}
+	return nil
+}
+
+func (s *sqlStore) ListDueDeliveries(now int64, limit int) ([]Delivery, error) {
+	query := "select id,url,payload,state,attempts,nextattempt,lasterror,updatedat from outbox where state=? AND nextattempt<=? order by nextattempt Asc"
+	args := []interface{}{DeliveryPending, now}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+	var deliveries []Delivery
+	if err := s.db.Select(&deliveries, query, args...); err != nil {
+		return nil, errors.WithMessage(err, "select due deliveries error")
+	}
+	return deliveries, nil
+}
+
+// rotation表字段
+const rotationColumns = "keyid,reencrypt,state,lasttxhash,total,processed,rewrapped,reencrypted,skipped,failed,lasterror,updatedat"
+
+func (s *sqlStore) SaveRotation(rotation Rotation) error {
+	_, err := s.db.Exec("replace into rotation("+rotationColumns+")values(?,?,?,?,?,?,?,?,?,?,?,?)",
+		rotation.KeyID, rotation.Reencrypt, rotation.State, rotation.LastTxHash, rotation.Total, rotation.Processed,
+		rotation.Rewrapped, rotation.Reencrypted, rotation.Skipped, rotation.Failed, rotation.LastError, rotation.UpdatedAt)
+	if err != nil {
+		return errors.WithMessage(err, "save rotation error")
+	}
+	return nil
+}
+
+func (s *sqlStore) FindRotation(keyID string) (*Rotation, error) {
+	rotation := &Rotation{}
+	err := s.db.Get(rotation, "select "+rotationColumns+" from rotation where keyid=?", keyID)
+	if err == sql.ErrNoRows {
+		return nil, ErrRotationNotFound
+	}
+	if err != nil {
+		return nil, errors.WithMessage(err, "select rotation error")
+	}
+	return rotation, nil
+}
+
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
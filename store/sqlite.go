@@ -0,0 +1,74 @@
+package store
+
+import (
+	// SQLite驱动
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var sqliteSchema = []string{
+	`create table if not exists deposit(
+		txhash text not null primary key,
+		phone text not null,
+		timestamp integer not null,
+		datakey text not null default '',
+		ismodify boolean not null default 0,
+		keyid text not null default '',
+		wrappedkey text not null default '',
+		prevhash text not null default '',
+		isrevoked boolean not null default 0
+	)`,
+	`create index if not exists idx_deposit_phone on deposit(phone)`,
+	`create table if not exists job(
+		id text not null primary key,
+		phone text not null,
+		datakey text not null,
+		data text not null,
+		contenthash text not null default '',
+		timestamp integer not null,
+		state text not null,
+		txhash text not null default '',
+		msg text not null default '',
+		callbackurl text not null default '',
+		updatedat integer not null
+	)`,
+	`create index if not exists idx_job_state on job(state)`,
+	`create table if not exists outbox(
+		id text not null primary key,
+		url text not null,
+		payload text not null,
+		state text not null,
+		attempts integer not null default 0,
+		nextattempt integer not null,
+		lasterror text not null default '',
+		updatedat integer not null
+	)`,
+	`create index if not exists idx_outbox_due on outbox(state,nextattempt)`,
+	`create table if not exists rotation(
+		keyid text not null primary key,
+		reencrypt boolean not null default 0,
+		state text not null,
+		lasttxhash text not null default '',
+		total integer not null default 0,
+		processed integer not null default 0,
+		rewrapped integer not null default 0,
+		reencrypted integer not null default 0,
+		skipped integer not null default 0,
+		failed integer not null default 0,
+		lasterror text not null default '',
+		updatedat integer not null
+	)`,
+}
+
+var sqliteMigrations = []string{
+	`alter table deposit add column keyid text not null default ''`,
+	`alter table deposit add column wrappedkey text not null default ''`,
+	`alter table deposit add column prevhash text not null default ''`,
+	`alter table job add column contenthash text not null default ''`,
+	`alter table deposit add column isrevoked boolean not null default 0`,
+}
+
+// NewSQLite 创建SQLite存证存储，dsn为数据库文件路径，":memory:"表示内存数据库
+func NewSQLite(dsn string) (DepositStore, error) {
+	// SQLite不支持并发写，且":memory:"数据库按连接隔离，因此只保留一个连接
+	return newSQLStore(DriverSQLite, dsn, sqliteSchema, sqliteMigrations, 1)
+}
@@ -0,0 +1,100 @@
+// Package store 存证本地索引存储，记录手机号与交易哈希之间的对应关系、异步上链任务、待投递的回调和KEK轮换进度
+package store
+
+import (
+	"github.com/pkg/errors"
+)
+
+// 支持的存储驱动
+const (
+	DriverMySQL  = "mysql"
+	DriverSQLite = "sqlite3"
+	DriverMemory = "memory"
+)
+
+// ErrNotFound 存证记录不存在
+var ErrNotFound = errors.New("deposit not found")
+
+// Deposit 存证结构
+type Deposit struct {
+	TxHash    string `db:"txhash"`
+	Phone     string `db:"phone"`
+	TimeStamp int64  `db:"timestamp"`
+	IsModify  bool   `db:"ismodify"`
+	DataKey   string `db:"datakey"`
+	// KeyID 包装数据密钥的KEK标识，旧版AES-ECB存证为空
+	KeyID string `db:"keyid"`
+	// WrappedKey 包装后的数据密钥（base64），轮换KEK时只需更新此字段
+	WrappedKey string `db:"wrappedkey"`
+	// PrevHash 上一版本的交易哈希，由修改或重新加密产生的记录不为空
+	PrevHash string `db:"prevhash"`
+	// IsRevoked 存证已在链上撤销，撤销的是存证ID，因此只标记最新版本
+	IsRevoked bool `db:"isrevoked"`
+}
+
+// DepositStore 存证索引存储接口
+type DepositStore interface {
+	// Insert 新增一条存证记录
+	Insert(deposit Deposit) error
+
+	// FindByPhone 按时间倒序查询手机号下未被修改且未撤销的存证，limit<=0时不限制条数
+	FindByPhone(phone string, limit int) ([]Deposit, error)
+
+	// FindByTxHash 根据交易哈希查询存证，记录不存在时返回ErrNotFound
+	FindByTxHash(txHash string) (*Deposit, error)
+
+	// MarkModified 将交易哈希对应的存证标记为已修改
+	MarkModified(txHash string) error
+
+	// MarkRevoked 将交易哈希对应的存证标记为已撤销
+	MarkRevoked(txHash string) error
+
+	// ListVersions 按时间顺序列出手机号下的全部存证，包括已被修改的版本
+	ListVersions(phone string) ([]Deposit, error)
+
+	// ListActive 按交易哈希顺序列出大于after且未被修改、未撤销的存证，用于分批遍历，limit<=0时不限制条数
+	ListActive(after string, limit int) ([]Deposit, error)
+
+	// CountStale 未被修改、未撤销且KEK标识不是keyID的存证数量，包括旧版AES-ECB存证
+	CountStale(keyID string) (int, error)
+
+	// UpdateKey 更新存证的KEK标识和包装后的数据密钥
+	UpdateKey(txHash string, keyID string, wrappedKey string) error
+
+	// SaveJob 新增或整体更新一条上链任务
+	SaveJob(job Job) error
+
+	// FindJob 根据任务ID查询，任务不存在时返回ErrJobNotFound
+	FindJob(id string) (*Job, error)
+
+	// ListUnfinishedJobs 按受理时间顺序列出尚未结束的任务，用于重启后恢复
+	ListUnfinishedJobs() ([]Job, error)
+
+	// SaveDelivery 新增或整体更新一条回调投递记录
+	SaveDelivery(delivery Delivery) error
+
+	// ListDueDeliveries 按投递时间顺序列出到期（NextAttempt<=now）且未结束的回调，limit<=0时不限制条数
+	ListDueDeliveries(now int64, limit int) ([]Delivery, error)
+
+	// SaveRotation 新增或整体更新KEK轮换进度
+	SaveRotation(rotation Rotation) error
+
+	// FindRotation 查询轮换到keyID的进度，记录不存在时返回ErrRotationNotFound
+	FindRotation(keyID string) (*Rotation, error)
+
+	// Close 释放底层连接
+	Close() error
+}
+
+// New 根据驱动名称创建存证索引存储
+func New(driver string, dsn string) (DepositStore, error) {
+	switch driver {
+	case DriverMySQL:
+		return NewMySQL(dsn)
+	case DriverSQLite, "sqlite":
+		return NewSQLite(dsn)
+	case DriverMemory, "":
+		return NewMemory(), nil
+	}
+	return nil, errors.Errorf("unsupported deposit store driver: %s", driver)
+}
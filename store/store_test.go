@@ -0,0 +1,252 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func testStores(t *testing.T) map[string]DepositStore {
+	sqlite, err := NewSQLite(":memory:")
+	if err != nil {
+		t.Fatalf("new sqlite store error: %v", err)
+	}
+	return map[string]DepositStore{
+		DriverMemory: NewMemory(),
+		DriverSQLite: sqlite,
+	}
+}
+
+func Test_DepositStore(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			defer s.Close()
+			deposits := []Deposit{
+				{TxHash: "h1", Phone: "13800000000", TimeStamp: 1, DataKey: "k1"},
+				{TxHash: "h2", Phone: "13800000000", TimeStamp: 2, DataKey: "k2", KeyID: "kek1", WrappedKey: "w2"},
+				{TxHash: "h3", Phone: "13800000000", TimeStamp: 3, DataKey: "k3"},
+				{TxHash: "h4", Phone: "13900000000", TimeStamp: 4, DataKey: "k4"},
+			}
+			for _, d := range deposits {
+				if err := s.Insert(d); err != nil {
+					t.Fatalf("insert error: %v", err)
+				}
+			}
+			if err := s.MarkModified("h2"); err != nil {
+				t.Fatalf("mark modified error: %v", err)
+			}
+
+			found, err := s.FindByPhone("13800000000", 3)
+			if err != nil {
+				t.Fatalf("find by phone error: %v", err)
+			}
+			if len(found) != 2 || found[0].TxHash != "h3" || found[1].TxHash != "h1" {
+				t.Errorf("unexpected find by phone result: %v", found)
+			}
+
+			d, err := s.FindByTxHash("h2")
+			if err != nil {
+				t.Fatalf("find by txhash error: %v", err)
+			}
+			if !d.IsModify || d.DataKey != "k2" || d.KeyID != "kek1" || d.WrappedKey != "w2" {
+				t.Errorf("unexpected deposit: %v", d)
+			}
+			if _, err := s.FindByTxHash("missing"); err != ErrNotFound {
+				t.Errorf("expected ErrNotFound, got %v", err)
+			}
+
+			versions, err := s.ListVersions("13800000000")
+			if err != nil {
+				t.Fatalf("list versions error: %v", err)
+			}
+			if len(versions) != 3 || versions[0].TxHash != "h1" || versions[2].TxHash != "h3" {
+				t.Errorf("unexpected versions: %v", versions)
+			}
+
+			active, err := s.ListActive("h1", 1)
+			if err != nil {
+				t.Fatalf("list active error: %v", err)
+			}
+			if len(active) != 1 || active[0].TxHash != "h3" {
+				t.Errorf("unexpected active deposits: %v", active)
+			}
+			if count, err := s.CountStale("kek1"); err != nil || count != 3 {
+				t.Errorf("unexpected stale count: %d %v", count, err)
+			}
+			if err := s.UpdateKey("h3", "kek2", "w3"); err != nil {
+				t.Fatalf("update key error: %v", err)
+			}
+			if d, _ := s.FindByTxHash("h3"); d.KeyID != "kek2" || d.WrappedKey != "w3" {
+				t.Errorf("key not updated: %v", d)
+			}
+			if count, _ := s.CountStale("kek2"); count != 2 {
+				t.Errorf("updated deposit should not be stale, count %d", count)
+			}
+
+			//撤销后不再出现在手机号查询和待轮换列表中，历史版本仍保留
+			if err := s.MarkRevoked("h1"); err != nil {
+				t.Fatalf("mark revoked error: %v", err)
+			}
+			if d, _ := s.FindByTxHash("h1"); !d.IsRevoked {
+				t.Errorf("deposit not revoked: %v", d)
+			}
+			if found, _ := s.FindByPhone("13800000000", 0); len(found) != 1 || found[0].TxHash != "h3" {
+				t.Errorf("revoked deposit still listed: %v", found)
+			}
+			if active, _ := s.ListActive("", 0); len(active) != 2 {
+				t.Errorf("unexpected active deposits after revoke: %v", active)
+			}
+			if count, _ := s.CountStale("kek2"); count != 1 {
+				t.Errorf("revoked deposit should not be stale, count %d", count)
+			}
+			if versions, _ := s.ListVersions("13800000000"); len(versions) != 3 {
+				t.Errorf("revoked deposit missing from versions: %v", versions)
+			}
+		})
+	}
+}
+
+func Test_JobStore(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			defer s.Close()
+			jobs := []Job{
+				{ID: "j1", Phone: "13800000000", DataKey: "k1", Data: "d1", TimeStamp: 1, State: JobPending},
+				{ID: "j2", Phone: "13800000000", DataKey: "k2", Data: "d2", TimeStamp: 2, State: JobPending},
+				{ID: "j3", Phone: "13900000000", DataKey: "k3", Data: "d3", TimeStamp: 3, State: JobPending},
+			}
+			for _, j := range jobs {
+				if err := s.SaveJob(j); err != nil {
+					t.Fatalf("save job error: %v", err)
+				}
+			}
+			jobs[0].State, jobs[0].TxHash = JobValid, "h1"
+			jobs[1].State, jobs[1].TxHash = JobSubmitted, "h2"
+			for _, j := range jobs[:2] {
+				if err := s.SaveJob(j); err != nil {
+					t.Fatalf("update job error: %v", err)
+				}
+			}
+
+			j, err := s.FindJob("j1")
+			if err != nil {
+				t.Fatalf("find job error: %v", err)
+			}
+			if j.State != JobValid || j.TxHash != "h1" || j.Data != "d1" {
+				t.Errorf("unexpected job: %v", j)
+			}
+			if _, err := s.FindJob("missing"); err != ErrJobNotFound {
+				t.Errorf("expected ErrJobNotFound, got %v", err)
+			}
+
+			unfinished, err := s.ListUnfinishedJobs()
+			if err != nil {
+				t.Fatalf("list unfinished jobs error: %v", err)
+			}
+			if len(unfinished) != 2 || unfinished[0].ID != "j2" || unfinished[1].ID != "j3" {
+				t.Errorf("unexpected unfinished jobs: %v", unfinished)
+			}
+		})
+	}
+}
+
+func Test_Outbox(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			defer s.Close()
+			deliveries := []Delivery{
+				{ID: "d1", URL: "http://a", Payload: "{}", State: DeliveryPending, NextAttempt: 30},
+				{ID: "d2", URL: "http://b", Payload: "{}", State: DeliveryPending, NextAttempt: 10},
+				{ID: "d3", URL: "http://c", Payload: "{}", State: DeliveryPending, NextAttempt: 100},
+				{ID: "d4", URL: "http://d", Payload: "{}", State: DeliveryDelivered, NextAttempt: 0},
+			}
+			for _, d := range deliveries {
+				if err := s.SaveDelivery(d); err != nil {
+					t.Fatalf("save delivery error: %v", err)
+				}
+			}
+			due, err := s.ListDueDeliveries(50, 0)
+			if err != nil {
+				t.Fatalf("list due deliveries error: %v", err)
+			}
+			if len(due) != 2 || due[0].ID != "d2" || due[1].ID != "d1" {
+				t.Errorf("unexpected due deliveries: %v", due)
+			}
+
+			deliveries[1].State, deliveries[1].Attempts = DeliveryFailed, 3
+			if err := s.SaveDelivery(deliveries[1]); err != nil {
+				t.Fatalf("update delivery error: %v", err)
+			}
+			due, err = s.ListDueDeliveries(200, 1)
+			if err != nil {
+				t.Fatalf("list due deliveries error: %v", err)
+			}
+			if len(due) != 1 || due[0].ID != "d1" {
+				t.Errorf("unexpected due deliveries with limit: %v", due)
+			}
+		})
+	}
+}
+
+func Test_RotationStore(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			defer s.Close()
+			if _, err := s.FindRotation("kek2"); err != ErrRotationNotFound {
+				t.Fatalf("expected ErrRotationNotFound, got %v", err)
+			}
+			rotation := Rotation{KeyID: "kek2", State: RotationRunning, Total: 3, UpdatedAt: 1}
+			if err := s.SaveRotation(rotation); err != nil {
+				t.Fatalf("save rotation error: %v", err)
+			}
+			rotation.LastTxHash = "h2"
+			rotation.Processed = 2
+			rotation.Rewrapped = 1
+			rotation.Skipped = 1
+			if err := s.SaveRotation(rotation); err != nil {
+				t.Fatalf("update rotation error: %v", err)
+			}
+			found, err := s.FindRotation("kek2")
+			if err != nil || *found != rotation {
+				t.Errorf("unexpected rotation: %+v %v", found, err)
+			}
+		})
+	}
+}
+
+// 旧版deposit表缺少的字段在打开时自动补齐
+func Test_SQLiteMigration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "deposit.db")
+	db, err := sqlx.Open(DriverSQLite, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`create table deposit(txhash text primary key, phone text, timestamp integer, datakey text, ismodify boolean)`)
+	if err == nil {
+		_, err = db.Exec(`insert into deposit values('h1','13800000000',1,'k1',0)`)
+	}
+	db.Close()
+	if err != nil {
+		t.Fatalf("create legacy table error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		s, err := NewSQLite(path)
+		if err != nil {
+			t.Fatalf("open legacy sqlite store error: %v", err)
+		}
+		d, err := s.FindByTxHash("h1")
+		if err != nil || d.KeyID != "" || d.PrevHash != "" || d.IsRevoked {
+			t.Errorf("unexpected legacy deposit: %v %v", d, err)
+		}
+		s.Close()
+	}
+}
@@ -0,0 +1,182 @@
+package usercontract
+
+import (
+	"encoding/json"
+
+	"git.huawei.com/poissonsearch/wienerchain/contract/docker-container/contract-go/contractapi"
+	"github.com/pkg/errors"
+)
+
+// ACL在状态数据库中的key
+const aclKey = internalPrefix + "acl"
+
+// 权限名称
+const (
+	PermAdmin  = "admin"  // 管理ACL
+	PermWrite  = "write"  // 新增存证
+	PermModify = "modify" // 修改存证
+	PermRevoke = "revoke" // 撤销存证
+)
+
+// ACL 各权限允许的组织，组织为交易发起者证书中的O
+type ACL struct {
+	Admin  []string `json:"admin"`
+	Write  []string `json:"write"`
+	Modify []string `json:"modify"`
+	Revoke []string `json:"revoke"`
+}
+
+// 权限对应的组织列表，权限名称无效时返回nil
+func (a *ACL) orgs(perm string) *[]string {
+	switch perm {
+	case PermAdmin:
+		return &a.Admin
+	case PermWrite:
+		return &a.Write
+	case PermModify:
+		return &a.Modify
+	case PermRevoke:
+		return &a.Revoke
+	}
+	return nil
+}
+
+// Allowed 组织是否拥有指定权限
+func (a *ACL) Allowed(perm string, org string) bool {
+	orgs := a.orgs(perm)
+	if orgs == nil {
+		return false
+	}
+	for _, o := range *orgs {
+		if o == org {
+			return true
+		}
+	}
+	return false
+}
+
+func getACL(stub contractapi.ContractStub) (*ACL, error) {
+	value, err := stub.GetKV(aclKey)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get acl error")
+	}
+	if value == nil {
+		return nil, nil
+	}
+	acl := &ACL{}
+	if err := json.Unmarshal(value, acl); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal acl error")
+	}
+	return acl, nil
+}
+
+func putACL(stub contractapi.ContractStub, acl *ACL) ([]byte, error) {
+	value, err := json.Marshal(acl)
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal acl error")
+	}
+	if err := stub.PutKV(aclKey, value); err != nil {
+		return nil, errors.WithMessage(err, "put acl error")
+	}
+	return value, nil
+}
+
+// 校验交易发起者所属组织拥有指定权限，返回发起者身份；未初始化ACL时拒绝所有写操作
+func checkPermission(stub contractapi.ContractStub, perm string) (*contractapi.Identity, error) {
+	creator, err := stub.Creator()
+	if err != nil {
+		return nil, errors.WithMessage(err, "get creator error")
+	}
+	acl, err := getACL(stub)
+	if err != nil {
+		return nil, err
+	}
+	if acl == nil {
+		return nil, errors.New("acl is not initialized, invoke init first")
+	}
+	if !acl.Allowed(perm, creator.Org) {
+		return nil, errors.Errorf("org %s has no %s permission", creator.Org, perm)
+	}
+	return creator, nil
+}
+
+// 初始化ACL，发起者所属组织获得全部权限；ACL已存在时保持不变
+func initACL(stub contractapi.ContractStub) ([]byte, error) {
+	acl, err := getACL(stub)
+	if err != nil || acl != nil {
+		return nil, err
+	}
+	creator, err := stub.Creator()
+	if err != nil {
+		return nil, errors.WithMessage(err, "get creator error")
+	}
+	if creator.Org == "" {
+		return nil, errors.New("creator org is empty")
+	}
+	org := []string{creator.Org}
+	return putACL(stub, &ACL{Admin: org, Write: org, Modify: org, Revoke: org})
+}
+
+// 查询ACL
+func queryACL(stub contractapi.ContractStub, args [][]byte) ([]byte, error) {
+	if len(args) != 0 {
+		return nil, errors.New("the argNum for queryACL is not correct,expected 0")
+	}
+	acl, err := getACL(stub)
+	if err != nil {
+		return nil, err
+	}
+	if acl == nil {
+		acl = &ACL{}
+	}
+	return json.Marshal(acl)
+}
+
+// 为组织授予权限，参数为 权限名称、组织，仅admin组织可调用
+func grantOrg(stub contractapi.ContractStub, args [][]byte) ([]byte, error) {
+	return updateACL(stub, args, "grantOrg", func(orgs []string, org string) []string {
+		for _, o := range orgs {
+			if o == org {
+				return orgs
+			}
+		}
+		return append(orgs, org)
+	})
+}
+
+// 撤销组织的权限，参数为 权限名称、组织，仅admin组织可调用，至少保留一个admin组织
+func removeOrg(stub contractapi.ContractStub, args [][]byte) ([]byte, error) {
+	return updateACL(stub, args, "removeOrg", func(orgs []string, org string) []string {
+		result := make([]string, 0, len(orgs))
+		for _, o := range orgs {
+			if o != org {
+				result = append(result, o)
+			}
+		}
+		return result
+	})
+}
+
+func updateACL(stub contractapi.ContractStub, args [][]byte, funcName string,
+	update func(orgs []string, org string) []string) ([]byte, error) {
+	if len(args) != 2 || len(args[0]) == 0 || len(args[1]) == 0 {
+		return nil, errors.Errorf("the argNum for %s is not correct,expected 2", funcName)
+	}
+	if _, err := checkPermission(stub, PermAdmin); err != nil {
+		return nil, err
+	}
+	acl, err := getACL(stub)
+	if err != nil {
+		return nil, err
+	}
+	perm, org := string(args[0]), string(args[1])
+	orgs := acl.orgs(perm)
+	if orgs == nil {
+		return nil, errors.Errorf("invalid permission: %s", perm)
+	}
+	*orgs = update(*orgs, org)
+	if len(acl.Admin) == 0 {
+		return nil, errors.New("at least one admin org is required")
+	}
+	return putACL(stub, acl)
+}
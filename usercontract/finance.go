@@ -1,6 +1,9 @@
 package usercontract
 
 import (
+	"encoding/hex"
+	"encoding/json"
+
 	"git.huawei.com/poissonsearch/wienerchain/contract/docker-container/contract-go/contractapi"
 	"github.com/pkg/errors"
 )
@@ -14,9 +17,10 @@ func NewSmartContract() contractapi.Contract {
 	return &FinanceInterface{}
 }
 
-// 合约的初始化接口，合约启动的时候，首先执行只需要执行一次的逻辑方法放入到此方法中
-func (f *FinanceInterface) Init(_ contractapi.ContractStub) ([]byte, error) {
-	return nil, nil
+// 合约的初始化接口，合约启动的时候，首先执行只需要执行一次的逻辑方法放入到此方法中；
+// 首次初始化时发起者所属组织获得全部权限
+func (f *FinanceInterface) Init(stub contractapi.ContractStub) ([]byte, error) {
+	return initACL(stub)
 }
 
 // 合约被调用的接口，将主要的合约执行逻辑，放到此方法中
@@ -27,18 +31,43 @@ func (f *FinanceInterface) Invoke(stub contractapi.ContractStub) ([]byte, error)
 	switch funcName {
 	case "saveRecode":
 		return saveRecode(stub, args)
+	case "putRecord":
+		return putRecord(stub, args)
+	case "getRecord":
+		return getRecord(stub, args)
+	case "listByOwner":
+		return listByOwner(stub, args)
+	case "rangeByTime":
+		return rangeByTime(stub, args)
+	case "history":
+		return history(stub, args)
+	case "revokeRecode":
+		return revokeRecode(stub, args)
+	case "queryRevocation":
+		return queryRevocation(stub, args)
+	case "queryACL":
+		return queryACL(stub, args)
+	case "grantOrg":
+		return grantOrg(stub, args)
+	case "removeOrg":
+		return removeOrg(stub, args)
 	}
 	return nil, errors.Errorf("func name is not correct, the function name is %s ", funcName)
 }
 
-// 保存数据
+// 保存数据，旧版接口，value为密文，新数据使用putRecord
 func saveRecode(stub contractapi.ContractStub, args [][]byte) ([]byte, error) {
 	if len(args) != 2 {
 		return nil, errors.New("the argNum for saveRecode is not correct,expected 2")
 	}
+	if err := checkRecordID(string(args[0])); err != nil {
+		return nil, err
+	}
+	if _, err := checkPermission(stub, PermWrite); err != nil {
+		return nil, err
+	}
 	key := args[0]
 	value := args[1]
-	
 
 	err := stub.PutKV(string(key), value)
 	if err != nil {
@@ -46,3 +75,39 @@ func saveRecode(stub contractapi.ContractStub, args [][]byte) ([]byte, error) {
 	}
 	return nil, nil
 }
+
+// Version 存证的一个历史版本
+type Version struct {
+	Value     string `json:"value"`     // 该版本写入的值，结构化存证为Record的JSON，删除时为空
+	TxHash    string `json:"txHash"`    // 写入该版本的交易哈希
+	BlockNum  uint64 `json:"blockNum"`  // 交易所在区块号
+	TxNum     int32  `json:"txNum"`     // 交易在区块中的序号
+	Timestamp uint64 `json:"timestamp"` // 交易时间戳
+	IsDeleted bool   `json:"isDeleted"` // 该版本是否为删除操作
+}
+
+// 查询存证的全部历史版本，参数为存证ID，按写入顺序返回JSON数组
+func history(stub contractapi.ContractStub, args [][]byte) ([]byte, error) {
+	if len(args) != 1 {
+		return nil, errors.New("the argNum for history is not correct,expected 1")
+	}
+	iter, err := stub.GetKeyHistoryIterator(string(args[0]))
+	if err != nil {
+		return nil, errors.WithMessage(err, "get key history iterator error")
+	}
+	defer iter.Close()
+
+	versions := []Version{}
+	for iter.Next() {
+		blockNum, txNum := iter.Version()
+		versions = append(versions, Version{
+			Value:     string(iter.Value()),
+			TxHash:    hex.EncodeToString(iter.TxHash()),
+			BlockNum:  blockNum,
+			TxNum:     txNum,
+			Timestamp: iter.Timestamp(),
+			IsDeleted: iter.IsDeleted(),
+		})
+	}
+	return json.Marshal(versions)
+}
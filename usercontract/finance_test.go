@@ -0,0 +1,310 @@
+package usercontract
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"git.huawei.com/poissonsearch/wienerchain/contract/docker-container/contract-go/contractapi"
+	ctesting "git.huawei.com/poissonsearch/wienerchain/contract/docker-container/contract-go/contractapi/testing"
+)
+
+var (
+	org1 = &contractapi.Identity{Org: "org1", CommonName: "user1"}
+	org2 = &contractapi.Identity{Org: "org2", CommonName: "user2"}
+)
+
+// 存证ID为64位十六进制，按序号生成便于比较索引顺序
+var r1, r2, r3 = recordID(1), recordID(2), recordID(3)
+
+func recordID(n int) string {
+	return fmt.Sprintf("%064x", n)
+}
+
+// 合约调用步骤，每步之后出块
+type step struct {
+	caller *contractapi.Identity
+	fn     string
+	args   []string
+	err    string // 期望的错误信息片段，为空表示调用成功
+	check  func(t *testing.T, result []byte)
+}
+
+func put(caller *contractapi.Identity, id string, owner string, ciphertext string, ts string) step {
+	return step{caller: caller, fn: "putRecord", args: []string{id, owner, ciphertext, "hash-" + ciphertext, ts}}
+}
+
+// 期望调用失败
+func (s step) fails(err string) step {
+	s.err = err
+	return s
+}
+
+func record(t *testing.T, result []byte) Record {
+	var r Record
+	if err := json.Unmarshal(result, &r); err != nil {
+		t.Fatalf("unmarshal record %s error: %v", result, err)
+	}
+	return r
+}
+
+func records(t *testing.T, result []byte) []Record {
+	var rs []Record
+	if err := json.Unmarshal(result, &rs); err != nil {
+		t.Fatalf("unmarshal records %s error: %v", result, err)
+	}
+	return rs
+}
+
+func versions(t *testing.T, result []byte) []Version {
+	var vs []Version
+	if err := json.Unmarshal(result, &vs); err != nil {
+		t.Fatalf("unmarshal versions %s error: %v", result, err)
+	}
+	return vs
+}
+
+func Test_FinanceInterface(t *testing.T) {
+	cases := []struct {
+		name   string
+		noInit bool
+		steps  []step
+	}{
+		{
+			name:   "writes are denied before init",
+			noInit: true,
+			steps: []step{
+				{caller: org1, fn: "saveRecode", args: []string{r1, "v1"}, err: "acl is not initialized"},
+				{caller: org1, fn: "queryACL", check: func(t *testing.T, result []byte) {
+					if string(result) != `{"admin":null,"write":null,"modify":null,"revoke":null}` {
+						t.Errorf("unexpected empty acl: %s", result)
+					}
+				}},
+			},
+		},
+		{
+			name: "put and modify record",
+			steps: []step{
+				put(org1, r1, "o1", "c1", "100"),
+				put(org1, r1, "o1", "c2", "200"),
+				{caller: org1, fn: "getRecord", args: []string{r1}, check: func(t *testing.T, result []byte) {
+					r := record(t, result)
+					if r.Version != 2 || r.Ciphertext != "c2" || r.CreatedAt != 100 || r.UpdatedAt != 200 || r.Org != "org1" {
+						t.Errorf("unexpected record: %+v", r)
+					}
+				}},
+				{caller: org1, fn: "history", args: []string{r1}, check: func(t *testing.T, result []byte) {
+					vs := versions(t, result)
+					if len(vs) != 2 || vs[0].BlockNum >= vs[1].BlockNum || vs[0].TxHash == "" || vs[0].IsDeleted {
+						t.Errorf("unexpected history: %+v", vs)
+					}
+				}},
+				put(org1, r1, "o2", "c3", "300").fails("another owner"),
+			},
+		},
+		{
+			name: "argument validation",
+			steps: []step{
+				{caller: org1, fn: "putRecord", args: []string{r1, "o1", "c1", "h1"}, err: "expected 5"},
+				{caller: org1, fn: "putRecord", args: []string{r1, "", "c1", "h1", "1"}, err: "is empty"},
+				{caller: org1, fn: "putRecord", args: []string{r1, "o1", "c1", "h1", "x"}, err: "invalid timestamp"},
+				{caller: org1, fn: "rangeByTime", args: []string{"2", "1"}, err: "invalid end time"},
+				{caller: org1, fn: "getRecord", args: []string{"missing"}, err: "not found"},
+				{caller: org1, fn: "unknown", err: "func name is not correct"},
+			},
+		},
+		{
+			name: "owner and time indexes",
+			steps: []step{
+				put(org1, r1, "o1", "c1", "100"),
+				put(org1, r2, "o1", "c2", "200"),
+				put(org1, r3, "o2", "c3", "300"),
+				{caller: org1, fn: "listByOwner", args: []string{"o1"}, check: func(t *testing.T, result []byte) {
+					if rs := records(t, result); len(rs) != 2 || rs[0].ID != r1 || rs[1].ID != r2 {
+						t.Errorf("unexpected owner records: %+v", rs)
+					}
+				}},
+				{caller: org1, fn: "rangeByTime", args: []string{"200", "300"}, check: func(t *testing.T, result []byte) {
+					if rs := records(t, result); len(rs) != 1 || rs[0].ID != r2 {
+						t.Errorf("unexpected time range records: %+v", rs)
+					}
+				}},
+			},
+		},
+		{
+			name: "legacy value is converted on modify",
+			steps: []step{
+				{caller: org1, fn: "saveRecode", args: []string{r1, "raw"}},
+				{caller: org1, fn: "getRecord", args: []string{r1}, check: func(t *testing.T, result []byte) {
+					if r := record(t, result); r.Ciphertext != "raw" || r.Version != 0 {
+						t.Errorf("unexpected legacy record: %+v", r)
+					}
+				}},
+				put(org1, r1, "o1", "c1", "100"),
+				{caller: org1, fn: "listByOwner", args: []string{"o1"}, check: func(t *testing.T, result []byte) {
+					if rs := records(t, result); len(rs) != 1 || rs[0].Version != 1 {
+						t.Errorf("legacy record not indexed: %+v", rs)
+					}
+				}},
+			},
+		},
+		{
+			name: "acl management",
+			steps: []step{
+				put(org2, r1, "o1", "c1", "100").fails("has no write permission"),
+				{caller: org2, fn: "grantOrg", args: []string{PermWrite, "org2"}, err: "has no admin permission"},
+				{caller: org1, fn: "grantOrg", args: []string{"read", "org2"}, err: "invalid permission"},
+				{caller: org1, fn: "grantOrg", args: []string{PermWrite, "org2"}},
+				{caller: org1, fn: "grantOrg", args: []string{PermModify, "org2"}},
+				put(org2, r1, "o1", "c1", "100"),
+				put(org1, r1, "o1", "c2", "200").fails("belongs to org org2"),
+				put(org2, r1, "o1", "c2", "200"),
+				{caller: org1, fn: "removeOrg", args: []string{PermAdmin, "org1"}, err: "at least one admin"},
+				{caller: org1, fn: "removeOrg", args: []string{PermWrite, "org2"}},
+				put(org2, r2, "o1", "c1", "100").fails("has no write permission"),
+				{caller: org1, fn: "queryACL", check: func(t *testing.T, result []byte) {
+					var acl ACL
+					json.Unmarshal(result, &acl)
+					if acl.Allowed(PermWrite, "org2") || !acl.Allowed(PermModify, "org2") || !acl.Allowed(PermAdmin, "org1") {
+						t.Errorf("unexpected acl: %s", result)
+					}
+				}},
+			},
+		},
+		{
+			name: "revoke record",
+			steps: []step{
+				put(org1, r1, "o1", "c1", "100"),
+				{caller: org1, fn: "revokeRecode", args: []string{r1, "o2", "legal", "200"}, err: "another owner"},
+				{caller: org2, fn: "revokeRecode", args: []string{r1, "o1", "legal", "200"}, err: "has no revoke permission"},
+				{caller: org1, fn: "revokeRecode", args: []string{r1, "o1", "legal", "200"}},
+				{caller: org1, fn: "revokeRecode", args: []string{r1, "o1", "legal", "300"}, err: "has been revoked"},
+				{caller: org1, fn: "getRecord", args: []string{r1}, err: "has been revoked"},
+				put(org1, r1, "o1", "c2", "300").fails("has been revoked"),
+				{caller: org1, fn: "queryRevocation", args: []string{r1}, check: func(t *testing.T, result []byte) {
+					var r Revocation
+					if err := json.Unmarshal(result, &r); err != nil || r.Reason != "legal" || r.RevokedAt != 200 || r.Org != "org1" {
+						t.Errorf("unexpected revocation: %s", result)
+					}
+				}},
+				{caller: org1, fn: "history", args: []string{r1}, check: func(t *testing.T, result []byte) {
+					if vs := versions(t, result); len(vs) != 2 || !vs[1].IsDeleted || vs[1].Value != "" {
+						t.Errorf("revocation missing from history: %+v", vs)
+					}
+				}},
+				{caller: org1, fn: "listByOwner", args: []string{"o1"}, check: func(t *testing.T, result []byte) {
+					if rs := records(t, result); len(rs) != 0 {
+						t.Errorf("revoked record still indexed: %+v", rs)
+					}
+				}},
+				{caller: org1, fn: "rangeByTime", args: []string{"0", "1000"}, check: func(t *testing.T, result []byte) {
+					if rs := records(t, result); len(rs) != 0 {
+						t.Errorf("revoked record still in time index: %+v", rs)
+					}
+				}},
+			},
+		},
+		{
+			name: "only the writing org can modify or revoke",
+			steps: []step{
+				{caller: org1, fn: "grantOrg", args: []string{PermModify, "org2"}},
+				{caller: org1, fn: "grantOrg", args: []string{PermRevoke, "org2"}},
+				put(org1, r1, "o1", "c1", "100"),
+				put(org2, r1, "o1", "c2", "200").fails("belongs to org org1"),
+				{caller: org2, fn: "revokeRecode", args: []string{r1, "o1", "legal", "200"}, err: "belongs to org org1"},
+			},
+		},
+		{
+			name: "legacy record without org cannot be revoked",
+			steps: []step{
+				{caller: org1, fn: "grantOrg", args: []string{PermRevoke, "org2"}},
+				{caller: org1, fn: "saveRecode", args: []string{r1, "raw"}},
+				{caller: org2, fn: "revokeRecode", args: []string{r1, "o1", "legal", "200"}, err: "has no org"},
+				{caller: org1, fn: "revokeRecode", args: []string{r1, "o1", "legal", "200"}, err: "has no org"},
+				put(org1, r1, "o1", "c1", "300"),
+				{caller: org2, fn: "revokeRecode", args: []string{r1, "o1", "legal", "400"}, err: "belongs to org org1"},
+				{caller: org1, fn: "revokeRecode", args: []string{r1, "o1", "legal", "400"}},
+			},
+		},
+		{
+			name: "internal keys cannot be written as records",
+			steps: []step{
+				{caller: org1, fn: "grantOrg", args: []string{PermWrite, "org2"}},
+				{caller: org2, fn: "saveRecode", args: []string{"acl", `{"admin":["org2"],"write":["org2"]}`}, err: "invalid record id"},
+				{caller: org2, fn: "saveRecode", args: []string{aclKey, `{"admin":["org2"],"write":["org2"]}`}, err: "reserved prefix"},
+				{caller: org2, fn: "saveRecode", args: []string{revokedPrefix + r1, `{"id":"x"}`}, err: "reserved prefix"},
+				put(org2, "acl", "o1", `{"admin":["org2"]}`, "100").fails("invalid record id"),
+				put(org2, aclKey, "o1", `{"admin":["org2"]}`, "100").fails("reserved prefix"),
+				put(org2, "ABCD", "o1", "c1", "100").fails("invalid record id"),
+				put(org2, strings.Repeat("g", 64), "o1", "c1", "100").fails("invalid record id"),
+				{caller: org1, fn: "queryACL", check: func(t *testing.T, result []byte) {
+					var acl ACL
+					json.Unmarshal(result, &acl)
+					if acl.Allowed(PermAdmin, "org2") || !acl.Allowed(PermAdmin, "org1") {
+						t.Errorf("acl taken over: %s", result)
+					}
+				}},
+				put(org1, r1, "o1", "c1", "100"),
+				{caller: org1, fn: "getRecord", args: []string{r1}, check: func(t *testing.T, result []byte) {
+					if r := record(t, result); r.Version != 1 || r.Org != "org1" {
+						t.Errorf("unexpected record: %+v", r)
+					}
+				}},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			l := ctesting.NewLedger("chain", "finance")
+			l.Now = func() time.Time { return time.Unix(1, 0) }
+			contract := NewSmartContract()
+			if !c.noInit {
+				if _, err := l.Init(contract, org1); err != nil {
+					t.Fatalf("init error: %v", err)
+				}
+				l.CutBlock()
+			}
+			for i, s := range c.steps {
+				result, err := l.Invoke(contract, s.caller, s.fn, s.args...)
+				block := l.CutBlock()
+				switch {
+				case s.err == "" && err != nil:
+					t.Fatalf("step %d %s: unexpected error %v", i, s.fn, err)
+				case s.err != "" && (err == nil || !strings.Contains(err.Error(), s.err)):
+					t.Fatalf("step %d %s: got error %v, want %q", i, s.fn, err, s.err)
+				case err == nil && !block.Txs[0].Valid:
+					t.Fatalf("step %d %s: transaction invalid", i, s.fn)
+				}
+				if s.check != nil {
+					s.check(t, result)
+				}
+			}
+		})
+	}
+}
+
+// 两个组织并发修改同一存证时只有先出块的交易有效
+func Test_FinanceInterface_ConcurrentModify(t *testing.T) {
+	l := ctesting.NewLedger("chain", "finance")
+	contract := NewSmartContract()
+	l.Init(contract, org1)
+	l.CutBlock()
+	l.Invoke(contract, org1, "putRecord", r1, "o1", "c1", "h1", "100")
+	l.CutBlock()
+
+	for _, ciphertext := range []string{"c2", "c3"} {
+		if _, err := l.Invoke(contract, org1, "putRecord", r1, "o1", ciphertext, "h", "200"); err != nil {
+			t.Fatalf("modify error: %v", err)
+		}
+	}
+	block := l.CutBlock()
+	if !block.Txs[0].Valid || block.Txs[1].Valid {
+		t.Fatalf("unexpected validation result: %+v", block.Txs)
+	}
+	if r, _ := ParseRecord(l.Get(r1)); r.Ciphertext != "c2" || r.Version != 2 {
+		t.Errorf("unexpected record after conflict: %+v", r)
+	}
+}
@@ -0,0 +1,319 @@
+package usercontract
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"git.huawei.com/poissonsearch/wienerchain/contract/docker-container/contract-go/contractapi"
+	"github.com/pkg/errors"
+)
+
+// OwnerIndex 按所有者查询存证的组合索引名称
+const OwnerIndex = "owner"
+
+// 合约内部状态（ACL、索引、撤销记录）的key前缀，存证ID为64位十六进制，不能以此开头
+const internalPrefix = "~"
+
+// 按创建时间排序的索引，key为 ~time~创建时间(20位补零)~存证ID，value为存证ID
+const timeIndexPrefix = internalPrefix + "time~"
+
+// 撤销记录的key前缀，key为 ~revoked~存证ID
+const revokedPrefix = internalPrefix + "revoked~"
+
+// 存证ID的长度，即手机号+时间戳的sha256的十六进制
+const recordIDLen = 64
+
+// Record 结构化存证，以存证ID为key保存为JSON
+type Record struct {
+	ID          string `json:"id"`
+	Owner       string `json:"owner"`         // 所有者哈希
+	Ciphertext  string `json:"ciphertext"`    // 加密后的存证内容
+	ContentHash string `json:"contentHash"`   // 明文的SHA-256，用于校验
+	CreatedAt   int64  `json:"createdAt"`     // 首次上链时间，Unix秒
+	UpdatedAt   int64  `json:"updatedAt"`     // 最近一次修改时间，Unix秒
+	Version     int    `json:"version"`       // 从1开始，每次修改加1
+	Org         string `json:"org,omitempty"` // 首次写入的组织，只有该组织可以修改和撤销
+}
+
+// Revocation 存证撤销记录，撤销后存证ID不能再写入
+type Revocation struct {
+	ID        string `json:"id"`
+	Owner     string `json:"owner"`     // 所有者哈希
+	Reason    string `json:"reason"`    // 撤销原因
+	RevokedAt int64  `json:"revokedAt"` // 撤销时间，Unix秒
+	Org       string `json:"org"`       // 发起撤销的组织
+}
+
+// ParseRecord 解析链上的值，saveRecode写入的旧版数据不是JSON，此时ok为false
+func ParseRecord(value []byte) (*Record, bool) {
+	record := &Record{}
+	if err := json.Unmarshal(value, record); err != nil || record.ID == "" || record.Version == 0 {
+		return nil, false
+	}
+	return record, true
+}
+
+// 校验写入的存证ID，避免覆盖ACL、索引和撤销记录等合约内部状态
+func checkRecordID(id string) error {
+	if strings.HasPrefix(id, internalPrefix) {
+		return errors.Errorf("record id %s uses the reserved prefix %s", id, internalPrefix)
+	}
+	if len(id) != recordIDLen {
+		return errors.Errorf("invalid record id %s, expected %d hex characters", id, recordIDLen)
+	}
+	if _, err := hex.DecodeString(id); err != nil {
+		return errors.Errorf("invalid record id %s, expected %d hex characters", id, recordIDLen)
+	}
+	return nil
+}
+
+func timeIndexKey(timestamp int64, id string) string {
+	return fmt.Sprintf("%s%020d~%s", timeIndexPrefix, timestamp, id)
+}
+
+// 新增或修改存证，参数为 存证ID、所有者哈希、密文、明文哈希、时间戳；
+// 新增需要write权限，修改需要modify权限且组织、所有者与原存证一致，版本号加1，创建时间不变
+func putRecord(stub contractapi.ContractStub, args [][]byte) ([]byte, error) {
+	if len(args) != 5 {
+		return nil, errors.New("the argNum for putRecord is not correct,expected 5")
+	}
+	for i := range args {
+		if len(args[i]) == 0 {
+			return nil, errors.Errorf("the arg %d for putRecord is empty", i)
+		}
+	}
+	timestamp, err := strconv.ParseInt(string(args[4]), 10, 64)
+	if err != nil || timestamp < 0 {
+		return nil, errors.Errorf("invalid timestamp: %s", args[4])
+	}
+	record := &Record{
+		ID:          string(args[0]),
+		Owner:       string(args[1]),
+		Ciphertext:  string(args[2]),
+		ContentHash: string(args[3]),
+		CreatedAt:   timestamp,
+		UpdatedAt:   timestamp,
+		Version:     1,
+	}
+	if err := checkRecordID(record.ID); err != nil {
+		return nil, err
+	}
+
+	if revocation, err := getRevocation(stub, record.ID); err != nil {
+		return nil, err
+	} else if revocation != nil {
+		return nil, errors.Errorf("record %s has been revoked", record.ID)
+	}
+	value, err := stub.GetKV(record.ID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get record error")
+	}
+	perm := PermWrite
+	if value != nil {
+		perm = PermModify
+	}
+	creator, err := checkPermission(stub, perm)
+	if err != nil {
+		return nil, err
+	}
+	record.Org = creator.Org
+	if existing, ok := ParseRecord(value); ok {
+		if existing.Owner != record.Owner {
+			return nil, errors.Errorf("record %s belongs to another owner", record.ID)
+		}
+		if existing.Org != "" && existing.Org != creator.Org {
+			return nil, errors.Errorf("record %s belongs to org %s", record.ID, existing.Org)
+		}
+		record.CreatedAt = existing.CreatedAt
+		record.Version = existing.Version + 1
+	} else {
+		//首次保存为结构化存证时建立索引，包括由saveRecode写入的旧版数据
+		if err := stub.SaveComIndex(OwnerIndex, []string{record.Owner}, record.ID); err != nil {
+			return nil, errors.WithMessage(err, "save owner index error")
+		}
+		if err := stub.PutKV(timeIndexKey(record.CreatedAt, record.ID), []byte(record.ID)); err != nil {
+			return nil, errors.WithMessage(err, "save time index error")
+		}
+	}
+
+	result, err := json.Marshal(record)
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal record error")
+	}
+	if err := stub.PutKV(record.ID, result); err != nil {
+		return nil, errors.WithMessage(err, "put record error")
+	}
+	return result, nil
+}
+
+// 查询一条存证，旧版数据只返回ID和密文
+func getRecord(stub contractapi.ContractStub, args [][]byte) ([]byte, error) {
+	if len(args) != 1 {
+		return nil, errors.New("the argNum for getRecord is not correct,expected 1")
+	}
+	value, err := stub.GetKV(string(args[0]))
+	if err != nil {
+		return nil, errors.WithMessage(err, "get record error")
+	}
+	if value == nil {
+		if revocation, err := getRevocation(stub, string(args[0])); err == nil && revocation != nil {
+			return nil, errors.Errorf("record %s has been revoked", args[0])
+		}
+		return nil, errors.Errorf("record %s not found", args[0])
+	}
+	record, ok := ParseRecord(value)
+	if !ok {
+		record = &Record{ID: string(args[0]), Ciphertext: string(value)}
+	}
+	return json.Marshal(record)
+}
+
+// 查询所有者的全部存证，参数为所有者哈希
+func listByOwner(stub contractapi.ContractStub, args [][]byte) ([]byte, error) {
+	if len(args) != 1 {
+		return nil, errors.New("the argNum for listByOwner is not correct,expected 1")
+	}
+	iter, err := stub.GetKVByComIndex(OwnerIndex, []string{string(args[0])})
+	if err != nil {
+		return nil, errors.WithMessage(err, "get owner index error")
+	}
+	defer iter.Close()
+
+	records := []Record{}
+	for iter.Next() {
+		if record, ok := ParseRecord(iter.Value()); ok {
+			records = append(records, *record)
+		}
+	}
+	return json.Marshal(records)
+}
+
+// 按创建时间查询存证，参数为起止时间（Unix秒），查询区间为左闭右开[start, end)
+func rangeByTime(stub contractapi.ContractStub, args [][]byte) ([]byte, error) {
+	if len(args) != 2 {
+		return nil, errors.New("the argNum for rangeByTime is not correct,expected 2")
+	}
+	start, err := strconv.ParseInt(string(args[0]), 10, 64)
+	if err != nil || start < 0 {
+		return nil, errors.Errorf("invalid start time: %s", args[0])
+	}
+	end, err := strconv.ParseInt(string(args[1]), 10, 64)
+	if err != nil || end < start {
+		return nil, errors.Errorf("invalid end time: %s", args[1])
+	}
+	iter, err := stub.GetIterator(timeIndexKey(start, ""), timeIndexKey(end, ""))
+	if err != nil {
+		return nil, errors.WithMessage(err, "get time index iterator error")
+	}
+	defer iter.Close()
+
+	records := []Record{}
+	for iter.Next() {
+		value, err := stub.GetKV(string(iter.Value()))
+		if err != nil {
+			return nil, errors.WithMessage(err, "get record error")
+		}
+		if record, ok := ParseRecord(value); ok {
+			records = append(records, *record)
+		}
+	}
+	return json.Marshal(records)
+}
+
+// 撤销存证，参数为 存证ID、所有者哈希、撤销原因、时间戳；
+// 删除存证及其索引，历史版本中可看到删除标记，撤销原因保存在 revoked~存证ID 下。
+// 需要revoke权限，且组织、所有者与存证一致。旧版数据和没有组织的存证无法确认归属，不能撤销，
+// 需先由有modify权限的组织按结构化存证修改一次，记录组织和所有者后再撤销
+func revokeRecode(stub contractapi.ContractStub, args [][]byte) ([]byte, error) {
+	if len(args) != 4 {
+		return nil, errors.New("the argNum for revokeRecode is not correct,expected 4")
+	}
+	for i := range args {
+		if len(args[i]) == 0 {
+			return nil, errors.Errorf("the arg %d for revokeRecode is empty", i)
+		}
+	}
+	timestamp, err := strconv.ParseInt(string(args[3]), 10, 64)
+	if err != nil || timestamp < 0 {
+		return nil, errors.Errorf("invalid timestamp: %s", args[3])
+	}
+	creator, err := checkPermission(stub, PermRevoke)
+	if err != nil {
+		return nil, err
+	}
+	revocation := &Revocation{ID: string(args[0]), Owner: string(args[1]), Reason: string(args[2]), RevokedAt: timestamp, Org: creator.Org}
+	if err := checkRecordID(revocation.ID); err != nil {
+		return nil, err
+	}
+
+	value, err := stub.GetKV(revocation.ID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get record error")
+	}
+	if value == nil {
+		if existing, err := getRevocation(stub, revocation.ID); err != nil {
+			return nil, err
+		} else if existing != nil {
+			return nil, errors.Errorf("record %s has been revoked", revocation.ID)
+		}
+		return nil, errors.Errorf("record %s not found", revocation.ID)
+	}
+	record, ok := ParseRecord(value)
+	if !ok || record.Org == "" {
+		return nil, errors.Errorf("record %s has no org, modify it before revoking", revocation.ID)
+	}
+	if record.Owner != revocation.Owner {
+		return nil, errors.Errorf("record %s belongs to another owner", revocation.ID)
+	}
+	if record.Org != creator.Org {
+		return nil, errors.Errorf("record %s belongs to org %s", revocation.ID, record.Org)
+	}
+	if err := stub.DelComIndexOneRow(OwnerIndex, []string{record.Owner}, record.ID); err != nil {
+		return nil, errors.WithMessage(err, "delete owner index error")
+	}
+	if err := stub.DelKV(timeIndexKey(record.CreatedAt, record.ID)); err != nil {
+		return nil, errors.WithMessage(err, "delete time index error")
+	}
+	if err := stub.DelKV(revocation.ID); err != nil {
+		return nil, errors.WithMessage(err, "delete record error")
+	}
+
+	result, err := json.Marshal(revocation)
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal revocation error")
+	}
+	if err := stub.PutKV(revokedPrefix+revocation.ID, result); err != nil {
+		return nil, errors.WithMessage(err, "put revocation error")
+	}
+	return result, nil
+}
+
+// 查询存证的撤销记录，参数为存证ID，未撤销时返回空
+func queryRevocation(stub contractapi.ContractStub, args [][]byte) ([]byte, error) {
+	if len(args) != 1 {
+		return nil, errors.New("the argNum for queryRevocation is not correct,expected 1")
+	}
+	revocation, err := getRevocation(stub, string(args[0]))
+	if err != nil || revocation == nil {
+		return nil, err
+	}
+	return json.Marshal(revocation)
+}
+
+func getRevocation(stub contractapi.ContractStub, id string) (*Revocation, error) {
+	value, err := stub.GetKV(revokedPrefix + id)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get revocation error")
+	}
+	if value == nil {
+		return nil, nil
+	}
+	revocation := &Revocation{}
+	if err := json.Unmarshal(value, revocation); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal revocation error")
+	}
+	return revocation, nil
+}
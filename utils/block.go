@@ -2,6 +2,7 @@
 package utils
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
@@ -87,7 +88,6 @@ func (bt *BlockTool) GetTxIdList(block *common.Block) ([]string, error) {
 	for i := range txList {
 		txIdList = append(txIdList, Hash2str(txList[i].Hash))
 	}
-	fmt.Println("GetTxIdList ", txIdList)
 	return txIdList, nil
 }
 
@@ -101,10 +101,6 @@ func (bt *BlockTool) GetTxList(block *common.Block) ([]*common.Tx, error) {
 	if err := proto.Unmarshal(block.Body, &blockBody); err != nil {
 		return nil, errors.WithMessage(err, "unmarshal tx payload error")
 	}
-	fmt.Println("GetTxList ", len(blockBody.TxList))
-	for key, value := range blockBody.TxList {
-		fmt.Printf("txList[%d] %s\n", key, Hash2str(value.Hash))
-	}
 	return blockBody.TxList, nil
 }
 
@@ -145,6 +141,14 @@ func (bt *BlockTool) PrintBlockTxsInfo(block *common.Block) error {
  *  @return uint64 当前最新区块的区块号
  */
 func (bt *BlockTool) QueryLastBlockNumber(gatewayClient *client.GatewayClient, config Config) (uint64, error) {
+	return bt.QueryLastBlockNumberCtx(context.Background(), gatewayClient, config)
+}
+
+/**
+ *  QueryLastBlockNumberCtx
+ *  @Description: 与QueryLastBlockNumber相同，ctx取消时立即返回，ctx未设置截止时间时使用SDK的超时时间
+ */
+func (bt *BlockTool) QueryLastBlockNumberCtx(ctx context.Context, gatewayClient *client.GatewayClient, config Config) (uint64, error) {
 	// 1.消息构建
 	rawMsg, err := gatewayClient.QueryRawMessage.BuildLatestChainStateRawMessage(config.ChainID)
 	if err != nil {
@@ -159,7 +163,7 @@ func (bt *BlockTool) QueryLastBlockNumber(gatewayClient *client.GatewayClient, c
 	}
 
 	// 3.消息发送
-	responseMsg, err := node.QueryAction.GetLatestChainState(rawMsg)
+	responseMsg, err := node.QueryAction.GetLatestChainStateCtx(ctx, rawMsg)
 	if err != nil {
 		return 0, errors.WithMessage(err, "query action get latest chain state error")
 	}
@@ -185,6 +189,14 @@ func (bt *BlockTool) QueryLastBlockNumber(gatewayClient *client.GatewayClient, c
  *  @return common.Block 区块信息
  */
 func (bt *BlockTool) QueryBlockByNumber(gatewayClient *client.GatewayClient, config Config, blockNum string) (*common.Block, error) {
+	return bt.QueryBlockByNumberCtx(context.Background(), gatewayClient, config, blockNum)
+}
+
+/**
+ *  QueryBlockByNumberCtx
+ *  @Description: 与QueryBlockByNumber相同，ctx取消时立即返回，ctx未设置截止时间时使用SDK的超时时间
+ */
+func (bt *BlockTool) QueryBlockByNumberCtx(ctx context.Context, gatewayClient *client.GatewayClient, config Config, blockNum string) (*common.Block, error) {
 	// 1. 入参校验
 	if blockNum == "" {
 		return nil, errors.Errorf("please specify a block number or transaction id.")
@@ -209,7 +221,7 @@ func (bt *BlockTool) QueryBlockByNumber(gatewayClient *client.GatewayClient, con
 
 	// 4.消息发送
 	var responseMsg *common.RawMessage
-	responseMsg, err = node.QueryAction.GetBlockByNum(rawMsg)
+	responseMsg, err = node.QueryAction.GetBlockByNumCtx(ctx, rawMsg)
 	if err != nil {
 		return nil, errors.WithMessage(err, "query action get latest chain state error")
 	}
@@ -241,6 +253,14 @@ func (bt *BlockTool) QueryBlockByNumber(gatewayClient *client.GatewayClient, con
  *  @return error
  */
 func (bt *BlockTool) QueryBlockByTxID(gatewayClient *client.GatewayClient, config Config, txID string) (*common.Block, error) {
+	return bt.QueryBlockByTxIDCtx(context.Background(), gatewayClient, config, txID)
+}
+
+/**
+ *  QueryBlockByTxIDCtx
+ *  @Description: 与QueryBlockByTxID相同，ctx取消时立即返回，ctx未设置截止时间时使用SDK的超时时间
+ */
+func (bt *BlockTool) QueryBlockByTxIDCtx(ctx context.Context, gatewayClient *client.GatewayClient, config Config, txID string) (*common.Block, error) {
 	// 1.入参处理
 	txHash, err := hex.DecodeString(txID)
 	if err != nil {
@@ -261,7 +281,7 @@ func (bt *BlockTool) QueryBlockByTxID(gatewayClient *client.GatewayClient, confi
 	}
 
 	// 4.消息发送
-	responseMsg, err := node.QueryAction.GetBlockByTxHash(rawMsg)
+	responseMsg, err := node.QueryAction.GetBlockByTxHashCtx(ctx, rawMsg)
 	if err != nil {
 		return nil, errors.WithMessage(err, "query action get tx result by tx id error")
 	}
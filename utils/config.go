@@ -1,25 +1,126 @@
 package utils
 
 import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
 	"git.huawei.com/huaweichain/sdk/config"
+	sdkutils "git.huawei.com/huaweichain/sdk/utils"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
 )
 
 type Config struct {
-	ConfigFilePath string // yaml配置文件路径
-	ContractName   string // 合约名称
-	EndorserNodes  string // 背书节点，若背书策略为“全部组织背书”，则背书节点为每个组织的中的任一节点即可，eg: "node-0.organization,node-0.organization1"
-	ConsensusNode  string // 共识节点，默认为"node-0.organization",选择共识组织下的任一节点即可
-	ChainID        string // 链ID，即实例概览页面的"链信息->链ID"
-	QueryNode      string // 查询节点,可选择一个或者若干个组织内的节点(参见yaml配置文件中)，用于发出执行请求
-	SignAlgorithm  string // 安全机制
+	ConfigFilePath   string        // yaml配置文件路径
+	ContractName     string        // 合约名称
+	EndorserNodes    string        // 背书节点，eg: "node-0.organization,node-0.organization1"，为空时NewSession按合约背书策略自动选择
+	ConsensusNode    string        // 共识节点，默认为"node-0.organization",选择共识组织下的任一节点即可
+	ChainID          string        // 链ID，即实例概览页面的"链信息->链ID"
+	QueryNode        string        // 查询节点,可选择一个或者若干个组织内的节点(参见yaml配置文件中)，用于发出执行请求
+	SignAlgorithm    string        // 安全机制
+	StoreDriver      string        // 本地存证索引存储驱动，可选 mysql、sqlite3、memory
+	StoreDSN         string        // 本地存证索引存储连接串，memory驱动时忽略
+	CommitTimeout    time.Duration // 等待交易落块的超时时间，为0时使用WaitTime
+	EndorseQuorum    int           // 执行结果一致即可提交的最少背书节点数，为0时需全部背书节点一致
+	EndorseTimeout   time.Duration // 等待背书响应的超时时间，为0时仅受单次gRPC调用超时限制
+	ProbeInterval    time.Duration // 节点健康探测间隔，为0时不主动探测
+	FailureThreshold int           // 节点连续失败次数达到该值后熔断，为0时使用3
+	BreakerTimeout   time.Duration // 节点熔断持续时间，为0时使用30秒
+}
+
+// ServerConfig 存证服务配置，对应 configuration/server.yaml
+type ServerConfig struct {
+	Server  ServerSection  `mapstructure:"server"`
+	Chain   ChainSection   `mapstructure:"chain"`
+	Store   StoreSection   `mapstructure:"store"`
+	Cache   CacheSection   `mapstructure:"cache"`
+	Crypto  CryptoSection  `mapstructure:"crypto"`
+	Job     JobSection     `mapstructure:"job"`
+	Webhook WebhookSection `mapstructure:"webhook"`
+}
+
+// ServerSection HTTP服务配置
+type ServerSection struct {
+	Listen       string        `mapstructure:"listen"`       // 监听地址，eg: ":8000"
+	AdminListen  string        `mapstructure:"adminListen"`  // 管理接口（KEK轮换）监听地址，只能为本机回环地址，为空时不启用
+	ReadTimeout  time.Duration `mapstructure:"readTimeout"`  // 读取请求超时
+	WriteTimeout time.Duration `mapstructure:"writeTimeout"` // 写响应超时，需大于 chain.commitTimeout
+}
+
+// ChainSection 链相关配置
+type ChainSection struct {
+	ConfigFilePath   string        `mapstructure:"configFilePath"`   // sdk.yaml路径
+	ContractName     string        `mapstructure:"contractName"`     // 合约名称
+	EndorserNodes    []string      `mapstructure:"endorserNodes"`    // 背书节点，为空时按合约背书策略选择
+	ConsensusNode    string        `mapstructure:"consensusNode"`    // 共识节点
+	QueryNode        string        `mapstructure:"queryNode"`        // 查询节点
+	ChainID          string        `mapstructure:"chainID"`          // 链ID
+	Timeout          time.Duration `mapstructure:"timeout"`          // 单次gRPC调用超时
+	CommitTimeout    time.Duration `mapstructure:"commitTimeout"`    // 等待交易落块超时
+	EndorseQuorum    int           `mapstructure:"endorseQuorum"`    // 执行结果一致的最少背书节点数，0为全部
+	EndorseTimeout   time.Duration `mapstructure:"endorseTimeout"`   // 等待背书响应超时，0为不限制
+	ProbeInterval    time.Duration `mapstructure:"probeInterval"`    // 节点健康探测间隔，0为不主动探测
+	FailureThreshold int           `mapstructure:"failureThreshold"` // 节点连续失败多少次后熔断
+	BreakerTimeout   time.Duration `mapstructure:"breakerTimeout"`   // 节点熔断持续时间
+}
+
+// StoreSection 本地存证索引配置
+type StoreSection struct {
+	Driver string `mapstructure:"driver"` // mysql、sqlite3、memory
+	DSN    string `mapstructure:"dsn"`    // 数据库连接串
+}
+
+// CacheSection 缓存配置
+type CacheSection struct {
+	Address string        `mapstructure:"address"` // Redis地址，为空时不启用缓存
+	Expire  time.Duration `mapstructure:"expire"`  // 查询结果缓存时间
+}
+
+// CryptoSection 存证数据加密配置，新数据使用信封加密，key与keyFile为旧版AES-ECB密钥，仅用于解密历史数据
+type CryptoSection struct {
+	Algorithm string `mapstructure:"algorithm"` // 数据加密算法，AES-GCM 或 SM4
+	KEKFile   string `mapstructure:"kekFile"`   // 密钥加密密钥（KEK）文件路径
+	Key       string `mapstructure:"key"`       // 旧版密钥明文，建议通过环境变量 CD_CRYPTO_KEY 注入
+	KeyFile   string `mapstructure:"keyFile"`   // 旧版密钥文件路径
+}
+
+// JobSection 异步上链任务配置
+type JobSection struct {
+	Workers   int `mapstructure:"workers"`   // 并发提交任务的协程数
+	QueueSize int `mapstructure:"queueSize"` // 等待提交的任务上限，队列满时拒绝新任务
 }
 
+// WebhookSection 交易落块回调配置，secret为空时不启用回调
+type WebhookSection struct {
+	Secret      string        `mapstructure:"secret"`      // 回调签名密钥，建议通过环境变量 CD_WEBHOOK_SECRET 注入
+	Timeout     time.Duration `mapstructure:"timeout"`     // 单次回调请求超时
+	MaxAttempts int           `mapstructure:"maxAttempts"` // 最多投递次数
+	Backoff     time.Duration `mapstructure:"backoff"`     // 首次重试间隔，之后每次翻倍
+	MaxBackoff  time.Duration `mapstructure:"maxBackoff"`  // 重试间隔上限
+	// 允许使用回环、私有和链路本地地址的回调主机，其它主机只能是公网地址
+	AllowedHosts []string `mapstructure:"allowedHosts"`
+}
+
+// EnvPrefix 环境变量前缀，如 CD_SERVER_LISTEN 覆盖 server.listen
+const EnvPrefix = "CD"
+
 var appConfig Config
 
+var serverConfig ServerConfig
+
 func AppConfig() Config {
 	return appConfig
 }
 
+// ServerCfg 获取已加载的服务配置
+func ServerCfg() ServerConfig {
+	return serverConfig
+}
+
 func SetSignAlg(cfg Config) {
 	clientConfig, err := config.NewClientConfig(cfg.ConfigFilePath)
 	if err != nil {
@@ -28,15 +129,199 @@ func SetSignAlg(cfg Config) {
 	appConfig.SignAlgorithm = clientConfig.Client.Type
 }
 
-func init() {
-	appConfig.ConfigFilePath = "C:\\Users\\HuJie\\Desktop\\Testing\\configuration\\sdk.yaml"
-	appConfig.ContractName = "example01"
+// LoadConfig 读取并校验服务配置文件，环境变量优先于文件内容，成功后据此构建AppConfig()
+func LoadConfig(path string) (*ServerConfig, error) {
+	cfg, err := ReadServerConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	applyServerConfig(*cfg)
+	return cfg, nil
+}
 
-	//背书节点名称node-2.organization-b4fydwesq
-	appConfig.EndorserNodes = "node-0.organization-b4fydwesq,node-1.organization-b4fydwesq,node-2.organization-b4fydwesq"
-	//共识节点名称
-	appConfig.ConsensusNode = "node-0.organization-b4fydwesq"
-	appConfig.QueryNode = "node-0.organization-b4fydwesq"
-	appConfig.ChainID = "bcs-z9z53b-c52cc9548"
+// ReadServerConfig 读取服务配置文件，不做校验；path为空时仅使用默认值和环境变量
+func ReadServerConfig(path string) (*ServerConfig, error) {
+	v := viper.New()
+	setDefaults(v)
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+	if path != "" {
+		v.SetConfigFile(path)
+		v.SetConfigType("yaml")
+		if err := v.ReadInConfig(); err != nil {
+			return nil, errors.WithMessagef(err, "read server config %s error", path)
+		}
+	}
+	cfg := &ServerConfig{}
+	if err := v.Unmarshal(cfg); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal server config error")
+	}
+	// 环境变量中的节点列表以逗号分隔
+	if len(cfg.Chain.EndorserNodes) == 1 {
+		cfg.Chain.EndorserNodes = splitNodes(cfg.Chain.EndorserNodes[0])
+	}
+	return cfg, nil
+}
+
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("server.listen", ":8000")
+	v.SetDefault("server.adminListen", "127.0.0.1:8001")
+	v.SetDefault("server.readTimeout", 10*time.Second)
+	v.SetDefault("server.writeTimeout", 90*time.Second)
+	v.SetDefault("chain.configFilePath", "")
+	v.SetDefault("chain.contractName", "")
+	v.SetDefault("chain.endorserNodes", []string{})
+	v.SetDefault("chain.consensusNode", "")
+	v.SetDefault("chain.queryNode", "")
+	v.SetDefault("chain.chainID", "")
+	v.SetDefault("chain.timeout", 60*time.Second)
+	v.SetDefault("chain.commitTimeout", WaitTime*time.Second)
+	v.SetDefault("chain.endorseQuorum", 0)
+	v.SetDefault("chain.endorseTimeout", 0)
+	v.SetDefault("chain.probeInterval", 10*time.Second)
+	v.SetDefault("chain.failureThreshold", 3)
+	v.SetDefault("chain.breakerTimeout", 30*time.Second)
+	v.SetDefault("store.driver", "memory")
+	v.SetDefault("store.dsn", "")
+	v.SetDefault("cache.address", "")
+	v.SetDefault("cache.expire", time.Hour)
+	v.SetDefault("crypto.algorithm", "AES-GCM")
+	v.SetDefault("crypto.kekFile", "")
+	v.SetDefault("crypto.key", "")
+	v.SetDefault("crypto.keyFile", "")
+	v.SetDefault("job.workers", 4)
+	v.SetDefault("job.queueSize", 1000)
+	v.SetDefault("webhook.secret", "")
+	v.SetDefault("webhook.timeout", 10*time.Second)
+	v.SetDefault("webhook.maxAttempts", 8)
+	v.SetDefault("webhook.backoff", 5*time.Second)
+	v.SetDefault("webhook.maxBackoff", 10*time.Minute)
+}
+
+func splitNodes(nodes string) []string {
+	var result []string
+	for _, n := range strings.Split(nodes, ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+// Validate 校验配置项，keyFile会在校验时读入Key
+func (c *ServerConfig) Validate() error {
+	var problems []string
+	check := func(ok bool, msg string) {
+		if !ok {
+			problems = append(problems, msg)
+		}
+	}
+
+	check(c.Server.Listen != "", "server.listen is required")
+	check(c.Server.AdminListen == "" || isLoopback(c.Server.AdminListen), "server.adminListen must be a loopback address")
+	check(c.Server.ReadTimeout >= 0, "server.readTimeout must not be negative")
+	check(c.Server.WriteTimeout == 0 || c.Server.WriteTimeout > c.Chain.CommitTimeout,
+		"server.writeTimeout must be greater than chain.commitTimeout")
+
+	check(c.Chain.ConfigFilePath != "", "chain.configFilePath is required")
+	if c.Chain.ConfigFilePath != "" {
+		_, err := os.Stat(c.Chain.ConfigFilePath)
+		check(err == nil, "chain.configFilePath does not exist: "+c.Chain.ConfigFilePath)
+	}
+	check(c.Chain.ContractName != "", "chain.contractName is required")
+	check(c.Chain.ConsensusNode != "", "chain.consensusNode is required")
+	check(c.Chain.QueryNode != "", "chain.queryNode is required")
+	check(c.Chain.ChainID != "", "chain.chainID is required")
+	check(c.Chain.Timeout >= time.Second, "chain.timeout must be at least 1s")
+	check(c.Chain.CommitTimeout > 0, "chain.commitTimeout must be positive")
+	check(c.Chain.EndorseQuorum >= 0 && (len(c.Chain.EndorserNodes) == 0 || c.Chain.EndorseQuorum <= len(c.Chain.EndorserNodes)),
+		"chain.endorseQuorum must be between 0 and the number of endorser nodes")
+	check(c.Chain.EndorseTimeout >= 0, "chain.endorseTimeout must not be negative")
+	check(c.Chain.ProbeInterval >= 0, "chain.probeInterval must not be negative")
+	check(c.Chain.FailureThreshold > 0, "chain.failureThreshold must be positive")
+	check(c.Chain.BreakerTimeout > 0, "chain.breakerTimeout must be positive")
+
+	switch c.Store.Driver {
+	case "mysql", "sqlite3", "sqlite":
+		check(c.Store.DSN != "", "store.dsn is required for driver "+c.Store.Driver)
+	case "memory":
+	default:
+		problems = append(problems, "store.driver must be one of mysql, sqlite3, memory")
+	}
+	check(c.Cache.Address == "" || c.Cache.Expire > 0, "cache.expire must be positive")
+
+	check(c.Job.Workers > 0, "job.workers must be positive")
+	check(c.Job.QueueSize > 0, "job.queueSize must be positive")
+	if c.Webhook.Secret != "" {
+		check(c.Webhook.Timeout > 0, "webhook.timeout must be positive")
+		check(c.Webhook.MaxAttempts > 0, "webhook.maxAttempts must be positive")
+		check(c.Webhook.Backoff > 0 && c.Webhook.MaxBackoff >= c.Webhook.Backoff,
+			"webhook.backoff must be positive and not greater than webhook.maxBackoff")
+	}
+
+	if c.Crypto.Key == "" && c.Crypto.KeyFile != "" {
+		key, err := ioutil.ReadFile(filepath.Clean(c.Crypto.KeyFile))
+		if err != nil {
+			problems = append(problems, "crypto.keyFile cannot be read: "+err.Error())
+		} else {
+			c.Crypto.Key = strings.TrimSpace(string(key))
+		}
+	}
+	switch len(c.Crypto.Key) {
+	case 0:
+		problems = append(problems, "crypto.key or crypto.keyFile is required")
+	case 16, 24, 32:
+	default:
+		problems = append(problems, "crypto.key must be 16, 24 or 32 bytes")
+	}
+	check(c.Crypto.Algorithm == "AES-GCM" || c.Crypto.Algorithm == "SM4", "crypto.algorithm must be AES-GCM or SM4")
+	check(c.Crypto.KEKFile != "", "crypto.kekFile is required")
+	if c.Crypto.KEKFile != "" {
+		_, err := os.Stat(c.Crypto.KEKFile)
+		check(err == nil, "crypto.kekFile does not exist: "+c.Crypto.KEKFile)
+	}
+
+	if len(problems) > 0 {
+		return errors.Errorf("invalid server config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// 监听地址的主机是否为本机回环地址，":8001"等监听全部网卡的地址不是
+func isLoopback(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func applyServerConfig(cfg ServerConfig) {
+	serverConfig = cfg
+	appConfig = Config{
+		ConfigFilePath:   cfg.Chain.ConfigFilePath,
+		ContractName:     cfg.Chain.ContractName,
+		EndorserNodes:    strings.Join(cfg.Chain.EndorserNodes, ","),
+		ConsensusNode:    cfg.Chain.ConsensusNode,
+		QueryNode:        cfg.Chain.QueryNode,
+		ChainID:          cfg.Chain.ChainID,
+		StoreDriver:      cfg.Store.Driver,
+		StoreDSN:         cfg.Store.DSN,
+		CommitTimeout:    cfg.Chain.CommitTimeout,
+		EndorseQuorum:    cfg.Chain.EndorseQuorum,
+		EndorseTimeout:   cfg.Chain.EndorseTimeout,
+		ProbeInterval:    cfg.Chain.ProbeInterval,
+		FailureThreshold: cfg.Chain.FailureThreshold,
+		BreakerTimeout:   cfg.Chain.BreakerTimeout,
+	}
+	sdkutils.SetTimeout(cfg.Chain.Timeout / time.Second)
 	SetSignAlg(appConfig)
 }
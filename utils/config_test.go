@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_LoadConfig(t *testing.T) {
+	os.Setenv("CD_CHAIN_CONFIGFILEPATH", "../configuration/sdk.yaml")
+	defer os.Unsetenv("CD_CHAIN_CONFIGFILEPATH")
+
+	// 仓库中的配置不包含数据库连接串、旧版密钥和KEK文件，未通过环境变量指定时启动失败
+	_, err := LoadConfig("../configuration/server.yaml")
+	for _, want := range []string{"store.dsn is required for driver mysql", "crypto.key or crypto.keyFile is required",
+		"crypto.kekFile is required"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error %q, got %v", want, err)
+		}
+	}
+	os.Setenv("CD_STORE_DRIVER", "memory")
+	os.Setenv("CD_CRYPTO_KEY", "0123456789abcdef")
+	defer os.Unsetenv("CD_STORE_DRIVER")
+	defer os.Unsetenv("CD_CRYPTO_KEY")
+	kekFile := filepath.Join(t.TempDir(), "kek.json")
+	if err := ioutil.WriteFile(kekFile, []byte(`{"current":"k1","keys":{"k1":"AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE="}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("CD_CRYPTO_KEKFILE", kekFile)
+	defer os.Unsetenv("CD_CRYPTO_KEKFILE")
+
+	cfg, err := LoadConfig("../configuration/server.yaml")
+	if err != nil {
+		t.Fatalf("load config error: %v", err)
+	}
+	if cfg.Store.Driver != "memory" {
+		t.Errorf("env override not applied, store driver: %v", cfg.Store.Driver)
+	}
+	if len(cfg.Chain.EndorserNodes) != 3 {
+		t.Errorf("unexpected endorser nodes: %v", cfg.Chain.EndorserNodes)
+	}
+	if cfg.Chain.CommitTimeout != 60*time.Second {
+		t.Errorf("unexpected commit timeout: %v", cfg.Chain.CommitTimeout)
+	}
+	if AppConfig().EndorserNodes != strings.Join(cfg.Chain.EndorserNodes, ",") ||
+		AppConfig().ChainID != cfg.Chain.ChainID {
+		t.Errorf("app config not built from server config: %+v", AppConfig())
+	}
+}
+
+func Test_ServerConfig_Validate(t *testing.T) {
+	cfg, err := ReadServerConfig("")
+	if err != nil {
+		t.Fatalf("read default config error: %v", err)
+	}
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "crypto.key or crypto.keyFile is required") {
+		t.Errorf("expected missing legacy key error, got %v", err)
+	}
+	cfg.Crypto.Key = "short"
+	cfg.Server.AdminListen = ":8001"
+	err = cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation error for empty config")
+	}
+	for _, want := range []string{"chain.configFilePath is required", "chain.chainID is required",
+		"crypto.key must be 16, 24 or 32 bytes", "crypto.kekFile is required", "server.adminListen must be a loopback address"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("validation error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func Test_IsLoopback(t *testing.T) {
+	for address, want := range map[string]bool{
+		"127.0.0.1:8001": true,
+		"localhost:8001": true,
+		"[::1]:8001":     true,
+		":8001":          false,
+		"0.0.0.0:8001":   false,
+		"10.0.0.1:8001":  false,
+		"127.0.0.1":      false,
+	} {
+		if got := isLoopback(address); got != want {
+			t.Errorf("isLoopback(%q) = %v, want %v", address, got, want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -19,23 +20,65 @@ type Nodes struct {
 	Endorsers     []*node.WNode
 	Proposer      *node.WNode
 	EventListener *node.WNode
+	Pool          *NodePool // 为nil时只使用上面指定的节点，不做故障切换
+}
+
+// do 在节点上执行call，设置了Pool时节点熔断或调用失败后切换到同组织的其它可用节点，返回实际使用的节点；
+// ctx取消后不再切换节点
+func (n *Nodes) do(ctx context.Context, primary *node.WNode, call func(n *node.WNode) error) (string, error) {
+	if n.Pool == nil {
+		return primary.ID, call(primary)
+	}
+	return n.Pool.DoCtx(ctx, primary.ID, call)
 }
 
 const (
+	// WaitTime 等待交易落块的默认超时时间（秒），可通过 chain.commitTimeout 配置
 	WaitTime = 60
 )
 
-var txEvent *event.TxEventService
+// 异步上链时交易所处的阶段，VALID及其它落块状态取自common.TxResult
+const (
+	TxStageEndorsed  = "endorsed"
+	TxStageSubmitted = "submitted"
+)
 
-func SetTxEvent(txEventClient *event.TxEventService) {
-	txEvent = txEventClient
+// TxProgress 交易进度回调，背书完成后即可得到交易哈希
+type TxProgress func(stage string, txHash string)
+
+// TxStatusError 交易已落块但未通过校验，Status为common.TxStatus的名称
+type TxStatusError struct {
+	TxHash string
+	Status string
+}
+
+func (e *TxStatusError) Error() string {
+	return fmt.Sprintf("transaction %s committed with status %s", e.TxHash, e.Status)
 }
 
-func getTxEvent() *event.TxEventService {
-	return txEvent
+// txRegistrar 注册交易哈希并返回交易结果通道，由event.TxEventService或Session实现
+type txRegistrar interface {
+	RegisterTx(txHash []byte) (chan *common.TxResult, error)
+	UnregisterTx(txHash []byte)
 }
 
+// Send 发送一笔交易并等待落块，每次调用都会在net.EventListener上新建交易事件服务，
+// 服务端长期运行时请使用Session.Send复用连接
 func Send(gatewayClient *client.GatewayClient, net *Nodes, config Config, funcName string, args string) (*common.RawMessage, string, error) {
+	return SendCtx(context.Background(), gatewayClient, net, config, funcName, args)
+}
+
+// SendCtx 与Send相同，背书请求受ctx控制，ctx取消时中止背书且不提交交易；交易提交后等待落块不受ctx影响
+func SendCtx(ctx context.Context, gatewayClient *client.GatewayClient, net *Nodes, config Config, funcName string, args string) (*common.RawMessage, string, error) {
+	txEvent, err := net.EventListener.EventAction.GetTxEventService(config.ChainID)
+	if err != nil {
+		return nil, "", errors.WithMessage(err, "event action get tx event service error")
+	}
+	defer txEvent.Close()
+	return send(ctx, gatewayClient, net, config, txEvent, funcName, args, nil)
+}
+
+func send(ctx context.Context, gatewayClient *client.GatewayClient, net *Nodes, config Config, txEvent txRegistrar, funcName string, args string, progress TxProgress) (*common.RawMessage, string, error) {
 	// 1.入参处理
 	var err error
 	argsSlice := strings.Split(strings.TrimSpace(args), ";")
@@ -48,7 +91,7 @@ func Send(gatewayClient *client.GatewayClient, net *Nodes, config Config, funcNa
 	}
 
 	// 3.背书消息请求发送
-	invokeResponses, net, err := sendInvokeRawMsg(gatewayClient, net, config, endorseNodes, rawMsg)
+	invokeResponses, net, err := sendInvokeRawMsg(ctx, gatewayClient, net, config, endorseNodes, rawMsg)
 	if err != nil {
 		return nil, "", errors.WithMessage(err, "send raw messessage error")
 	}
@@ -58,9 +101,12 @@ func Send(gatewayClient *client.GatewayClient, net *Nodes, config Config, funcNa
 	if err != nil {
 		return nil, "", errors.WithMessage(err, "build transaction message error")
 	}
+	if progress != nil {
+		progress(TxStageEndorsed, Hash2str(txRawMsg.Hash))
+	}
 
 	// 5.落盘消息发送
-	responseMsg, txResult, err := sendTransactionRawMsg(config, txRawMsg, net)
+	responseMsg, txResult, err := sendTransactionRawMsg(ctx, config, txRawMsg, net, txEvent, progress)
 	if err != nil {
 		return nil, "", errors.WithMessage(err, "build transaction message error")
 	}
@@ -77,14 +123,41 @@ func Send(gatewayClient *client.GatewayClient, net *Nodes, config Config, funcNa
 		return nil, "", errors.WithMessage(err, "unmarshal transaction response error")
 	}
 
-	if txResult.Status.String() == "VALID" {
+	if txResult.Status == common.VALID {
 		return txResponse, Hash2str(txResult.TxHash), nil
 	}
 
-	return txResponse, "", errors.WithMessage(err, "send transaction error")
+	return txResponse, "", &TxStatusError{TxHash: Hash2str(txRawMsg.Hash), Status: txResult.Status.String()}
+}
+
+// SendTx 提交已构造的交易（如合约生命周期投票交易）并等待落块，交易在net.Proposer上提交
+func SendTx(net *Nodes, config Config, txRawMsg *rawmessage.TxRawMsg) (*common.TxResult, error) {
+	return SendTxCtx(context.Background(), net, config, txRawMsg)
+}
+
+// SendTxCtx 与SendTx相同，ctx在提交前已取消时不提交交易
+func SendTxCtx(ctx context.Context, net *Nodes, config Config, txRawMsg *rawmessage.TxRawMsg) (*common.TxResult, error) {
+	txEvent, err := net.EventListener.EventAction.GetTxEventService(config.ChainID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "event action get tx event service error")
+	}
+	defer txEvent.Close()
+	_, txResult, err := sendTransactionRawMsg(ctx, config, txRawMsg, net, txEvent, nil)
+	if err != nil {
+		return nil, errors.WithMessage(err, "send transaction error")
+	}
+	if txResult.Status != common.VALID {
+		return txResult, &TxStatusError{TxHash: Hash2str(txRawMsg.Hash), Status: txResult.Status.String()}
+	}
+	return txResult, nil
 }
 
 func Query(gatewayClient *client.GatewayClient, net *Nodes, config Config, funcName string, args string) (string, error) {
+	return QueryCtx(context.Background(), gatewayClient, net, config, funcName, args)
+}
+
+// QueryCtx 与Query相同，ctx取消时立即返回
+func QueryCtx(ctx context.Context, gatewayClient *client.GatewayClient, net *Nodes, config Config, funcName string, args string) (string, error) {
 	// 1.入参处理
 	var err error
 	argsSlice := strings.Split(strings.TrimSpace(args), ";")
@@ -97,7 +170,7 @@ func Query(gatewayClient *client.GatewayClient, net *Nodes, config Config, funcN
 	}
 
 	// 3.发送请求消息
-	invokeResponses, _, err := sendInvokeRawMsg(gatewayClient, net, config, endorseNodes, rawMsg)
+	invokeResponses, _, err := sendInvokeRawMsg(ctx, gatewayClient, net, config, endorseNodes, rawMsg)
 	if err != nil {
 		return "", errors.WithMessage(err, "send raw messessage error")
 	}
@@ -122,7 +195,6 @@ func Query(gatewayClient *client.GatewayClient, net *Nodes, config Config, funcN
 	if err := proto.Unmarshal(txPayLoad.Data, txData); err != nil {
 		return "", errors.WithMessage(err, "unmarshal common tx data error")
 	}
-	fmt.Printf("query transaction success, result:%s\n", string(txData.Response.Payload))
 	return string(txData.Response.Payload), nil
 }
 
@@ -130,16 +202,11 @@ func getInvokeRawMsg(gatewayClient *client.GatewayClient, config Config, endorse
 	return gatewayClient.ContractRawMessage.BuildInvokeMessage(config.ChainID, config.ContractName, function, args)
 }
 
-func sendInvokeRawMsg(gatewayClient *client.GatewayClient, net *Nodes, config Config, endorseNodes []string, rawMsg *common.RawMessage) ([]*common.RawMessage, *Nodes, error) {
-	// 背书请求消息发送
-	var invokeResponses []*common.RawMessage
-	for _, node := range net.Endorsers {
-		var invokeResponse *common.RawMessage
-		invokeResponse, err := node.ContractAction.Invoke(rawMsg)
-		if err != nil {
-			return nil, nil, errors.WithMessage(err, "invoke error")
-		}
-		invokeResponses = append(invokeResponses, invokeResponse)
+func sendInvokeRawMsg(ctx context.Context, gatewayClient *client.GatewayClient, net *Nodes, config Config, endorseNodes []string, rawMsg *common.RawMessage) ([]*common.RawMessage, *Nodes, error) {
+	// 背书请求并行发送，返回执行结果一致的背书响应
+	invokeResponses, err := endorse(ctx, net, rawMsg, config.EndorseQuorum, config.EndorseTimeout)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "endorse error")
 	}
 	return invokeResponses, net, nil
 }
@@ -148,23 +215,44 @@ func getTransactionRawMsg(client *client.GatewayClient, transactionRawMsg []*com
 	return client.ContractRawMessage.BuildTxRawMsg(transactionRawMsg)
 }
 
-func sendTransactionRawMsg(config Config, txRawMsg *rawmessage.TxRawMsg, net *Nodes) (*common.RawMessage, *common.TxResult, error) {
-	txEventClient := getTxEvent()
-
-	resultChan, err := txEventClient.RegisterTx(txRawMsg.Hash)
+// sendTransactionRawMsg 提交交易并等待落块。ctx在提交前已取消时不再提交；
+// 交易一经提交，提交和等待落块不再受ctx影响，以便调用方得到确定的结果。
+// 提交失败或等待超时时取消交易的注册，不在共享的交易事件服务上遗留等待
+func sendTransactionRawMsg(ctx context.Context, config Config, txRawMsg *rawmessage.TxRawMsg, net *Nodes, txEvent txRegistrar, progress TxProgress) (*common.RawMessage, *common.TxResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, errors.WithMessage(err, "transaction not submitted")
+	}
+	resultChan, err := txEvent.RegisterTx(txRawMsg.Hash)
 	if err != nil {
 		return nil, nil, errors.WithMessage(err, "tx event register tx id error")
 	}
 
-	transactionResponse, err := net.Proposer.ContractAction.Transaction(txRawMsg.Msg)
+	var transactionResponse *common.RawMessage
+	_, err = net.do(context.Background(), net.Proposer, func(n *node.WNode) (err error) {
+		transactionResponse, err = n.ContractAction.Transaction(txRawMsg.Msg)
+		return err
+	})
 	if err != nil {
+		txEvent.UnregisterTx(txRawMsg.Hash)
 		return nil, nil, errors.WithMessage(err, "invoke error")
 	}
+	if progress != nil {
+		progress(TxStageSubmitted, Hash2str(txRawMsg.Hash))
+	}
 
+	commitTimeout := config.CommitTimeout
+	if commitTimeout <= 0 {
+		commitTimeout = WaitTime * time.Second
+	}
 	select {
-	case txResult := <-resultChan:
+	case txResult, ok := <-resultChan:
+		if !ok {
+			// 事件流断开且无法恢复，交易可能已经落块，需按交易哈希查询结果
+			return nil, nil, errors.WithMessagef(event.ErrStreamLost, "wait result of tx %s error", Hash2str(txRawMsg.Hash))
+		}
 		return transactionResponse, txResult, nil
-	case <-time.After(WaitTime * time.Second):
+	case <-time.After(commitTimeout):
+		txEvent.UnregisterTx(txRawMsg.Hash)
 		return nil, nil, errors.Errorf("send transaction time out")
 	}
 }
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"git.huawei.com/huaweichain/proto/common"
+	"git.huawei.com/huaweichain/sdk/client"
+	"git.huawei.com/huaweichain/sdk/config"
+	"git.huawei.com/huaweichain/sdk/node"
+	"git.huawei.com/huaweichain/sdk/rawmessage"
+)
+
+// fakeRegistrar 记录等待结果的交易
+type fakeRegistrar struct {
+	pending map[string]bool
+}
+
+func (f *fakeRegistrar) RegisterTx(txHash []byte) (chan *common.TxResult, error) {
+	f.pending[hex.EncodeToString(txHash)] = true
+	return make(chan *common.TxResult, 1), nil
+}
+
+func (f *fakeRegistrar) UnregisterTx(txHash []byte) {
+	delete(f.pending, hex.EncodeToString(txHash))
+}
+
+func Test_sendTransactionRawMsg_Unregister(t *testing.T) {
+	// 共识节点不可用，交易提交失败
+	proposer := &node.WNode{Node: &config.Node{ID: "node-0.org1"}}
+	net := &Nodes{Proposer: proposer, Pool: NewNodePool(&client.GatewayClient{Nodes: map[string]*node.WNode{}}, "chain", PoolOptions{})}
+	registrar := &fakeRegistrar{pending: map[string]bool{}}
+	txRawMsg := &rawmessage.TxRawMsg{Hash: []byte{1, 2, 3}, Msg: &common.RawMessage{}}
+
+	if _, _, err := sendTransactionRawMsg(context.Background(), Config{}, txRawMsg, net, registrar, nil); err == nil {
+		t.Fatal("expected error submitting to unavailable node")
+	}
+	if len(registrar.pending) != 0 {
+		t.Errorf("tx still registered after submission failed: %v", registrar.pending)
+	}
+}
@@ -0,0 +1,215 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"git.huawei.com/huaweichain/proto"
+	"git.huawei.com/huaweichain/proto/common"
+	"git.huawei.com/huaweichain/sdk/node"
+	"github.com/pkg/errors"
+)
+
+// EndorseMismatchError 背书节点的执行结果不一致，Node为与Reference节点结果不同的节点，
+// Field为不一致的部分，取值为"response"或"read/write set"
+type EndorseMismatchError struct {
+	Node      string
+	Reference string
+	Field     string
+}
+
+func (e *EndorseMismatchError) Error() string {
+	return fmt.Sprintf("endorsement of node %s differs from node %s in %s", e.Node, e.Reference, e.Field)
+}
+
+// endorsement 单个背书节点的响应
+type endorsement struct {
+	node string
+	msg  *common.RawMessage
+	err  error
+}
+
+// endorseGroup 执行结果一致的一组背书响应
+type endorseGroup struct {
+	response []byte // 合约执行结果
+	updates  []byte // 读写集
+	nodes    []string
+	msgs     []*common.RawMessage
+}
+
+// endorse 并行向全部背书节点发送背书请求，收到quorum个一致的响应后立即返回，
+// quorum为0或大于背书节点数时需全部节点一致；timeout为0时仅受SDK调用超时限制。
+// 背书节点不可用时由同组织的其它可用节点背书，ctx取消时未完成的背书请求随之取消
+func endorse(ctx context.Context, net *Nodes, rawMsg *common.RawMessage, quorum int, timeout time.Duration) ([]*common.RawMessage, error) {
+	return endorseAll(ctx, len(net.Endorsers), func(ctx context.Context, i int) endorsement {
+		endorser := net.Endorsers[i]
+		var msg *common.RawMessage
+		name, err := net.do(ctx, endorser, func(n *node.WNode) (err error) {
+			msg, err = n.ContractAction.InvokeCtx(ctx, rawMsg)
+			return err
+		})
+		if name == "" {
+			name = endorser.ID
+		}
+		return endorsement{node: name, msg: msg, err: err}
+	}, quorum, timeout)
+}
+
+// endorseAll 并行执行total个背书调用，每个调用使用由ctx派生、timeout后超时的ctx，
+// 收集结束（达到quorum、判定失败或超时）后取消仍未完成的调用，避免其占用连接直到SDK超时
+func endorseAll(ctx context.Context, total int, call func(ctx context.Context, i int) endorsement, quorum int,
+	timeout time.Duration) ([]*common.RawMessage, error) {
+	// 缓冲区容纳全部响应，提前返回后剩余的调用不会阻塞
+	results := make(chan endorsement, total)
+	cancels := make([]context.CancelFunc, 0, total)
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+	for i := 0; i < total; i++ {
+		var callCtx context.Context
+		var cancel context.CancelFunc
+		if timeout > 0 {
+			callCtx, cancel = context.WithTimeout(ctx, timeout)
+		} else {
+			callCtx, cancel = context.WithCancel(ctx)
+		}
+		cancels = append(cancels, cancel)
+		go func(i int) {
+			results <- call(callCtx, i)
+		}(i)
+	}
+	return collectEndorsements(results, total, quorum, timeout)
+}
+
+func collectEndorsements(results <-chan endorsement, total int, quorum int, timeout time.Duration) ([]*common.RawMessage, error) {
+	if quorum <= 0 || quorum > total {
+		quorum = total
+	}
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	var groups []*endorseGroup
+	var failures []string
+	for received := 1; received <= total; received++ {
+		select {
+		case e := <-results:
+			group, err := addEndorsement(&groups, e)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", e.node, err))
+			} else if len(group.msgs) >= quorum {
+				return group.msgs, nil
+			} else if len(groups) > 1 && quorum == total {
+				// 要求全部节点一致时，出现不同结果即可判定失败
+				return nil, mismatch(groups[0], group)
+			}
+			if largest(groups)+total-received < quorum {
+				return nil, notEnough(groups, failures, quorum)
+			}
+		case <-deadline:
+			return nil, errors.WithMessagef(notEnough(groups, failures, quorum), "endorsement timed out after %v", timeout)
+		}
+	}
+	return nil, notEnough(groups, failures, quorum)
+}
+
+// addEndorsement 解析背书响应中的执行结果和读写集，并归入结果一致的分组
+func addEndorsement(groups *[]*endorseGroup, e endorsement) (*endorseGroup, error) {
+	if e.err != nil {
+		return nil, errors.WithMessage(e.err, "invoke error")
+	}
+	// 多个背书节点切换到同一节点时，只计一次
+	for _, group := range *groups {
+		if containsString(group.nodes, e.node) {
+			return nil, errors.New("duplicate endorsement")
+		}
+	}
+	payload, err := GetPayloadWithResp(e.msg)
+	if err != nil {
+		return nil, err
+	}
+	transaction := &common.Transaction{}
+	if err := proto.Unmarshal(payload, transaction); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal transaction error")
+	}
+	txPayload := &common.TxPayload{}
+	if err := proto.Unmarshal(transaction.Payload, txPayload); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal tx payload error")
+	}
+	txData := &common.CommonTxData{}
+	if err := proto.Unmarshal(txPayload.Data, txData); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal common tx data error")
+	}
+	response, err := proto.Marshal(txData.Response)
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal invocation response error")
+	}
+	var updates []byte
+	for _, stateUpdates := range txData.StateUpdates {
+		raw, err := proto.Marshal(stateUpdates)
+		if err != nil {
+			return nil, errors.WithMessage(err, "marshal state updates error")
+		}
+		updates = append(updates, raw...)
+	}
+
+	for _, group := range *groups {
+		if bytes.Equal(group.response, response) && bytes.Equal(group.updates, updates) {
+			group.nodes = append(group.nodes, e.node)
+			group.msgs = append(group.msgs, e.msg)
+			return group, nil
+		}
+	}
+	group := &endorseGroup{response: response, updates: updates, nodes: []string{e.node}, msgs: []*common.RawMessage{e.msg}}
+	*groups = append(*groups, group)
+	return group, nil
+}
+
+func mismatch(reference *endorseGroup, group *endorseGroup) error {
+	field := "read/write set"
+	if !bytes.Equal(reference.response, group.response) {
+		field = "response"
+	}
+	return &EndorseMismatchError{Node: group.nodes[0], Reference: reference.nodes[0], Field: field}
+}
+
+func largest(groups []*endorseGroup) int {
+	size := 0
+	for _, group := range groups {
+		if len(group.msgs) > size {
+			size = len(group.msgs)
+		}
+	}
+	return size
+}
+
+// notEnough 一致的背书不足时的错误，存在不一致的结果时指明与多数结果不同的节点
+func notEnough(groups []*endorseGroup, failures []string, quorum int) error {
+	var reference *endorseGroup
+	for _, group := range groups {
+		if reference == nil || len(group.msgs) > len(reference.msgs) {
+			reference = group
+		}
+	}
+	for _, group := range groups {
+		if group != reference {
+			return errors.WithMessagef(mismatch(reference, group), "%d of %d required matching endorsements", len(reference.msgs), quorum)
+		}
+	}
+	received := 0
+	if reference != nil {
+		received = len(reference.msgs)
+	}
+	if len(failures) > 0 {
+		return errors.Errorf("%d of %d required endorsements received, %s", received, quorum, strings.Join(failures, "; "))
+	}
+	return errors.Errorf("%d of %d required endorsements received", received, quorum)
+}
@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"git.huawei.com/huaweichain/proto"
+	"git.huawei.com/huaweichain/proto/common"
+	"github.com/pkg/errors"
+)
+
+// 构造背书响应，result为合约执行结果，value为写入key的值
+func endorsementOf(node string, result string, value string) endorsement {
+	txData, _ := proto.Marshal(&common.CommonTxData{
+		Response: &common.InvocationResponse{Status: common.SUCCESS, Payload: []byte(result)},
+		StateUpdates: []*common.StateUpdates{{
+			Namespace: "finance",
+			Updates: &common.StateUpdates_KvUpdates{KvUpdates: &common.KvStateUpdates{
+				Updates: []*common.KeyValue{{Key: "key", Value: []byte(value)}},
+			}},
+		}},
+	})
+	txPayload, _ := proto.Marshal(&common.TxPayload{Data: txData})
+	transaction, _ := proto.Marshal(&common.Transaction{Payload: txPayload})
+	response, _ := proto.Marshal(&common.Response{Status: common.SUCCESS, Payload: transaction})
+	return endorsement{node: node, msg: &common.RawMessage{Payload: response}}
+}
+
+func Test_collectEndorsements(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []endorsement
+		total   int
+		quorum  int
+		want    int
+		wantErr string
+	}{
+		{"all match", []endorsement{endorsementOf("n0", "ok", "v"), endorsementOf("n1", "ok", "v")}, 2, 0, 2, ""},
+		// 第三个节点未响应，两个一致的背书即可返回
+		{"quorum reached", []endorsement{endorsementOf("n0", "ok", "v"), endorsementOf("n1", "ok", "v")}, 3, 2, 2, ""},
+		{"quorum with divergent node", []endorsement{endorsementOf("n0", "ok", "v"), endorsementOf("n1", "ok", "x"),
+			endorsementOf("n2", "ok", "v")}, 3, 2, 2, ""},
+		{"write set differs", []endorsement{endorsementOf("n0", "ok", "v"), endorsementOf("n1", "ok", "x")}, 3, 0, 0,
+			"endorsement of node n1 differs from node n0 in read/write set"},
+		{"response differs", []endorsement{endorsementOf("n0", "ok", "v"), endorsementOf("n1", "ko", "v"),
+			endorsementOf("n2", "no", "v")}, 3, 2, 0, "differs from node n0 in response"},
+		{"node error", []endorsement{{node: "n0", err: errors.New("connection refused")}}, 2, 0, 0,
+			"0 of 2 required endorsements received, n0: invoke error: connection refused"},
+		{"timeout", []endorsement{endorsementOf("n0", "ok", "v")}, 2, 0, 0, "endorsement timed out"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := make(chan endorsement, len(tt.results))
+			for _, result := range tt.results {
+				results <- result
+			}
+			msgs, err := collectEndorsements(results, tt.total, tt.quorum, 50*time.Millisecond)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || len(msgs) != tt.want {
+				t.Fatalf("got %d endorsements, error %v, want %d", len(msgs), err, tt.want)
+			}
+		})
+	}
+
+	results := make(chan endorsement, 2)
+	results <- endorsementOf("n0", "ok", "v")
+	results <- endorsementOf("n1", "ok", "x")
+	_, err := collectEndorsements(results, 2, 0, time.Second)
+	var mismatch *EndorseMismatchError
+	if !errors.As(err, &mismatch) || mismatch.Node != "n1" || mismatch.Field != "read/write set" {
+		t.Errorf("unexpected mismatch error: %v", err)
+	}
+}
+
+func Test_EndorseAll_Cancel(t *testing.T) {
+	// n0、n1先返回一致结果达到quorum，n2阻塞至ctx结束
+	blocked := make(chan error, 1)
+	call := func(ctx context.Context, i int) endorsement {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Errorf("endorsement call %d has no deadline", i)
+		}
+		if i == 2 {
+			<-ctx.Done()
+			blocked <- ctx.Err()
+			return endorsement{node: "n2", err: ctx.Err()}
+		}
+		return endorsementOf([]string{"n0", "n1"}[i], "ok", "v")
+	}
+	msgs, err := endorseAll(context.Background(), 3, call, 2, time.Minute)
+	if err != nil || len(msgs) != 2 {
+		t.Fatalf("got %d endorsements, error %v", len(msgs), err)
+	}
+	select {
+	case err := <-blocked:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("unexpected error of the pending call: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the pending endorsement call was not canceled")
+	}
+
+	// 全部调用阻塞时，超时后各调用的ctx随之超时
+	pending := make(chan error, 2)
+	call = func(ctx context.Context, i int) endorsement {
+		<-ctx.Done()
+		pending <- ctx.Err()
+		return endorsement{node: "n", err: ctx.Err()}
+	}
+	if _, err := endorseAll(context.Background(), 2, call, 0, 50*time.Millisecond); err == nil {
+		t.Fatal("expected endorsement timeout")
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-pending:
+			if err == nil {
+				t.Error("the endorsement call finished without error")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("the endorsement call did not time out")
+		}
+	}
+}
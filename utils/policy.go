@@ -0,0 +1,314 @@
+// policy.go 合约背书策略解析和背书节点选择
+package utils
+
+import (
+	"sort"
+	"strings"
+
+	"git.huawei.com/huaweichain/proto/common"
+	"git.huawei.com/huaweichain/proto/nodeservice"
+	"git.huawei.com/huaweichain/sdk/client"
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// policyExtension 背书策略在合约定义ValidatorExtensions中的key，由LifecycleRawMessage.BuildVoteRawMessage写入
+const policyExtension = "policy"
+
+// QueryEndorsementPolicy 通过查询节点的QueryAction.GetContractInfo查询合约的背书策略
+func QueryEndorsementPolicy(gatewayClient *client.GatewayClient, config Config) (*common.Policy, error) {
+	node, ok := gatewayClient.Nodes[config.QueryNode]
+	if !ok {
+		return nil, errors.Errorf("node not exist： %v", config.QueryNode)
+	}
+	rawMsg, err := gatewayClient.QueryRawMessage.BuildContractRawMessage(config.ChainID, config.ContractName)
+	if err != nil {
+		return nil, errors.WithMessage(err, "build contract raw message error")
+	}
+	responseMsg, err := node.QueryAction.GetContractInfo(rawMsg)
+	if err != nil {
+		return nil, errors.WithMessage(err, "query action get contract info error")
+	}
+	payload, err := GetPayloadWithResp(responseMsg)
+	if err != nil {
+		return nil, errors.WithMessage(err, "parse response msg with struct type error")
+	}
+	contractInfo := &nodeservice.ContractInfoQueryResponse{}
+	if err := proto.Unmarshal(payload, contractInfo); err != nil {
+		return nil, errors.WithMessage(err, "unmarshal contract info error")
+	}
+	if contractInfo.Definition == nil || len(contractInfo.Definition.ValidatorExtensions[policyExtension]) == 0 {
+		return nil, errors.Errorf("contract %s has no endorsement policy", config.ContractName)
+	}
+	return ParsePolicy(contractInfo.Definition.ValidatorExtensions[policyExtension])
+}
+
+// ParsePolicy 解析背书策略，支持序列化的common.Policy和文本表达式。
+// 文本表达式由组织名、"&"（或AND）、"|"（或OR）和括号组成，AND优先于OR，如 "org1 & (org2 | org3)"
+func ParsePolicy(raw []byte) (*common.Policy, error) {
+	policy := &common.Policy{}
+	if err := proto.Unmarshal(raw, policy); err == nil && validPolicy(policy) {
+		return policy, nil
+	}
+	p := &policyParser{tokens: tokenizePolicy(string(raw))}
+	policy, err := p.or()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "parse endorsement policy %q error", raw)
+	}
+	if p.pos < len(p.tokens) {
+		return nil, errors.Errorf("parse endorsement policy %q error: unexpected %q", raw, p.tokens[p.pos])
+	}
+	return policy, nil
+}
+
+func validPolicy(policy *common.Policy) bool {
+	if policy == nil {
+		return false
+	}
+	switch ast := policy.Ast.(type) {
+	case *common.Policy_Terminal:
+		return ast.Terminal != nil && ast.Terminal.Token != ""
+	case *common.Policy_NonTerminal:
+		return ast.NonTerminal != nil && validPolicy(ast.NonTerminal.LeftToken) && validPolicy(ast.NonTerminal.RightToken)
+	}
+	return false
+}
+
+func tokenizePolicy(expr string) []string {
+	for _, op := range []string{"(", ")", "&", "|"} {
+		expr = strings.ReplaceAll(expr, op, " "+op+" ")
+	}
+	tokens := strings.Fields(expr)
+	for i, token := range tokens {
+		switch strings.ToUpper(token) {
+		case "AND":
+			tokens[i] = "&"
+		case "OR":
+			tokens[i] = "|"
+		}
+	}
+	return tokens
+}
+
+// policyParser 递归下降解析：or := and {"|" and}，and := term {"&" term}，term := 组织名 | "(" or ")"
+type policyParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *policyParser) next() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *policyParser) binary(op common.Operator, symbol string, operand func() (*common.Policy, error)) (*common.Policy, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.next() == symbol {
+		p.pos++
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &common.Policy{Ast: &common.Policy_NonTerminal{NonTerminal: &common.NonTerminal{Op: op, LeftToken: left, RightToken: right}}}
+	}
+	return left, nil
+}
+
+func (p *policyParser) or() (*common.Policy, error) {
+	return p.binary(common.OR, "|", p.and)
+}
+
+func (p *policyParser) and() (*common.Policy, error) {
+	return p.binary(common.AND, "&", p.term)
+}
+
+func (p *policyParser) term() (*common.Policy, error) {
+	switch token := p.next(); token {
+	case "":
+		return nil, errors.New("unexpected end of policy")
+	case "&", "|", ")":
+		return nil, errors.Errorf("unexpected %q", token)
+	case "(":
+		p.pos++
+		policy, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, errors.New("missing )")
+		}
+		p.pos++
+		return policy, nil
+	default:
+		p.pos++
+		return &common.Policy{Ast: &common.Policy_Terminal{Terminal: &common.Terminal{Token: token}}}, nil
+	}
+}
+
+// policyOrg 策略中的组织名，"组织.角色"形式的token取组织部分
+func policyOrg(token string) string {
+	if i := strings.Index(token, "."); i > 0 {
+		return token[:i]
+	}
+	return token
+}
+
+// policyAlternatives 将策略展开为满足策略的组织组合，每个组合中的组织都背书即满足策略
+func policyAlternatives(policy *common.Policy) [][]string {
+	switch ast := policy.Ast.(type) {
+	case *common.Policy_Terminal:
+		return [][]string{{policyOrg(ast.Terminal.Token)}}
+	case *common.Policy_NonTerminal:
+		left, right := policyAlternatives(ast.NonTerminal.LeftToken), policyAlternatives(ast.NonTerminal.RightToken)
+		if ast.NonTerminal.Op == common.OR {
+			return append(left, right...)
+		}
+		var result [][]string
+		for _, l := range left {
+			for _, r := range right {
+				result = append(result, unionOrgs(l, r))
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+func unionOrgs(a []string, b []string) []string {
+	result := append([]string(nil), a...)
+	for _, org := range b {
+		if !containsString(result, org) {
+			result = append(result, org)
+		}
+	}
+	return result
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// MinimalOrgs 满足策略所需的最少组织，只考虑available返回true的组织，组织数相同时取策略中靠前的组合
+func MinimalOrgs(policy *common.Policy, available func(org string) bool) ([]string, error) {
+	alternatives := policyAlternatives(policy)
+	sort.SliceStable(alternatives, func(i, j int) bool {
+		return len(alternatives[i]) < len(alternatives[j])
+	})
+	for _, orgs := range alternatives {
+		usable := true
+		for _, org := range orgs {
+			usable = usable && available(org)
+		}
+		if usable {
+			return orgs, nil
+		}
+	}
+	return nil, errors.New("endorsement policy cannot be satisfied by available orgs")
+}
+
+// PolicySatisfied orgs中的组织是否满足策略
+func PolicySatisfied(policy *common.Policy, orgs []string) bool {
+	_, err := MinimalOrgs(policy, func(org string) bool {
+		return containsString(orgs, org)
+	})
+	return err == nil
+}
+
+// MissingOrgs 在orgs之外还需要哪些组织才能满足策略，取缺少组织最少的组合，已满足时返回nil
+func MissingOrgs(policy *common.Policy, orgs []string) []string {
+	var missing []string
+	found := false
+	for _, alternative := range policyAlternatives(policy) {
+		var lack []string
+		for _, org := range alternative {
+			if !containsString(orgs, org) {
+				lack = append(lack, org)
+			}
+		}
+		if !found || len(lack) < len(missing) {
+			missing, found = lack, true
+		}
+	}
+	return missing
+}
+
+// NodeOrg 节点所属组织，sdk.yaml中的节点名格式为"节点.组织"，如 node-0.organization-b4fydwesq
+func NodeOrg(nodeName string) string {
+	if i := strings.Index(nodeName, "."); i >= 0 {
+		return nodeName[i+1:]
+	}
+	return ""
+}
+
+// SelectEndorsers 查询合约背书策略，按最少组织选择背书节点，每个组织选择一个能查询到链状态的节点
+func SelectEndorsers(gatewayClient *client.GatewayClient, config Config) ([]string, error) {
+	policy, err := QueryEndorsementPolicy(gatewayClient, config)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for name := range gatewayClient.Nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	healthy := make(map[string]string) // 组织 -> 节点
+	checked := make(map[string]bool)
+	available := func(org string) bool {
+		if checked[org] {
+			return healthy[org] != ""
+		}
+		checked[org] = true
+		for _, name := range names {
+			if NodeOrg(name) != org {
+				continue
+			}
+			if _, err := probeNode(gatewayClient, config, name); err == nil {
+				healthy[org] = name
+				return true
+			}
+		}
+		return false
+	}
+
+	orgs, err := MinimalOrgs(policy, available)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "select endorsers for contract %s error", config.ContractName)
+	}
+	endorsers := make([]string, len(orgs))
+	for i, org := range orgs {
+		endorsers[i] = healthy[org]
+	}
+	return endorsers, nil
+}
+
+// probeNode 查询节点的最新链状态，检查节点是否可用，返回节点的区块高度
+func probeNode(gatewayClient *client.GatewayClient, config Config, nodeName string) (uint64, error) {
+	rawMsg, err := gatewayClient.QueryRawMessage.BuildLatestChainStateRawMessage(config.ChainID)
+	if err != nil {
+		return 0, errors.WithMessage(err, "build latest chain state raw message error")
+	}
+	responseMsg, err := gatewayClient.Nodes[nodeName].QueryAction.GetLatestChainState(rawMsg)
+	if err != nil {
+		return 0, errors.WithMessage(err, "query action get latest chain state error")
+	}
+	payload, err := GetPayloadWithResp(responseMsg)
+	if err != nil {
+		return 0, err
+	}
+	latestChainState := &nodeservice.LatestChainState{}
+	if err := proto.Unmarshal(payload, latestChainState); err != nil {
+		return 0, errors.WithMessage(err, "unmarshal latest chain state error")
+	}
+	return latestChainState.Height, nil
+}
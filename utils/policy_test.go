@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"git.huawei.com/huaweichain/proto/common"
+	"github.com/gogo/protobuf/proto"
+)
+
+func Test_MinimalOrgs(t *testing.T) {
+	tests := []struct {
+		policy string
+		down   string
+		want   []string
+	}{
+		{"org1", "", []string{"org1"}},
+		{"org1 & org2 | org3", "", []string{"org3"}},
+		{"org1 AND (org2 OR org3)", "", []string{"org1", "org2"}},
+		{"org1 AND (org2 OR org3)", "org2", []string{"org1", "org3"}},
+		{"(org1 | org2) & (org1 | org3)", "", []string{"org1"}},
+		{"org1.member & org2.member", "", []string{"org1", "org2"}},
+		{"org1 | org2", "org2", []string{"org1"}},
+		{"org1 & org2", "org2", nil},
+	}
+	for _, tt := range tests {
+		policy, err := ParsePolicy([]byte(tt.policy))
+		if err != nil {
+			t.Fatalf("parse policy %q error: %v", tt.policy, err)
+		}
+		orgs, err := MinimalOrgs(policy, func(org string) bool { return org != tt.down })
+		if !reflect.DeepEqual(orgs, tt.want) || (err == nil) != (tt.want != nil) {
+			t.Errorf("policy %q with %q down: got %v %v, want %v", tt.policy, tt.down, orgs, err, tt.want)
+		}
+	}
+}
+
+func Test_MissingOrgs(t *testing.T) {
+	tests := []struct {
+		policy string
+		orgs   []string
+		want   []string
+	}{
+		{"org1 & org2 & org3", []string{"org2"}, []string{"org1", "org3"}},
+		{"org1 & org2 | org3 & org4", []string{"org3"}, []string{"org4"}},
+		{"org1 | org2", []string{"org2"}, nil},
+		{"org1 & (org2 | org3)", nil, []string{"org1", "org2"}},
+	}
+	for _, tt := range tests {
+		policy, err := ParsePolicy([]byte(tt.policy))
+		if err != nil {
+			t.Fatalf("parse policy %q error: %v", tt.policy, err)
+		}
+		if missing := MissingOrgs(policy, tt.orgs); !reflect.DeepEqual(missing, tt.want) {
+			t.Errorf("policy %q with %v: got %v, want %v", tt.policy, tt.orgs, missing, tt.want)
+		}
+	}
+}
+
+func Test_ParsePolicy(t *testing.T) {
+	for _, bad := range []string{"", "org1 &", "(org1 | org2", "org1 org2", "| org1"} {
+		if _, err := ParsePolicy([]byte(bad)); err == nil {
+			t.Errorf("expected error for policy %q", bad)
+		}
+	}
+	// 序列化的common.Policy
+	raw, _ := proto.Marshal(&common.Policy{Ast: &common.Policy_NonTerminal{NonTerminal: &common.NonTerminal{
+		Op:         common.OR,
+		LeftToken:  &common.Policy{Ast: &common.Policy_Terminal{Terminal: &common.Terminal{Token: "org1"}}},
+		RightToken: &common.Policy{Ast: &common.Policy_Terminal{Terminal: &common.Terminal{Token: "org2"}}},
+	}}})
+	policy, err := ParsePolicy(raw)
+	if err != nil || !reflect.DeepEqual(policyAlternatives(policy), [][]string{{"org1"}, {"org2"}}) {
+		t.Errorf("parse serialized policy: %v %v", policy, err)
+	}
+	if NodeOrg("node-0.organization-b4fydwesq") != "organization-b4fydwesq" || NodeOrg("node-0") != "" {
+		t.Error("unexpected node org")
+	}
+}
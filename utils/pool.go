@@ -0,0 +1,331 @@
+// pool.go 节点健康检查、熔断和故障切换
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"git.huawei.com/huaweichain/sdk/client"
+	"git.huawei.com/huaweichain/sdk/node"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 节点的熔断状态
+const (
+	NodeHealthy  = "healthy"   // 正常
+	NodeOpen     = "open"      // 连续失败达到阈值，熔断期间不参与选择
+	NodeHalfOpen = "half-open" // 熔断时间已过，只放行一个试探调用，成功后恢复，失败后重新熔断
+)
+
+const (
+	defaultFailureThreshold = 3
+	defaultBreakerTimeout   = 30 * time.Second
+)
+
+// PoolOptions 节点池配置
+type PoolOptions struct {
+	ProbeInterval    time.Duration // 主动探测间隔，为0时不主动探测
+	FailureThreshold int           // 连续失败次数达到该值后熔断，为0时使用3
+	BreakerTimeout   time.Duration // 熔断持续时间，为0时使用30秒
+}
+
+// NodeHealth 节点健康状态
+type NodeHealth struct {
+	Name                string `json:"name"`
+	Org                 string `json:"org"`
+	State               string `json:"state"`                 // healthy、open 或 half-open
+	ConsecutiveFailures int    `json:"consecutiveFailures"`   // 连续失败次数
+	TotalFailures       uint64 `json:"totalFailures"`         // 累计失败次数
+	LastError           string `json:"lastError,omitempty"`   // 最近一次失败的原因
+	LastSuccess         string `json:"lastSuccess,omitempty"` // 最近一次成功的时间
+	LastFailure         string `json:"lastFailure,omitempty"` // 最近一次失败的时间
+	Height              uint64 `json:"height"`                // 最近一次探测到的区块高度
+}
+
+// breaker 单个节点的失败计数和熔断状态
+type breaker struct {
+	failures    int
+	total       uint64
+	openedAt    time.Time
+	lastErr     string
+	lastSuccess time.Time
+	lastFailure time.Time
+	height      uint64
+	probing     bool // 半开状态下的试探调用尚未返回
+}
+
+func (b *breaker) state(opts PoolOptions, now time.Time) string {
+	if b.failures < opts.FailureThreshold {
+		return NodeHealthy
+	}
+	if now.Sub(b.openedAt) >= opts.BreakerTimeout {
+		return NodeHalfOpen
+	}
+	return NodeOpen
+}
+
+func (b *breaker) success(now time.Time) {
+	b.failures = 0
+	b.lastSuccess = now
+	b.probing = false
+}
+
+func (b *breaker) failure(opts PoolOptions, now time.Time, err error) {
+	b.failures++
+	b.total++
+	b.lastErr = err.Error()
+	b.lastFailure = now
+	b.probing = false
+	// 达到阈值或半开状态下再次失败时重新计算熔断时间
+	if b.failures >= opts.FailureThreshold {
+		b.openedAt = now
+	}
+}
+
+// NodePool GatewayClient.Nodes中全部节点的健康状态，调用结果被动计入失败次数，
+// 设置探测间隔时定期查询各节点的最新链状态，并发安全
+type NodePool struct {
+	client  *client.GatewayClient
+	chainID string
+	opts    PoolOptions
+	now     func() time.Time
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewNodePool 创建节点池，需调用Start开始主动探测
+func NewNodePool(gatewayClient *client.GatewayClient, chainID string, opts PoolOptions) *NodePool {
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = defaultFailureThreshold
+	}
+	if opts.BreakerTimeout <= 0 {
+		opts.BreakerTimeout = defaultBreakerTimeout
+	}
+	p := &NodePool{
+		client:   gatewayClient,
+		chainID:  chainID,
+		opts:     opts,
+		now:      time.Now,
+		breakers: make(map[string]*breaker),
+		done:     make(chan struct{}),
+	}
+	for name := range gatewayClient.Nodes {
+		p.breakers[name] = &breaker{}
+	}
+	return p
+}
+
+// Start 按探测间隔定期探测全部节点，探测间隔为0时不做任何事
+func (p *NodePool) Start() {
+	if p.opts.ProbeInterval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(p.opts.ProbeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.Probe()
+			case <-p.done:
+				return
+			}
+		}
+	}()
+}
+
+// Close 停止主动探测，可重复调用
+func (p *NodePool) Close() {
+	p.closeOnce.Do(func() {
+		close(p.done)
+	})
+}
+
+// Probe 并行查询全部节点的最新链状态并记录结果
+func (p *NodePool) Probe() {
+	var wg sync.WaitGroup
+	for _, name := range p.names() {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			height, err := probeNode(p.client, Config{ChainID: p.chainID}, name)
+			if err != nil {
+				p.Failure(name, err)
+				return
+			}
+			p.mu.Lock()
+			p.breakers[name].height = height
+			p.mu.Unlock()
+			p.Success(name)
+		}(name)
+	}
+	wg.Wait()
+}
+
+// Available 节点是否可以参与选择，熔断中的节点不可用
+func (p *NodePool) Available(name string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	b, ok := p.breakers[name]
+	return ok && b.state(p.opts, p.now()) != NodeOpen
+}
+
+// acquire 节点能否执行本次调用：熔断中的节点不能调用；半开状态只放行一个试探调用，
+// 试探调用返回前其它调用跳过该节点
+func (p *NodePool) acquire(name string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	b, ok := p.breakers[name]
+	if !ok {
+		return false
+	}
+	switch b.state(p.opts, p.now()) {
+	case NodeOpen:
+		return false
+	case NodeHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+	}
+	return true
+}
+
+// release 试探调用被取消、没有结果时放弃试探，下一次调用重新试探
+func (p *NodePool) release(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if b, ok := p.breakers[name]; ok {
+		b.probing = false
+	}
+}
+
+// Success 记录一次成功的调用，清除连续失败次数
+func (p *NodePool) Success(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if b, ok := p.breakers[name]; ok {
+		b.success(p.now())
+	}
+}
+
+// Failure 记录一次失败的调用，连续失败达到阈值后熔断
+func (p *NodePool) Failure(name string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if b, ok := p.breakers[name]; ok {
+		b.failure(p.opts, p.now(), err)
+	}
+}
+
+// Candidates 节点本身及同组织的其它节点，同组织的节点按名称排序
+func (p *NodePool) Candidates(primary string) []string {
+	candidates := []string{primary}
+	org := NodeOrg(primary)
+	if org == "" {
+		return candidates
+	}
+	for _, name := range p.names() {
+		if name != primary && NodeOrg(name) == org {
+			candidates = append(candidates, name)
+		}
+	}
+	return candidates
+}
+
+// Do 依次在primary及同组织的可用节点上执行call，直到调用成功，返回实际使用的节点；
+// 只有连接、传输错误和gRPC的Unavailable、DeadlineExceeded计为节点失败并切换节点，
+// 合约执行失败等节点已正常响应的错误直接返回
+func (p *NodePool) Do(primary string, call func(n *node.WNode) error) (string, error) {
+	return p.DoCtx(context.Background(), primary, call)
+}
+
+// DoCtx 与Do相同，ctx取消后call返回的错误不计为节点失败，也不再切换节点
+func (p *NodePool) DoCtx(ctx context.Context, primary string, call func(n *node.WNode) error) (string, error) {
+	var failures []string
+	for _, name := range p.Candidates(primary) {
+		n, ok := p.client.Nodes[name]
+		if !ok || !p.acquire(name) {
+			continue
+		}
+		if err := call(n); err != nil {
+			if ctx.Err() != nil {
+				p.release(name)
+				return name, errors.WithMessagef(err, "call on node %s canceled", name)
+			}
+			if !isNodeFailure(err) {
+				p.Success(name)
+				return name, errors.WithMessagef(err, "call on node %s failed", name)
+			}
+			p.Failure(name, err)
+			failures = append(failures, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		p.Success(name)
+		return name, nil
+	}
+	if len(failures) == 0 {
+		return "", errors.Errorf("no available node among %s", strings.Join(p.Candidates(primary), ","))
+	}
+	return "", errors.Errorf("all nodes failed, %s", strings.Join(failures, "; "))
+}
+
+// isNodeFailure 错误是否说明节点不可用：gRPC的Unavailable、DeadlineExceeded，以及不是gRPC状态的连接、传输错误；
+// 节点返回的其它gRPC状态（如合约执行失败、参数错误）说明节点可以正常响应，不计为节点失败
+func isNodeFailure(err error) bool {
+	s, ok := status.FromError(errors.Cause(err))
+	if !ok {
+		return true
+	}
+	return s.Code() == codes.Unavailable || s.Code() == codes.DeadlineExceeded
+}
+
+// Status 全部节点的健康状态，按节点名称排序
+func (p *NodePool) Status() []NodeHealth {
+	names := p.names()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	result := make([]NodeHealth, 0, len(names))
+	for _, name := range names {
+		b := p.breakers[name]
+		health := NodeHealth{
+			Name:                name,
+			Org:                 NodeOrg(name),
+			State:               b.state(p.opts, now),
+			ConsecutiveFailures: b.failures,
+			TotalFailures:       b.total,
+			LastError:           b.lastErr,
+			Height:              b.height,
+		}
+		if !b.lastSuccess.IsZero() {
+			health.LastSuccess = FormatTime(b.lastSuccess)
+		}
+		if !b.lastFailure.IsZero() {
+			health.LastFailure = FormatTime(b.lastFailure)
+		}
+		result = append(result, health)
+	}
+	return result
+}
+
+func (p *NodePool) names() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	names := make([]string, 0, len(p.breakers))
+	for name := range p.breakers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
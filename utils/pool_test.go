@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"git.huawei.com/huaweichain/sdk/client"
+	"git.huawei.com/huaweichain/sdk/node"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_NodePool(t *testing.T) {
+	gatewayClient := &client.GatewayClient{Nodes: map[string]*node.WNode{
+		"node-0.org1": {}, "node-1.org1": {}, "node-0.org2": {},
+	}}
+	pool := NewNodePool(gatewayClient, "chain", PoolOptions{FailureThreshold: 2, BreakerTimeout: time.Minute})
+	now := time.Unix(1600000000, 0)
+	pool.now = func() time.Time { return now }
+
+	if got := pool.Candidates("node-1.org1"); len(got) != 2 || got[0] != "node-1.org1" || got[1] != "node-0.org1" {
+		t.Errorf("unexpected candidates: %v", got)
+	}
+
+	// 连续失败达到阈值后熔断，调用切换到同组织的其它节点
+	failed := errors.New("connection refused")
+	var called []string
+	call := func(name string) func(n *node.WNode) error {
+		return func(n *node.WNode) error {
+			called = append(called, name)
+			if n == gatewayClient.Nodes["node-0.org1"] {
+				return failed
+			}
+			return nil
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if used, err := pool.Do("node-0.org1", call("attempt")); err != nil || used != "node-1.org1" {
+			t.Fatalf("do %d: %s %v", i, used, err)
+		}
+	}
+	if pool.Available("node-0.org1") || len(called) != 4 {
+		t.Fatalf("node should be open after 2 failures, calls %v", called)
+	}
+	called = nil
+	if used, _ := pool.Do("node-0.org1", call("open")); used != "node-1.org1" || len(called) != 1 {
+		t.Errorf("open node should be skipped: %s %v", used, called)
+	}
+
+	// 熔断时间过后进入半开状态，再次失败时重新熔断，成功后恢复
+	now = now.Add(time.Minute)
+	if status := pool.Status(); status[0].State != NodeHalfOpen || status[0].ConsecutiveFailures != 2 {
+		t.Errorf("unexpected status: %+v", status[0])
+	}
+	pool.Failure("node-0.org1", failed)
+	if pool.Available("node-0.org1") {
+		t.Error("node should be open again after failing in half-open state")
+	}
+	now = now.Add(time.Minute)
+	pool.Success("node-0.org1")
+	if status := pool.Status(); status[0].State != NodeHealthy || status[0].TotalFailures != 3 || status[0].LastError != failed.Error() {
+		t.Errorf("unexpected status after recovery: %+v", status[0])
+	}
+
+	// 没有同组织的节点时直接返回错误
+	if _, err := pool.Do("node-0.org2", func(n *node.WNode) error { return failed }); err == nil {
+		t.Error("expected error when all candidates fail")
+	}
+}
+
+func Test_NodePool_HalfOpenProbe(t *testing.T) {
+	gatewayClient := &client.GatewayClient{Nodes: map[string]*node.WNode{"node-0.org1": {}, "node-1.org1": {}}}
+	pool := NewNodePool(gatewayClient, "chain", PoolOptions{FailureThreshold: 1, BreakerTimeout: time.Minute})
+	now := time.Unix(1600000000, 0)
+	pool.now = func() time.Time { return now }
+	pool.Failure("node-0.org1", status.Error(codes.Unavailable, "unavailable"))
+	now = now.Add(time.Minute)
+
+	// 半开状态只放行一个试探调用，试探返回前其它调用切换到同组织的其它节点
+	entered, done := make(chan struct{}), make(chan struct{})
+	probe := make(chan error, 1)
+	go func() {
+		_, err := pool.Do("node-0.org1", func(n *node.WNode) error {
+			close(entered)
+			<-done
+			return nil
+		})
+		probe <- err
+	}()
+	<-entered
+	var called []*node.WNode
+	used, err := pool.Do("node-0.org1", func(n *node.WNode) error {
+		called = append(called, n)
+		return nil
+	})
+	if err != nil || used != "node-1.org1" || len(called) != 1 {
+		t.Errorf("concurrent call during probe: %s %v %d", used, err, len(called))
+	}
+	close(done)
+	if err := <-probe; err != nil {
+		t.Fatalf("probe error: %v", err)
+	}
+	if status := pool.Status(); status[0].State != NodeHealthy {
+		t.Errorf("node should recover after a successful probe: %+v", status[0])
+	}
+}
+
+func Test_NodePool_NodeFailure(t *testing.T) {
+	gatewayClient := &client.GatewayClient{Nodes: map[string]*node.WNode{"node-0.org1": {}, "node-1.org1": {}}}
+	pool := NewNodePool(gatewayClient, "chain", PoolOptions{FailureThreshold: 1, BreakerTimeout: time.Minute})
+
+	// 合约执行失败说明节点可以正常响应，直接返回错误，不切换节点也不熔断
+	calls := 0
+	contractErr := status.Error(codes.Unknown, "contract execute failed")
+	used, err := pool.Do("node-0.org1", func(n *node.WNode) error {
+		calls++
+		return contractErr
+	})
+	if err == nil || used != "node-0.org1" || calls != 1 || !pool.Available("node-0.org1") {
+		t.Errorf("contract error should not trip the breaker: %s %v %d", used, err, calls)
+	}
+
+	for _, err := range []error{
+		status.Error(codes.Unavailable, "unavailable"),
+		status.Error(codes.DeadlineExceeded, "deadline exceeded"),
+		errors.New("grpc dial context error"),
+	} {
+		if !isNodeFailure(err) {
+			t.Errorf("%v should count as a node failure", err)
+		}
+	}
+	for _, err := range []error{contractErr, status.Error(codes.InvalidArgument, "invalid argument")} {
+		if isNodeFailure(err) {
+			t.Errorf("%v should not count as a node failure", err)
+		}
+	}
+}
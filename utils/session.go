@@ -0,0 +1,186 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"git.huawei.com/huaweichain/proto/common"
+	"git.huawei.com/huaweichain/sdk/action/event"
+	"git.huawei.com/huaweichain/sdk/client"
+	"git.huawei.com/huaweichain/sdk/node"
+	"github.com/pkg/errors"
+)
+
+// Session 链会话，服务启动时创建一次，持有网关客户端、节点网络和共享的交易事件服务，
+// 可被多个请求并发使用。节点熔断或调用失败时，背书、提交交易和事件订阅自动切换到同组织的其它可用节点
+type Session struct {
+	Client *client.GatewayClient
+	Nodes  *Nodes
+	Config Config
+
+	// txEvent 为所有请求共享的交易事件服务，其底层gRPC流不支持并发发送，注册交易时需持有txMu；
+	// listener 为当前订阅交易事件的节点
+	txEvent  *event.TxEventService
+	listener string
+	txMu     sync.Mutex
+
+	closeOnce sync.Once
+}
+
+// NewSession 根据配置创建链会话
+func NewSession(config Config) (*Session, error) {
+	gatewayClient, err := client.NewGatewayClient(config.ConfigFilePath)
+	if err != nil {
+		return nil, errors.WithMessage(err, "init new gateway client error")
+	}
+	// 未配置背书节点时按合约背书策略选择
+	if config.EndorserNodes == "" {
+		endorsers, err := SelectEndorsers(gatewayClient, config)
+		if err != nil {
+			gatewayClient.Close()
+			return nil, errors.WithMessage(err, "select endorsers error")
+		}
+		config.EndorserNodes = strings.Join(endorsers, ",")
+	}
+	net, err := NewNodes(gatewayClient, strings.Split(config.EndorserNodes, ","), config.ConsensusNode)
+	if err != nil {
+		gatewayClient.Close()
+		return nil, errors.WithMessage(err, "new nodes network error")
+	}
+	net.Pool = NewNodePool(gatewayClient, config.ChainID, PoolOptions{
+		ProbeInterval:    config.ProbeInterval,
+		FailureThreshold: config.FailureThreshold,
+		BreakerTimeout:   config.BreakerTimeout,
+	})
+	s := &Session{
+		Client: gatewayClient,
+		Nodes:  net,
+		Config: config,
+	}
+	if err := s.subscribe(); err != nil {
+		gatewayClient.Close()
+		return nil, err
+	}
+	net.Pool.Start()
+	return s, nil
+}
+
+// subscribe 在事件节点或同组织的可用节点上建立交易事件服务，替换原有的服务，调用时需持有txMu
+func (s *Session) subscribe() error {
+	var txEvent *event.TxEventService
+	listener, err := s.Nodes.do(context.Background(), s.Nodes.EventListener, func(n *node.WNode) (err error) {
+		txEvent, err = n.EventAction.GetTxEventService(s.Config.ChainID)
+		return err
+	})
+	if err != nil {
+		return errors.WithMessage(err, "event action get tx event service error")
+	}
+	// 等待中的交易转移到新的事件服务，避免关闭旧服务后收不到结果
+	if s.txEvent != nil {
+		s.txEvent.Handover(txEvent)
+		s.txEvent.Close()
+	}
+	s.txEvent, s.listener = txEvent, listener
+	return nil
+}
+
+// RegisterTx 在共享的交易事件服务上注册交易哈希，返回交易结果通道。
+// 事件流断开时交易事件服务自动重连，无法恢复、事件节点熔断或注册失败时先在其它可用节点上重新订阅
+func (s *Session) RegisterTx(txHash []byte) (chan *common.TxResult, error) {
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+	// 事件流无法恢复时计为事件节点的失败，在可用节点上重新订阅
+	lost := s.txEvent.Err()
+	if lost != nil {
+		s.Nodes.Pool.Failure(s.listener, lost)
+	}
+	if lost != nil || !s.Nodes.Pool.Available(s.listener) {
+		if err := s.subscribe(); err != nil {
+			return nil, err
+		}
+	}
+	ch, err := s.txEvent.RegisterTx(txHash)
+	if err == nil {
+		return ch, nil
+	}
+	s.Nodes.Pool.Failure(s.listener, err)
+	if err := s.subscribe(); err != nil {
+		return nil, err
+	}
+	return s.txEvent.RegisterTx(txHash)
+}
+
+// UnregisterTx 不再等待交易结果，交易提交失败或等待超时时调用，避免注册一直留在共享的交易事件服务上
+func (s *Session) UnregisterTx(txHash []byte) {
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+	s.txEvent.UnregisterTx(txHash)
+}
+
+// EventListener 当前订阅交易事件的节点
+func (s *Session) EventListener() string {
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+	return s.listener
+}
+
+// NodeHealth 全部节点的健康状态
+func (s *Session) NodeHealth() []NodeHealth {
+	return s.Nodes.Pool.Status()
+}
+
+// Send 发送一笔交易并等待落块，返回交易响应和交易哈希
+func (s *Session) Send(funcName string, args string) (*common.RawMessage, string, error) {
+	return s.SendCtx(context.Background(), funcName, args)
+}
+
+// SendCtx 与Send相同，ctx取消时中止背书且不提交交易，交易提交后等待落块不受ctx影响
+func (s *Session) SendCtx(ctx context.Context, funcName string, args string) (*common.RawMessage, string, error) {
+	return send(ctx, s.Client, s.Nodes, s.Config, s, funcName, args, nil)
+}
+
+// SendWithProgress 与Send相同，并在背书完成和交易提交后通过progress回报进度
+func (s *Session) SendWithProgress(funcName string, args string, progress TxProgress) (*common.RawMessage, string, error) {
+	return s.SendWithProgressCtx(context.Background(), funcName, args, progress)
+}
+
+// SendWithProgressCtx 与SendWithProgress相同，ctx的作用同SendCtx
+func (s *Session) SendWithProgressCtx(ctx context.Context, funcName string, args string, progress TxProgress) (*common.RawMessage, string, error) {
+	return send(ctx, s.Client, s.Nodes, s.Config, s, funcName, args, progress)
+}
+
+// QueryTxResult 查询交易落块结果，交易尚未落块时返回错误
+func (s *Session) QueryTxResult(txHash string) (*common.TxResult, error) {
+	return s.QueryTxResultCtx(context.Background(), txHash)
+}
+
+// QueryTxResultCtx 与QueryTxResult相同，ctx取消时立即返回
+func (s *Session) QueryTxResultCtx(ctx context.Context, txHash string) (*common.TxResult, error) {
+	txTool := TxTool{}
+	return txTool.QueryTxResultByTxIDCtx(ctx, s.Client, s.Config, txHash)
+}
+
+// Query 调用合约查询函数，不产生交易
+func (s *Session) Query(funcName string, args string) (string, error) {
+	return s.QueryCtx(context.Background(), funcName, args)
+}
+
+// QueryCtx 与Query相同，ctx取消时立即返回
+func (s *Session) QueryCtx(ctx context.Context, funcName string, args string) (string, error) {
+	return QueryCtx(ctx, s.Client, s.Nodes, s.Config, funcName, args)
+}
+
+// Close 关闭交易事件服务和所有节点连接，可重复调用
+func (s *Session) Close() {
+	s.closeOnce.Do(func() {
+		s.Nodes.Pool.Close()
+		s.txMu.Lock()
+		s.txEvent.Close()
+		s.txMu.Unlock()
+		if err := s.Client.Close(); err != nil {
+			fmt.Println("close gateway client error:", err)
+		}
+	})
+}
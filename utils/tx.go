@@ -2,6 +2,7 @@
 package utils
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
 
@@ -215,6 +216,14 @@ func (tt *TxTool) PrintTxInfo(tx common.Tx) {
  *  @return 若common.TxResult common.TxResult.Status == Valid && len(common.TxResult.TxHash)!=0，则交易执行成功
  */
 func (tt *TxTool) QueryTxResultByTxID(gatewayClient *client.GatewayClient, config Config, txID string) (*common.TxResult, error) {
+	return tt.QueryTxResultByTxIDCtx(context.Background(), gatewayClient, config, txID)
+}
+
+/**
+ *  QueryTxResultByTxIDCtx
+ *  @Description: 与QueryTxResultByTxID相同，ctx取消时立即返回，ctx未设置截止时间时使用SDK的超时时间
+ */
+func (tt *TxTool) QueryTxResultByTxIDCtx(ctx context.Context, gatewayClient *client.GatewayClient, config Config, txID string) (*common.TxResult, error) {
 	// 1.入参处理
 	txHash, err := hex.DecodeString(txID)
 	if err != nil {
@@ -235,7 +244,7 @@ func (tt *TxTool) QueryTxResultByTxID(gatewayClient *client.GatewayClient, confi
 	}
 
 	// 4.消息发送
-	responseMsg, err := node.QueryAction.GetTxResultByTxHash(rawMsg)
+	responseMsg, err := node.QueryAction.GetTxResultByTxHashCtx(ctx, rawMsg)
 	if err != nil {
 		return nil, errors.WithMessage(err, "query action get tx result by tx id error")
 	}
@@ -260,6 +269,14 @@ func (tt *TxTool) QueryTxResultByTxID(gatewayClient *client.GatewayClient, confi
  *  @return common.Tx 返回交易结构体
  */
 func (tt *TxTool) QueryTxByTxID(gatewayClient *client.GatewayClient, config Config, txID string) (*common.Tx, error) {
+	return tt.QueryTxByTxIDCtx(context.Background(), gatewayClient, config, txID)
+}
+
+/**
+ *  QueryTxByTxIDCtx
+ *  @Description: 与QueryTxByTxID相同，ctx取消时立即返回，ctx未设置截止时间时使用SDK的超时时间
+ */
+func (tt *TxTool) QueryTxByTxIDCtx(ctx context.Context, gatewayClient *client.GatewayClient, config Config, txID string) (*common.Tx, error) {
 	// 1.入参处理
 	txHash, err := hex.DecodeString(txID)
 	if err != nil {
@@ -280,7 +297,7 @@ func (tt *TxTool) QueryTxByTxID(gatewayClient *client.GatewayClient, config Conf
 	}
 
 	// 4.消息发送
-	responseMsg, err := node.QueryAction.GetTxByHash(rawMsg)
+	responseMsg, err := node.QueryAction.GetTxByHashCtx(ctx, rawMsg)
 	if err != nil {
 		return nil, errors.WithMessage(err, "query action get tx by tx id error")
 	}
@@ -0,0 +1,350 @@
+// Package webhook 存证交易落块后的回调通知，回调先写入发件箱再投递，失败时按指数退避重试
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"git.huawei.com/goclient/store"
+	"github.com/pkg/errors"
+)
+
+// 回调请求头
+const (
+	HeaderSignature = "X-CD-Signature" // 签名，hex(HMAC-SHA256(secret, timestamp + "." + body))
+	HeaderTimestamp = "X-CD-Timestamp" // 签名时间（Unix秒），接收方可据此拒绝过旧的请求
+	HeaderDelivery  = "X-CD-Delivery"  // 投递ID，重试时保持不变，接收方可据此去重
+)
+
+// Payload 回调内容
+type Payload struct {
+	TxHash      string `json:"txHash"`
+	BlockHeight uint64 `json:"blockHeight"`
+	Status      string `json:"status"`    // VALID 或链上返回的其它校验状态
+	Timestamp   int64  `json:"timestamp"` // 观察到交易落块的时间（Unix秒）
+}
+
+// Outbox 回调发件箱，由store.DepositStore实现
+type Outbox interface {
+	SaveDelivery(delivery store.Delivery) error
+	ListDueDeliveries(now int64, limit int) ([]store.Delivery, error)
+}
+
+// Options 投递参数，零值字段使用默认值
+type Options struct {
+	Timeout      time.Duration // 单次请求超时，默认10s
+	MaxAttempts  int           // 最多投递次数，默认8
+	Backoff      time.Duration // 首次重试间隔，之后每次翻倍，默认5s
+	MaxBackoff   time.Duration // 重试间隔上限，默认10m
+	PollInterval time.Duration // 扫描发件箱的间隔，默认1s
+	AllowedHosts []string      // 允许使用回环、私有和链路本地地址的回调主机（域名或IP），用于内网的接收方
+	TLSConfig    *tls.Config   // 回调请求的TLS配置，为空时使用系统根证书
+}
+
+// Dispatcher 回调投递器
+type Dispatcher struct {
+	outbox  Outbox
+	secret  []byte
+	opts    Options
+	allowed map[string]bool
+	client  *http.Client
+
+	wake     chan struct{}
+	quit     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// New 创建回调投递器，需调用Start后才会投递
+func New(outbox Outbox, secret string, opts Options) *Dispatcher {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 8
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 5 * time.Second
+	}
+	if opts.MaxBackoff < opts.Backoff {
+		opts.MaxBackoff = 10 * time.Minute
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	allowed := make(map[string]bool, len(opts.AllowedHosts))
+	for _, host := range opts.AllowedHosts {
+		allowed[strings.ToLower(host)] = true
+	}
+	d := &Dispatcher{
+		outbox:  outbox,
+		secret:  []byte(secret),
+		opts:    opts,
+		allowed: allowed,
+		wake:    make(chan struct{}, 1),
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	d.client = &http.Client{
+		Timeout: opts.Timeout,
+		// 不使用代理，建立连接时校验的就是接收方的实际地址
+		Transport: &http.Transport{
+			DialContext:         d.dialContext,
+			TLSClientConfig:     opts.TLSConfig,
+			TLSHandshakeTimeout: opts.Timeout,
+		},
+		// 不跟随重定向，避免被接收方重定向到内网地址
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return d
+}
+
+// 不允许回调的私有网段，回环、链路本地和组播地址由net.IP的方法判断
+var privateNets = func() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range []string{"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"} {
+		_, n, _ := net.ParseCIDR(cidr)
+		nets = append(nets, n)
+	}
+	return nets
+}()
+
+// 是否为可以回调的公网地址
+func publicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, n := range privateNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL 校验回调地址：只支持https，主机不在AllowedHosts中时不能是localhost或回环、私有、链路本地地址。
+// 域名解析出的地址在每次投递建立连接时校验
+func (d *Dispatcher) CheckURL(callbackURL string) error {
+	u, err := url.Parse(callbackURL)
+	if err != nil {
+		return errors.WithMessage(err, "parse callback url error")
+	}
+	if u.Scheme != "https" || u.Hostname() == "" {
+		return errors.Errorf("callback url %s is not an https url", callbackURL)
+	}
+	host := strings.ToLower(u.Hostname())
+	if d.allowed[host] {
+		return nil
+	}
+	if ip := net.ParseIP(host); (ip != nil && !publicIP(ip)) || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errors.Errorf("callback host %s is not allowed", host)
+	}
+	return nil
+}
+
+// dialContext 主机不在AllowedHosts中时，拒绝连接解析出的非公网地址，防止域名指向内网地址
+func (d *Dispatcher) dialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: d.opts.Timeout}
+	if !d.allowed[strings.ToLower(host)] {
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			ip, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if parsed := net.ParseIP(ip); parsed == nil || !publicIP(parsed) {
+				return errors.Errorf("callback address %s is not a public address", ip)
+			}
+			return nil
+		}
+	}
+	return dialer.DialContext(ctx, network, address)
+}
+
+// Enqueue 将回调写入发件箱，写入成功即返回，由后台协程投递
+func (d *Dispatcher) Enqueue(callbackURL string, payload Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errors.WithMessage(err, "marshal webhook payload error")
+	}
+	id, err := newDeliveryID()
+	if err != nil {
+		return errors.WithMessage(err, "new delivery id error")
+	}
+	now := time.Now()
+	delivery := store.Delivery{
+		ID:          id,
+		URL:         callbackURL,
+		Payload:     string(body),
+		State:       store.DeliveryPending,
+		NextAttempt: now.UnixNano() / int64(time.Millisecond),
+		UpdatedAt:   now.Unix(),
+	}
+	if err := d.outbox.SaveDelivery(delivery); err != nil {
+		return err
+	}
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Start 启动后台投递协程，发件箱中上次未投递完成的回调会继续投递
+func (d *Dispatcher) Start() {
+	go d.run()
+}
+
+// Stop 停止投递，正在进行的请求结束或ctx到期后返回
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	d.stopOnce.Do(func() {
+		close(d.quit)
+	})
+	select {
+	case <-d.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sign 计算回调签名，接收方使用相同的密钥、X-CD-Timestamp和请求体验签
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify 校验回调签名
+func Verify(secret []byte, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		d.deliverDue()
+		select {
+		case <-d.quit:
+			return
+		case <-d.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Dispatcher) deliverDue() {
+	deliveries, err := d.outbox.ListDueDeliveries(time.Now().UnixNano()/int64(time.Millisecond), 100)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for i := range deliveries {
+		select {
+		case <-d.quit:
+			return
+		default:
+		}
+		d.deliver(&deliveries[i])
+	}
+}
+
+// 投递一次并记录结果，非2xx响应和网络错误都会重试
+func (d *Dispatcher) deliver(delivery *store.Delivery) {
+	err := d.post(delivery)
+	now := time.Now()
+	delivery.Attempts++
+	delivery.UpdatedAt = now.Unix()
+	switch {
+	case err == nil:
+		delivery.State, delivery.LastError = store.DeliveryDelivered, ""
+	case delivery.Attempts >= d.opts.MaxAttempts:
+		delivery.State, delivery.LastError = store.DeliveryFailed, truncate(err.Error(), 255)
+	default:
+		delivery.LastError = truncate(err.Error(), 255)
+		delivery.NextAttempt = now.Add(d.backoff(delivery.Attempts)).UnixNano() / int64(time.Millisecond)
+	}
+	if err := d.outbox.SaveDelivery(*delivery); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func (d *Dispatcher) post(delivery *store.Delivery) error {
+	// 发件箱中的回调可能写入于配置变更前，投递前重新校验
+	if err := d.CheckURL(delivery.URL); err != nil {
+		return err
+	}
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return errors.WithMessage(err, "new webhook request error")
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(d.secret, timestamp, body))
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return errors.WithMessage(err, "post webhook error")
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("webhook receiver responded %s", resp.Status)
+	}
+	return nil
+}
+
+// 第n次失败后的重试间隔
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.opts.Backoff
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if wait >= d.opts.MaxBackoff {
+			return d.opts.MaxBackoff
+		}
+	}
+	return wait
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+func newDeliveryID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,206 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"git.huawei.com/goclient/store"
+)
+
+const testSecret = "webhook-secret"
+
+// receiver 回调接收方，前failures次返回500
+type receiver struct {
+	mu       sync.Mutex
+	failures int
+	calls    int
+	payloads []Payload
+	ids      map[string]int
+	badSign  int
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	r.ids[req.Header.Get(HeaderDelivery)]++
+	if !Verify([]byte(testSecret), req.Header.Get(HeaderTimestamp), body, req.Header.Get(HeaderSignature)) {
+		r.badSign++
+	}
+	if r.calls <= r.failures {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	var payload Payload
+	json.Unmarshal(body, &payload)
+	r.payloads = append(r.payloads, payload)
+}
+
+func (r *receiver) snapshot() (int, []Payload) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls, append([]Payload(nil), r.payloads...)
+}
+
+// 启动https接收方，返回信任其证书并允许回调回环地址的投递参数
+func newTestServer(recv *receiver) (*httptest.Server, Options) {
+	server := httptest.NewTLSServer(recv)
+	return server, Options{Timeout: time.Second, MaxAttempts: 3, Backoff: 10 * time.Millisecond, PollInterval: 5 * time.Millisecond,
+		AllowedHosts: []string{"127.0.0.1"}, TLSConfig: server.Client().Transport.(*http.Transport).TLSClientConfig}
+}
+
+func stop(t *testing.T, d *Dispatcher) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := d.Stop(ctx); err != nil {
+		t.Errorf("stop dispatcher error: %v", err)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func Test_Dispatcher_Retry(t *testing.T) {
+	recv := &receiver{failures: 2, ids: map[string]int{}}
+	server, opts := newTestServer(recv)
+	defer server.Close()
+
+	outbox := store.NewMemory()
+	d := New(outbox, testSecret, opts)
+	d.Start()
+	defer stop(t, d)
+
+	want := Payload{TxHash: "abc", BlockHeight: 12, Status: "VALID", Timestamp: 1600000000}
+	if err := d.Enqueue(server.URL, want); err != nil {
+		t.Fatalf("enqueue error: %v", err)
+	}
+	waitFor(t, func() bool {
+		_, payloads := recv.snapshot()
+		return len(payloads) == 1
+	})
+
+	calls, payloads := recv.snapshot()
+	if calls != 3 || payloads[0] != want {
+		t.Errorf("unexpected delivery, calls: %d, payloads: %v", calls, payloads)
+	}
+	if recv.badSign != 0 {
+		t.Errorf("%d requests with bad signature", recv.badSign)
+	}
+	if len(recv.ids) != 1 {
+		t.Errorf("delivery id should be stable across retries: %v", recv.ids)
+	}
+	waitFor(t, func() bool {
+		due, _ := outbox.ListDueDeliveries(time.Now().Add(time.Hour).UnixNano()/int64(time.Millisecond), 0)
+		return len(due) == 0
+	})
+}
+
+func Test_Dispatcher_GiveUp(t *testing.T) {
+	recv := &receiver{failures: 100, ids: map[string]int{}}
+	server, opts := newTestServer(recv)
+	defer server.Close()
+
+	outbox := store.NewMemory()
+	d := New(outbox, testSecret, opts)
+	d.Start()
+	defer stop(t, d)
+
+	if err := d.Enqueue(server.URL, Payload{TxHash: "abc", Status: "VALID"}); err != nil {
+		t.Fatalf("enqueue error: %v", err)
+	}
+	waitFor(t, func() bool {
+		calls, _ := recv.snapshot()
+		return calls >= 3
+	})
+	time.Sleep(50 * time.Millisecond)
+	if calls, _ := recv.snapshot(); calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls)
+	}
+	due, _ := outbox.ListDueDeliveries(time.Now().Add(time.Hour).UnixNano()/int64(time.Millisecond), 0)
+	if len(due) != 0 {
+		t.Errorf("failed delivery should not be retried: %v", due)
+	}
+}
+
+// 发件箱中已有的回调在投递器重新启动后继续投递
+func Test_Dispatcher_Resume(t *testing.T) {
+	recv := &receiver{ids: map[string]int{}}
+	server, opts := newTestServer(recv)
+	defer server.Close()
+
+	outbox := store.NewMemory()
+	outbox.SaveDelivery(store.Delivery{ID: "d1", URL: server.URL, Payload: `{"txHash":"abc","status":"VALID"}`,
+		State: store.DeliveryPending, Attempts: 1})
+
+	d := New(outbox, testSecret, opts)
+	d.Start()
+	defer stop(t, d)
+	waitFor(t, func() bool {
+		_, payloads := recv.snapshot()
+		return len(payloads) == 1
+	})
+	if recv.ids["d1"] != 1 {
+		t.Errorf("unexpected delivery ids: %v", recv.ids)
+	}
+}
+
+func Test_CheckURL(t *testing.T) {
+	d := New(store.NewMemory(), testSecret, Options{AllowedHosts: []string{"hook.internal", "10.0.0.2"}})
+	for url, want := range map[string]bool{
+		"https://loan.example.com/cd/notify": true,
+		"https://8.8.8.8/hook":               true,
+		"https://hook.internal:8443/notify":  true,
+		"https://10.0.0.2/hook":              true,
+		"http://loan.example.com/cd/notify":  false,
+		"https://10.0.0.1:8080/hook":         false,
+		"https://172.16.0.1/hook":            false,
+		"https://192.168.1.1/hook":           false,
+		"https://127.0.0.1/hook":             false,
+		"https://[::1]/hook":                 false,
+		"https://169.254.169.254/latest":     false,
+		"https://[fe80::1]/hook":             false,
+		"https://localhost/hook":             false,
+		"https://app.localhost/hook":         false,
+		"https://0.0.0.0/hook":               false,
+		"ftp://example.com":                  false,
+		"/relative":                          false,
+		"":                                   false,
+	} {
+		if err := d.CheckURL(url); (err == nil) != want {
+			t.Errorf("CheckURL(%q): %v, should be allowed: %v", url, err, want)
+		}
+	}
+}
+
+// 域名解析到内网地址时在建立连接时拒绝，发件箱中不合规的回调不会投递
+func Test_Dispatcher_PrivateAddress(t *testing.T) {
+	recv := &receiver{ids: map[string]int{}}
+	server, opts := newTestServer(recv)
+	defer server.Close()
+
+	opts.AllowedHosts = nil
+	d := New(store.NewMemory(), testSecret, opts)
+	if _, err := d.dialContext(context.Background(), "tcp", server.Listener.Addr().String()); err == nil {
+		t.Error("expected error dialing loopback address")
+	}
+	if err := d.post(&store.Delivery{ID: "d1", URL: server.URL, Payload: "{}"}); err == nil {
+		t.Error("expected error posting to loopback address")
+	}
+	if calls, _ := recv.snapshot(); calls != 0 {
+		t.Errorf("receiver on loopback address called %d times", calls)
+	}
+}